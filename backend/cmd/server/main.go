@@ -2,21 +2,26 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	goose "github.com/pressly/goose/v3"
 
 	"github.com/kevsommer/runplanner/internal/ai"
 	"github.com/kevsommer/runplanner/internal/controller"
+	"github.com/kevsommer/runplanner/internal/mail"
+	"github.com/kevsommer/runplanner/internal/oidc"
+	"github.com/kevsommer/runplanner/internal/ratelimit"
 	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/session"
 	"github.com/kevsommer/runplanner/internal/store"
 	"github.com/kevsommer/runplanner/internal/store/mem"
 	sqliteStore "github.com/kevsommer/runplanner/internal/store/sqlite"
@@ -31,10 +36,36 @@ func main() {
 	var userStore store.UserStore
 	var trainingPlanStore store.TrainingPlanStore
 	var workoutStore store.WorkoutStore
+	var activityStore store.ActivityStore
+	var generateJobStore store.GenerateJobStore
+	var coachStore store.CoachStore
+	var aiUsageStore store.AIUsageStore
+	var passwordResetStore store.PasswordResetStore
+	var emailVerificationStore store.EmailVerificationStore
+	var apiTokenStore store.APITokenStore
+	var identityStore store.IdentityStore
+	var twoFactorStore store.TwoFactorStore
+	var sessionStore store.SessionStore
+	var coachingStore store.CoachingStore
+	var planEditStore store.PlanEditStore
+	var shareLinkStore store.ShareLinkStore
 	if dbURL == "" {
 		userStore = mem.NewMemUserStore()
 		trainingPlanStore = mem.NewMemTrainingPlanStore()
 		workoutStore = mem.NewMemWorkoutStore()
+		activityStore = mem.NewMemActivityStore()
+		generateJobStore = mem.NewMemGenerateJobStore()
+		coachStore = mem.NewMemCoachStore()
+		aiUsageStore = mem.NewMemAIUsageStore()
+		passwordResetStore = mem.NewMemPasswordResetStore()
+		emailVerificationStore = mem.NewMemEmailVerificationStore()
+		apiTokenStore = mem.NewMemAPITokenStore()
+		identityStore = mem.NewMemIdentityStore()
+		twoFactorStore = mem.NewMemTwoFactorStore()
+		sessionStore = mem.NewMemSessionStore()
+		coachingStore = mem.NewMemCoachingStore()
+		planEditStore = mem.NewMemPlanEditStore()
+		shareLinkStore = mem.NewMemShareLinkStore()
 	} else {
 		db, err := sqliteStore.Open(dbURL) // uses modernc.org/sqlite
 		if err != nil {
@@ -46,20 +77,113 @@ func main() {
 		userStore = sqliteStore.NewUserStore(db)
 		trainingPlanStore = sqliteStore.NewTrainingPlanStore(db)
 		workoutStore = sqliteStore.NewWorkoutStore(db)
+		activityStore = sqliteStore.NewActivityStore(db)
+		generateJobStore = sqliteStore.NewGenerateJobStore(db)
+		coachStore = sqliteStore.NewCoachStore(db)
+		aiUsageStore = sqliteStore.NewAIUsageStore(db)
+		passwordResetStore = sqliteStore.NewPasswordResetStore(db)
+		emailVerificationStore = sqliteStore.NewEmailVerificationStore(db)
+		apiTokenStore = sqliteStore.NewAPITokenStore(db)
+		identityStore = sqliteStore.NewIdentityStore(db)
+		twoFactorStore = sqliteStore.NewTwoFactorStore(db)
+		sessionStore = sqliteStore.NewSessionStore(db)
+		coachingStore = sqliteStore.NewCoachingStore(db)
+		planEditStore = sqliteStore.NewPlanEditStore(db)
+		shareLinkStore = sqliteStore.NewShareLinkStore(db)
 	}
 
 	authSvc := service.NewAuthService(userStore)
-	trainingPlanSvc := service.NewTrainingPlanService(trainingPlanStore)
+	trainingPlanSvc := service.NewTrainingPlanServiceWithDependents(trainingPlanStore, service.PlanDependents{
+		Workouts:   workoutStore,
+		Activities: activityStore,
+		Coach:      coachStore,
+		PlanEdits:  planEditStore,
+		ShareLinks: shareLinkStore,
+	})
 	workoutSvc := service.NewWorkoutService(workoutStore)
+	activitySvc := service.NewActivityService(activityStore, workoutSvc)
+	calendarSvc := service.NewCalendarService(userStore, trainingPlanSvc, workoutSvc)
+	coachingSvc := service.NewCoachingService(userStore, coachingStore)
+	accessSvc := service.NewAccessService(trainingPlanSvc, workoutSvc, coachingSvc, planEditStore)
+	shareLinkSvc := service.NewShareLinkService(shareLinkStore, trainingPlanSvc, workoutSvc)
+	mailer, err := newMailer()
+	if err != nil {
+		log.Fatalf("mailer: %v", err)
+	}
+	appURL := getenv("APP_URL", "http://localhost:5173")
+	sessionSvc := service.NewSessionService(sessionStore)
+	go pruneSessions(sessionSvc)
+	passwordResetSvc := service.NewPasswordResetService(userStore, passwordResetStore, sessionSvc, mailer, appURL)
+	// REQUIRE_EMAIL_VERIFICATION=true limits plan generation, replanning,
+	// the coach and sharing (calendar feed URLs and plan share links) to
+	// verified addresses.
+	requireVerification := os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+	emailVerificationSvc := service.NewEmailVerificationService(userStore, emailVerificationStore, mailer, appURL, requireVerification)
+	apiTokenSvc := service.NewAPITokenService(apiTokenStore)
+	oidcProviders, err := oidcProvidersFromEnv()
+	if err != nil {
+		log.Fatalf("oidc: %v", err)
+	}
+	oidcSvc := service.NewOIDCService(oidcProviders, userStore, identityStore)
+	twoFactorSvc := service.NewTwoFactorService(userStore, twoFactorStore)
+	accountSvc := service.NewAccountService(service.AccountStores{
+		Users:              userStore,
+		Plans:              trainingPlanStore,
+		Workouts:           workoutStore,
+		Activities:         activityStore,
+		Coach:              coachStore,
+		GenerateJobs:       generateJobStore,
+		AIUsage:            aiUsageStore,
+		PasswordResets:     passwordResetStore,
+		EmailVerifications: emailVerificationStore,
+		APITokens:          apiTokenStore,
+		Identities:         identityStore,
+		TwoFactor:          twoFactorStore,
+		Sessions:           sessionStore,
+		Coachings:          coachingStore,
+		PlanEdits:          planEditStore,
+		ShareLinks:         shareLinkStore,
+	})
 
-	var aiClient ai.Client
-	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
-		aiClient = ai.NewOpenAIClient(key)
+	aiClient, err := newAIClient()
+	if err != nil {
+		log.Fatalf("ai client: %v", err)
+	}
+	aiClient, err = withAIFixtures(aiClient)
+	if err != nil {
+		log.Fatalf("ai fixtures: %v", err)
+	}
+	quota, err := aiQuotaFromEnv()
+	if err != nil {
+		log.Fatalf("ai quota: %v", err)
+	}
+	aiUsageSvc := service.NewAIUsageService(aiUsageStore, quota)
+	generateSvc := service.NewGenerateService(aiClient, aiUsageSvc, trainingPlanSvc, workoutSvc)
+	replanSvc := service.NewReplanService(aiClient, aiUsageSvc, workoutSvc)
+	coachSvc := service.NewCoachService(aiClient, aiUsageSvc, coachStore, workoutSvc)
+	generateJobSvc := service.NewGenerateJobService(generateJobStore, generateSvc)
+	if err := generateJobSvc.FailInterrupted(); err != nil {
+		log.Fatalf("generate jobs: %v", err)
+	}
+	authLimiter, generateLimiter, err := rateLimitersFromEnv()
+	if err != nil {
+		log.Fatalf("rate limits: %v", err)
 	}
-	generateSvc := service.NewGenerateService(aiClient, trainingPlanSvc, workoutSvc)
 
 	r := gin.Default()
 	r.RedirectTrailingSlash = false
+	// TRUSTED_PROXIES lists the proxies (IPs or CIDRs, comma-separated)
+	// whose X-Forwarded-For is believed; rate limits are per client IP, so
+	// behind a proxy it should name only that proxy.
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		var proxies []string
+		for _, p := range strings.Split(v, ",") {
+			proxies = append(proxies, strings.TrimSpace(p))
+		}
+		if err := r.SetTrustedProxies(proxies); err != nil {
+			log.Fatalf("TRUSTED_PROXIES: %v", err)
+		}
+	}
 	allowedOrigins := []string{"http://localhost:5173", "http://127.0.0.1:5173"}
 	if extra := os.Getenv("CORS_ORIGINS"); extra != "" {
 		for _, o := range strings.Split(extra, ",") {
@@ -69,29 +193,51 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-CSRF-Token"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	// Sessions middleware
-	storeCookie := cookie.NewStore([]byte(sessionSecret))
-	storeCookie.Options(sessions.Options{
+	// Sessions middleware; the cookie only carries a signed session token
+	sessionCookies := session.NewStore(sessionStore, []byte(sessionSecret))
+	sessionCookies.Options(sessions.Options{
 		Path:     "/",
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
 	})
-	r.Use(sessions.Sessions("rp.sid", storeCookie))
+	r.Use(session.ClientIP(), sessions.Sessions("rp.sid", sessionCookies))
 
 	// Health
 	r.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
 
 	// API routes
 	api := r.Group("/api")
-	controller.RegisterAuthRoutes(api, authSvc)
-	controller.RegisterTrainingPlanRoutes(api, trainingPlanSvc, workoutSvc, generateSvc, authSvc)
-	controller.RegisterWorkoutRoutes(api, workoutSvc, trainingPlanSvc)
+	api.Use(controller.BearerAuth(apiTokenSvc))
+	// Writes from the browser must come from the app itself.
+	csrfOrigins := append([]string{appURL}, allowedOrigins...)
+	api.Use(controller.CSRF(csrfOrigins))
+	controller.RegisterCSRFRoutes(api)
+	controller.RegisterAuthRoutes(api, authSvc, emailVerificationSvc, twoFactorSvc, authLimiter)
+	controller.RegisterTwoFactorRoutes(api, twoFactorSvc)
+	controller.RegisterSessionRoutes(api, sessionSvc)
+	controller.RegisterAccountRoutes(api, authSvc, accountSvc, sessionSvc, emailVerificationSvc)
+	controller.RegisterPasswordResetRoutes(api, passwordResetSvc, authLimiter)
+	controller.RegisterOIDCRoutes(api, oidcSvc, twoFactorSvc, appURL)
+	controller.RegisterTrainingPlanRoutes(api, trainingPlanSvc, workoutSvc, accessSvc, generateJobSvc, authSvc, emailVerificationSvc, generateLimiter)
+	controller.RegisterGenerateJobRoutes(api, generateJobSvc)
+	controller.RegisterReplanRoutes(api, replanSvc, accessSvc, emailVerificationSvc)
+	controller.RegisterCoachRoutes(api, coachSvc, accessSvc, emailVerificationSvc)
+	controller.RegisterAIUsageRoutes(api, aiUsageSvc)
+	controller.RegisterAPITokenRoutes(api, apiTokenSvc)
+	controller.RegisterWorkoutRoutes(api, workoutSvc, accessSvc)
+	controller.RegisterActivityRoutes(api, activitySvc, workoutSvc, accessSvc)
+	controller.RegisterCoachingRoutes(api, coachingSvc)
+	controller.RegisterShareLinkRoutes(api, shareLinkSvc, accessSvc, emailVerificationSvc)
+	controller.RegisterCalendarRoutes(api, calendarSvc, emailVerificationSvc, appURL)
+
+	// Calendar feed, authenticated by the token in the URL
+	controller.RegisterCalendarFeedRoutes(r, calendarSvc)
 
 	log.Printf("listening on :%s", port)
 	if err := r.Run(":" + port); err != nil {
@@ -99,6 +245,18 @@ func main() {
 	}
 }
 
+// pruneSessions deletes expired sessions at startup and then hourly.
+func pruneSessions(sessions *service.SessionService) {
+	for {
+		if n, err := sessions.PruneExpired(); err != nil {
+			log.Printf("prune sessions: %v", err)
+		} else if n > 0 {
+			log.Printf("pruned %d expired sessions", n)
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
 func runMigrations(db *sql.DB) error {
 	if err := goose.SetDialect("sqlite3"); err != nil {
 		return err
@@ -107,6 +265,210 @@ func runMigrations(db *sql.DB) error {
 	return goose.Up(db, "db/migrations")
 }
 
+// newAIClient configures the AI provider from the environment:
+//
+//	AI_PROVIDER     openai (default), anthropic or ollama
+//	AI_BASE_URL     e.g. http://localhost:8000/v1 for an OpenAI-compatible server
+//	AI_MODEL        provider model name
+//	AI_TEMPERATURE  sampling temperature
+//	AI_TIMEOUT      request timeout, e.g. 120s
+//	AI_API_KEY      overrides OPENAI_API_KEY / ANTHROPIC_API_KEY
+//
+// Without AI_PROVIDER, OpenAI is used when OPENAI_API_KEY is set and AI
+// generation is disabled otherwise.
+func newAIClient() (ai.Client, error) {
+	provider := os.Getenv("AI_PROVIDER")
+	if provider == "" {
+		if os.Getenv("OPENAI_API_KEY") == "" {
+			return nil, nil
+		}
+		provider = ai.ProviderOpenAI
+	}
+
+	cfg := ai.Config{
+		Provider: provider,
+		BaseURL:  os.Getenv("AI_BASE_URL"),
+		Model:    os.Getenv("AI_MODEL"),
+	}
+	switch provider {
+	case ai.ProviderOpenAI:
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+	case ai.ProviderAnthropic:
+		cfg.APIKey = os.Getenv("ANTHROPIC_API_KEY")
+	}
+	if key := os.Getenv("AI_API_KEY"); key != "" {
+		cfg.APIKey = key
+	}
+	if v := os.Getenv("AI_TEMPERATURE"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("AI_TEMPERATURE: %w", err)
+		}
+		cfg.Temperature = &t
+	}
+	if v := os.Getenv("AI_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("AI_TIMEOUT: %w", err)
+		}
+		cfg.Timeout = d
+	}
+	return ai.NewClient(cfg)
+}
+
+// newMailer configures outgoing mail from the environment:
+//
+//	SMTP_HOST      mail server; without it mail is only logged
+//	SMTP_PORT      default 587
+//	SMTP_USERNAME  enables PLAIN auth
+//	SMTP_PASSWORD
+//	MAIL_FROM      sender address, default runplanner@localhost
+func newMailer() (mail.Mailer, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		log.Printf("SMTP_HOST not set, logging outgoing mail")
+		return mail.NewLogMailer(nil), nil
+	}
+	cfg := mail.SMTPConfig{
+		Host:     host,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     getenv("MAIL_FROM", "runplanner@localhost"),
+	}
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("SMTP_PORT: %w", err)
+		}
+		cfg.Port = port
+	}
+	return mail.NewSMTPMailer(cfg), nil
+}
+
+// oidcProvidersFromEnv loads the OpenID Connect providers users can sign
+// in with from the JSON file named by OIDC_PROVIDERS_FILE:
+//
+//	[{"name": "corp", "displayName": "Corp SSO", "issuer": "https://id.corp.example",
+//	  "clientId": "runplanner", "clientSecret": "..."}]
+//
+// Register APP_URL/api/auth/oidc/<name>/callback as the redirect URI with
+// each provider. Without the variable, only password sign-in is offered.
+func oidcProvidersFromEnv() ([]*oidc.Provider, error) {
+	path := os.Getenv("OIDC_PROVIDERS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	configs, err := oidc.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	providers := make([]*oidc.Provider, 0, len(configs))
+	for _, cfg := range configs {
+		providers = append(providers, oidc.NewProvider(cfg, nil))
+	}
+	return providers, nil
+}
+
+// withAIFixtures records or replays AI completions, for reproducible tests
+// and offline demos:
+//
+//	AI_FIXTURES     record (wrap the configured provider) or replay (no provider needed)
+//	AI_FIXTURE_DIR  where fixtures are kept, default data/ai-fixtures
+func withAIFixtures(client ai.Client) (ai.Client, error) {
+	dir := getenv("AI_FIXTURE_DIR", "data/ai-fixtures")
+	switch mode := os.Getenv("AI_FIXTURES"); mode {
+	case "":
+		return client, nil
+	case ai.FixtureReplay:
+		log.Printf("replaying AI responses from %s", dir)
+		return ai.NewReplayClient(dir), nil
+	case ai.FixtureRecord:
+		if client == nil {
+			return nil, fmt.Errorf("AI_FIXTURES=record needs a configured AI provider")
+		}
+		log.Printf("recording AI responses to %s", dir)
+		return ai.NewRecordingClient(client, dir), nil
+	default:
+		return nil, fmt.Errorf("AI_FIXTURES must be %q or %q, got %q", ai.FixtureRecord, ai.FixtureReplay, mode)
+	}
+}
+
+// aiQuotaFromEnv reads the per-user limits on AI requests (plan
+// generations, replans and coach messages):
+//
+//	AI_DAILY_QUOTA    requests per UTC day
+//	AI_MONTHLY_QUOTA  requests per calendar month
+//
+// Unset or 0 means unlimited.
+func aiQuotaFromEnv() (service.AIQuota, error) {
+	var quota service.AIQuota
+	for _, q := range []struct {
+		env string
+		dst *int
+	}{{"AI_DAILY_QUOTA", &quota.Daily}, {"AI_MONTHLY_QUOTA", &quota.Monthly}} {
+		v := os.Getenv(q.env)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return quota, fmt.Errorf("%s must be a non-negative integer", q.env)
+		}
+		*q.dst = n
+	}
+	return quota, nil
+}
+
+// rateLimitersFromEnv configures throttling of the login, registration and
+// password reset routes and of AI plan generation:
+//
+//	AUTH_RATE_LIMIT       requests per IP and per account, default 10/1m
+//	GENERATE_RATE_LIMIT   plan generations per IP and per user, default 10/1h
+//	LOGIN_LOCKOUT_AFTER   failed logins before an account or IP is locked out, default 5
+//	LOGIN_LOCKOUT         first lockout, doubling with every further failure, default 1m
+//	LOGIN_LOCKOUT_MAX     longest lockout, default 1h
+//
+// A rate of "off" disables that limiter.
+func rateLimitersFromEnv() (auth, generate *ratelimit.Limiter, err error) {
+	lockout := ratelimit.Lockout{After: 5, Base: time.Minute, Max: time.Hour}
+	if v := os.Getenv("LOGIN_LOCKOUT_AFTER"); v != "" {
+		if lockout.After, err = strconv.Atoi(v); err != nil || lockout.After < 0 {
+			return nil, nil, fmt.Errorf("LOGIN_LOCKOUT_AFTER must be a non-negative integer")
+		}
+	}
+	for _, d := range []struct {
+		env string
+		dst *time.Duration
+	}{{"LOGIN_LOCKOUT", &lockout.Base}, {"LOGIN_LOCKOUT_MAX", &lockout.Max}} {
+		v := os.Getenv(d.env)
+		if v == "" {
+			continue
+		}
+		if *d.dst, err = time.ParseDuration(v); err != nil || *d.dst <= 0 {
+			return nil, nil, fmt.Errorf("%s must be a positive duration", d.env)
+		}
+	}
+
+	newLimiter := func(env, def string, lockout ratelimit.Lockout) (*ratelimit.Limiter, error) {
+		v := getenv(env, def)
+		if v == "off" {
+			return nil, nil
+		}
+		rate, err := ratelimit.ParseRate(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env, err)
+		}
+		return ratelimit.New(ratelimit.Config{Rate: rate, Lockout: lockout}), nil
+	}
+	if auth, err = newLimiter("AUTH_RATE_LIMIT", "10/1m", lockout); err != nil {
+		return nil, nil, err
+	}
+	if generate, err = newLimiter("GENERATE_RATE_LIMIT", "10/1h", ratelimit.Lockout{}); err != nil {
+		return nil, nil, err
+	}
+	return auth, generate, nil
+}
+
 func getenv(k, def string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS activities (
+  id TEXT PRIMARY KEY,
+  workout_id TEXT NOT NULL UNIQUE REFERENCES workouts(id) ON DELETE CASCADE,
+  source TEXT NOT NULL,
+  start_time TIMESTAMP NOT NULL,
+  distance REAL NOT NULL,
+  moving_time INTEGER NOT NULL,
+  avg_pace REAL NOT NULL,
+  elevation_gain REAL NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS activities;
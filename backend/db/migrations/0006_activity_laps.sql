@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE activities ADD COLUMN avg_heart_rate INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE activities ADD COLUMN max_heart_rate INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE activities ADD COLUMN avg_cadence INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE activities ADD COLUMN laps TEXT NOT NULL DEFAULT '[]';
+
+-- +goose Down
+ALTER TABLE activities DROP COLUMN laps;
+ALTER TABLE activities DROP COLUMN avg_cadence;
+ALTER TABLE activities DROP COLUMN max_heart_rate;
+ALTER TABLE activities DROP COLUMN avg_heart_rate;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE workouts ADD COLUMN steps TEXT NOT NULL DEFAULT '[]';
+
+-- +goose Down
+ALTER TABLE workouts DROP COLUMN steps;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN calendar_token_hash TEXT;
+CREATE UNIQUE INDEX idx_users_calendar_token_hash ON users(calendar_token_hash);
+
+-- +goose Down
+DROP INDEX idx_users_calendar_token_hash;
+ALTER TABLE users DROP COLUMN calendar_token_hash;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS generate_jobs (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL,
+  phase TEXT NOT NULL DEFAULT '',
+  plan_id TEXT REFERENCES training_plans(id) ON DELETE SET NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  warnings TEXT NOT NULL DEFAULT '[]',
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_generate_jobs_user_id ON generate_jobs(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS generate_jobs;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS coach_threads (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  plan_id TEXT NOT NULL REFERENCES training_plans(id) ON DELETE CASCADE,
+  title TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_coach_threads_plan_id ON coach_threads(plan_id);
+
+CREATE TABLE IF NOT EXISTS coach_messages (
+  id TEXT PRIMARY KEY,
+  thread_id TEXT NOT NULL REFERENCES coach_threads(id) ON DELETE CASCADE,
+  role TEXT NOT NULL,
+  content TEXT NOT NULL,
+  actions TEXT NOT NULL DEFAULT '[]',
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_coach_messages_thread_id ON coach_messages(thread_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS coach_messages;
+DROP TABLE IF EXISTS coach_threads;
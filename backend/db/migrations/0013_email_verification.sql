@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Accounts created before verification existed are trusted.
+UPDATE users SET email_verified_at = created_at;
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash TEXT NOT NULL UNIQUE,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_email_verifications_user ON email_verifications(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS email_verifications;
+ALTER TABLE users DROP COLUMN email_verified_at;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS two_factor (
+  user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret TEXT NOT NULL,
+  enabled_at TIMESTAMP,
+  last_used_step INTEGER NOT NULL DEFAULT 0,
+  recovery_code_hashes TEXT NOT NULL DEFAULT '[]',
+  created_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS two_factor;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS coachings (
+  id TEXT PRIMARY KEY,
+  coach_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  athlete_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  accepted_at TIMESTAMP,
+  UNIQUE (coach_id, athlete_id)
+);
+
+CREATE INDEX idx_coachings_athlete ON coachings(athlete_id);
+
+CREATE TABLE IF NOT EXISTS plan_edits (
+  id TEXT PRIMARY KEY,
+  plan_id TEXT NOT NULL REFERENCES training_plans(id) ON DELETE CASCADE,
+  workout_id TEXT,
+  editor_id TEXT NOT NULL,
+  action TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_plan_edits_plan ON plan_edits(plan_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS plan_edits;
+DROP TABLE IF EXISTS coachings;
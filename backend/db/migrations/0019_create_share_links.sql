@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS share_links (
+  id TEXT PRIMARY KEY,
+  plan_id TEXT NOT NULL REFERENCES training_plans(id) ON DELETE CASCADE,
+  slug TEXT NOT NULL UNIQUE,
+  expires_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_share_links_plan ON share_links(plan_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS share_links;
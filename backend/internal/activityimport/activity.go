@@ -0,0 +1,238 @@
+// Package activityimport decodes recorded runs from device export formats
+// into a single Activity type.
+package activityimport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unsupported activity file format")
+	ErrNoData        = errors.New("activity file contains no samples or laps")
+)
+
+// movingSpeedThreshold is the speed in m/s below which the time between two
+// samples counts as standing still.
+const movingSpeedThreshold = 0.5
+
+const earthRadiusMeters = 6371000
+
+// Sample is a single recorded data point. Zero HeartRate or Cadence means
+// the device did not record it.
+type Sample struct {
+	Time        time.Time
+	Lat         float64
+	Lon         float64
+	HasPosition bool
+	Elevation   *float64 // in meters
+	Distance    *float64 // cumulative meters as measured by the device
+	HeartRate   int      // in bpm
+	Cadence     int      // in steps per minute
+}
+
+// Lap is a device-recorded lap. Zero heart rate or cadence values mean the
+// device did not record them.
+type Lap struct {
+	StartTime    time.Time
+	Duration     time.Duration
+	Distance     float64 // in kilometers
+	AvgHeartRate int
+	MaxHeartRate int
+	AvgCadence   int
+}
+
+type Activity struct {
+	Format  string
+	Samples []Sample
+	Laps    []Lap
+}
+
+// Decoder turns the raw bytes of one file format into an Activity.
+type Decoder interface {
+	// Format is the short name of the format, which is also the file
+	// extension, e.g. "gpx".
+	Format() string
+	// Sniff reports whether the start of a file looks like this format.
+	Sniff(head []byte) bool
+	Decode(r io.Reader) (*Activity, error)
+}
+
+var (
+	mu       sync.RWMutex
+	decoders = []Decoder{gpxDecoder{}, tcxDecoder{}, fitDecoder{}}
+)
+
+// Register adds a decoder. Decoders registered later take precedence when
+// both match a file.
+func Register(d Decoder) {
+	mu.Lock()
+	defer mu.Unlock()
+	decoders = append([]Decoder{d}, decoders...)
+}
+
+// Decode picks a decoder by the file name's extension, falling back to
+// sniffing the content, and decodes the file.
+func Decode(filename string, r io.Reader) (*Activity, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := detect(filename, data)
+	if d == nil {
+		return nil, ErrUnknownFormat
+	}
+	a, err := d.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(a.Samples) == 0 && len(a.Laps) == 0 {
+		return nil, ErrNoData
+	}
+	a.Format = d.Format()
+	return a, nil
+}
+
+func detect(filename string, data []byte) Decoder {
+	mu.RLock()
+	defer mu.RUnlock()
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	for _, d := range decoders {
+		if ext == d.Format() {
+			return d
+		}
+	}
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	for _, d := range decoders {
+		if d.Sniff(head) {
+			return d
+		}
+	}
+	return nil
+}
+
+type Summary struct {
+	StartTime     time.Time
+	Distance      float64 // in kilometers
+	MovingTime    time.Duration
+	ElevationGain float64 // in meters
+	AvgHeartRate  int
+	MaxHeartRate  int
+	AvgCadence    int
+	Laps          []Lap
+}
+
+// AvgPace returns the average pace over the moving time in seconds per kilometer.
+func (s Summary) AvgPace() float64 {
+	if s.Distance == 0 {
+		return 0
+	}
+	return s.MovingTime.Seconds() / s.Distance
+}
+
+// Summarize computes totals for the activity. Distances come from the
+// device's own distance channel when present and from GPS positions
+// otherwise. Files without samples are summarised from their laps.
+func (a *Activity) Summarize() Summary {
+	var s Summary
+	var meters float64
+	for i, cur := range a.Samples {
+		if i == 0 {
+			s.StartTime = cur.Time
+			continue
+		}
+		prev := a.Samples[i-1]
+		d := segmentDistance(prev, cur)
+		meters += d
+		if !prev.Time.IsZero() && !cur.Time.IsZero() {
+			dt := cur.Time.Sub(prev.Time)
+			if dt > 0 && d/dt.Seconds() >= movingSpeedThreshold {
+				s.MovingTime += dt
+			}
+		}
+		if prev.Elevation != nil && cur.Elevation != nil && *cur.Elevation > *prev.Elevation {
+			s.ElevationGain += *cur.Elevation - *prev.Elevation
+		}
+	}
+	s.Distance = meters / 1000
+	s.AvgHeartRate, s.MaxHeartRate, s.AvgCadence = sampleStats(a.Samples)
+
+	if s.Distance == 0 && len(a.Laps) > 0 {
+		s.MovingTime = 0
+		for _, l := range a.Laps {
+			s.Distance += l.Distance
+			s.MovingTime += l.Duration
+		}
+	}
+	if s.StartTime.IsZero() && len(a.Laps) > 0 {
+		s.StartTime = a.Laps[0].StartTime
+	}
+
+	s.Laps = a.Laps
+	if len(s.Laps) == 0 {
+		s.Laps = []Lap{{
+			StartTime:    s.StartTime,
+			Duration:     s.MovingTime,
+			Distance:     s.Distance,
+			AvgHeartRate: s.AvgHeartRate,
+			MaxHeartRate: s.MaxHeartRate,
+			AvgCadence:   s.AvgCadence,
+		}}
+	}
+	return s
+}
+
+func segmentDistance(prev, cur Sample) float64 {
+	if prev.Distance != nil && cur.Distance != nil {
+		return math.Max(0, *cur.Distance-*prev.Distance)
+	}
+	if prev.HasPosition && cur.HasPosition {
+		return Haversine(prev.Lat, prev.Lon, cur.Lat, cur.Lon)
+	}
+	return 0
+}
+
+// Haversine returns the great-circle distance in meters between two coordinates.
+func Haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// sampleStats returns average and maximum heart rate and average cadence
+// over the samples that recorded them.
+func sampleStats(samples []Sample) (avgHR, maxHR, avgCad int) {
+	var hrSum, hrCount, cadSum, cadCount int
+	for _, s := range samples {
+		if s.HeartRate > 0 {
+			hrSum += s.HeartRate
+			hrCount++
+			if s.HeartRate > maxHR {
+				maxHR = s.HeartRate
+			}
+		}
+		if s.Cadence > 0 {
+			cadSum += s.Cadence
+			cadCount++
+		}
+	}
+	if hrCount > 0 {
+		avgHR = int(math.Round(float64(hrSum) / float64(hrCount)))
+	}
+	if cadCount > 0 {
+		avgCad = int(math.Round(float64(cadSum) / float64(cadCount)))
+	}
+	return avgHR, maxHR, avgCad
+}
@@ -0,0 +1,63 @@
+package activityimport
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	t.Run("picks decoder by extension", func(t *testing.T) {
+		a, err := Decode("run.TCX", strings.NewReader(sampleTCX))
+		require.NoError(t, err)
+		assert.Equal(t, "tcx", a.Format)
+	})
+
+	t.Run("sniffs content when extension is unknown", func(t *testing.T) {
+		for name, data := range map[string][]byte{
+			"gpx": []byte(sampleGPX),
+			"tcx": []byte(sampleTCX),
+			"fit": sampleFIT(),
+		} {
+			a, err := Decode("upload.bin", bytes.NewReader(data))
+			require.NoError(t, err, name)
+			assert.Equal(t, name, a.Format)
+		}
+	})
+
+	t.Run("rejects unknown content", func(t *testing.T) {
+		_, err := Decode("notes.txt", strings.NewReader("hello"))
+		assert.ErrorIs(t, err, ErrUnknownFormat)
+	})
+
+	t.Run("rejects files without data", func(t *testing.T) {
+		_, err := Decode("empty.gpx", strings.NewReader(`<gpx><trk><trkseg></trkseg></trk></gpx>`))
+		assert.ErrorIs(t, err, ErrNoData)
+	})
+}
+
+type csvDecoder struct{}
+
+func (csvDecoder) Format() string    { return "csv" }
+func (csvDecoder) Sniff([]byte) bool { return false }
+func (csvDecoder) Decode(io.Reader) (*Activity, error) {
+	return &Activity{Laps: []Lap{{Distance: 5}}}, nil
+}
+
+func TestRegister(t *testing.T) {
+	Register(csvDecoder{})
+	t.Cleanup(func() {
+		mu.Lock()
+		decoders = decoders[1:]
+		mu.Unlock()
+	})
+
+	a, err := Decode("run.csv", strings.NewReader("time,distance"))
+	require.NoError(t, err)
+	assert.Equal(t, "csv", a.Format)
+	assert.Equal(t, 5.0, a.Summarize().Distance)
+}
@@ -0,0 +1,113 @@
+package activityimport
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/fit"
+)
+
+// Field numbers of the FIT record and lap messages we read.
+const (
+	recordPositionLat      = 0
+	recordPositionLong     = 1
+	recordAltitude         = 2
+	recordHeartRate        = 3
+	recordCadence          = 4
+	recordDistance         = 5
+	recordEnhancedAltitude = 78
+
+	lapStartTime      = 2
+	lapTotalTimerTime = 8
+	lapTotalDistance  = 9
+	lapAvgHeartRate   = 15
+	lapMaxHeartRate   = 16
+	lapAvgCadence     = 17
+)
+
+// semicircleToDegrees converts FIT's 32-bit angular unit to degrees.
+const semicircleToDegrees = 180.0 / (1 << 31)
+
+type fitDecoder struct{}
+
+func (fitDecoder) Format() string { return "fit" }
+
+func (fitDecoder) Sniff(head []byte) bool {
+	return len(head) >= 12 && bytes.Equal(head[8:12], []byte(".FIT"))
+}
+
+// Decode reads record and lap messages. Running cadence in FIT is in
+// strides per minute and is doubled to steps per minute.
+func (fitDecoder) Decode(r io.Reader) (*Activity, error) {
+	msgs, err := fit.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	a := &Activity{}
+	for _, m := range msgs {
+		switch m.Num {
+		case fit.MesgRecord:
+			a.Samples = append(a.Samples, fitSample(m))
+		case fit.MesgLap:
+			a.Laps = append(a.Laps, fitLap(m))
+		}
+	}
+	return a, nil
+}
+
+func fitSample(m fit.Message) Sample {
+	var s Sample
+	if ts, ok := m.Field(fit.FieldTimestamp); ok {
+		s.Time = fit.Time(ts)
+	}
+	lat, okLat := m.Field(recordPositionLat)
+	lon, okLon := m.Field(recordPositionLong)
+	if okLat && okLon {
+		s.Lat = float64(lat) * semicircleToDegrees
+		s.Lon = float64(lon) * semicircleToDegrees
+		s.HasPosition = true
+	}
+	alt, ok := m.Field(recordEnhancedAltitude)
+	if !ok {
+		alt, ok = m.Field(recordAltitude)
+	}
+	if ok {
+		e := float64(alt)/5 - 500
+		s.Elevation = &e
+	}
+	if d, ok := m.Field(recordDistance); ok {
+		meters := float64(d) / 100
+		s.Distance = &meters
+	}
+	if hr, ok := m.Field(recordHeartRate); ok {
+		s.HeartRate = int(hr)
+	}
+	if cad, ok := m.Field(recordCadence); ok {
+		s.Cadence = int(cad) * 2
+	}
+	return s
+}
+
+func fitLap(m fit.Message) Lap {
+	var l Lap
+	if ts, ok := m.Field(lapStartTime); ok {
+		l.StartTime = fit.Time(ts)
+	}
+	if ms, ok := m.Field(lapTotalTimerTime); ok {
+		l.Duration = time.Duration(ms) * time.Millisecond
+	}
+	if cm, ok := m.Field(lapTotalDistance); ok {
+		l.Distance = float64(cm) / 100000
+	}
+	if hr, ok := m.Field(lapAvgHeartRate); ok {
+		l.AvgHeartRate = int(hr)
+	}
+	if hr, ok := m.Field(lapMaxHeartRate); ok {
+		l.MaxHeartRate = int(hr)
+	}
+	if cad, ok := m.Field(lapAvgCadence); ok {
+		l.AvgCadence = int(cad) * 2
+	}
+	return l
+}
@@ -0,0 +1,74 @@
+package activityimport
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/fit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleFIT() []byte {
+	start := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+	ts := int64(start.Sub(fit.Time(0)).Seconds())
+	lat := int64(math.Round(52.52 / semicircleToDegrees))
+	lon := int64(math.Round(13.405 / semicircleToDegrees))
+	e := fit.NewEncoder()
+	e.Write(fit.MesgFileID, fit.Field{Num: 0, Type: fit.Enum, Value: 4})
+	for i, hr := range []int64{140, 150, 160} {
+		e.Write(fit.MesgRecord,
+			fit.Field{Num: fit.FieldTimestamp, Type: fit.Uint32, Value: ts + int64(i)*300},
+			fit.Field{Num: recordPositionLat, Type: fit.Sint32, Value: lat},
+			fit.Field{Num: recordPositionLong, Type: fit.Sint32, Value: lon},
+			fit.Field{Num: recordAltitude, Type: fit.Uint16, Value: (30 + int64(i)*2 + 500) * 5},
+			fit.Field{Num: recordHeartRate, Type: fit.Uint8, Value: hr},
+			fit.Field{Num: recordCadence, Type: fit.Uint8, Value: 85},
+			fit.Field{Num: recordDistance, Type: fit.Uint32, Value: int64(i) * 100000},
+		)
+	}
+	e.Write(fit.MesgLap,
+		fit.Field{Num: fit.FieldTimestamp, Type: fit.Uint32, Value: ts + 600},
+		fit.Field{Num: lapStartTime, Type: fit.Uint32, Value: ts},
+		fit.Field{Num: lapTotalTimerTime, Type: fit.Uint32, Value: 600000},
+		fit.Field{Num: lapTotalDistance, Type: fit.Uint32, Value: 200000},
+		fit.Field{Num: lapAvgHeartRate, Type: fit.Uint8, Value: 150},
+		fit.Field{Num: lapMaxHeartRate, Type: fit.Uint8, Value: 160},
+		fit.Field{Num: lapAvgCadence, Type: fit.Uint8, Value: 85},
+	)
+	return e.Bytes()
+}
+
+func TestFITDecoder(t *testing.T) {
+	a, err := fitDecoder{}.Decode(bytes.NewReader(sampleFIT()))
+	require.NoError(t, err)
+
+	require.Len(t, a.Samples, 3)
+	first := a.Samples[0]
+	assert.Equal(t, time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC), first.Time)
+	assert.InDelta(t, 52.52, first.Lat, 1e-6)
+	assert.InDelta(t, 13.405, first.Lon, 1e-6)
+	require.NotNil(t, first.Elevation)
+	assert.Equal(t, 30.0, *first.Elevation)
+	assert.Equal(t, 140, first.HeartRate)
+	assert.Equal(t, 170, first.Cadence)
+
+	require.Len(t, a.Laps, 1)
+	assert.Equal(t, 10*time.Minute, a.Laps[0].Duration)
+	assert.Equal(t, 2.0, a.Laps[0].Distance)
+	assert.Equal(t, 150, a.Laps[0].AvgHeartRate)
+	assert.Equal(t, 170, a.Laps[0].AvgCadence)
+
+	s := a.Summarize()
+	assert.Equal(t, 2.0, s.Distance)
+	assert.Equal(t, 10*time.Minute, s.MovingTime)
+	assert.Equal(t, 4.0, s.ElevationGain)
+	assert.Equal(t, 150, s.AvgHeartRate)
+}
+
+func TestFITDecoder_Invalid(t *testing.T) {
+	_, err := fitDecoder{}.Decode(bytes.NewReader([]byte("definitely not fit")))
+	assert.ErrorIs(t, err, fit.ErrInvalidFile)
+}
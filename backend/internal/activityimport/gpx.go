@@ -0,0 +1,64 @@
+package activityimport
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+type gpxDecoder struct{}
+
+func (gpxDecoder) Format() string { return "gpx" }
+
+func (gpxDecoder) Sniff(head []byte) bool { return bytes.Contains(head, []byte("<gpx")) }
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []struct {
+				Lat       float64  `xml:"lat,attr"`
+				Lon       float64  `xml:"lon,attr"`
+				Elevation *float64 `xml:"ele"`
+				Time      string   `xml:"time"`
+				HeartRate int      `xml:"extensions>TrackPointExtension>hr"`
+				Cadence   int      `xml:"extensions>TrackPointExtension>cad"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+// Decode flattens all tracks and segments into samples in file order. Heart
+// rate and cadence are read from Garmin's TrackPointExtension; GPX has no
+// laps.
+func (gpxDecoder) Decode(r io.Reader) (*Activity, error) {
+	var f gpxFile
+	if err := xml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("invalid gpx: %w", err)
+	}
+	a := &Activity{}
+	for _, trk := range f.Tracks {
+		for _, seg := range trk.Segments {
+			for _, p := range seg.Points {
+				s := Sample{
+					Lat:         p.Lat,
+					Lon:         p.Lon,
+					HasPosition: true,
+					Elevation:   p.Elevation,
+					HeartRate:   p.HeartRate,
+					Cadence:     p.Cadence * 2, // Garmin records strides per minute
+				}
+				if p.Time != "" {
+					t, err := time.Parse(time.RFC3339, p.Time)
+					if err != nil {
+						return nil, fmt.Errorf("invalid gpx: bad time %q", p.Time)
+					}
+					s.Time = t.UTC()
+				}
+				a.Samples = append(a.Samples, s)
+			}
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,74 @@
+package activityimport
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1"
+  xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">
+  <trk>
+    <name>Morning Run</name>
+    <trkseg>
+      <trkpt lat="52.5200" lon="13.4050"><ele>30</ele><time>2025-06-01T06:00:00Z</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>140</gpxtpx:hr><gpxtpx:cad>85</gpxtpx:cad></gpxtpx:TrackPointExtension></extensions>
+      </trkpt>
+      <trkpt lat="52.5290" lon="13.4050"><ele>35</ele><time>2025-06-01T06:05:00Z</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>150</gpxtpx:hr><gpxtpx:cad>87</gpxtpx:cad></gpxtpx:TrackPointExtension></extensions>
+      </trkpt>
+      <trkpt lat="52.5290" lon="13.4050"><ele>35</ele><time>2025-06-01T06:07:00Z</time></trkpt>
+      <trkpt lat="52.5380" lon="13.4050"><ele>32</ele><time>2025-06-01T06:12:00Z</time></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestGPXDecoder(t *testing.T) {
+	t.Run("parses track points and extensions", func(t *testing.T) {
+		a, err := gpxDecoder{}.Decode(strings.NewReader(sampleGPX))
+		require.NoError(t, err)
+		require.Len(t, a.Samples, 4)
+		assert.Equal(t, 52.52, a.Samples[0].Lat)
+		assert.True(t, a.Samples[0].HasPosition)
+		require.NotNil(t, a.Samples[0].Elevation)
+		assert.Equal(t, 30.0, *a.Samples[0].Elevation)
+		assert.Equal(t, time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC), a.Samples[0].Time)
+		assert.Equal(t, 140, a.Samples[0].HeartRate)
+		assert.Equal(t, 170, a.Samples[0].Cadence)
+		assert.Equal(t, 0, a.Samples[2].HeartRate)
+	})
+
+	t.Run("rejects invalid xml", func(t *testing.T) {
+		_, err := gpxDecoder{}.Decode(strings.NewReader("not xml"))
+		assert.Error(t, err)
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		a, err := gpxDecoder{}.Decode(strings.NewReader(sampleGPX))
+		require.NoError(t, err)
+
+		s := a.Summarize()
+		assert.Equal(t, time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC), s.StartTime)
+		// 0.009 degrees of latitude is ~1.0 km, twice.
+		assert.InDelta(t, 2.0, s.Distance, 0.01)
+		// The two minute stop is not counted as moving time.
+		assert.Equal(t, 10*time.Minute, s.MovingTime)
+		assert.Equal(t, 5.0, s.ElevationGain)
+		assert.InDelta(t, 300, s.AvgPace(), 2)
+		assert.Equal(t, 145, s.AvgHeartRate)
+		assert.Equal(t, 150, s.MaxHeartRate)
+		assert.Equal(t, 172, s.AvgCadence)
+		require.Len(t, s.Laps, 1, "a single lap is derived when the file has none")
+		assert.InDelta(t, 2.0, s.Laps[0].Distance, 0.01)
+	})
+}
+
+func TestHaversine(t *testing.T) {
+	assert.Equal(t, 0.0, Haversine(52.52, 13.405, 52.52, 13.405))
+	// Berlin to Paris is roughly 878 km.
+	assert.InDelta(t, 878000, Haversine(52.52, 13.405, 48.8566, 2.3522), 5000)
+}
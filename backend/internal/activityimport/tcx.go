@@ -0,0 +1,99 @@
+package activityimport
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+type tcxDecoder struct{}
+
+func (tcxDecoder) Format() string { return "tcx" }
+
+func (tcxDecoder) Sniff(head []byte) bool {
+	return bytes.Contains(head, []byte("<TrainingCenterDatabase"))
+}
+
+type tcxFile struct {
+	Activities []struct {
+		Laps []struct {
+			StartTime        string          `xml:"StartTime,attr"`
+			TotalTimeSeconds float64         `xml:"TotalTimeSeconds"`
+			DistanceMeters   float64         `xml:"DistanceMeters"`
+			AvgHeartRate     int             `xml:"AverageHeartRateBpm>Value"`
+			MaxHeartRate     int             `xml:"MaximumHeartRateBpm>Value"`
+			AvgRunCadence    int             `xml:"Extensions>LX>AvgRunCadence"`
+			Trackpoints      []tcxTrackpoint `xml:"Track>Trackpoint"`
+		} `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+type tcxTrackpoint struct {
+	Time           string   `xml:"Time"`
+	Lat            *float64 `xml:"Position>LatitudeDegrees"`
+	Lon            *float64 `xml:"Position>LongitudeDegrees"`
+	AltitudeMeters *float64 `xml:"AltitudeMeters"`
+	DistanceMeters *float64 `xml:"DistanceMeters"`
+	HeartRate      int      `xml:"HeartRateBpm>Value"`
+	RunCadence     int      `xml:"Extensions>TPX>RunCadence"`
+}
+
+// Decode reads every lap of every activity in the file. Cadence values in
+// TCX are strides per minute and are doubled to steps per minute.
+func (tcxDecoder) Decode(r io.Reader) (*Activity, error) {
+	var f tcxFile
+	if err := xml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("invalid tcx: %w", err)
+	}
+	a := &Activity{}
+	for _, act := range f.Activities {
+		for _, l := range act.Laps {
+			start, err := time.Parse(time.RFC3339, l.StartTime)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tcx: bad lap start time %q", l.StartTime)
+			}
+			var lapSamples []Sample
+			for _, tp := range l.Trackpoints {
+				s := Sample{
+					Elevation: tp.AltitudeMeters,
+					Distance:  tp.DistanceMeters,
+					HeartRate: tp.HeartRate,
+					Cadence:   tp.RunCadence * 2,
+				}
+				if tp.Lat != nil && tp.Lon != nil {
+					s.Lat, s.Lon, s.HasPosition = *tp.Lat, *tp.Lon, true
+				}
+				t, err := time.Parse(time.RFC3339, tp.Time)
+				if err != nil {
+					return nil, fmt.Errorf("invalid tcx: bad time %q", tp.Time)
+				}
+				s.Time = t.UTC()
+				lapSamples = append(lapSamples, s)
+			}
+
+			lap := Lap{
+				StartTime:    start.UTC(),
+				Duration:     time.Duration(l.TotalTimeSeconds * float64(time.Second)),
+				Distance:     l.DistanceMeters / 1000,
+				AvgHeartRate: l.AvgHeartRate,
+				MaxHeartRate: l.MaxHeartRate,
+				AvgCadence:   l.AvgRunCadence * 2,
+			}
+			avgHR, maxHR, avgCad := sampleStats(lapSamples)
+			if lap.AvgHeartRate == 0 {
+				lap.AvgHeartRate = avgHR
+			}
+			if lap.MaxHeartRate == 0 {
+				lap.MaxHeartRate = maxHR
+			}
+			if lap.AvgCadence == 0 {
+				lap.AvgCadence = avgCad
+			}
+			a.Laps = append(a.Laps, lap)
+			a.Samples = append(a.Samples, lapSamples...)
+		}
+	}
+	return a, nil
+}
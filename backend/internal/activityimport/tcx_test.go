@@ -0,0 +1,79 @@
+package activityimport
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleTCX = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2"
+  xmlns:ns3="http://www.garmin.com/xmlschemas/ActivityExtension/v2">
+  <Activities>
+    <Activity Sport="Running">
+      <Id>2025-06-01T06:00:00Z</Id>
+      <Lap StartTime="2025-06-01T06:00:00Z">
+        <TotalTimeSeconds>300</TotalTimeSeconds>
+        <DistanceMeters>1000</DistanceMeters>
+        <AverageHeartRateBpm><Value>142</Value></AverageHeartRateBpm>
+        <MaximumHeartRateBpm><Value>151</Value></MaximumHeartRateBpm>
+        <Track>
+          <Trackpoint>
+            <Time>2025-06-01T06:00:00Z</Time>
+            <Position><LatitudeDegrees>52.52</LatitudeDegrees><LongitudeDegrees>13.405</LongitudeDegrees></Position>
+            <AltitudeMeters>30</AltitudeMeters>
+            <DistanceMeters>0</DistanceMeters>
+            <HeartRateBpm><Value>135</Value></HeartRateBpm>
+            <Extensions><ns3:TPX><ns3:RunCadence>84</ns3:RunCadence></ns3:TPX></Extensions>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2025-06-01T06:05:00Z</Time>
+            <AltitudeMeters>34</AltitudeMeters>
+            <DistanceMeters>1000</DistanceMeters>
+            <HeartRateBpm><Value>150</Value></HeartRateBpm>
+            <Extensions><ns3:TPX><ns3:RunCadence>86</ns3:RunCadence></ns3:TPX></Extensions>
+          </Trackpoint>
+        </Track>
+      </Lap>
+      <Lap StartTime="2025-06-01T06:05:00Z">
+        <TotalTimeSeconds>270</TotalTimeSeconds>
+        <DistanceMeters>1000</DistanceMeters>
+        <Track>
+          <Trackpoint>
+            <Time>2025-06-01T06:09:30Z</Time>
+            <DistanceMeters>2000</DistanceMeters>
+            <HeartRateBpm><Value>160</Value></HeartRateBpm>
+          </Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+func TestTCXDecoder(t *testing.T) {
+	a, err := tcxDecoder{}.Decode(strings.NewReader(sampleTCX))
+	require.NoError(t, err)
+
+	require.Len(t, a.Laps, 2)
+	assert.Equal(t, time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC), a.Laps[0].StartTime)
+	assert.Equal(t, 300*time.Second, a.Laps[0].Duration)
+	assert.Equal(t, 1.0, a.Laps[0].Distance)
+	assert.Equal(t, 142, a.Laps[0].AvgHeartRate)
+	assert.Equal(t, 151, a.Laps[0].MaxHeartRate)
+	assert.Equal(t, 170, a.Laps[0].AvgCadence, "cadence is derived from trackpoints when the lap lacks it")
+	assert.Equal(t, 160, a.Laps[1].AvgHeartRate, "heart rate is derived from trackpoints when the lap lacks it")
+
+	require.Len(t, a.Samples, 3)
+	assert.True(t, a.Samples[0].HasPosition)
+	assert.False(t, a.Samples[1].HasPosition)
+
+	s := a.Summarize()
+	assert.InDelta(t, 2.0, s.Distance, 0.001, "device distance is used without GPS positions")
+	assert.Equal(t, 570*time.Second, s.MovingTime)
+	assert.Equal(t, 4.0, s.ElevationGain)
+	assert.Equal(t, 160, s.MaxHeartRate)
+	assert.Len(t, s.Laps, 2)
+}
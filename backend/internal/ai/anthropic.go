@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 16384
+)
+
+// AnthropicClient calls the Anthropic Messages API.
+type AnthropicClient struct {
+	apiKey      string
+	baseURL     string
+	model       string
+	temperature *float64
+	httpClient  *http.Client
+}
+
+func NewAnthropicClient(cfg Config) *AnthropicClient {
+	return &AnthropicClient{
+		apiKey:      cfg.APIKey,
+		baseURL:     strings.TrimRight(valueOr(cfg.BaseURL, "https://api.anthropic.com"), "/"),
+		model:       valueOr(cfg.Model, "claude-3-5-haiku-latest"),
+		temperature: cfg.Temperature,
+		httpClient:  newHTTPClient(cfg.Timeout),
+	}
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	Temperature *float64           `json:"temperature,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *AnthropicClient) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	// The system prompt is a separate field here, so only the turns go into
+	// messages.
+	messages := make([]anthropicMessage, 0, len(req.History)+1)
+	for _, m := range req.History {
+		messages = append(messages, anthropicMessage{Role: m.Role, Content: m.Content})
+	}
+	messages = append(messages, anthropicMessage{Role: RoleUser, Content: req.UserPrompt})
+
+	body := anthropicRequest{
+		Model:       c.model,
+		MaxTokens:   anthropicMaxTokens,
+		System:      req.SystemPrompt,
+		Messages:    messages,
+		Temperature: c.temperature,
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var aResp anthropicResponse
+	if err := json.Unmarshal(respBody, &aResp); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	if aResp.Error != nil {
+		return nil, fmt.Errorf("anthropic error: %s", aResp.Error.Message)
+	}
+	if aResp.StopReason == "max_tokens" {
+		return nil, fmt.Errorf("anthropic response was truncated at %d tokens", anthropicMaxTokens)
+	}
+
+	var text strings.Builder
+	for _, block := range aResp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return nil, fmt.Errorf("anthropic returned no text")
+	}
+	return &CompletionResponse{
+		Content: stripCodeFence(text.String()),
+		Usage:   Usage{InputTokens: aResp.Usage.InputTokens, OutputTokens: aResp.Usage.OutputTokens},
+	}, nil
+}
+
+// stripCodeFence removes a surrounding ```json fence. Models without a JSON
+// mode sometimes wrap their answer in one despite the prompt.
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") {
+		return s
+	}
+	s = strings.TrimSuffix(s, "```")
+	if i := strings.Index(s, "\n"); i >= 0 {
+		s = s[i+1:]
+	} else {
+		s = strings.TrimPrefix(s, "```")
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicClient_Complete(t *testing.T) {
+	t.Run("sends messages request and joins text blocks", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/messages", r.URL.Path)
+			assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+			assert.Equal(t, anthropicVersion, r.Header.Get("anthropic-version"))
+
+			var body anthropicRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "my-model", body.Model)
+			assert.Equal(t, "You are a coach", body.System)
+			require.Len(t, body.Messages, 1)
+			assert.Equal(t, "user", body.Messages[0].Role)
+			assert.Equal(t, "Create a plan", body.Messages[0].Content)
+			assert.Nil(t, body.Temperature)
+			assert.Equal(t, anthropicMaxTokens, body.MaxTokens)
+
+			w.Write([]byte(`{"content": [{"type": "text", "text": "{\"workouts\": "}, {"type": "text", "text": "[]}"}], "stop_reason": "end_turn", "usage": {"input_tokens": 200, "output_tokens": 50}}`))
+		}))
+		defer server.Close()
+
+		client := NewAnthropicClient(Config{APIKey: "test-key", BaseURL: server.URL, Model: "my-model"})
+		result, err := client.Complete(context.Background(), CompletionRequest{
+			SystemPrompt: "You are a coach",
+			UserPrompt:   "Create a plan",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `{"workouts": []}`, result.Content)
+		assert.Equal(t, Usage{InputTokens: 200, OutputTokens: 50}, result.Usage)
+	})
+
+	t.Run("sends history before the prompt", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body anthropicRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, []anthropicMessage{
+				{Role: "user", Content: "Hi"},
+				{Role: "assistant", Content: "Hello"},
+				{Role: "user", Content: "Move my long run"},
+			}, body.Messages)
+
+			w.Write([]byte(`{"content": [{"type": "text", "text": "{}"}]}`))
+		}))
+		defer server.Close()
+
+		client := NewAnthropicClient(Config{APIKey: "k", BaseURL: server.URL})
+		_, err := client.Complete(context.Background(), CompletionRequest{
+			SystemPrompt: "You are a coach",
+			History:      []Message{{Role: RoleUser, Content: "Hi"}, {Role: RoleAssistant, Content: "Hello"}},
+			UserPrompt:   "Move my long run",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("strips code fence", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"content": [{"type": "text", "text": "` + "```json\\n{\\\"a\\\": 1}\\n```" + `"}]}`))
+		}))
+		defer server.Close()
+
+		client := NewAnthropicClient(Config{APIKey: "k", BaseURL: server.URL})
+		result, err := client.Complete(context.Background(), CompletionRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, `{"a": 1}`, result.Content)
+	})
+
+	t.Run("returns API error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`))
+		}))
+		defer server.Close()
+
+		client := NewAnthropicClient(Config{APIKey: "bad", BaseURL: server.URL})
+		_, err := client.Complete(context.Background(), CompletionRequest{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid x-api-key")
+	})
+
+	t.Run("rejects truncated response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"content": [{"type": "text", "text": "{\"workouts\": ["}], "stop_reason": "max_tokens"}`))
+		}))
+		defer server.Close()
+
+		client := NewAnthropicClient(Config{APIKey: "k", BaseURL: server.URL})
+		_, err := client.Complete(context.Background(), CompletionRequest{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "truncated")
+	})
+}
+
+func TestStripCodeFence(t *testing.T) {
+	assert.Equal(t, `{"a": 1}`, stripCodeFence("```json\n{\"a\": 1}\n```"))
+	assert.Equal(t, `{"a": 1}`, stripCodeFence("```\n{\"a\": 1}\n```"))
+	assert.Equal(t, `{"a": 1}`, stripCodeFence("  {\"a\": 1}\n"))
+}
@@ -4,32 +4,141 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
 type CompletionRequest struct {
 	SystemPrompt string
 	UserPrompt   string
+	// History holds earlier turns of a conversation, oldest first. They are
+	// sent between the system prompt and UserPrompt.
+	History []Message
+}
+
+// Message roles used in CompletionRequest.History.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// CompletionResponse is the model's answer together with what it cost.
+type CompletionResponse struct {
+	Content string
+	Usage   Usage
+}
+
+// Usage counts the tokens of one or more completions. Providers that do not
+// report usage leave it zero.
+type Usage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
 }
 
 type Client interface {
-	Complete(ctx context.Context, req CompletionRequest) (string, error)
+	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
+}
+
+// Providers selectable through Config.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown AI provider")
+	ErrMissingAPIKey   = errors.New("AI provider requires an API key")
+)
+
+// Config selects and tunes a provider. Zero values fall back to the
+// provider's defaults; Temperature is a pointer because 0 is meaningful.
+type Config struct {
+	Provider    string
+	APIKey      string
+	BaseURL     string
+	Model       string
+	Temperature *float64
+	Timeout     time.Duration
+}
+
+const defaultTimeout = 90 * time.Second
+
+// NewClient builds the client for cfg.Provider, defaulting to OpenAI. The
+// OpenAI client may run without a key when BaseURL points at a local
+// OpenAI-compatible server.
+func NewClient(cfg Config) (Client, error) {
+	switch cfg.Provider {
+	case "", ProviderOpenAI:
+		if cfg.APIKey == "" && cfg.BaseURL == "" {
+			return nil, fmt.Errorf("%w: %s", ErrMissingAPIKey, ProviderOpenAI)
+		}
+		return NewOpenAIClientWithConfig(cfg), nil
+	case ProviderAnthropic:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("%w: %s", ErrMissingAPIKey, ProviderAnthropic)
+		}
+		return NewAnthropicClient(cfg), nil
+	case ProviderOllama:
+		return NewOllamaClient(cfg), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, cfg.Provider)
+	}
+}
+
+func newHTTPClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+func valueOr(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
 }
 
 type OpenAIClient struct {
-	apiKey     string
-	httpClient *http.Client
+	apiKey      string
+	baseURL     string
+	model       string
+	temperature float64
+	httpClient  *http.Client
 }
 
 func NewOpenAIClient(apiKey string) *OpenAIClient {
+	return NewOpenAIClientWithConfig(Config{APIKey: apiKey})
+}
+
+// NewOpenAIClientWithConfig also serves OpenAI-compatible servers such as
+// llama.cpp or vLLM through BaseURL, e.g. "http://localhost:8000/v1".
+func NewOpenAIClientWithConfig(cfg Config) *OpenAIClient {
+	temperature := 1.0
+	if cfg.Temperature != nil {
+		temperature = *cfg.Temperature
+	}
 	return &OpenAIClient{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: 90 * time.Second,
-		},
+		apiKey:      cfg.APIKey,
+		baseURL:     strings.TrimRight(valueOr(cfg.BaseURL, "https://api.openai.com/v1"), "/"),
+		model:       valueOr(cfg.Model, "gpt-4o-mini"),
+		temperature: temperature,
+		httpClient:  newHTTPClient(cfg.Timeout),
 	}
 }
 
@@ -55,57 +164,74 @@ type openAIResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error"`
 }
 
-func (c *OpenAIClient) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+func (c *OpenAIClient) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
 	body := openAIRequest{
-		Model: "gpt-4o-mini",
-		Messages: []openAIMessage{
-			{Role: "system", Content: req.SystemPrompt},
-			{Role: "user", Content: req.UserPrompt},
-		},
+		Model:          c.model,
+		Messages:       chatMessages(req),
 		ResponseFormat: openAIRespFormat{Type: "json_object"},
-		Temperature:    1,
+		Temperature:    c.temperature,
 	}
 
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
-		return "", fmt.Errorf("marshal request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/chat/completions", bytes.NewReader(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(jsonBody))
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("openai request failed: %w", err)
+		return nil, fmt.Errorf("openai request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("read response: %w", err)
+		return nil, fmt.Errorf("read response: %w", err)
 	}
 
 	var oaiResp openAIResponse
 	if err := json.Unmarshal(respBody, &oaiResp); err != nil {
-		return "", fmt.Errorf("unmarshal response: %w", err)
+		return nil, fmt.Errorf("unmarshal response: %w", err)
 	}
 
 	if oaiResp.Error != nil {
-		return "", fmt.Errorf("openai error: %s", oaiResp.Error.Message)
+		return nil, fmt.Errorf("openai error: %s", oaiResp.Error.Message)
 	}
 
 	if len(oaiResp.Choices) == 0 {
-		return "", fmt.Errorf("openai returned no choices")
+		return nil, fmt.Errorf("openai returned no choices")
 	}
 
-	return oaiResp.Choices[0].Message.Content, nil
+	return &CompletionResponse{
+		Content: oaiResp.Choices[0].Message.Content,
+		Usage:   Usage{InputTokens: oaiResp.Usage.PromptTokens, OutputTokens: oaiResp.Usage.CompletionTokens},
+	}, nil
+}
+
+// chatMessages lays out a request in the OpenAI chat format, which Ollama
+// shares.
+func chatMessages(req CompletionRequest) []openAIMessage {
+	messages := make([]openAIMessage, 0, len(req.History)+2)
+	messages = append(messages, openAIMessage{Role: "system", Content: req.SystemPrompt})
+	for _, m := range req.History {
+		messages = append(messages, openAIMessage{Role: m.Role, Content: m.Content})
+	}
+	return append(messages, openAIMessage{Role: RoleUser, Content: req.UserPrompt})
 }
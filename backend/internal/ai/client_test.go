@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -38,6 +39,8 @@ func TestOpenAIClient_Complete(t *testing.T) {
 					}{Content: `{"workouts": []}`}},
 				},
 			}
+			resp.Usage.PromptTokens = 120
+			resp.Usage.CompletionTokens = 30
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(resp)
 		}))
@@ -58,7 +61,8 @@ func TestOpenAIClient_Complete(t *testing.T) {
 			UserPrompt:   "Create a plan",
 		})
 		require.NoError(t, err)
-		assert.Equal(t, `{"workouts": []}`, result)
+		assert.Equal(t, `{"workouts": []}`, result.Content)
+		assert.Equal(t, Usage{InputTokens: 120, OutputTokens: 30}, result.Usage)
 	})
 
 	t.Run("returns error on API error response", func(t *testing.T) {
@@ -147,3 +151,90 @@ func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	}
 	return http.DefaultTransport.RoundTrip(req)
 }
+
+func TestChatMessages(t *testing.T) {
+	messages := chatMessages(CompletionRequest{
+		SystemPrompt: "You are a coach",
+		History:      []Message{{Role: RoleUser, Content: "Hi"}, {Role: RoleAssistant, Content: "Hello"}},
+		UserPrompt:   "Move my long run",
+	})
+	assert.Equal(t, []openAIMessage{
+		{Role: "system", Content: "You are a coach"},
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello"},
+		{Role: "user", Content: "Move my long run"},
+	}, messages)
+}
+
+func TestOpenAIClient_Config(t *testing.T) {
+	t.Run("uses base URL, model and temperature", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+			assert.Empty(t, r.Header.Get("Authorization"))
+
+			var body openAIRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "local-model", body.Model)
+			assert.Equal(t, float64(0), body.Temperature)
+
+			w.Write([]byte(`{"choices": [{"message": {"content": "{}"}}]}`))
+		}))
+		defer server.Close()
+
+		temperature := 0.0
+		client := NewOpenAIClientWithConfig(Config{
+			BaseURL:     server.URL + "/v1/",
+			Model:       "local-model",
+			Temperature: &temperature,
+			Timeout:     5 * time.Second,
+		})
+		assert.Equal(t, 5*time.Second, client.httpClient.Timeout)
+
+		result, err := client.Complete(context.Background(), CompletionRequest{SystemPrompt: "s", UserPrompt: "u"})
+		require.NoError(t, err)
+		assert.Equal(t, "{}", result.Content)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		client := NewOpenAIClient("key")
+		assert.Equal(t, "https://api.openai.com/v1", client.baseURL)
+		assert.Equal(t, "gpt-4o-mini", client.model)
+		assert.Equal(t, float64(1), client.temperature)
+		assert.Equal(t, 90*time.Second, client.httpClient.Timeout)
+	})
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want interface{}
+		err  error
+	}{
+		{"openai by default", Config{APIKey: "k"}, &OpenAIClient{}, nil},
+		{"openai-compatible without key", Config{Provider: ProviderOpenAI, BaseURL: "http://localhost:8000/v1"}, &OpenAIClient{}, nil},
+		{"openai needs key or base URL", Config{Provider: ProviderOpenAI}, nil, ErrMissingAPIKey},
+		{"anthropic", Config{Provider: ProviderAnthropic, APIKey: "k"}, &AnthropicClient{}, nil},
+		{"anthropic needs key", Config{Provider: ProviderAnthropic}, nil, ErrMissingAPIKey},
+		{"ollama", Config{Provider: ProviderOllama}, &OllamaClient{}, nil},
+		{"unknown", Config{Provider: "gemini"}, nil, ErrUnknownProvider},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(tt.cfg)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, tt.want, client)
+		})
+	}
+}
+
+func TestUsage_Add(t *testing.T) {
+	var u Usage
+	u.Add(Usage{InputTokens: 10, OutputTokens: 2})
+	u.Add(Usage{InputTokens: 5, OutputTokens: 1})
+	assert.Equal(t, Usage{InputTokens: 15, OutputTokens: 3}, u)
+}
@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Fixture modes, selected on the server with AI_FIXTURES.
+const (
+	FixtureRecord = "record"
+	FixtureReplay = "replay"
+)
+
+var ErrFixtureNotFound = errors.New("no recorded AI response for this prompt")
+
+// Fixture is one recorded completion, stored as <dir>/<key>.json. The
+// request is kept so fixtures can be read and reviewed.
+type Fixture struct {
+	Key      string          `json:"key"`
+	Request  fixtureRequest  `json:"request"`
+	Response fixtureResponse `json:"response"`
+}
+
+type fixtureRequest struct {
+	SystemPrompt string    `json:"systemPrompt"`
+	History      []Message `json:"history,omitempty"`
+	UserPrompt   string    `json:"userPrompt"`
+}
+
+type fixtureResponse struct {
+	Content string `json:"content"`
+	Usage   Usage  `json:"usage"`
+}
+
+// FixtureKey identifies a request by the hash of its prompts, so a replay
+// only matches when the prompt is exactly the one recorded.
+func FixtureKey(req CompletionRequest) string {
+	raw, _ := json.Marshal(newFixtureRequest(req))
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func newFixtureRequest(req CompletionRequest) fixtureRequest {
+	return fixtureRequest{SystemPrompt: req.SystemPrompt, History: req.History, UserPrompt: req.UserPrompt}
+}
+
+func fixturePath(dir, key string) string {
+	return filepath.Join(dir, key+".json")
+}
+
+// RecordingClient passes requests to another client and writes every
+// successful completion to a fixture directory.
+type RecordingClient struct {
+	next Client
+	dir  string
+}
+
+func NewRecordingClient(next Client, dir string) *RecordingClient {
+	return &RecordingClient{next: next, dir: dir}
+}
+
+func (c *RecordingClient) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	resp, err := c.next.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	key := FixtureKey(req)
+	fixture := Fixture{
+		Key:      key,
+		Request:  newFixtureRequest(req),
+		Response: fixtureResponse{Content: resp.Content, Usage: resp.Usage},
+	}
+	if err := writeFixture(c.dir, &fixture); err != nil {
+		return nil, fmt.Errorf("record fixture %s: %w", key, err)
+	}
+	return resp, nil
+}
+
+// writeFixture replaces the fixture atomically so a concurrent replay never
+// reads half a file.
+func writeFixture(dir string, fixture *Fixture) error {
+	raw, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, fixture.Key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(raw, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fixturePath(dir, fixture.Key))
+}
+
+// ReplayClient answers from fixtures written by RecordingClient and never
+// reaches a provider. Requests that were not recorded fail with
+// ErrFixtureNotFound.
+type ReplayClient struct {
+	dir string
+}
+
+func NewReplayClient(dir string) *ReplayClient {
+	return &ReplayClient{dir: dir}
+}
+
+func (c *ReplayClient) Complete(_ context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	key := FixtureKey(req)
+	raw, err := os.ReadFile(fixturePath(c.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s in %s", ErrFixtureNotFound, key, c.dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read fixture %s: %w", key, err)
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(raw, &fixture); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: %w", key, err)
+	}
+	return &CompletionResponse{Content: fixture.Response.Content, Usage: fixture.Response.Usage}, nil
+}
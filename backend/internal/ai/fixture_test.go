@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubClient struct {
+	resp  *CompletionResponse
+	err   error
+	calls int
+}
+
+func (s *stubClient) Complete(_ context.Context, _ CompletionRequest) (*CompletionResponse, error) {
+	s.calls++
+	return s.resp, s.err
+}
+
+func TestFixtureKey(t *testing.T) {
+	req := CompletionRequest{SystemPrompt: "s", UserPrompt: "u"}
+	assert.Equal(t, FixtureKey(req), FixtureKey(CompletionRequest{SystemPrompt: "s", UserPrompt: "u"}))
+	assert.Len(t, FixtureKey(req), 64)
+
+	for _, other := range []CompletionRequest{
+		{SystemPrompt: "s2", UserPrompt: "u"},
+		{SystemPrompt: "s", UserPrompt: "u2"},
+		{SystemPrompt: "s", UserPrompt: "u", History: []Message{{Role: RoleUser, Content: "hi"}}},
+		{SystemPrompt: "su", UserPrompt: ""},
+	} {
+		assert.NotEqual(t, FixtureKey(req), FixtureKey(other))
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	req := CompletionRequest{
+		SystemPrompt: "You are a coach.",
+		History:      []Message{{Role: RoleUser, Content: "Hi"}, {Role: RoleAssistant, Content: "Hello"}},
+		UserPrompt:   "Plan my week",
+	}
+	stub := &stubClient{resp: &CompletionResponse{Content: `{"workouts": []}`, Usage: Usage{InputTokens: 12, OutputTokens: 3}}}
+
+	t.Run("records successful completions", func(t *testing.T) {
+		resp, err := NewRecordingClient(stub, dir).Complete(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, stub.resp, resp)
+
+		raw, err := os.ReadFile(filepath.Join(dir, FixtureKey(req)+".json"))
+		require.NoError(t, err)
+		assert.Contains(t, string(raw), `"userPrompt": "Plan my week"`)
+		assert.Contains(t, string(raw), `"role": "assistant"`)
+	})
+
+	t.Run("replays the recorded response", func(t *testing.T) {
+		resp, err := NewReplayClient(dir).Complete(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, `{"workouts": []}`, resp.Content)
+		assert.Equal(t, Usage{InputTokens: 12, OutputTokens: 3}, resp.Usage)
+	})
+
+	t.Run("unknown prompts are not found", func(t *testing.T) {
+		other := req
+		other.UserPrompt = "Plan my month"
+		_, err := NewReplayClient(dir).Complete(context.Background(), other)
+		assert.ErrorIs(t, err, ErrFixtureNotFound)
+		assert.Contains(t, err.Error(), FixtureKey(other))
+	})
+
+	t.Run("provider errors are passed on and not recorded", func(t *testing.T) {
+		failing := &stubClient{err: errors.New("rate limited")}
+		other := req
+		other.UserPrompt = "Plan my year"
+		_, err := NewRecordingClient(failing, dir).Complete(context.Background(), other)
+		assert.EqualError(t, err, "rate limited")
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+}
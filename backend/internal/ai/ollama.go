@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OllamaClient calls a local Ollama server's chat endpoint with JSON output
+// enabled.
+type OllamaClient struct {
+	baseURL     string
+	model       string
+	temperature *float64
+	httpClient  *http.Client
+}
+
+func NewOllamaClient(cfg Config) *OllamaClient {
+	return &OllamaClient{
+		baseURL:     strings.TrimRight(valueOr(cfg.BaseURL, "http://localhost:11434"), "/"),
+		model:       valueOr(cfg.Model, "llama3.1"),
+		temperature: cfg.Temperature,
+		httpClient:  newHTTPClient(cfg.Timeout),
+	}
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []openAIMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   string          `json:"format"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+func (c *OllamaClient) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	body := ollamaRequest{
+		Model:    c.model,
+		Messages: chatMessages(req),
+		Format:   "json",
+	}
+	if c.temperature != nil {
+		body.Options = &ollamaOptions{Temperature: *c.temperature}
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/chat", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ollama request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var oResp ollamaResponse
+	if err := json.Unmarshal(respBody, &oResp); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	if oResp.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", oResp.Error)
+	}
+	if oResp.Message.Content == "" {
+		return nil, fmt.Errorf("ollama returned no content")
+	}
+	return &CompletionResponse{
+		Content: oResp.Message.Content,
+		Usage:   Usage{InputTokens: oResp.PromptEvalCount, OutputTokens: oResp.EvalCount},
+	}, nil
+}
@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaClient_Complete(t *testing.T) {
+	t.Run("sends non-streaming JSON chat request", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/chat", r.URL.Path)
+
+			var body ollamaRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "llama3.1", body.Model)
+			assert.False(t, body.Stream)
+			assert.Equal(t, "json", body.Format)
+			require.Len(t, body.Messages, 2)
+			assert.Equal(t, "system", body.Messages[0].Role)
+			require.NotNil(t, body.Options)
+			assert.Equal(t, 0.2, body.Options.Temperature)
+
+			w.Write([]byte(`{"message": {"role": "assistant", "content": "{\"workouts\": []}"}, "done": true, "prompt_eval_count": 80, "eval_count": 20}`))
+		}))
+		defer server.Close()
+
+		temperature := 0.2
+		client := NewOllamaClient(Config{BaseURL: server.URL, Temperature: &temperature})
+		result, err := client.Complete(context.Background(), CompletionRequest{SystemPrompt: "s", UserPrompt: "u"})
+		require.NoError(t, err)
+		assert.Equal(t, `{"workouts": []}`, result.Content)
+		assert.Equal(t, Usage{InputTokens: 80, OutputTokens: 20}, result.Usage)
+	})
+
+	t.Run("returns server error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "model \"llama3.1\" not found, try pulling it first"}`))
+		}))
+		defer server.Close()
+
+		client := NewOllamaClient(Config{BaseURL: server.URL})
+		_, err := client.Complete(context.Background(), CompletionRequest{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not found")
+	})
+}
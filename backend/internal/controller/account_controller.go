@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type AccountController struct {
+	auth     *service.AuthService
+	accounts *service.AccountService
+	sessions *service.SessionService
+	verify   *service.EmailVerificationService
+}
+
+// RegisterAccountRoutes adds changing the password and email address,
+// deleting the account and exporting its data. The changes need the
+// current password; none of them are available to API tokens. verify may
+// be nil, in which case a new address is not sent a verification link.
+func RegisterAccountRoutes(rg *gin.RouterGroup, auth *service.AuthService, accounts *service.AccountService, sessions *service.SessionService, verify *service.EmailVerificationService) {
+	ac := &AccountController{auth: auth, accounts: accounts, sessions: sessions, verify: verify}
+	me := rg.Group("/me")
+	me.Use(requireAuth, requireSession)
+	{
+		me.PUT("/password", ac.putPassword)
+		me.PUT("/email", ac.putEmail)
+		me.DELETE("", ac.deleteAccount)
+		me.GET("/export", ac.getExport)
+	}
+}
+
+type changePasswordInput struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
+type changeEmailInput struct {
+	Password string `json:"password"`
+	Email    string `json:"email" binding:"required"`
+}
+
+type confirmPasswordInput struct {
+	Password string `json:"password"`
+}
+
+// writeAccountError answers a wrong password with 403 rather than 401, so
+// the client does not take it for an expired session. Other errors are
+// invalid input, as on registration.
+func writeAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrWrongPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNoPassword):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, store.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "email already registered"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func (ac *AccountController) putPassword(c *gin.Context) {
+	var req changePasswordInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "newPassword required"})
+		return
+	}
+	uid := model.UserID(currentUserID(c))
+	if err := ac.auth.ChangePassword(uid, req.CurrentPassword, req.NewPassword); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	// Whoever knew the old password is logged out; this device stays in.
+	if err := ac.sessions.RevokeOthers(uid, model.SessionID(sessions.Default(c).ID())); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "password changed, but other sessions could not be logged out"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (ac *AccountController) putEmail(c *gin.Context) {
+	var req changeEmailInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email required"})
+		return
+	}
+	u, err := ac.auth.ChangeEmail(model.UserID(currentUserID(c)), req.Password, req.Email)
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	if ac.verify != nil && u.EmailVerifiedAt == nil {
+		// As on registration, a failed email can be resent later.
+		_ = ac.verify.Restart(c.Request.Context(), u)
+	}
+	c.JSON(http.StatusOK, gin.H{"user": u.Public()})
+}
+
+func (ac *AccountController) deleteAccount(c *gin.Context) {
+	var req confirmPasswordInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password required"})
+		return
+	}
+	uid := model.UserID(currentUserID(c))
+	if _, err := ac.auth.CheckPassword(uid, req.Password); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	if err := ac.accounts.Delete(uid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete account"})
+		return
+	}
+	sess := sessions.Default(c)
+	sess.Clear()
+	_ = sess.Save()
+	c.Status(http.StatusNoContent)
+}
+
+func (ac *AccountController) getExport(c *gin.Context) {
+	export, err := ac.accounts.Export(model.UserID(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export account"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"runplanner-export-%s.zip\"", export.ExportedAt.Format("2006-01-02")))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	// The status is sent; a failure from here on can only cut the zip
+	// short, which the client notices when opening it.
+	_ = export.WriteZip(c.Writer)
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/mail"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/session"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAccountTestRouter sends verification emails to a log mailer
+// writing into the returned buffer.
+func setupAccountTestRouter(t *testing.T) (*gin.Engine, *service.AuthService, *service.TrainingPlanService, *bytes.Buffer) {
+	gin.SetMode(gin.TestMode)
+	stores := service.AccountStores{
+		Users:              mem.NewMemUserStore(),
+		Plans:              mem.NewMemTrainingPlanStore(),
+		Workouts:           mem.NewMemWorkoutStore(),
+		Activities:         mem.NewMemActivityStore(),
+		Coach:              mem.NewMemCoachStore(),
+		GenerateJobs:       mem.NewMemGenerateJobStore(),
+		AIUsage:            mem.NewMemAIUsageStore(),
+		PasswordResets:     mem.NewMemPasswordResetStore(),
+		EmailVerifications: mem.NewMemEmailVerificationStore(),
+		APITokens:          mem.NewMemAPITokenStore(),
+		Identities:         mem.NewMemIdentityStore(),
+		TwoFactor:          mem.NewMemTwoFactorStore(),
+		Sessions:           mem.NewMemSessionStore(),
+		Coachings:          mem.NewMemCoachingStore(),
+		PlanEdits:          mem.NewMemPlanEditStore(),
+		ShareLinks:         mem.NewMemShareLinkStore(),
+	}
+	var mails bytes.Buffer
+	authSvc := service.NewAuthService(stores.Users)
+	verifySvc := service.NewEmailVerificationService(stores.Users, stores.EmailVerifications, mail.NewLogMailer(log.New(&mails, "", 0)), "http://localhost:5173", false)
+	sessionSvc := service.NewSessionService(stores.Sessions)
+	planSvc := service.NewTrainingPlanServiceWithDependents(stores.Plans, stores.PlanDependents())
+
+	r := gin.New()
+	r.Use(sessions.Sessions("rp.sid", session.NewStore(stores.Sessions, []byte("test-secret"))))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterAccountRoutes(api, authSvc, service.NewAccountService(stores), sessionSvc, verifySvc)
+
+	return r, authSvc, planSvc, &mails
+}
+
+func accountRequest(r *gin.Engine, cookies []*http.Cookie, method, url string, body interface{}) *httptest.ResponseRecorder {
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, url, bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAccountController_ChangePassword(t *testing.T) {
+	r, authSvc, _, _ := setupAccountTestRouter(t)
+	_, err := authSvc.Register("runner@example.com", "password123")
+	require.NoError(t, err)
+	laptop := loginForGenerate(t, r, "runner@example.com", "password123")
+	phone := loginForGenerate(t, r, "runner@example.com", "password123")
+
+	t.Run("wrong current password is forbidden", func(t *testing.T) {
+		w := accountRequest(r, laptop, http.MethodPut, "/api/me/password",
+			map[string]string{"currentPassword": "wrong-password", "newPassword": "new-password"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("weak new password is rejected", func(t *testing.T) {
+		w := accountRequest(r, laptop, http.MethodPut, "/api/me/password",
+			map[string]string{"currentPassword": "password123", "newPassword": "short"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("changes the password and logs out other sessions", func(t *testing.T) {
+		w := accountRequest(r, laptop, http.MethodPut, "/api/me/password",
+			map[string]string{"currentPassword": "password123", "newPassword": "new-password"})
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		w = coachRequest(r, laptop, http.MethodGet, "/api/auth/me")
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = coachRequest(r, phone, http.MethodGet, "/api/auth/me")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		loginForGenerate(t, r, "runner@example.com", "new-password")
+	})
+}
+
+func TestAccountController_ChangeEmail(t *testing.T) {
+	r, authSvc, _, mails := setupAccountTestRouter(t)
+	_, err := authSvc.Register("old@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("taken@example.com", "password123")
+	require.NoError(t, err)
+	cookies := loginForGenerate(t, r, "old@example.com", "password123")
+
+	t.Run("address of another account conflicts", func(t *testing.T) {
+		w := accountRequest(r, cookies, http.MethodPut, "/api/me/email",
+			map[string]string{"password": "password123", "email": "taken@example.com"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("wrong password is forbidden", func(t *testing.T) {
+		w := accountRequest(r, cookies, http.MethodPut, "/api/me/email",
+			map[string]string{"password": "wrong-password", "email": "new@example.com"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("changes the address and sends a verification link", func(t *testing.T) {
+		w := accountRequest(r, cookies, http.MethodPut, "/api/me/email",
+			map[string]string{"password": "password123", "email": "new@example.com"})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"email":"new@example.com"`)
+		assert.Contains(t, w.Body.String(), `"emailVerified":false`)
+		assert.Contains(t, mails.String(), "new@example.com")
+		assert.Contains(t, mails.String(), "http://localhost:5173/verify-email?token=")
+	})
+}
+
+func TestAccountController_Delete(t *testing.T) {
+	r, authSvc, planSvc, _ := setupAccountTestRouter(t)
+	u, err := authSvc.Register("leaving@example.com", "password123")
+	require.NoError(t, err)
+	plan, err := planSvc.Create(u.ID, "Marathon", time.Now().AddDate(0, 0, 70), 8)
+	require.NoError(t, err)
+	cookies := loginForGenerate(t, r, "leaving@example.com", "password123")
+	other := loginForGenerate(t, r, "leaving@example.com", "password123")
+
+	t.Run("wrong password is forbidden", func(t *testing.T) {
+		w := accountRequest(r, cookies, http.MethodDelete, "/api/me", map[string]string{"password": "wrong-password"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("deletes the account and its data", func(t *testing.T) {
+		w := accountRequest(r, cookies, http.MethodDelete, "/api/me", map[string]string{"password": "password123"})
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		for _, c := range [][]*http.Cookie{cookies, other} {
+			w = coachRequest(r, c, http.MethodGet, "/api/auth/me")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+		_, err := planSvc.GetByID(plan.ID)
+		assert.Error(t, err)
+		_, err = authSvc.Login("leaving@example.com", "password123")
+		assert.Error(t, err)
+	})
+}
+
+func TestAccountController_Export(t *testing.T) {
+	r, authSvc, planSvc, _ := setupAccountTestRouter(t)
+	u, err := authSvc.Register("export@example.com", "password123")
+	require.NoError(t, err)
+	_, err = planSvc.Create(u.ID, "Marathon", time.Now().AddDate(0, 0, 70), 8)
+	require.NoError(t, err)
+	cookies := loginForGenerate(t, r, "export@example.com", "password123")
+
+	t.Run("requires login", func(t *testing.T) {
+		w := coachRequest(r, nil, http.MethodGet, "/api/me/export")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("streams a zip of the account", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodGet, "/api/me/export")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "runplanner-export-")
+
+		zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		require.NoError(t, err)
+		names := map[string]bool{}
+		for _, f := range zr.File {
+			names[f.Name] = true
+		}
+		assert.True(t, names["account.json"])
+		assert.True(t, names["plans.json"])
+	})
+}
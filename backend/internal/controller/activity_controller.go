@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+// maxActivityUploadBytes caps uploaded activity files; a multi-hour GPX or
+// TCX recording with one point per second stays well below this.
+const maxActivityUploadBytes = 20 << 20
+
+type ActivityController struct {
+	activities *service.ActivityService
+	workouts   *service.WorkoutService
+	access     *service.AccessService
+}
+
+// RegisterActivityRoutes adds the activity uploads. Coaches can see their
+// athletes' activities; only the athletes upload them.
+func RegisterActivityRoutes(rg *gin.RouterGroup, activities *service.ActivityService, workouts *service.WorkoutService, access *service.AccessService) {
+	ac := &ActivityController{
+		activities: activities,
+		workouts:   workouts,
+		access:     access,
+	}
+
+	ws := rg.Group("/workouts")
+	ws.Use(requireAuth)
+	{
+		ws.POST("/:id/activity", ac.postWorkoutActivity)
+		ws.GET("/:id/activity", ac.getWorkoutActivity)
+	}
+
+	plansGroup := rg.Group("/plans")
+	plansGroup.Use(requireAuth)
+	{
+		plansGroup.POST("/:id/activity", ac.postPlanActivity)
+	}
+}
+
+// accessibleWorkout loads the workout from the :id path param if the
+// current user may access it. It writes the error response itself.
+func (a *ActivityController) accessibleWorkout(c *gin.Context, level service.Access) (*model.Workout, bool) {
+	workout, _, ok := accessibleWorkout(c, a.access, model.WorkoutID(c.Param("id")), level)
+	return workout, ok
+}
+
+func (a *ActivityController) postWorkoutActivity(c *gin.Context) {
+	workout, ok := a.accessibleWorkout(c, service.AccessOwner)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxActivityUploadBytes)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer f.Close()
+
+	activity, err := a.activities.Import(workout, fh.Filename, f)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidActivityFile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import activity"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"activity": activity, "workout": workout})
+}
+
+func (a *ActivityController) getWorkoutActivity(c *gin.Context) {
+	workout, ok := a.accessibleWorkout(c, service.AccessView)
+	if !ok {
+		return
+	}
+
+	activity, err := a.activities.GetByWorkoutID(workout.ID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get activity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"activity": activity})
+}
+
+func (a *ActivityController) postPlanActivity(c *gin.Context) {
+	planID := model.TrainingPlanID(c.Param("id"))
+
+	plan, ok := accessiblePlan(c, a.access, planID, service.AccessOwner)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxActivityUploadBytes)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	// The activity start time is in UTC, which can fall on the previous or
+	// next calendar day for early or late runs, so allow overriding it.
+	var day *time.Time
+	if s := c.PostForm("date"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+			return
+		}
+		day = &d
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer f.Close()
+
+	activity, workout, err := a.activities.ImportForPlan(plan, fh.Filename, f, day)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidActivityFile):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNoMatchingWorkout):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import activity"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"activity": activity, "workout": workout})
+}
@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const activityTestGPX = `<gpx version="1.1">
+  <trk><trkseg>
+    <trkpt lat="52.5200" lon="13.4050"><ele>30</ele><time>2025-04-01T06:00:00Z</time></trkpt>
+    <trkpt lat="52.5290" lon="13.4050"><ele>40</ele><time>2025-04-01T06:05:00Z</time></trkpt>
+  </trkseg></trk>
+</gpx>`
+
+func setupActivityTestRouter(t *testing.T) (*gin.Engine, *service.AuthService, *service.TrainingPlanService, *service.WorkoutService) {
+	gin.SetMode(gin.TestMode)
+	authSvc := service.NewAuthService(mem.NewMemUserStore())
+	planSvc := service.NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workoutSvc := service.NewWorkoutService(mem.NewMemWorkoutStore())
+	activitySvc := service.NewActivityService(mem.NewMemActivityStore(), workoutSvc)
+
+	r := gin.New()
+	storeCookie := cookie.NewStore([]byte("test-secret"))
+	r.Use(sessions.Sessions("rp.sid", storeCookie))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterWorkoutRoutes(api, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil))
+	RegisterActivityRoutes(api, activitySvc, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil))
+
+	return r, authSvc, planSvc, workoutSvc
+}
+
+func newActivityUpload(t *testing.T, url, filename, content string, fields map[string]string) *http.Request {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = fw.Write([]byte(content))
+	require.NoError(t, err)
+	for k, v := range fields {
+		require.NoError(t, mw.WriteField(k, v))
+	}
+	require.NoError(t, mw.Close())
+	req := httptest.NewRequest(http.MethodPost, url, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestActivityController_PostWorkoutActivity(t *testing.T) {
+	r, authSvc, planSvc, workoutSvc := setupActivityTestRouter(t)
+	u, _ := authSvc.Register("activity@example.com", "password123")
+	plan, _ := planSvc.Create(u.ID, "My Plan", mustParseDate("2025-05-01"), 8)
+	workout, _ := workoutSvc.Create(plan.ID, "easy_run", mustParseDate("2025-04-01"), "Easy", 5.0)
+	cookies := loginAndGetWorkoutCookies(t, r, "activity@example.com", "password123")
+
+	t.Run("imports gpx and completes workout", func(t *testing.T) {
+		req := newActivityUpload(t, "/api/workouts/"+string(workout.ID)+"/activity", "run.gpx", activityTestGPX, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "completed", resp["workout"]["status"])
+		assert.Equal(t, string(workout.ID), resp["activity"]["workoutId"])
+		assert.InDelta(t, 1.0, resp["activity"]["distance"], 0.01)
+	})
+
+	t.Run("accepts tcx on the same endpoint", func(t *testing.T) {
+		tcx := `<TrainingCenterDatabase><Activities><Activity Sport="Running">
+			<Lap StartTime="2025-04-01T06:00:00Z">
+				<TotalTimeSeconds>300</TotalTimeSeconds><DistanceMeters>1000</DistanceMeters>
+				<AverageHeartRateBpm><Value>145</Value></AverageHeartRateBpm>
+				<Track>
+					<Trackpoint><Time>2025-04-01T06:00:00Z</Time><DistanceMeters>0</DistanceMeters><HeartRateBpm><Value>140</Value></HeartRateBpm></Trackpoint>
+					<Trackpoint><Time>2025-04-01T06:05:00Z</Time><DistanceMeters>1000</DistanceMeters><HeartRateBpm><Value>150</Value></HeartRateBpm></Trackpoint>
+				</Track>
+			</Lap>
+		</Activity></Activities></TrainingCenterDatabase>`
+		req := newActivityUpload(t, "/api/workouts/"+string(workout.ID)+"/activity", "run.tcx", tcx, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "tcx", resp["activity"]["source"])
+		assert.Equal(t, 145.0, resp["activity"]["avgHeartRate"])
+		assert.Len(t, resp["activity"]["laps"], 1)
+	})
+
+	t.Run("returns stored activity", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/workouts/"+string(workout.ID)+"/activity", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("returns 400 for invalid file", func(t *testing.T) {
+		req := newActivityUpload(t, "/api/workouts/"+string(workout.ID)+"/activity", "run.gpx", "not a gpx file", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("returns 404 for another user's workout", func(t *testing.T) {
+		_, _ = authSvc.Register("other-activity@example.com", "password123")
+		otherCookies := loginAndGetWorkoutCookies(t, r, "other-activity@example.com", "password123")
+		req := newActivityUpload(t, "/api/workouts/"+string(workout.ID)+"/activity", "run.gpx", activityTestGPX, nil)
+		for _, c := range otherCookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("returns 401 when not authenticated", func(t *testing.T) {
+		req := newActivityUpload(t, "/api/workouts/"+string(workout.ID)+"/activity", "run.gpx", activityTestGPX, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestActivityController_PostPlanActivity(t *testing.T) {
+	r, authSvc, planSvc, workoutSvc := setupActivityTestRouter(t)
+	u, _ := authSvc.Register("planactivity@example.com", "password123")
+	plan, _ := planSvc.Create(u.ID, "My Plan", mustParseDate("2025-05-01"), 8)
+	workout, _ := workoutSvc.Create(plan.ID, "easy_run", mustParseDate("2025-04-01"), "Easy", 5.0)
+	cookies := loginAndGetWorkoutCookies(t, r, "planactivity@example.com", "password123")
+
+	t.Run("matches workout by activity date", func(t *testing.T) {
+		req := newActivityUpload(t, "/api/plans/"+string(plan.ID)+"/activity", "run.gpx", activityTestGPX, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, string(workout.ID), resp["workout"]["id"])
+	})
+
+	t.Run("returns 404 when no workout on date", func(t *testing.T) {
+		req := newActivityUpload(t, "/api/plans/"+string(plan.ID)+"/activity", "run.gpx", activityTestGPX, map[string]string{"date": "2025-04-02"})
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("returns 400 for malformed date", func(t *testing.T) {
+		req := newActivityUpload(t, "/api/plans/"+string(plan.ID)+"/activity", "run.gpx", activityTestGPX, map[string]string{"date": "April 2nd"})
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
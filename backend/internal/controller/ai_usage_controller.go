@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+)
+
+type AIUsageController struct {
+	usage *service.AIUsageService
+}
+
+func RegisterAIUsageRoutes(rg *gin.RouterGroup, usage *service.AIUsageService) {
+	uc := &AIUsageController{usage: usage}
+
+	me := rg.Group("/me")
+	me.Use(requireAuth)
+	{
+		me.GET("/ai-usage", uc.getUsage)
+	}
+}
+
+func (u *AIUsageController) getUsage(c *gin.Context) {
+	summary, err := u.usage.Summary(model.UserID(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get AI usage"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"usage": summary})
+}
+
+// writeQuotaExceeded answers 429 with a Retry-After header telling the
+// client when the quota resets.
+func writeQuotaExceeded(c *gin.Context, quotaErr *service.QuotaError) {
+	retryAfter := int(time.Until(quotaErr.ResetAt).Seconds()) + 1
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":   quotaErr.Error(),
+		"period":  quotaErr.Period,
+		"limit":   quotaErr.Limit,
+		"resetAt": quotaErr.ResetAt,
+	})
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unlimitedAIUsage() *service.AIUsageService {
+	return service.NewAIUsageService(mem.NewMemAIUsageStore(), service.AIQuota{})
+}
+
+func setupAIUsageTestRouter(t *testing.T, quota service.AIQuota) (*gin.Engine, *service.AuthService, *service.GenerateJobService) {
+	gin.SetMode(gin.TestMode)
+	authSvc := service.NewAuthService(mem.NewMemUserStore())
+	planSvc := service.NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workoutSvc := service.NewWorkoutService(mem.NewMemWorkoutStore())
+	usageSvc := service.NewAIUsageService(mem.NewMemAIUsageStore(), quota)
+	genSvc := service.NewGenerateService(&mockAIClient{response: validMockResponse()}, usageSvc, planSvc, workoutSvc)
+	jobSvc := service.NewGenerateJobService(mem.NewMemGenerateJobStore(), genSvc)
+
+	r := gin.New()
+	storeCookie := cookie.NewStore([]byte("test-secret"))
+	r.Use(sessions.Sessions("rp.sid", storeCookie))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil), jobSvc, nil, nil, nil)
+	RegisterGenerateJobRoutes(api, jobSvc)
+	RegisterAIUsageRoutes(api, usageSvc)
+
+	return r, authSvc, jobSvc
+}
+
+func TestAIUsageController(t *testing.T) {
+	r, authSvc, jobs := setupAIUsageTestRouter(t, service.AIQuota{Daily: 1, Monthly: 10})
+	_, err := authSvc.Register("usage@example.com", "password123")
+	require.NoError(t, err)
+	cookies := loginForGenerate(t, r, "usage@example.com", "password123")
+
+	getUsage := func(t *testing.T) service.AIUsageSummary {
+		t.Helper()
+		w := coachRequest(r, cookies, http.MethodGet, "/api/me/ai-usage")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Usage service.AIUsageSummary `json:"usage"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Usage
+	}
+
+	body := map[string]interface{}{
+		"name":          "Plan",
+		"endDate":       "2025-09-14",
+		"weeks":         8,
+		"baseKmPerWeek": 30.0,
+		"runsPerWeek":   3,
+		"raceGoal":      "halfmarathon",
+	}
+
+	t.Run("starts empty", func(t *testing.T) {
+		usage := getUsage(t)
+		assert.Equal(t, 0, usage.Day.Used)
+		assert.Equal(t, 1, usage.Day.Limit)
+		assert.Equal(t, 10, usage.Month.Limit)
+	})
+
+	t.Run("counts a generation", func(t *testing.T) {
+		finishGenerateJob(t, r, cookies, postReplan(r, cookies, "/api/plans/generate", body))
+		jobs.Wait()
+		usage := getUsage(t)
+		assert.Equal(t, 1, usage.Day.Used)
+		assert.Equal(t, 1, usage.ByFeature[service.FeatureGenerate])
+		assert.GreaterOrEqual(t, usage.Day.Requests, 1)
+	})
+
+	t.Run("returns 429 once the quota is used up", func(t *testing.T) {
+		w := postReplan(r, cookies, "/api/plans/generate", body)
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, w.Body.String(), "daily AI quota of 1 requests reached")
+
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.Greater(t, retryAfter, 0)
+		assert.LessOrEqual(t, retryAfter, int((24*time.Hour).Seconds())+1)
+
+		var resp struct {
+			Period  string    `json:"period"`
+			Limit   int       `json:"limit"`
+			ResetAt time.Time `json:"resetAt"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "daily", resp.Period)
+		assert.Equal(t, 1, resp.Limit)
+	})
+
+	t.Run("rule-based generation is not limited", func(t *testing.T) {
+		rules := map[string]interface{}{}
+		for k, v := range body {
+			rules[k] = v
+		}
+		rules["strategy"] = service.StrategyRules
+		w := postReplan(r, cookies, "/api/plans/generate", rules)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		jobs.Wait()
+	})
+
+	t.Run("unauthenticated returns 401", func(t *testing.T) {
+		w := coachRequest(r, nil, http.MethodGet, "/api/me/ai-usage")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+// Context keys set by BearerAuth.
+const (
+	ctxUserID   = "rp.uid"
+	ctxAPIToken = "rp.apiToken"
+)
+
+// BearerAuth authenticates requests that carry "Authorization: Bearer"
+// with a personal API token. GET requests need the token's read scope and
+// all other methods its write scope. Requests without the header fall
+// through to the session cookie.
+func BearerAuth(tokens *service.APITokenService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+			c.Next()
+			return
+		}
+		token, err := tokens.Authenticate(strings.TrimSpace(header[7:]))
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidAPIToken) {
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check API token"})
+			return
+		}
+
+		scope := model.ScopeWrite
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = model.ScopeRead
+		}
+		if !token.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API token lacks the " + scope + " scope"})
+			return
+		}
+		c.Set(ctxUserID, string(token.UserID))
+		c.Set(ctxAPIToken, token)
+		c.Next()
+	}
+}
+
+// apiToken returns the token that authenticated the request, or nil for
+// session requests.
+func apiToken(c *gin.Context) *model.APIToken {
+	if v, ok := c.Get(ctxAPIToken); ok {
+		return v.(*model.APIToken)
+	}
+	return nil
+}
+
+// requireScope checks an extra scope on token requests; session requests
+// pass.
+func requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if t := apiToken(c); t != nil && !t.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API token lacks the " + scope + " scope"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireSession rejects token requests, so a token cannot be used to
+// manage tokens.
+func requireSession(c *gin.Context) {
+	if apiToken(c) != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not available with an API token"})
+		return
+	}
+	c.Next()
+}
+
+type APITokenController struct {
+	tokens *service.APITokenService
+}
+
+func RegisterAPITokenRoutes(rg *gin.RouterGroup, tokens *service.APITokenService) {
+	tc := &APITokenController{tokens: tokens}
+	group := rg.Group("/me/tokens")
+	group.Use(requireAuth, requireSession)
+	{
+		group.GET("", tc.getTokens)
+		group.POST("", tc.postToken)
+		group.DELETE("/:id", tc.deleteToken)
+	}
+}
+
+type createAPITokenInput struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expiresInDays"`
+}
+
+func (tc *APITokenController) getTokens(c *gin.Context) {
+	tokens, err := tc.tokens.List(model.UserID(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tokens"})
+		return
+	}
+	if tokens == nil {
+		tokens = []*model.APIToken{}
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (tc *APITokenController) postToken(c *gin.Context) {
+	var req createAPITokenInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and scopes required"})
+		return
+	}
+	token, secret, err := tc.tokens.Create(model.UserID(currentUserID(c)), service.CreateAPITokenInput{
+		Name:          req.Name,
+		Scopes:        req.Scopes,
+		ExpiresInDays: req.ExpiresInDays,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		return
+	}
+	// The secret is only ever shown here.
+	c.JSON(http.StatusCreated, gin.H{"token": token, "secret": secret})
+}
+
+func (tc *APITokenController) deleteToken(c *gin.Context) {
+	err := tc.tokens.Revoke(model.UserID(currentUserID(c)), model.APITokenID(c.Param("id")))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAPITokenTestRouter(t *testing.T) (*gin.Engine, *service.AuthService, *service.TrainingPlanService) {
+	gin.SetMode(gin.TestMode)
+	authSvc := service.NewAuthService(mem.NewMemUserStore())
+	planSvc := service.NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workoutSvc := service.NewWorkoutService(mem.NewMemWorkoutStore())
+	tokenSvc := service.NewAPITokenService(mem.NewMemAPITokenStore())
+
+	r := gin.New()
+	storeCookie := cookie.NewStore([]byte("test-secret"))
+	r.Use(sessions.Sessions("rp.sid", storeCookie))
+
+	api := r.Group("/api")
+	api.Use(BearerAuth(tokenSvc))
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil), nil, nil, nil, nil)
+	RegisterWorkoutRoutes(api, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil))
+	RegisterAPITokenRoutes(api, tokenSvc)
+
+	return r, authSvc, planSvc
+}
+
+func bearerRequest(r *gin.Engine, secret, method, url string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, url, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+secret)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPITokenController(t *testing.T) {
+	r, authSvc, planSvc := setupAPITokenTestRouter(t)
+	user, err := authSvc.Register("script@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("other-script@example.com", "password123")
+	require.NoError(t, err)
+	cookies := loginForGenerate(t, r, "script@example.com", "password123")
+	other := loginForGenerate(t, r, "other-script@example.com", "password123")
+	plan, err := planSvc.Create(user.ID, "Scripted", time.Date(2025, 9, 14, 0, 0, 0, 0, time.UTC), 8)
+	require.NoError(t, err)
+
+	createToken := func(t *testing.T, scopes ...string) (model.APIToken, string) {
+		t.Helper()
+		w := postReplan(r, cookies, "/api/me/tokens", map[string]interface{}{"name": "loader", "scopes": scopes})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var resp struct {
+			Token  model.APIToken `json:"token"`
+			Secret string         `json:"secret"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.NotEmpty(t, resp.Secret)
+		return resp.Token, resp.Secret
+	}
+
+	writeToken, writeSecret := createToken(t, "read", "write")
+	_, readSecret := createToken(t, "read")
+
+	t.Run("lists tokens without secrets", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodGet, "/api/me/tokens")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), writeSecret)
+		assert.NotContains(t, w.Body.String(), "tokenHash")
+		var resp struct {
+			Tokens []model.APIToken `json:"tokens"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.Tokens, 2)
+
+		w = coachRequest(r, other, http.MethodGet, "/api/me/tokens")
+		assert.JSONEq(t, `{"tokens": []}`, w.Body.String())
+	})
+
+	t.Run("invalid scopes return 400", func(t *testing.T) {
+		w := postReplan(r, cookies, "/api/me/tokens", map[string]interface{}{"name": "bad", "scopes": []string{"admin"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("bearer token reads as its user", func(t *testing.T) {
+		w := bearerRequest(r, readSecret, http.MethodGet, "/api/plans", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), string(plan.ID))
+
+		w = bearerRequest(r, readSecret, http.MethodGet, "/api/auth/me", nil)
+		assert.Contains(t, w.Body.String(), "script@example.com")
+	})
+
+	bulk := map[string]interface{}{"workouts": []map[string]interface{}{
+		{"runType": "easy_run", "week": 1, "dayOfWeek": 2, "distance": 6},
+		{"runType": "long_run", "week": 1, "dayOfWeek": 7, "distance": 14},
+	}}
+
+	t.Run("write scope allows bulk loading workouts", func(t *testing.T) {
+		w := bearerRequest(r, writeSecret, http.MethodPost, "/api/plans/"+string(plan.ID)+"/workouts/bulk", bulk)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	})
+
+	t.Run("read-only token cannot write", func(t *testing.T) {
+		w := bearerRequest(r, readSecret, http.MethodPost, "/api/plans/"+string(plan.ID)+"/workouts/bulk", bulk)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "write scope")
+	})
+
+	t.Run("generation needs the generate scope", func(t *testing.T) {
+		w := bearerRequest(r, writeSecret, http.MethodPost, "/api/plans/generate", map[string]interface{}{})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "generate scope")
+	})
+
+	t.Run("tokens cannot manage tokens", func(t *testing.T) {
+		w := bearerRequest(r, writeSecret, http.MethodGet, "/api/me/tokens", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("invalid token returns 401", func(t *testing.T) {
+		w := bearerRequest(r, "rp_nope", http.MethodGet, "/api/plans", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "invalid_token")
+	})
+
+	t.Run("other user's token cannot be revoked", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/me/tokens/"+string(writeToken.ID), nil)
+		for _, c := range other {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("revoked token stops working", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodDelete, "/api/me/tokens/"+string(writeToken.ID))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = bearerRequest(r, writeSecret, http.MethodGet, "/api/plans", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -1,28 +1,55 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 
 	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/ratelimit"
 	"github.com/kevsommer/runplanner/internal/service"
 	"github.com/kevsommer/runplanner/internal/store"
 )
 
+// Session keys for a password login waiting for its second factor.
+const (
+	sessPendingUID = "2fa.uid"
+	sessPendingAt  = "2fa.at"
+)
+
+// pendingLoginTTL is how long the second factor may take after the
+// password was accepted.
+const pendingLoginTTL = 5 * time.Minute
+
 type AuthController struct {
-	svc *service.AuthService
+	svc       *service.AuthService
+	verify    *service.EmailVerificationService
+	twoFactor *service.TwoFactorService
 }
 
-func RegisterAuthRoutes(rg *gin.RouterGroup, svc *service.AuthService) {
-	ac := &AuthController{svc: svc}
+// RegisterAuthRoutes adds registration and login. With a verification
+// service, new accounts are sent a verification email and the verify
+// endpoints are added; with a two-factor service, users who enabled it
+// finish logging in with a code. Both may be nil. The limiter throttles
+// registration and login per IP and per account; nil disables it.
+func RegisterAuthRoutes(rg *gin.RouterGroup, svc *service.AuthService, verify *service.EmailVerificationService, twoFactor *service.TwoFactorService, limiter *ratelimit.Limiter) {
+	ac := &AuthController{svc: svc, verify: verify, twoFactor: twoFactor}
 	auth := rg.Group("/auth")
 	{
-		auth.POST("/register", ac.postRegister)
-		auth.POST("/login", ac.postLogin)
+		auth.POST("/register", rateLimit(limiter, emailKey), ac.postRegister)
+		auth.POST("/login", rateLimit(limiter, emailKey), ac.postLogin)
+		if twoFactor != nil {
+			auth.POST("/login/2fa", rateLimit(limiter, pendingLoginKey), ac.postLoginTwoFactor)
+		}
 		auth.POST("/logout", ac.postLogout)
 		auth.GET("/me", ac.getMe)
+		if verify != nil {
+			auth.GET("/verify", ac.getVerify)
+			auth.POST("/verify/resend", requireAuth, ac.postResendVerification)
+		}
 	}
 }
 
@@ -47,6 +74,11 @@ func (a *AuthController) postRegister(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if a.verify != nil {
+		// A failed email does not undo the registration; the user can ask
+		// for another one.
+		_ = a.verify.Send(c.Request.Context(), u)
+	}
 	// Auto-login after register
 	sess := sessions.Default(c)
 	sess.Set("uid", string(u.ID))
@@ -66,6 +98,63 @@ func (a *AuthController) postLogin(c *gin.Context) {
 		return
 	}
 	sess := sessions.Default(c)
+	if a.twoFactor != nil {
+		enabled, err := a.twoFactor.Enabled(u.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log in"})
+			return
+		}
+		if enabled {
+			// No session yet: the password only unlocks the code step.
+			sess.Delete("uid")
+			sess.Set(sessPendingUID, string(u.ID))
+			sess.Set(sessPendingAt, time.Now().Unix())
+			_ = sess.Save()
+			c.JSON(http.StatusOK, gin.H{"twoFactorRequired": true})
+			return
+		}
+	}
+	sess.Set("uid", string(u.ID))
+	_ = sess.Save()
+	c.JSON(http.StatusOK, gin.H{"user": u.Public()})
+}
+
+type twoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func (a *AuthController) postLoginTwoFactor(c *gin.Context) {
+	var req twoFactorCodeInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code required"})
+		return
+	}
+	sess := sessions.Default(c)
+	uid, _ := sess.Get(sessPendingUID).(string)
+	at, _ := sess.Get(sessPendingAt).(int64)
+	if uid == "" || time.Since(time.Unix(at, 0)) > pendingLoginTTL {
+		sess.Delete(sessPendingUID)
+		sess.Delete(sessPendingAt)
+		_ = sess.Save()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "log in with your password first"})
+		return
+	}
+
+	if err := a.twoFactor.Verify(model.UserID(uid), req.Code); err != nil {
+		if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify code"})
+		return
+	}
+	u, err := a.svc.GetUser(model.UserID(uid))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "log in with your password first"})
+		return
+	}
+	sess.Delete(sessPendingUID)
+	sess.Delete(sessPendingAt)
 	sess.Set("uid", string(u.ID))
 	_ = sess.Save()
 	c.JSON(http.StatusOK, gin.H{"user": u.Public()})
@@ -92,7 +181,61 @@ func (a *AuthController) getMe(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"user": u.Public()})
 }
 
+func (a *AuthController) getVerify(c *gin.Context) {
+	u, err := a.verify.Verify(c.Query("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": u.Public()})
+}
+
+func (a *AuthController) postResendVerification(c *gin.Context) {
+	err := a.verify.Resend(c.Request.Context(), model.UserID(currentUserID(c)))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmailAlreadyVerified):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send verification email"})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+// requireVerifiedEmail rejects users whose email is unverified when the
+// server requires verification. A nil service lets everyone through.
+func requireVerifiedEmail(verify *service.EmailVerificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verify == nil {
+			c.Next()
+			return
+		}
+		err := verify.RequireVerified(model.UserID(currentUserID(c)))
+		switch {
+		case err == nil:
+			c.Next()
+		case errors.Is(err, service.ErrEmailNotVerified):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "verify your email address to use this feature"})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check email verification"})
+		}
+	}
+}
+
+// currentUserID returns the user authenticated by an API token or, failing
+// that, by the session; "" if neither.
 func currentUserID(c *gin.Context) string {
+	if uid := c.GetString(ctxUserID); uid != "" {
+		return uid
+	}
 	sess := sessions.Default(c)
 	if v := sess.Get("uid"); v != nil {
 		if s, ok := v.(string); ok {
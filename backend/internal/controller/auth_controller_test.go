@@ -3,13 +3,17 @@ package controller
 import (
 	"bytes"
 	"encoding/json"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
 	"testing"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/mail"
 	"github.com/kevsommer/runplanner/internal/service"
 	"github.com/kevsommer/runplanner/internal/store/mem"
 	"github.com/stretchr/testify/assert"
@@ -26,7 +30,7 @@ func setupAuthTestRouter(t *testing.T) (*gin.Engine, *service.AuthService) {
 	r.Use(sessions.Sessions("rp.sid", storeCookie))
 
 	api := r.Group("/api")
-	RegisterAuthRoutes(api, authSvc)
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
 
 	return r, authSvc
 }
@@ -311,3 +315,104 @@ func TestAuthController_GetMe(t *testing.T) {
 		assert.Equal(t, string(u.ID), user["id"])
 	})
 }
+
+// setupVerificationTestRouter requires verified emails for plan generation,
+// calendar feeds and share links, and logs verification emails into the returned buffer.
+func setupVerificationTestRouter(t *testing.T) (*gin.Engine, *bytes.Buffer) {
+	gin.SetMode(gin.TestMode)
+	userStore := mem.NewMemUserStore()
+	authSvc := service.NewAuthService(userStore)
+	planSvc := service.NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workoutSvc := service.NewWorkoutService(mem.NewMemWorkoutStore())
+	var mails bytes.Buffer
+	verifySvc := service.NewEmailVerificationService(userStore, mem.NewMemEmailVerificationStore(), mail.NewLogMailer(log.New(&mails, "", 0)), "http://localhost:5173", true)
+
+	r := gin.New()
+	storeCookie := cookie.NewStore([]byte("test-secret"))
+	r.Use(sessions.Sessions("rp.sid", storeCookie))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, verifySvc, nil, nil)
+	accessSvc := service.NewAccessService(planSvc, workoutSvc, nil, nil)
+	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, accessSvc, nil, authSvc, verifySvc, nil)
+	RegisterShareLinkRoutes(api, service.NewShareLinkService(mem.NewMemShareLinkStore(), planSvc, workoutSvc), accessSvc, verifySvc)
+	RegisterCalendarRoutes(api, service.NewCalendarService(userStore, planSvc, workoutSvc), verifySvc, "http://localhost:5173")
+
+	return r, &mails
+}
+
+var verifyLinkRe = regexp.MustCompile(`http://localhost:5173/verify-email\?token=(\S+)`)
+
+func TestAuthController_EmailVerification(t *testing.T) {
+	r, mails := setupVerificationTestRouter(t)
+
+	w := postReplan(r, nil, "/api/auth/register", map[string]string{"email": "verify@example.com", "password": "password123"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"emailVerified":false`)
+	cookies := w.Result().Cookies()
+
+	match := verifyLinkRe.FindStringSubmatch(mails.String())
+	require.Len(t, match, 2)
+	token := match[1]
+
+	t.Run("generation requires a verified email", func(t *testing.T) {
+		w := postReplan(r, cookies, "/api/plans/generate", map[string]string{})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "verify your email")
+	})
+
+	t.Run("calendar feed URLs require a verified email", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodPost, "/api/calendar/token")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = coachRequest(r, cookies, http.MethodPost, "/api/calendar/token/rotate")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("share links require a verified email", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodPost, "/api/plans/some-plan/shares")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("other plan routes are not gated", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodGet, "/api/plans")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("resend sends another email", func(t *testing.T) {
+		mails.Reset()
+		w := postReplan(r, cookies, "/api/auth/verify/resend", nil)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Regexp(t, verifyLinkRe, mails.String())
+
+		w = postReplan(r, nil, "/api/auth/verify/resend", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("invalid token returns 400", func(t *testing.T) {
+		w := coachRequest(r, nil, http.MethodGet, "/api/auth/verify?token=nope")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("verifies the account", func(t *testing.T) {
+		w := coachRequest(r, nil, http.MethodGet, "/api/auth/verify?token="+url.QueryEscape(token))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"emailVerified":true`)
+
+		w = coachRequest(r, cookies, http.MethodGet, "/api/auth/me")
+		assert.Contains(t, w.Body.String(), `"emailVerified":true`)
+	})
+
+	t.Run("verified users pass the gate", func(t *testing.T) {
+		w := postReplan(r, cookies, "/api/plans/generate", map[string]string{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = coachRequest(r, cookies, http.MethodPost, "/api/calendar/token")
+		assert.Equal(t, http.StatusCreated, w.Code)
+		w = coachRequest(r, cookies, http.MethodPost, "/api/plans/some-plan/shares")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("resend after verification returns 409", func(t *testing.T) {
+		w := postReplan(r, cookies, "/api/auth/verify/resend", nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
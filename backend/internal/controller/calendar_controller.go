@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type CalendarController struct {
+	calendar *service.CalendarService
+	// appURL is the public base URL the feed links are built on.
+	appURL string
+}
+
+// RegisterCalendarRoutes adds the feed token endpoints under the API group.
+// Feed URLs are built on appURL rather than the request's Host header.
+// Handing out a feed URL shares the user's plans, so it needs a verified
+// email when verify requires one.
+func RegisterCalendarRoutes(rg *gin.RouterGroup, calendar *service.CalendarService, verify *service.EmailVerificationService, appURL string) {
+	cc := &CalendarController{calendar: calendar, appURL: strings.TrimRight(appURL, "/")}
+
+	cal := rg.Group("/calendar")
+	cal.Use(requireAuth)
+	{
+		cal.GET("", cc.getStatus)
+		cal.POST("/token", requireVerifiedEmail(verify), cc.postCreateToken)
+		cal.POST("/token/rotate", requireVerifiedEmail(verify), cc.postRotateToken)
+		cal.DELETE("/token", cc.deleteToken)
+	}
+}
+
+// RegisterCalendarFeedRoutes serves /ical/:token.ics. The token in the URL is
+// the only credential, since calendar apps do not send the session cookie.
+func RegisterCalendarFeedRoutes(r gin.IRouter, calendar *service.CalendarService) {
+	cc := &CalendarController{calendar: calendar}
+	r.GET("/ical/:file", cc.getFeed)
+}
+
+func (cc *CalendarController) getStatus(c *gin.Context) {
+	uid := currentUserID(c)
+
+	enabled, err := cc.calendar.Enabled(model.UserID(uid))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get calendar feed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+}
+
+func (cc *CalendarController) postCreateToken(c *gin.Context) {
+	uid := currentUserID(c)
+
+	token, err := cc.calendar.CreateToken(model.UserID(uid))
+	if err != nil {
+		if err == service.ErrCalendarTokenExists {
+			c.JSON(http.StatusConflict, gin.H{"error": "calendar feed already enabled, rotate the token to get a new URL"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar token"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"token": token, "url": cc.feedURL(token)})
+}
+
+func (cc *CalendarController) postRotateToken(c *gin.Context) {
+	uid := currentUserID(c)
+
+	token, err := cc.calendar.RotateToken(model.UserID(uid))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate calendar token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"token": token, "url": cc.feedURL(token)})
+}
+
+func (cc *CalendarController) deleteToken(c *gin.Context) {
+	uid := currentUserID(c)
+
+	if err := cc.calendar.DeleteToken(model.UserID(uid)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to disable calendar feed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (cc *CalendarController) getFeed(c *gin.Context) {
+	token, ok := strings.CutSuffix(c.Param("file"), ".ics")
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar not found"})
+		return
+	}
+
+	ics, err := cc.calendar.Feed(token, c.Query("plans") == "all")
+	if err != nil {
+		if err == store.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "calendar not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build calendar"})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(ics))
+}
+
+// feedURL builds the absolute feed URL on the configured app URL.
+func (cc *CalendarController) feedURL(token string) string {
+	return cc.appURL + "/ical/" + token + ".ics"
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCalendarTestRouter(t *testing.T) (*gin.Engine, *service.AuthService, *service.TrainingPlanService, *service.WorkoutService) {
+	gin.SetMode(gin.TestMode)
+	userStore := mem.NewMemUserStore()
+	authSvc := service.NewAuthService(userStore)
+	planSvc := service.NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workoutSvc := service.NewWorkoutService(mem.NewMemWorkoutStore())
+	calendarSvc := service.NewCalendarService(userStore, planSvc, workoutSvc)
+
+	r := gin.New()
+	storeCookie := cookie.NewStore([]byte("test-secret"))
+	r.Use(sessions.Sessions("rp.sid", storeCookie))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterCalendarRoutes(api, calendarSvc, nil, "http://app.test/")
+	RegisterCalendarFeedRoutes(r, calendarSvc)
+
+	return r, authSvc, planSvc, workoutSvc
+}
+
+func TestCalendarController(t *testing.T) {
+	r, authSvc, planSvc, workoutSvc := setupCalendarTestRouter(t)
+	u, _ := authSvc.Register("cal@example.com", "password123")
+	plan, _ := planSvc.Create(u.ID, "My Plan", mustParseDate("2025-05-01"), 8)
+	workout, _ := workoutSvc.Create(plan.ID, "easy_run", mustParseDate("2025-04-01"), "Easy", 8)
+	cookies := loginAndGetWorkoutCookies(t, r, "cal@example.com", "password123")
+
+	send := func(method, url string, withCookies bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		if withCookies {
+			for _, c := range cookies {
+				req.AddCookie(c)
+			}
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	tokenURL := func(w *httptest.ResponseRecorder) string {
+		var resp map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, "http://app.test/ical/"+resp["token"]+".ics", resp["url"])
+		return "/ical/" + resp["token"] + ".ics"
+	}
+
+	t.Run("requires auth", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/api/calendar/token", false).Code)
+	})
+
+	var feed string
+	t.Run("creates token", func(t *testing.T) {
+		w := send(http.MethodPost, "/api/calendar/token", true)
+		require.Equal(t, http.StatusCreated, w.Code)
+		feed = tokenURL(w)
+
+		w = send(http.MethodGet, "/api/calendar", true)
+		assert.JSONEq(t, `{"enabled": true}`, w.Body.String())
+	})
+
+	t.Run("rejects second create", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/api/calendar/token", true).Code)
+	})
+
+	t.Run("serves feed without session", func(t *testing.T) {
+		w := send(http.MethodGet, feed, false)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "UID:"+string(workout.ID)+"@runplanner")
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/ical/nope.ics", false).Code)
+		assert.Equal(t, http.StatusNotFound, send(http.MethodGet, strings.TrimSuffix(feed, ".ics"), false).Code)
+	})
+
+	t.Run("rotate invalidates old URL", func(t *testing.T) {
+		w := send(http.MethodPost, "/api/calendar/token/rotate", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		rotated := tokenURL(w)
+
+		assert.Equal(t, http.StatusNotFound, send(http.MethodGet, feed, false).Code)
+		assert.Equal(t, http.StatusOK, send(http.MethodGet, rotated, false).Code)
+		feed = rotated
+	})
+
+	t.Run("delete disables feed", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/api/calendar/token", true).Code)
+		assert.Equal(t, http.StatusNotFound, send(http.MethodGet, feed, false).Code)
+		assert.JSONEq(t, `{"enabled": false}`, send(http.MethodGet, "/api/calendar", true).Body.String())
+	})
+}
@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type CoachController struct {
+	coach  *service.CoachService
+	access *service.AccessService
+}
+
+// RegisterCoachRoutes adds the chat threads with the AI coach. Threads
+// belong to a plan; workout edits the coach proposes are applied through
+// the accept endpoint.
+func RegisterCoachRoutes(rg *gin.RouterGroup, coach *service.CoachService, access *service.AccessService, verify *service.EmailVerificationService) {
+	cc := &CoachController{coach: coach, access: access}
+
+	plansGroup := rg.Group("/plans")
+	plansGroup.Use(requireAuth)
+	{
+		plansGroup.GET("/:id/coach/threads", cc.getThreads)
+		plansGroup.POST("/:id/coach/threads", cc.postThread)
+	}
+
+	threads := rg.Group("/coach/threads")
+	threads.Use(requireAuth)
+	{
+		threads.GET("/:id", cc.getThread)
+		threads.DELETE("/:id", cc.deleteThread)
+		threads.POST("/:id/messages", requireScope(model.ScopeGenerate), requireVerifiedEmail(verify), cc.postMessage)
+		threads.POST("/:id/actions/:actionId/accept", cc.postAccept)
+		threads.POST("/:id/actions/:actionId/dismiss", cc.postDismiss)
+	}
+}
+
+type createThreadInput struct {
+	Title string `json:"title"`
+}
+
+type sendMessageInput struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// ownedPlan only lets the plan's owner in: the AI coach works on their
+// quota, and threads are private to them.
+func (cc *CoachController) ownedPlan(c *gin.Context, id model.TrainingPlanID) (*model.TrainingPlan, bool) {
+	return accessiblePlan(c, cc.access, id, service.AccessOwner)
+}
+
+func (cc *CoachController) ownedThread(c *gin.Context) (*model.CoachThread, bool) {
+	thread, err := cc.coach.GetThread(model.CoachThreadID(c.Param("id")))
+	if err != nil {
+		if err == store.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "thread not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get thread"})
+		return nil, false
+	}
+	if thread.UserID != model.UserID(currentUserID(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "thread not found"})
+		return nil, false
+	}
+	return thread, true
+}
+
+func (cc *CoachController) getThreads(c *gin.Context) {
+	plan, ok := cc.ownedPlan(c, model.TrainingPlanID(c.Param("id")))
+	if !ok {
+		return
+	}
+	threads, err := cc.coach.ListThreads(plan.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list threads"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"threads": threads})
+}
+
+func (cc *CoachController) postThread(c *gin.Context) {
+	plan, ok := cc.ownedPlan(c, model.TrainingPlanID(c.Param("id")))
+	if !ok {
+		return
+	}
+	// The body is optional; without a title the first message names the
+	// thread.
+	var req createThreadInput
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	thread, err := cc.coach.CreateThread(plan, req.Title)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create thread"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"thread": thread})
+}
+
+func (cc *CoachController) getThread(c *gin.Context) {
+	thread, ok := cc.ownedThread(c)
+	if !ok {
+		return
+	}
+	messages, err := cc.coach.ListMessages(thread.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"thread": thread, "messages": messages})
+}
+
+func (cc *CoachController) deleteThread(c *gin.Context) {
+	thread, ok := cc.ownedThread(c)
+	if !ok {
+		return
+	}
+	if err := cc.coach.DeleteThread(thread.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete thread"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+func (cc *CoachController) postMessage(c *gin.Context) {
+	thread, ok := cc.ownedThread(c)
+	if !ok {
+		return
+	}
+	plan, ok := cc.ownedPlan(c, thread.PlanID)
+	if !ok {
+		return
+	}
+	var req sendMessageInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content is required"})
+		return
+	}
+
+	question, answer, err := cc.coach.SendMessage(c.Request.Context(), thread, plan, req.Content, time.Now())
+	if err != nil {
+		var quotaErr *service.QuotaError
+		switch {
+		case errors.As(err, &quotaErr):
+			writeQuotaExceeded(c, quotaErr)
+		case errors.Is(err, service.ErrAINotConfigured):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "AI generation is not configured on the server"})
+		case errors.Is(err, service.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAIGeneration):
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send message"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"thread": thread, "message": question, "reply": answer})
+}
+
+func (cc *CoachController) postAccept(c *gin.Context) {
+	thread, ok := cc.ownedThread(c)
+	if !ok {
+		return
+	}
+	plan, ok := cc.ownedPlan(c, thread.PlanID)
+	if !ok {
+		return
+	}
+
+	workout, message, err := cc.coach.AcceptAction(thread, plan, c.Param("actionId"), time.Now())
+	if err != nil {
+		writeActionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"workout": workout, "message": message})
+}
+
+func (cc *CoachController) postDismiss(c *gin.Context) {
+	thread, ok := cc.ownedThread(c)
+	if !ok {
+		return
+	}
+
+	message, err := cc.coach.DismissAction(thread, c.Param("actionId"))
+	if err != nil {
+		writeActionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+func writeActionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrActionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "action not found"})
+	case errors.Is(err, service.ErrActionResolved), errors.Is(err, service.ErrStaleAction):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidDistance), errors.Is(err, service.ErrInvalidRunType),
+		errors.Is(err, service.ErrStrengthTrainingNonZeroDist), errors.Is(err, service.ErrInvalidStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update action"})
+	}
+}
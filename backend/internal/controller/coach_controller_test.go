@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCoachTestRouter(t *testing.T, mock ai.Client) (*gin.Engine, *service.AuthService, *service.TrainingPlanService, *service.WorkoutService) {
+	gin.SetMode(gin.TestMode)
+	userStore := mem.NewMemUserStore()
+	planStore := mem.NewMemTrainingPlanStore()
+	workoutStore := mem.NewMemWorkoutStore()
+	authSvc := service.NewAuthService(userStore)
+	planSvc := service.NewTrainingPlanService(planStore)
+	workoutSvc := service.NewWorkoutService(workoutStore)
+
+	r := gin.New()
+	storeCookie := cookie.NewStore([]byte("test-secret"))
+	r.Use(sessions.Sessions("rp.sid", storeCookie))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterCoachRoutes(api, service.NewCoachService(mock, unlimitedAIUsage(), mem.NewMemCoachStore(), workoutSvc), service.NewAccessService(planSvc, workoutSvc, nil, nil), nil)
+
+	return r, authSvc, planSvc, workoutSvc
+}
+
+func coachRequest(r *gin.Engine, cookies []*http.Cookie, method, url string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCoachController(t *testing.T) {
+	mock := &mockAIClient{}
+	r, authSvc, planSvc, workoutSvc := setupCoachTestRouter(t, mock)
+	user, err := authSvc.Register("coach@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("other-coach@example.com", "password123")
+	require.NoError(t, err)
+	cookies := loginForGenerate(t, r, "coach@example.com", "password123")
+	other := loginForGenerate(t, r, "other-coach@example.com", "password123")
+	plan := createUpcomingPlan(t, planSvc, workoutSvc, user.ID)
+
+	workouts, err := workoutSvc.GetByPlanID(plan.ID)
+	require.NoError(t, err)
+	target := workouts[0]
+	mock.response = fmt.Sprintf(`{"reply": "Skip it and rest.", "actions": [{"workoutId": %q, "reason": "sore calf", "status": "skipped"}]}`, target.ID)
+
+	threadsURL := "/api/plans/" + string(plan.ID) + "/coach/threads"
+	var thread model.CoachThread
+	t.Run("creates a thread", func(t *testing.T) {
+		w := postReplan(r, cookies, threadsURL, map[string]interface{}{"title": "Calf"})
+		require.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Thread model.CoachThread `json:"thread"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		thread = resp.Thread
+		assert.Equal(t, "Calf", thread.Title)
+		assert.Equal(t, plan.ID, thread.PlanID)
+	})
+	threadURL := "/api/coach/threads/" + string(thread.ID)
+
+	t.Run("creates a thread without a body", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodPost, threadsURL)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	var action model.CoachAction
+	t.Run("sends a message", func(t *testing.T) {
+		w := postReplan(r, cookies, threadURL+"/messages", map[string]interface{}{"content": "Should I run tomorrow?"})
+		require.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Message model.CoachMessage `json:"message"`
+			Reply   model.CoachMessage `json:"reply"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "Should I run tomorrow?", resp.Message.Content)
+		assert.Equal(t, "Skip it and rest.", resp.Reply.Content)
+		require.Len(t, resp.Reply.Actions, 1)
+		action = resp.Reply.Actions[0]
+		assert.Equal(t, model.ActionProposed, action.State)
+	})
+
+	t.Run("missing content returns 400", func(t *testing.T) {
+		w := postReplan(r, cookies, threadURL+"/messages", map[string]interface{}{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("gets the thread with messages", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodGet, threadURL)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Thread   model.CoachThread    `json:"thread"`
+			Messages []model.CoachMessage `json:"messages"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.Messages, 2)
+	})
+
+	t.Run("lists the plan's threads", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodGet, threadsURL)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Threads []model.CoachThread `json:"threads"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Threads, 2)
+		assert.Equal(t, thread.ID, resp.Threads[0].ID, "most recently updated first")
+	})
+
+	t.Run("other user's thread returns 404", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, coachRequest(r, other, http.MethodGet, threadURL).Code)
+		assert.Equal(t, http.StatusNotFound, coachRequest(r, other, http.MethodGet, threadsURL).Code)
+		assert.Equal(t, http.StatusNotFound, coachRequest(r, other, http.MethodPost, threadURL+"/actions/"+action.ID+"/accept").Code)
+	})
+
+	t.Run("unauthenticated returns 401", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, coachRequest(r, nil, http.MethodGet, threadURL).Code)
+	})
+
+	t.Run("accepts an action", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodPost, threadURL+"/actions/"+action.ID+"/accept")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Workout model.Workout      `json:"workout"`
+			Message model.CoachMessage `json:"message"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "skipped", resp.Workout.Status)
+		assert.Equal(t, model.ActionAccepted, resp.Message.Actions[0].State)
+
+		stored, err := workoutSvc.GetByID(target.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "skipped", stored.Status)
+	})
+
+	t.Run("resolved action returns 409", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodPost, threadURL+"/actions/"+action.ID+"/dismiss")
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("unknown action returns 404", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodPost, threadURL+"/actions/missing/accept")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("AI errors return 502", func(t *testing.T) {
+		mock.response = `not json`
+		w := postReplan(r, cookies, threadURL+"/messages", map[string]interface{}{"content": "Hello"})
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+
+	t.Run("deletes the thread", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, coachRequest(r, other, http.MethodDelete, threadURL).Code)
+		assert.Equal(t, http.StatusOK, coachRequest(r, cookies, http.MethodDelete, threadURL).Code)
+		assert.Equal(t, http.StatusNotFound, coachRequest(r, cookies, http.MethodGet, threadURL).Code)
+	})
+
+	t.Run("AI not configured returns 400", func(t *testing.T) {
+		r, authSvc, planSvc, workoutSvc := setupCoachTestRouter(t, nil)
+		user, err := authSvc.Register("noai-coach@example.com", "password123")
+		require.NoError(t, err)
+		cookies := loginForGenerate(t, r, "noai-coach@example.com", "password123")
+		plan := createUpcomingPlan(t, planSvc, workoutSvc, user.ID)
+
+		w := coachRequest(r, cookies, http.MethodPost, "/api/plans/"+string(plan.ID)+"/coach/threads")
+		require.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Thread model.CoachThread `json:"thread"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+		w = postReplan(r, cookies, "/api/coach/threads/"+string(resp.Thread.ID)+"/messages", map[string]interface{}{"content": "Hello"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "not configured")
+	})
+}
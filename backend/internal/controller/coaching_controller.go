@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type CoachingController struct {
+	coaching *service.CoachingService
+}
+
+// RegisterCoachingRoutes adds inviting athletes, accepting invitations and
+// ending coachings. The athletes' plans themselves are reached through the
+// plan and workout endpoints.
+func RegisterCoachingRoutes(rg *gin.RouterGroup, coaching *service.CoachingService) {
+	cc := &CoachingController{coaching: coaching}
+	group := rg.Group("/coaching")
+	group.Use(requireAuth, requireSession)
+	{
+		group.GET("", cc.getList)
+		group.POST("/invitations", cc.postInvitation)
+		group.POST("/:id/accept", cc.postAccept)
+		group.DELETE("/:id", cc.deleteCoaching)
+	}
+}
+
+type inviteAthleteInput struct {
+	Email string `json:"email" binding:"required"`
+}
+
+func (cc *CoachingController) getList(c *gin.Context) {
+	list, err := cc.coaching.List(model.UserID(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list coachings"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (cc *CoachingController) postInvitation(c *gin.Context) {
+	var req inviteAthleteInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+	coaching, err := cc.coaching.Invite(model.UserID(currentUserID(c)), req.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNoAthleteAccount):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrCoachSelf):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAlreadyCoaching):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to invite athlete"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"coaching": coaching})
+}
+
+func (cc *CoachingController) postAccept(c *gin.Context) {
+	coaching, err := cc.coaching.Accept(model.UserID(currentUserID(c)), model.CoachingID(c.Param("id")))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "invitation not found"})
+		case errors.Is(err, service.ErrInvitationAccepted):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to accept invitation"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"coaching": coaching})
+}
+
+// deleteCoaching ends a coaching, declines an invitation or withdraws it.
+func (cc *CoachingController) deleteCoaching(c *gin.Context) {
+	err := cc.coaching.End(model.UserID(currentUserID(c)), model.CoachingID(c.Param("id")))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "coaching not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to end coaching"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
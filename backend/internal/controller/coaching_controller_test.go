@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCoachingTestRouter(t *testing.T) (*gin.Engine, *service.AuthService, *service.TrainingPlanService, *service.WorkoutService) {
+	gin.SetMode(gin.TestMode)
+	userStore := mem.NewMemUserStore()
+	authSvc := service.NewAuthService(userStore)
+	planSvc := service.NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workoutSvc := service.NewWorkoutService(mem.NewMemWorkoutStore())
+	coachingSvc := service.NewCoachingService(userStore, mem.NewMemCoachingStore())
+	accessSvc := service.NewAccessService(planSvc, workoutSvc, coachingSvc, mem.NewMemPlanEditStore())
+
+	r := gin.New()
+	r.Use(sessions.Sessions("rp.sid", cookie.NewStore([]byte("test-secret"))))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, accessSvc, nil, authSvc, nil, nil)
+	RegisterWorkoutRoutes(api, workoutSvc, accessSvc)
+	RegisterCoachingRoutes(api, coachingSvc)
+
+	return r, authSvc, planSvc, workoutSvc
+}
+
+func TestCoachingController(t *testing.T) {
+	r, authSvc, planSvc, workoutSvc := setupCoachingTestRouter(t)
+	athleteUser, err := authSvc.Register("athlete@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("coach@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("stranger@example.com", "password123")
+	require.NoError(t, err)
+	athlete := loginForGenerate(t, r, "athlete@example.com", "password123")
+	coach := loginForGenerate(t, r, "coach@example.com", "password123")
+	stranger := loginForGenerate(t, r, "stranger@example.com", "password123")
+
+	plan := createUpcomingPlan(t, planSvc, workoutSvc, athleteUser.ID)
+	workouts, err := workoutSvc.GetByPlanID(plan.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, workouts)
+	workoutURL := "/api/workouts/" + string(workouts[0].ID)
+	planURL := "/api/plans/" + string(plan.ID)
+
+	var coachingID string
+	t.Run("invites an athlete", func(t *testing.T) {
+		w := accountRequest(r, coach, http.MethodPost, "/api/coaching/invitations", map[string]string{"email": "nobody@example.com"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = accountRequest(r, coach, http.MethodPost, "/api/coaching/invitations", map[string]string{"email": "coach@example.com"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = accountRequest(r, coach, http.MethodPost, "/api/coaching/invitations", map[string]string{"email": "athlete@example.com"})
+		require.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Coaching struct {
+				ID     string `json:"id"`
+				Status string `json:"status"`
+			} `json:"coaching"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "pending", resp.Coaching.Status)
+		coachingID = resp.Coaching.ID
+
+		w = accountRequest(r, coach, http.MethodPost, "/api/coaching/invitations", map[string]string{"email": "athlete@example.com"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("a pending invitation grants no access", func(t *testing.T) {
+		w := coachRequest(r, coach, http.MethodGet, planURL)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("only the athlete accepts", func(t *testing.T) {
+		w := coachRequest(r, coach, http.MethodPost, "/api/coaching/"+coachingID+"/accept")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = coachRequest(r, athlete, http.MethodGet, "/api/coaching")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "coach@example.com")
+
+		w = coachRequest(r, athlete, http.MethodPost, "/api/coaching/"+coachingID+"/accept")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"active"`)
+	})
+
+	t.Run("the coach lists and views the athlete's plans", func(t *testing.T) {
+		w := coachRequest(r, coach, http.MethodGet, "/api/plans?userId="+string(athleteUser.ID))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), string(plan.ID))
+
+		w = coachRequest(r, coach, http.MethodGet, planURL)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = coachRequest(r, coach, http.MethodGet, workoutURL)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = coachRequest(r, stranger, http.MethodGet, "/api/plans?userId="+string(athleteUser.ID))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = coachRequest(r, stranger, http.MethodGet, workoutURL)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("the coach's edits are attributed", func(t *testing.T) {
+		w := accountRequest(r, coach, http.MethodPut, workoutURL, map[string]string{"description": "Keep it easy"})
+		require.Equal(t, http.StatusOK, w.Code)
+		w = accountRequest(r, athlete, http.MethodPut, workoutURL, map[string]string{"notes": "Felt fine"})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = coachRequest(r, athlete, http.MethodGet, planURL+"/edits")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Edits []struct {
+				WorkoutID   string `json:"workoutId"`
+				Action      string `json:"action"`
+				EditorEmail string `json:"editorEmail"`
+			} `json:"edits"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Edits, 1)
+		assert.Equal(t, service.EditWorkoutUpdated, resp.Edits[0].Action)
+		assert.Equal(t, string(workouts[0].ID), resp.Edits[0].WorkoutID)
+		assert.Equal(t, "coach@example.com", resp.Edits[0].EditorEmail)
+	})
+
+	t.Run("the coach cannot delete or activate the plan", func(t *testing.T) {
+		w := coachRequest(r, coach, http.MethodDelete, planURL)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = coachRequest(r, coach, http.MethodPost, planURL+"/activate")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("ending the coaching revokes access", func(t *testing.T) {
+		w := coachRequest(r, stranger, http.MethodDelete, "/api/coaching/"+coachingID)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = coachRequest(r, athlete, http.MethodDelete, "/api/coaching/"+coachingID)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = coachRequest(r, coach, http.MethodGet, workoutURL)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = accountRequest(r, coach, http.MethodPut, workoutURL, map[string]string{"description": "Sneaky"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
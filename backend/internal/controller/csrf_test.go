@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCSRFTestRouter(t *testing.T) (*gin.Engine, *service.AuthService, *service.APITokenService) {
+	gin.SetMode(gin.TestMode)
+	userStore := mem.NewMemUserStore()
+	authSvc := service.NewAuthService(userStore)
+	tokenSvc := service.NewAPITokenService(mem.NewMemAPITokenStore())
+	planSvc := service.NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workoutSvc := service.NewWorkoutService(mem.NewMemWorkoutStore())
+
+	r := gin.New()
+	r.Use(sessions.Sessions("rp.sid", cookie.NewStore([]byte("test-secret"))))
+
+	api := r.Group("/api")
+	api.Use(BearerAuth(tokenSvc), CSRF([]string{"http://localhost:5173"}))
+	RegisterCSRFRoutes(api)
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil), nil, nil, nil, nil)
+
+	return r, authSvc, tokenSvc
+}
+
+// csrfClient holds the cookies and token of a browser that has read its
+// CSRF token.
+type csrfClient struct {
+	cookies []*http.Cookie
+	token   string
+}
+
+func newCSRFClient(t *testing.T, r *gin.Engine) *csrfClient {
+	t.Helper()
+	w := coachRequest(r, nil, http.MethodGet, "/api/auth/csrf")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		CSRFToken string `json:"csrfToken"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.CSRFToken)
+	return &csrfClient{cookies: w.Result().Cookies(), token: resp.CSRFToken}
+}
+
+func (cc *csrfClient) post(r *gin.Engine, url string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	for _, c := range cc.cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cc.cookies = append(cc.cookies, w.Result().Cookies()...)
+	return w
+}
+
+func TestCSRF(t *testing.T) {
+	r, authSvc, tokenSvc := setupCSRFTestRouter(t)
+	u, err := authSvc.Register("csrf@example.com", "password123")
+	require.NoError(t, err)
+	credentials := map[string]string{"email": "csrf@example.com", "password": "password123"}
+
+	t.Run("issues the same token while the cookie lasts", func(t *testing.T) {
+		cc := newCSRFClient(t, r)
+		w := coachRequest(r, cc.cookies, http.MethodGet, "/api/auth/csrf")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), cc.token)
+	})
+
+	t.Run("writes need the token", func(t *testing.T) {
+		cc := newCSRFClient(t, r)
+		w := cc.post(r, "/api/auth/login", credentials, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid CSRF token")
+
+		w = cc.post(r, "/api/auth/login", credentials, map[string]string{csrfHeader: "forged"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = cc.post(r, "/api/auth/login", credentials, map[string]string{csrfHeader: cc.token})
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = cc.post(r, "/api/plans", map[string]interface{}{"name": "Spring", "endDate": "2030-05-01", "weeks": 8},
+			map[string]string{csrfHeader: cc.token})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("a header without the cookie is refused", func(t *testing.T) {
+		cc := newCSRFClient(t, r)
+		cc.cookies = nil
+		w := cc.post(r, "/api/auth/login", credentials, map[string]string{csrfHeader: cc.token})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("checks the origin", func(t *testing.T) {
+		cc := newCSRFClient(t, r)
+		for _, tc := range []struct {
+			headers map[string]string
+			want    int
+		}{
+			{map[string]string{"Origin": "http://localhost:5173"}, http.StatusOK},
+			// httptest requests are addressed to example.com.
+			{map[string]string{"Origin": "http://example.com"}, http.StatusOK},
+			{map[string]string{"Referer": "http://localhost:5173/login"}, http.StatusOK},
+			{map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+			{map[string]string{"Origin": "null"}, http.StatusForbidden},
+			{map[string]string{"Referer": "https://evil.example/page"}, http.StatusForbidden},
+		} {
+			tc.headers[csrfHeader] = cc.token
+			w := cc.post(r, "/api/auth/login", credentials, tc.headers)
+			assert.Equal(t, tc.want, w.Code, "%v", tc.headers)
+		}
+	})
+
+	t.Run("API tokens are exempt", func(t *testing.T) {
+		_, secret, err := tokenSvc.Create(u.ID, service.CreateAPITokenInput{Name: "script", Scopes: []string{model.ScopeRead, model.ScopeWrite}})
+		require.NoError(t, err)
+		w := bearerRequest(r, secret, http.MethodPost, "/api/plans",
+			map[string]interface{}{"name": "Autumn", "endDate": "2030-10-01", "weeks": 8})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+}
@@ -8,11 +8,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
 	"github.com/kevsommer/runplanner/internal/service"
 	"github.com/kevsommer/runplanner/internal/store/mem"
 	"github.com/stretchr/testify/assert"
@@ -24,8 +26,11 @@ type mockAIClient struct {
 	err      error
 }
 
-func (m *mockAIClient) Complete(_ context.Context, _ ai.CompletionRequest) (string, error) {
-	return m.response, m.err
+func (m *mockAIClient) Complete(_ context.Context, _ ai.CompletionRequest) (*ai.CompletionResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &ai.CompletionResponse{Content: m.response}, nil
 }
 
 func validMockResponse() string {
@@ -44,15 +49,17 @@ func setupGenerateTestRouter(t *testing.T, mock ai.Client) (*gin.Engine, *servic
 	authSvc := service.NewAuthService(userStore)
 	planSvc := service.NewTrainingPlanService(planStore)
 	workoutSvc := service.NewWorkoutService(workoutStore)
-	genSvc := service.NewGenerateService(mock, planSvc, workoutSvc)
+	genSvc := service.NewGenerateService(mock, unlimitedAIUsage(), planSvc, workoutSvc)
+	jobSvc := service.NewGenerateJobService(mem.NewMemGenerateJobStore(), genSvc)
 
 	r := gin.New()
 	storeCookie := cookie.NewStore([]byte("test-secret"))
 	r.Use(sessions.Sessions("rp.sid", storeCookie))
 
 	api := r.Group("/api")
-	RegisterAuthRoutes(api, authSvc)
-	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, genSvc, nil)
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil), jobSvc, nil, nil, nil)
+	RegisterGenerateJobRoutes(api, jobSvc)
 
 	return r, authSvc
 }
@@ -68,8 +75,38 @@ func loginForGenerate(t *testing.T, r *gin.Engine, email, password string) []*ht
 	return w.Result().Cookies()
 }
 
+// finishGenerateJob polls the job started by a generate request until it
+// has finished.
+func finishGenerateJob(t *testing.T, r *gin.Engine, cookies []*http.Cookie, started *httptest.ResponseRecorder) model.GenerateJob {
+	t.Helper()
+	require.Equal(t, http.StatusAccepted, started.Code)
+	var resp struct {
+		Job model.GenerateJob `json:"job"`
+	}
+	require.NoError(t, json.Unmarshal(started.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.Job.ID)
+
+	var job model.GenerateJob
+	require.Eventually(t, func() bool {
+		req := httptest.NewRequest(http.MethodGet, "/api/generate-jobs/"+string(resp.Job.ID), nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var got struct {
+			Job model.GenerateJob `json:"job"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		job = got.Job
+		return job.Finished()
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
 func TestGenerateController_PostGenerate(t *testing.T) {
-	t.Run("generates plan and returns 201", func(t *testing.T) {
+	t.Run("starts a job that generates the plan", func(t *testing.T) {
 		mock := &mockAIClient{response: validMockResponse()}
 		r, authSvc := setupGenerateTestRouter(t, mock)
 		_, err := authSvc.Register("gen@example.com", "password123")
@@ -93,18 +130,32 @@ func TestGenerateController_PostGenerate(t *testing.T) {
 		w := httptest.NewRecorder()
 		r.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusCreated, w.Code)
-		var resp map[string]interface{}
-		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
-
-		plan, ok := resp["plan"].(map[string]interface{})
-		require.True(t, ok)
-		assert.Equal(t, "My AI Plan", plan["name"])
-		assert.Equal(t, float64(8), plan["weeks"])
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Header().Get("Location"), "/api/generate-jobs/")
+		job := finishGenerateJob(t, r, cookies, w)
+		assert.Equal(t, model.JobSucceeded, job.Status)
+		require.NotNil(t, job.PlanID)
 
-		workouts, ok := resp["workouts"].([]interface{})
-		require.True(t, ok)
-		assert.Len(t, workouts, 4) // 3 AI-generated + 1 race workout
+		req = httptest.NewRequest(http.MethodGet, "/api/plans/"+string(*job.PlanID), nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Plan service.PlanDetail `json:"plan"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "My AI Plan", resp.Plan.Name)
+		assert.Equal(t, 8, resp.Plan.Weeks)
+		count := 0
+		for _, week := range resp.Plan.WeeksSummary {
+			for _, day := range week.Days {
+				count += len(day.Workouts)
+			}
+		}
+		assert.Equal(t, 4, count) // 3 AI-generated + 1 race workout
 	})
 
 	t.Run("unauthenticated returns 401", func(t *testing.T) {
@@ -235,7 +286,92 @@ func TestGenerateController_PostGenerate(t *testing.T) {
 		assert.Contains(t, resp["error"], "not configured")
 	})
 
-	t.Run("AI failure returns 502", func(t *testing.T) {
+	t.Run("rules strategy works without AI client", func(t *testing.T) {
+		r, authSvc := setupGenerateTestRouter(t, nil)
+		_, _ = authSvc.Register("gen-rules@example.com", "password123")
+		cookies := loginForGenerate(t, r, "gen-rules@example.com", "password123")
+
+		body := map[string]interface{}{
+			"name":          "Plan",
+			"endDate":       "2025-09-14",
+			"weeks":         8,
+			"baseKmPerWeek": 30.0,
+			"runsPerWeek":   3,
+			"raceGoal":      "halfmarathon",
+			"strategy":      "rules",
+		}
+		bodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/plans/generate", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		job := finishGenerateJob(t, r, cookies, w)
+		assert.Equal(t, model.JobSucceeded, job.Status)
+		assert.NotNil(t, job.PlanID)
+		assert.Equal(t, 1, job.Attempts)
+		assert.Equal(t, []service.LintFinding{}, job.Warnings)
+	})
+
+	t.Run("reports attempts and remaining warnings", func(t *testing.T) {
+		mock := &mockAIClient{response: validMockResponse()}
+		r, authSvc := setupGenerateTestRouter(t, mock)
+		_, _ = authSvc.Register("gen-report@example.com", "password123")
+		cookies := loginForGenerate(t, r, "gen-report@example.com", "password123")
+
+		body := map[string]interface{}{
+			"name":          "Plan",
+			"endDate":       "2025-09-14",
+			"weeks":         8,
+			"baseKmPerWeek": 30.0,
+			"runsPerWeek":   3,
+			"raceGoal":      "halfmarathon",
+		}
+		bodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/plans/generate", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		job := finishGenerateJob(t, r, cookies, w)
+		assert.Equal(t, model.JobSucceeded, job.Status)
+		assert.Equal(t, 3, job.Attempts)
+		assert.NotEmpty(t, job.Warnings)
+	})
+
+	t.Run("unknown strategy returns 400", func(t *testing.T) {
+		r, authSvc := setupGenerateTestRouter(t, nil)
+		_, _ = authSvc.Register("gen-strategy@example.com", "password123")
+		cookies := loginForGenerate(t, r, "gen-strategy@example.com", "password123")
+
+		body := map[string]interface{}{
+			"name":          "Plan",
+			"endDate":       "2025-09-14",
+			"weeks":         8,
+			"baseKmPerWeek": 30.0,
+			"runsPerWeek":   3,
+			"raceGoal":      "halfmarathon",
+			"strategy":      "magic",
+		}
+		bodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/plans/generate", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("AI failure fails the job", func(t *testing.T) {
 		mock := &mockAIClient{err: errors.New("rate limited")}
 		r, authSvc := setupGenerateTestRouter(t, mock)
 		_, _ = authSvc.Register("gen6@example.com", "password123")
@@ -258,7 +394,10 @@ func TestGenerateController_PostGenerate(t *testing.T) {
 		w := httptest.NewRecorder()
 		r.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusBadGateway, w.Code)
+		job := finishGenerateJob(t, r, cookies, w)
+		assert.Equal(t, model.JobFailed, job.Status)
+		assert.Contains(t, job.Error, "rate limited")
+		assert.Nil(t, job.PlanID)
 	})
 
 	t.Run("runsPerWeek out of range returns 400", func(t *testing.T) {
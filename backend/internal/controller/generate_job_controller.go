@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+// sseKeepAlive is how often an idle event stream sends a comment, so proxies
+// do not close it while the AI is working.
+const sseKeepAlive = 15 * time.Second
+
+type GenerateJobController struct {
+	jobs *service.GenerateJobService
+}
+
+func RegisterGenerateJobRoutes(rg *gin.RouterGroup, jobs *service.GenerateJobService) {
+	gc := &GenerateJobController{jobs: jobs}
+
+	g := rg.Group("/generate-jobs")
+	g.Use(requireAuth)
+	{
+		g.GET("/:id", gc.getByID)
+		g.GET("/:id/events", gc.getEvents)
+	}
+}
+
+// loadJob returns the job if it belongs to the current user and writes the
+// error response otherwise.
+func (gc *GenerateJobController) loadJob(c *gin.Context) (*model.GenerateJob, bool) {
+	uid := currentUserID(c)
+	job, err := gc.jobs.GetByID(model.GenerateJobID(c.Param("id")))
+	if err != nil {
+		if err == store.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get job"})
+		return nil, false
+	}
+	if job.UserID != model.UserID(uid) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return nil, false
+	}
+	return job, true
+}
+
+func (gc *GenerateJobController) getByID(c *gin.Context) {
+	job, ok := gc.loadJob(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// getEvents streams the job as Server-Sent Events. Every event is named
+// "job" and carries the whole job; the stream ends after the job finishes.
+func (gc *GenerateJobController) getEvents(c *gin.Context) {
+	job, ok := gc.loadJob(c)
+	if !ok {
+		return
+	}
+
+	updates, cancel := gc.jobs.Subscribe(job.ID)
+	defer cancel()
+	// Read again now that we are subscribed, so no change is missed.
+	job, err := gc.jobs.GetByID(job.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get job"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("job", job)
+	c.Writer.Flush()
+	if job.Finished() {
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case update, open := <-updates:
+			if !open {
+				// Updates were dropped; the store has the final state.
+				if final, err := gc.jobs.GetByID(job.ID); err == nil {
+					c.SSEvent("job", final)
+				}
+				return false
+			}
+			c.SSEvent("job", update)
+			return !update.Finished()
+		case <-keepAlive.C:
+			_, _ = io.WriteString(w, ": keep-alive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingAIClient signals each call on started and waits for release
+// before answering.
+type blockingAIClient struct {
+	response string
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (m *blockingAIClient) Complete(_ context.Context, _ ai.CompletionRequest) (*ai.CompletionResponse, error) {
+	m.started <- struct{}{}
+	<-m.release
+	return &ai.CompletionResponse{Content: m.response}, nil
+}
+
+func startGenerateJob(t *testing.T, handler http.Handler, cookies []*http.Cookie, strategy string) model.GenerateJobID {
+	t.Helper()
+	body := map[string]interface{}{
+		"name":          "Plan",
+		"endDate":       "2025-09-14",
+		"weeks":         8,
+		"baseKmPerWeek": 30.0,
+		"runsPerWeek":   3,
+		"raceGoal":      "halfmarathon",
+		"strategy":      strategy,
+	}
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/plans/generate", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var resp struct {
+		Job model.GenerateJob `json:"job"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Job.ID
+}
+
+// readSSEJob reads the next "job" event from an event stream.
+func readSSEJob(t *testing.T, r *bufio.Reader) model.GenerateJob {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = line[len("event:"):]
+		case strings.HasPrefix(line, "data:"):
+			data = line[len("data:"):]
+		case line == "" && data != "":
+			require.Equal(t, "job", event)
+			var job model.GenerateJob
+			require.NoError(t, json.Unmarshal([]byte(data), &job))
+			return job
+		}
+	}
+}
+
+func TestGenerateJobController_GetByID(t *testing.T) {
+	r, authSvc := setupGenerateTestRouter(t, nil)
+	_, _ = authSvc.Register("owner@example.com", "password123")
+	_, _ = authSvc.Register("other@example.com", "password123")
+	owner := loginForGenerate(t, r, "owner@example.com", "password123")
+	other := loginForGenerate(t, r, "other@example.com", "password123")
+
+	id := startGenerateJob(t, r, owner, service.StrategyRules)
+
+	t.Run("returns own job", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/generate-jobs/"+string(id), nil)
+		for _, c := range owner {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("other user's job returns 404", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/generate-jobs/"+string(id), nil)
+		for _, c := range other {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("unknown job returns 404", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/generate-jobs/missing", nil)
+		for _, c := range owner {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("unauthenticated returns 401", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/generate-jobs/"+string(id), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestGenerateJobController_Events(t *testing.T) {
+	mock := &blockingAIClient{
+		response: validMockResponse(),
+		started:  make(chan struct{}, 3), // one per repair attempt
+		release:  make(chan struct{}),
+	}
+	r, authSvc := setupGenerateTestRouter(t, mock)
+	_, _ = authSvc.Register("events@example.com", "password123")
+	cookies := loginForGenerate(t, r, "events@example.com", "password123")
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	id := startGenerateJob(t, r, cookies, service.StrategyAI)
+	<-mock.started
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/generate-jobs/"+string(id)+"/events", nil)
+	require.NoError(t, err)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	body := bufio.NewReader(resp.Body)
+	first := readSSEJob(t, body)
+	assert.Equal(t, model.JobRunning, first.Status)
+	assert.Equal(t, service.PhasePrompting, first.Phase)
+
+	// The sparse mock plan fails the coaching rules, so every attempt runs.
+	close(mock.release)
+
+	var phases []string
+	var last model.GenerateJob
+	for !last.Finished() {
+		last = readSSEJob(t, body)
+		phases = append(phases, last.Phase)
+	}
+	assert.Equal(t, []string{
+		service.PhaseParsing, service.PhaseValidating,
+		service.PhasePrompting, service.PhaseParsing, service.PhaseValidating,
+		service.PhasePrompting, service.PhaseParsing, service.PhaseValidating,
+		service.PhasePersisting, service.PhasePersisting,
+	}, phases)
+	assert.Equal(t, model.JobSucceeded, last.Status)
+	assert.NotNil(t, last.PlanID)
+	assert.Equal(t, 3, last.Attempts)
+}
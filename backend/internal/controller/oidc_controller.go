@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+// sessOIDCLogin holds the sign-in in progress between the redirect to the
+// provider and its callback.
+const sessOIDCLogin = "oidc"
+
+type OIDCController struct {
+	oidc      *service.OIDCService
+	twoFactor *service.TwoFactorService
+	appURL    string
+	// callbackBase is the public URL of the callback without the provider
+	// name.
+	callbackBase string
+}
+
+// RegisterOIDCRoutes adds sign-in with the configured OpenID Connect
+// providers. The browser is sent to the provider and back to the callback,
+// which starts a session and redirects to the app, or to its login page
+// with an error. Accounts with two-factor authentication enabled are sent
+// to the login page's code step instead of getting a session.
+func RegisterOIDCRoutes(rg *gin.RouterGroup, oidc *service.OIDCService, twoFactor *service.TwoFactorService, appURL string) {
+	appURL = strings.TrimRight(appURL, "/")
+	oc := &OIDCController{oidc: oidc, twoFactor: twoFactor, appURL: appURL, callbackBase: appURL + rg.BasePath() + "/auth/oidc/"}
+	auth := rg.Group("/auth/oidc")
+	{
+		auth.GET("/providers", oc.getProviders)
+		auth.GET("/identities", requireAuth, oc.getIdentities)
+		auth.GET("/:provider/login", oc.getLogin)
+		auth.GET("/:provider/callback", oc.getCallback)
+	}
+}
+
+func (oc *OIDCController) redirectURI(provider string) string {
+	return oc.callbackBase + url.PathEscape(provider) + "/callback"
+}
+
+// fail sends the browser back to the login page with a message to show.
+// The session is saved, so a sign-in state dropped before is gone too.
+func (oc *OIDCController) fail(c *gin.Context, message string) {
+	_ = sessions.Default(c).Save()
+	c.Redirect(http.StatusFound, oc.appURL+"/login?error="+url.QueryEscape(message))
+}
+
+func (oc *OIDCController) getProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": oc.oidc.Providers()})
+}
+
+func (oc *OIDCController) getIdentities(c *gin.Context) {
+	identities, err := oc.oidc.Identities(model.UserID(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list identities"})
+		return
+	}
+	if identities == nil {
+		identities = []*model.Identity{}
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+func (oc *OIDCController) getLogin(c *gin.Context) {
+	provider := c.Param("provider")
+	login, err := oc.oidc.Begin(c.Request.Context(), provider, oc.redirectURI(provider))
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownOIDCProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		oc.fail(c, "the sign-in provider is not available right now")
+		return
+	}
+	state, err := json.Marshal(login)
+	if err != nil {
+		oc.fail(c, "sign-in failed")
+		return
+	}
+	sess := sessions.Default(c)
+	sess.Set(sessOIDCLogin, string(state))
+	_ = sess.Save()
+	c.Redirect(http.StatusFound, login.URL)
+}
+
+func (oc *OIDCController) getCallback(c *gin.Context) {
+	provider := c.Param("provider")
+	sess := sessions.Default(c)
+	raw, _ := sess.Get(sessOIDCLogin).(string)
+	// The state is single use, whatever the outcome.
+	sess.Delete(sessOIDCLogin)
+
+	var login service.OIDCLogin
+	if raw == "" || json.Unmarshal([]byte(raw), &login) != nil ||
+		login.Provider != provider || login.State != c.Query("state") {
+		oc.fail(c, "the sign-in expired, please try again")
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		if desc := c.Query("error_description"); desc != "" {
+			e = desc
+		}
+		oc.fail(c, "the provider refused the sign-in: "+e)
+		return
+	}
+	if c.Query("code") == "" {
+		oc.fail(c, "the provider did not return a sign-in code")
+		return
+	}
+
+	u, err := oc.oidc.Complete(c.Request.Context(), &login, oc.redirectURI(provider), c.Query("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrOIDCEmailUnverified), errors.Is(err, service.ErrOIDCAccountUnverified):
+			oc.fail(c, err.Error())
+		case errors.Is(err, service.ErrOIDCLogin):
+			oc.fail(c, service.ErrOIDCLogin.Error())
+		case errors.Is(err, store.ErrNotFound):
+			oc.fail(c, "the linked account no longer exists")
+		default:
+			oc.fail(c, "sign-in failed")
+		}
+		return
+	}
+
+	if oc.twoFactor != nil {
+		enabled, err := oc.twoFactor.Enabled(u.ID)
+		if err != nil {
+			oc.fail(c, "sign-in failed")
+			return
+		}
+		if enabled {
+			// As with a password, the provider only unlocks the code step.
+			sess.Delete("uid")
+			sess.Set(sessPendingUID, string(u.ID))
+			sess.Set(sessPendingAt, time.Now().Unix())
+			_ = sess.Save()
+			c.Redirect(http.StatusFound, oc.appURL+"/login?twoFactor=1")
+			return
+		}
+	}
+	sess.Set("uid", string(u.ID))
+	_ = sess.Save()
+	c.Redirect(http.StatusFound, oc.appURL+"/")
+}
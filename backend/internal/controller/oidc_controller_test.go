@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/oidc"
+	"github.com/kevsommer/runplanner/internal/oidc/oidctest"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/kevsommer/runplanner/internal/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const oidcAppURL = "http://app.test"
+
+func setupOIDCTestRouter(t *testing.T) (*gin.Engine, *oidctest.Server, *service.AuthService, *service.TwoFactorService) {
+	gin.SetMode(gin.TestMode)
+	idp := oidctest.NewServer("runplanner", "s3cret")
+	t.Cleanup(idp.Close)
+	userStore := mem.NewMemUserStore()
+	authSvc := service.NewAuthService(userStore)
+	oidcSvc := service.NewOIDCService([]*oidc.Provider{oidc.NewProvider(idp.Config("corp"), nil)}, userStore, mem.NewMemIdentityStore())
+	twoFactorSvc := service.NewTwoFactorService(userStore, mem.NewMemTwoFactorStore())
+
+	r := gin.New()
+	storeCookie := cookie.NewStore([]byte("test-secret"))
+	r.Use(sessions.Sessions("rp.sid", storeCookie))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, twoFactorSvc, nil)
+	RegisterOIDCRoutes(api, oidcSvc, twoFactorSvc, oidcAppURL)
+
+	return r, idp, authSvc, twoFactorSvc
+}
+
+// oidcLogin follows the sign-in redirects through the mock provider and
+// returns the callback's response.
+func oidcLogin(t *testing.T, r *gin.Engine, idp *oidctest.Server) *httptest.ResponseRecorder {
+	t.Helper()
+	w := coachRequest(r, nil, http.MethodGet, "/api/auth/oidc/corp/login")
+	require.Equal(t, http.StatusFound, w.Code)
+	back, err := idp.Authorize(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, oidcAppURL+"/api/auth/oidc/corp/callback", back.Scheme+"://"+back.Host+back.Path)
+
+	return coachRequest(r, w.Result().Cookies(), http.MethodGet, back.RequestURI())
+}
+
+func loginError(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	require.Equal(t, http.StatusFound, w.Code)
+	loc, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "/login", loc.Path)
+	return loc.Query().Get("error")
+}
+
+func TestOIDCController(t *testing.T) {
+	t.Run("lists providers", func(t *testing.T) {
+		r, _, _, _ := setupOIDCTestRouter(t)
+		w := coachRequest(r, nil, http.MethodGet, "/api/auth/oidc/providers")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"providers": [{"name": "corp", "displayName": "Test provider"}]}`, w.Body.String())
+	})
+
+	t.Run("unknown provider returns 404", func(t *testing.T) {
+		r, _, _, _ := setupOIDCTestRouter(t)
+		w := coachRequest(r, nil, http.MethodGet, "/api/auth/oidc/nope/login")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("signs in and starts a session", func(t *testing.T) {
+		r, idp, _, _ := setupOIDCTestRouter(t)
+		idp.SetUser(oidctest.User{Subject: "abc", Email: "sso@corp.example", EmailVerified: true})
+
+		w := oidcLogin(t, r, idp)
+		require.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, oidcAppURL+"/", w.Header().Get("Location"))
+
+		me := coachRequest(r, w.Result().Cookies(), http.MethodGet, "/api/auth/me")
+		require.Equal(t, http.StatusOK, me.Code)
+		var resp struct {
+			User model.PublicUser `json:"user"`
+		}
+		require.NoError(t, json.Unmarshal(me.Body.Bytes(), &resp))
+		assert.Equal(t, "sso@corp.example", resp.User.Email)
+		assert.True(t, resp.User.EmailVerified)
+
+		ids := coachRequest(r, w.Result().Cookies(), http.MethodGet, "/api/auth/oidc/identities")
+		require.Equal(t, http.StatusOK, ids.Code)
+		assert.Contains(t, ids.Body.String(), `"subject":"abc"`)
+	})
+
+	t.Run("two-factor accounts need the code step", func(t *testing.T) {
+		r, idp, _, twoFactorSvc := setupOIDCTestRouter(t)
+		idp.SetUser(oidctest.User{Subject: "abc", Email: "sso@corp.example", EmailVerified: true})
+		w := oidcLogin(t, r, idp)
+		require.Equal(t, http.StatusFound, w.Code)
+		me := coachRequest(r, w.Result().Cookies(), http.MethodGet, "/api/auth/me")
+		require.Equal(t, http.StatusOK, me.Code)
+		var resp struct {
+			User model.PublicUser `json:"user"`
+		}
+		require.NoError(t, json.Unmarshal(me.Body.Bytes(), &resp))
+
+		enrolment, err := twoFactorSvc.Begin(resp.User.ID)
+		require.NoError(t, err)
+		code, err := totp.Code(enrolment.Secret, totp.Step(time.Now()))
+		require.NoError(t, err)
+		_, err = twoFactorSvc.Confirm(resp.User.ID, code)
+		require.NoError(t, err)
+
+		w = oidcLogin(t, r, idp)
+		require.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, oidcAppURL+"/login?twoFactor=1", w.Header().Get("Location"))
+		pending := w.Result().Cookies()
+		me = coachRequest(r, pending, http.MethodGet, "/api/auth/me")
+		assert.Equal(t, http.StatusUnauthorized, me.Code)
+
+		w = postReplan(r, pending, "/api/auth/login/2fa", map[string]interface{}{"code": "000000"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = postReplan(r, pending, "/api/auth/login/2fa", map[string]interface{}{"code": nextCode(t, enrolment.Secret)})
+		require.Equal(t, http.StatusOK, w.Code)
+		me = coachRequest(r, w.Result().Cookies(), http.MethodGet, "/api/auth/me")
+		assert.Equal(t, http.StatusOK, me.Code)
+	})
+
+	t.Run("does not link an unverified account", func(t *testing.T) {
+		r, idp, authSvc, _ := setupOIDCTestRouter(t)
+		_, err := authSvc.Register("runner@corp.example", "password123")
+		require.NoError(t, err)
+		idp.SetUser(oidctest.User{Subject: "abc", Email: "runner@corp.example", EmailVerified: true})
+
+		// Registered accounts start unverified, so they are not linked.
+		msg := loginError(t, oidcLogin(t, r, idp))
+		assert.Contains(t, msg, "not verified")
+	})
+
+	t.Run("state mismatch is rejected", func(t *testing.T) {
+		r, idp, _, _ := setupOIDCTestRouter(t)
+		w := coachRequest(r, nil, http.MethodGet, "/api/auth/oidc/corp/login")
+		require.Equal(t, http.StatusFound, w.Code)
+		back, err := idp.Authorize(w.Header().Get("Location"))
+		require.NoError(t, err)
+		q := back.Query()
+		q.Set("state", "forged")
+		back.RawQuery = q.Encode()
+
+		resp := coachRequest(r, w.Result().Cookies(), http.MethodGet, back.RequestURI())
+		assert.Contains(t, loginError(t, resp), "expired")
+	})
+
+	t.Run("callback without a started sign-in is rejected", func(t *testing.T) {
+		r, _, _, _ := setupOIDCTestRouter(t)
+		w := coachRequest(r, nil, http.MethodGet, "/api/auth/oidc/corp/callback?code=x&state=y")
+		assert.Contains(t, loginError(t, w), "expired")
+	})
+
+	t.Run("provider errors are shown", func(t *testing.T) {
+		r, idp, _, _ := setupOIDCTestRouter(t)
+		w := coachRequest(r, nil, http.MethodGet, "/api/auth/oidc/corp/login")
+		require.Equal(t, http.StatusFound, w.Code)
+		back, err := idp.Authorize(w.Header().Get("Location"))
+		require.NoError(t, err)
+		q := back.Query()
+		q.Del("code")
+		q.Set("error", "access_denied")
+		back.RawQuery = q.Encode()
+
+		resp := coachRequest(r, w.Result().Cookies(), http.MethodGet, back.RequestURI())
+		assert.Contains(t, loginError(t, resp), "access_denied")
+	})
+
+	t.Run("invalid ID token fails the sign-in", func(t *testing.T) {
+		r, idp, _, _ := setupOIDCTestRouter(t)
+		idp.ClaimsHook = func(c map[string]interface{}) { c["aud"] = "someone-else" }
+		msg := loginError(t, oidcLogin(t, r, idp))
+		assert.Equal(t, service.ErrOIDCLogin.Error(), msg)
+	})
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/ratelimit"
+	"github.com/kevsommer/runplanner/internal/service"
+)
+
+type PasswordResetController struct {
+	resets *service.PasswordResetService
+}
+
+// RegisterPasswordResetRoutes adds the public forgot/reset password
+// endpoints under /auth, throttled by the limiter unless it is nil.
+func RegisterPasswordResetRoutes(rg *gin.RouterGroup, resets *service.PasswordResetService, limiter *ratelimit.Limiter) {
+	pc := &PasswordResetController{resets: resets}
+	auth := rg.Group("/auth")
+	{
+		auth.POST("/forgot-password", rateLimit(limiter, emailKey), pc.postForgotPassword)
+		auth.POST("/reset-password", rateLimit(limiter, nil), pc.postResetPassword)
+	}
+}
+
+type forgotPasswordInput struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type resetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (pc *PasswordResetController) postForgotPassword(c *gin.Context) {
+	var req forgotPasswordInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email required"})
+		return
+	}
+	if err := pc.resets.RequestReset(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send reset email"})
+		return
+	}
+	// Same answer whether or not the email is registered.
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the email is registered, a reset link has been sent"})
+}
+
+func (pc *PasswordResetController) postResetPassword(c *gin.Context) {
+	var req resetPasswordInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token and password required"})
+		return
+	}
+	if err := pc.resets.ResetPassword(req.Token, req.Password); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidResetToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/mail"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPasswordResetTestRouter sends reset emails to a log mailer writing
+// into the returned buffer.
+func setupPasswordResetTestRouter(t *testing.T) (*gin.Engine, *service.AuthService, *bytes.Buffer) {
+	gin.SetMode(gin.TestMode)
+	userStore := mem.NewMemUserStore()
+	authSvc := service.NewAuthService(userStore)
+	var mails bytes.Buffer
+	resetSvc := service.NewPasswordResetService(userStore, mem.NewMemPasswordResetStore(), service.NewSessionService(mem.NewMemSessionStore()), mail.NewLogMailer(log.New(&mails, "", 0)), "http://localhost:5173")
+
+	r := gin.New()
+	storeCookie := cookie.NewStore([]byte("test-secret"))
+	r.Use(sessions.Sessions("rp.sid", storeCookie))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterPasswordResetRoutes(api, resetSvc, nil)
+
+	return r, authSvc, &mails
+}
+
+var resetLinkRe = regexp.MustCompile(`http://localhost:5173/reset-password\?token=\S+`)
+
+func TestPasswordResetController(t *testing.T) {
+	r, authSvc, mails := setupPasswordResetTestRouter(t)
+	_, err := authSvc.Register("forgetful@example.com", "password123")
+	require.NoError(t, err)
+
+	var token string
+	t.Run("forgot password emails a link", func(t *testing.T) {
+		w := postReplan(r, nil, "/api/auth/forgot-password", map[string]string{"email": "forgetful@example.com"})
+		require.Equal(t, http.StatusAccepted, w.Code)
+
+		link := resetLinkRe.FindString(mails.String())
+		require.NotEmpty(t, link)
+		u, err := url.Parse(link)
+		require.NoError(t, err)
+		token = u.Query().Get("token")
+		assert.NotEmpty(t, token)
+	})
+
+	t.Run("unknown email gets the same answer", func(t *testing.T) {
+		mails.Reset()
+		w := postReplan(r, nil, "/api/auth/forgot-password", map[string]string{"email": "unknown@example.com"})
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Empty(t, mails.String())
+	})
+
+	t.Run("missing email returns 400", func(t *testing.T) {
+		w := postReplan(r, nil, "/api/auth/forgot-password", map[string]string{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("weak password returns 400", func(t *testing.T) {
+		w := postReplan(r, nil, "/api/auth/reset-password", map[string]string{"token": token, "password": "short"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "at least 8 chars")
+	})
+
+	t.Run("resets the password", func(t *testing.T) {
+		w := postReplan(r, nil, "/api/auth/reset-password", map[string]string{"token": token, "password": "new-password"})
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		w = postReplan(r, nil, "/api/auth/login", map[string]string{"email": "forgetful@example.com", "password": "new-password"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = postReplan(r, nil, "/api/auth/login", map[string]string{"email": "forgetful@example.com", "password": "password123"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("used token returns 400", func(t *testing.T) {
+		w := postReplan(r, nil, "/api/auth/reset-password", map[string]string{"token": token, "password": "another-password"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid or expired reset token")
+	})
+
+	t.Run("missing fields return 400", func(t *testing.T) {
+		w := postReplan(r, nil, "/api/auth/reset-password", map[string]string{"password": "new-password"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
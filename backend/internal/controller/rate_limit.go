@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/ratelimit"
+)
+
+// rateLimit throttles a route per client IP and per the keys returned by
+// extra, answering 429 with Retry-After once a bucket is empty or a key is
+// locked out. A 401 response counts as a failed attempt towards a lockout
+// and a 2xx response clears earlier failures. A nil limiter lets every
+// request through.
+func rateLimit(limiter *ratelimit.Limiter, extra func(*gin.Context) []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		keys := []string{"ip:" + c.ClientIP()}
+		if extra != nil {
+			keys = append(keys, extra(c)...)
+		}
+		if ok, wait := limiter.Allow(keys...); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many attempts, try again later"})
+			return
+		}
+		c.Next()
+		switch status := c.Writer.Status(); {
+		case status == http.StatusUnauthorized:
+			limiter.Fail(keys...)
+		case status >= 200 && status < 300:
+			limiter.Succeed(keys...)
+		}
+	}
+}
+
+// maxEmailKeyBody caps how much of an unauthenticated body emailKey reads;
+// credentials fit easily.
+const maxEmailKeyBody = 4 << 10
+
+// emailKey limits by the email address in a JSON body, so that guesses at
+// one account are throttled from any number of addresses. The body is put
+// back for the handler; one over the size cap comes back cut short, so it
+// fails to parse there.
+func emailKey(c *gin.Context) []string {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxEmailKeyBody))
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	var req struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.Email == "" {
+		return nil
+	}
+	return []string{"email:" + strings.ToLower(strings.TrimSpace(req.Email))}
+}
+
+// pendingLoginKey limits second-factor codes by the account waiting for
+// them.
+func pendingLoginKey(c *gin.Context) []string {
+	if uid, _ := sessions.Default(c).Get(sessPendingUID).(string); uid != "" {
+		return []string{"user:" + uid}
+	}
+	return nil
+}
+
+// userKey limits by the authenticated user.
+func userKey(c *gin.Context) []string {
+	if uid := currentUserID(c); uid != "" {
+		return []string{"user:" + uid}
+	}
+	return nil
+}
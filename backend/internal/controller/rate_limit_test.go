@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/ratelimit"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRateLimitTestRouter(t *testing.T, auth, generate *ratelimit.Limiter) (*gin.Engine, *service.AuthService) {
+	gin.SetMode(gin.TestMode)
+	userStore := mem.NewMemUserStore()
+	authSvc := service.NewAuthService(userStore)
+	planSvc := service.NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workoutSvc := service.NewWorkoutService(mem.NewMemWorkoutStore())
+
+	r := gin.New()
+	r.Use(sessions.Sessions("rp.sid", cookie.NewStore([]byte("test-secret"))))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, auth)
+	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil), nil, nil, nil, generate)
+
+	return r, authSvc
+}
+
+// postFrom sends a JSON POST from the given client IP.
+func postFrom(r *gin.Engine, ip, url string, body interface{}) *httptest.ResponseRecorder {
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_Login(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Config{
+		Rate:    ratelimit.Rate{Burst: 4, Per: time.Minute},
+		Lockout: ratelimit.Lockout{After: 3, Base: time.Minute, Max: time.Hour},
+	})
+	r, authSvc := setupRateLimitTestRouter(t, limiter, nil)
+	_, err := authSvc.Register("target@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("bystander@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("returning@example.com", "password123")
+	require.NoError(t, err)
+
+	t.Run("throttles bursts from one IP", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			w := postFrom(r, "198.51.100.1", "/api/auth/register", map[string]string{"email": "new@example.com", "password": "x"})
+			require.Equal(t, http.StatusBadRequest, w.Code, "request %d", i)
+		}
+		w := postFrom(r, "198.51.100.1", "/api/auth/register", map[string]string{"email": "new@example.com", "password": "x"})
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "15", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("locks the account out after failed logins from any IP", func(t *testing.T) {
+		for i, ip := range []string{"198.51.100.2", "198.51.100.3", "198.51.100.4"} {
+			w := postFrom(r, ip, "/api/auth/login", map[string]string{"email": "Target@example.com", "password": "wrong-password"})
+			require.Equal(t, http.StatusUnauthorized, w.Code, "attempt %d", i)
+		}
+		w := postFrom(r, "198.51.100.5", "/api/auth/login", map[string]string{"email": "target@example.com", "password": "password123"})
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+		w = postFrom(r, "198.51.100.6", "/api/auth/login", map[string]string{"email": "bystander@example.com", "password": "password123"})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("oversized bodies are not read in full", func(t *testing.T) {
+		w := postFrom(r, "198.51.100.11", "/api/auth/login", map[string]string{
+			"email":    "bystander@example.com",
+			"password": "password123",
+			"padding":  strings.Repeat("x", maxEmailKeyBody),
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("a successful login clears earlier failures", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			w := postFrom(r, "198.51.100.7", "/api/auth/login", map[string]string{"email": "returning@example.com", "password": "wrong-password"})
+			require.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+		w := postFrom(r, "198.51.100.8", "/api/auth/login", map[string]string{"email": "returning@example.com", "password": "password123"})
+		require.Equal(t, http.StatusOK, w.Code)
+		w = postFrom(r, "198.51.100.9", "/api/auth/login", map[string]string{"email": "returning@example.com", "password": "wrong-password"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestRateLimit_Generate(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Config{Rate: ratelimit.Rate{Burst: 1, Per: time.Hour}})
+	r, authSvc := setupRateLimitTestRouter(t, nil, limiter)
+	_, err := authSvc.Register("gen@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("other-gen@example.com", "password123")
+	require.NoError(t, err)
+	cookies := loginForGenerate(t, r, "gen@example.com", "password123")
+	other := loginForGenerate(t, r, "other-gen@example.com", "password123")
+
+	w := postReplan(r, cookies, "/api/plans/generate", map[string]interface{}{})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	w = postReplan(r, cookies, "/api/plans/generate", map[string]interface{}{})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+
+	// Without a distinct address the other user shares the test client's
+	// IP bucket.
+	req := httptest.NewRequest(http.MethodPost, "/api/plans/generate", bytes.NewReader([]byte("{}")))
+	req.RemoteAddr = "198.51.100.10:1234"
+	for _, c := range other {
+		req.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
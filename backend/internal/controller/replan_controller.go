@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+)
+
+type ReplanController struct {
+	replan *service.ReplanService
+	access *service.AccessService
+}
+
+// RegisterReplanRoutes adds the endpoints that rework part of a plan: a
+// preview proposed by the AI and the apply step that stores it.
+func RegisterReplanRoutes(rg *gin.RouterGroup, replan *service.ReplanService, access *service.AccessService, verify *service.EmailVerificationService) {
+	rc := &ReplanController{replan: replan, access: access}
+
+	plansGroup := rg.Group("/plans")
+	plansGroup.Use(requireAuth, requireScope(model.ScopeGenerate), requireVerifiedEmail(verify))
+	{
+		plansGroup.POST("/:id/replan/preview", rc.postPreview)
+		plansGroup.POST("/:id/replan/apply", rc.postApply)
+	}
+}
+
+// replanRangeInput selects the weeks to rework. Without toWeek the rest of
+// the plan is adjusted; set it to fromWeek to regenerate a single week.
+type replanRangeInput struct {
+	FromWeek int    `json:"fromWeek" binding:"required"`
+	ToWeek   int    `json:"toWeek"`
+	Reason   string `json:"reason"`
+}
+
+type replanApplyInput struct {
+	replanRangeInput
+	Replace  []model.WorkoutID `json:"replace"`
+	Workouts []bulkWorkoutItem `json:"workouts" binding:"dive"`
+}
+
+// ownedPlan only lets the plan's owner in, as replanning uses their AI quota.
+func (r *ReplanController) ownedPlan(c *gin.Context) (*model.TrainingPlan, bool) {
+	return accessiblePlan(c, r.access, model.TrainingPlanID(c.Param("id")), service.AccessOwner)
+}
+
+func (in replanRangeInput) toService(plan *model.TrainingPlan) service.ReplanInput {
+	toWeek := in.ToWeek
+	if toWeek == 0 {
+		toWeek = plan.Weeks
+	}
+	return service.ReplanInput{FromWeek: in.FromWeek, ToWeek: toWeek, Reason: in.Reason, Today: time.Now()}
+}
+
+func (r *ReplanController) postPreview(c *gin.Context) {
+	plan, ok := r.ownedPlan(c)
+	if !ok {
+		return
+	}
+	var req replanRangeInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fromWeek is required"})
+		return
+	}
+
+	preview, err := r.replan.Preview(c.Request.Context(), plan, req.toService(plan))
+	if err != nil {
+		var quotaErr *service.QuotaError
+		switch {
+		case errors.As(err, &quotaErr):
+			writeQuotaExceeded(c, quotaErr)
+		case errors.Is(err, service.ErrAINotConfigured):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "AI generation is not configured on the server"})
+		case errors.Is(err, service.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAIGeneration):
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to replan"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preview": preview})
+}
+
+func (r *ReplanController) postApply(c *gin.Context) {
+	plan, ok := r.ownedPlan(c)
+	if !ok {
+		return
+	}
+	var req replanApplyInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fromWeek, replace and workouts from the preview are required"})
+		return
+	}
+
+	items := make([]service.BulkWorkoutInput, len(req.Workouts))
+	for i, item := range req.Workouts {
+		items[i] = service.BulkWorkoutInput{
+			RunType:     item.RunType,
+			Week:        item.Week,
+			DayOfWeek:   item.DayOfWeek,
+			Description: item.Description,
+			Distance:    item.Distance,
+			Steps:       item.Steps,
+		}
+	}
+
+	created, err := r.replan.Apply(plan, req.toService(plan), req.Replace, items)
+	if err != nil {
+		var batchErr *service.BatchValidationError
+		switch {
+		case errors.Is(err, service.ErrStaleReplan):
+			c.JSON(http.StatusConflict, gin.H{"error": "the plan changed since the preview, request a new one"})
+		case errors.Is(err, service.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.As(err, &batchErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": batchErr.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply replan"})
+		}
+		return
+	}
+	if req.Replace == nil {
+		req.Replace = []model.WorkoutID{}
+	}
+	c.JSON(http.StatusOK, gin.H{"workouts": created, "removed": req.Replace})
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const replanMockResponse = `{"workouts": [
+	{"runType": "easy_run", "week": 5, "dayOfWeek": 2, "description": "Easy", "distance": 5},
+	{"runType": "long_run", "week": 5, "dayOfWeek": 7, "description": "All Easy", "distance": 10},
+	{"runType": "easy_run", "week": 6, "dayOfWeek": 3, "description": "Easy", "distance": 6},
+	{"runType": "long_run", "week": 6, "dayOfWeek": 6, "description": "All Easy", "distance": 12}
+]}`
+
+func setupReplanTestRouter(t *testing.T, mock ai.Client) (*gin.Engine, *service.AuthService, *service.TrainingPlanService, *service.WorkoutService) {
+	gin.SetMode(gin.TestMode)
+	userStore := mem.NewMemUserStore()
+	planStore := mem.NewMemTrainingPlanStore()
+	workoutStore := mem.NewMemWorkoutStore()
+	authSvc := service.NewAuthService(userStore)
+	planSvc := service.NewTrainingPlanService(planStore)
+	workoutSvc := service.NewWorkoutService(workoutStore)
+
+	r := gin.New()
+	storeCookie := cookie.NewStore([]byte("test-secret"))
+	r.Use(sessions.Sessions("rp.sid", storeCookie))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterReplanRoutes(api, service.NewReplanService(mock, unlimitedAIUsage(), workoutSvc), service.NewAccessService(planSvc, workoutSvc, nil, nil), nil)
+
+	return r, authSvc, planSvc, workoutSvc
+}
+
+// createUpcomingPlan creates an 8-week plan that starts after today, so
+// every week can be replanned.
+func createUpcomingPlan(t *testing.T, planSvc *service.TrainingPlanService, workoutSvc *service.WorkoutService, userID model.UserID) *model.TrainingPlan {
+	t.Helper()
+	end := time.Now().UTC().AddDate(0, 0, 70)
+	for end.Weekday() != time.Sunday {
+		end = end.AddDate(0, 0, 1)
+	}
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	input := service.GenerateInput{Name: "Upcoming", EndDate: end, Weeks: 8, BaseKmPerWeek: 30, RunsPerWeek: 3, RaceGoal: "halfmarathon"}
+
+	plan, err := planSvc.Create(userID, input.Name, input.EndDate, input.Weeks)
+	require.NoError(t, err)
+	_, err = workoutSvc.CreateBatch(plan, service.BuildRuleBasedWorkouts(input))
+	require.NoError(t, err)
+	return plan
+}
+
+func postReplan(r *gin.Engine, cookies []*http.Cookie, url string, body interface{}) *httptest.ResponseRecorder {
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestReplanController(t *testing.T) {
+	r, authSvc, planSvc, workoutSvc := setupReplanTestRouter(t, &mockAIClient{response: replanMockResponse})
+	user, err := authSvc.Register("replan@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("other-replan@example.com", "password123")
+	require.NoError(t, err)
+	cookies := loginForGenerate(t, r, "replan@example.com", "password123")
+	plan := createUpcomingPlan(t, planSvc, workoutSvc, user.ID)
+	previewURL := "/api/plans/" + string(plan.ID) + "/replan/preview"
+	applyURL := "/api/plans/" + string(plan.ID) + "/replan/apply"
+
+	var preview service.ReplanPreview
+	t.Run("preview returns the diff without changing the plan", func(t *testing.T) {
+		w := postReplan(r, cookies, previewURL, map[string]interface{}{"fromWeek": 5, "toWeek": 6, "reason": "Sick for a week"})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Preview service.ReplanPreview `json:"preview"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		preview = resp.Preview
+		assert.Equal(t, 5, preview.FromWeek)
+		assert.Equal(t, 6, preview.ToWeek)
+		assert.Equal(t, "Sick for a week", preview.Reason)
+		assert.NotEmpty(t, preview.Replace)
+		assert.Len(t, preview.Workouts, 4)
+		assert.Len(t, preview.Weeks, 2)
+		assert.NotEmpty(t, preview.Days)
+
+		for _, id := range preview.Replace {
+			_, err := workoutSvc.GetByID(id)
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("toWeek defaults to the end of the plan", func(t *testing.T) {
+		w := postReplan(r, cookies, previewURL, map[string]interface{}{"fromWeek": 8})
+		// The mock answers with weeks 5 and 6, so every attempt is rejected.
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		assert.Contains(t, w.Body.String(), "must be between 8 and 8")
+	})
+
+	t.Run("missing fromWeek returns 400", func(t *testing.T) {
+		w := postReplan(r, cookies, previewURL, map[string]interface{}{"reason": "x"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("range outside the plan returns 400", func(t *testing.T) {
+		w := postReplan(r, cookies, previewURL, map[string]interface{}{"fromWeek": 5, "toWeek": 9})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("other user's plan returns 404", func(t *testing.T) {
+		other := loginForGenerate(t, r, "other-replan@example.com", "password123")
+		w := postReplan(r, other, previewURL, map[string]interface{}{"fromWeek": 5})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("unauthenticated returns 401", func(t *testing.T) {
+		w := postReplan(r, nil, previewURL, map[string]interface{}{"fromWeek": 5})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("apply replaces the workouts", func(t *testing.T) {
+		before, err := workoutSvc.GetByPlanID(plan.ID)
+		require.NoError(t, err)
+
+		w := postReplan(r, cookies, applyURL, map[string]interface{}{
+			"fromWeek": 5,
+			"toWeek":   6,
+			"replace":  preview.Replace,
+			"workouts": preview.Workouts,
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Workouts []model.Workout   `json:"workouts"`
+			Removed  []model.WorkoutID `json:"removed"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.Workouts, 4)
+		assert.ElementsMatch(t, preview.Replace, resp.Removed)
+
+		after, err := workoutSvc.GetByPlanID(plan.ID)
+		require.NoError(t, err)
+		assert.Len(t, after, len(before)-len(preview.Replace)+4)
+	})
+
+	t.Run("applying the same preview again returns 409", func(t *testing.T) {
+		w := postReplan(r, cookies, applyURL, map[string]interface{}{
+			"fromWeek": 5,
+			"toWeek":   6,
+			"replace":  preview.Replace,
+			"workouts": preview.Workouts,
+		})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("AI not configured returns 400", func(t *testing.T) {
+		r, authSvc, planSvc, workoutSvc := setupReplanTestRouter(t, nil)
+		user, err := authSvc.Register("noai@example.com", "password123")
+		require.NoError(t, err)
+		cookies := loginForGenerate(t, r, "noai@example.com", "password123")
+		plan := createUpcomingPlan(t, planSvc, workoutSvc, user.ID)
+
+		w := postReplan(r, cookies, "/api/plans/"+string(plan.ID)+"/replan/preview", map[string]interface{}{"fromWeek": 5})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "not configured")
+	})
+}
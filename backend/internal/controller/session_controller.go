@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type SessionController struct {
+	sessions *service.SessionService
+}
+
+// sessionView marks the session the request came from, so the client can
+// tell "this device" apart.
+type sessionView struct {
+	*model.Session
+	Current bool `json:"current"`
+}
+
+// RegisterSessionRoutes adds listing and ending the user's login sessions.
+// DELETE on the collection logs out everywhere, including here.
+func RegisterSessionRoutes(rg *gin.RouterGroup, sessions *service.SessionService) {
+	sc := &SessionController{sessions: sessions}
+	group := rg.Group("/auth/sessions")
+	group.Use(requireAuth, requireSession)
+	{
+		group.GET("", sc.getList)
+		group.DELETE("", sc.deleteAll)
+		group.DELETE("/:id", sc.deleteOne)
+	}
+}
+
+func (sc *SessionController) getList(c *gin.Context) {
+	list, err := sc.sessions.List(model.UserID(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+	current := sessions.Default(c).ID()
+	views := make([]sessionView, 0, len(list))
+	for _, s := range list {
+		views = append(views, sessionView{Session: s, Current: current != "" && string(s.ID) == current})
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": views})
+}
+
+func (sc *SessionController) deleteOne(c *gin.Context) {
+	id := c.Param("id")
+	err := sc.sessions.Revoke(model.UserID(currentUserID(c)), model.SessionID(id))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	if sess := sessions.Default(c); sess.ID() == id {
+		// The row is gone; clearing expires the cookie too.
+		sess.Clear()
+		_ = sess.Save()
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+func (sc *SessionController) deleteAll(c *gin.Context) {
+	if err := sc.sessions.RevokeAll(model.UserID(currentUserID(c))); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	sess := sessions.Default(c)
+	sess.Clear()
+	_ = sess.Save()
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/session"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSessionTestRouter(t *testing.T) (*gin.Engine, *service.AuthService) {
+	gin.SetMode(gin.TestMode)
+	userStore := mem.NewMemUserStore()
+	sessionStore := mem.NewMemSessionStore()
+	authSvc := service.NewAuthService(userStore)
+	sessionSvc := service.NewSessionService(sessionStore)
+
+	r := gin.New()
+	r.Use(sessions.Sessions("rp.sid", session.NewStore(sessionStore, []byte("test-secret"))))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterSessionRoutes(api, sessionSvc)
+
+	return r, authSvc
+}
+
+type sessionListResponse struct {
+	Sessions []struct {
+		ID      string `json:"id"`
+		Current bool   `json:"current"`
+	} `json:"sessions"`
+}
+
+func listSessions(t *testing.T, r *gin.Engine, cookies []*http.Cookie) sessionListResponse {
+	t.Helper()
+	w := coachRequest(r, cookies, http.MethodGet, "/api/auth/sessions")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp sessionListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestSessionController(t *testing.T) {
+	r, authSvc := setupSessionTestRouter(t)
+	_, err := authSvc.Register("sessions@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("other-sessions@example.com", "password123")
+	require.NoError(t, err)
+
+	t.Run("requires login", func(t *testing.T) {
+		w := coachRequest(r, nil, http.MethodGet, "/api/auth/sessions")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("lists sessions and marks the current one", func(t *testing.T) {
+		laptop := loginForGenerate(t, r, "sessions@example.com", "password123")
+		phone := loginForGenerate(t, r, "sessions@example.com", "password123")
+
+		resp := listSessions(t, r, laptop)
+		require.Len(t, resp.Sessions, 2)
+		current := 0
+		for _, s := range resp.Sessions {
+			if s.Current {
+				current++
+			}
+		}
+		assert.Equal(t, 1, current)
+		assert.Len(t, listSessions(t, r, phone).Sessions, 2)
+
+		w := coachRequest(r, laptop, http.MethodPost, "/api/auth/logout")
+		require.Equal(t, http.StatusNoContent, w.Code)
+		assert.Len(t, listSessions(t, r, phone).Sessions, 1)
+	})
+
+	t.Run("revokes another device", func(t *testing.T) {
+		laptop := loginForGenerate(t, r, "sessions@example.com", "password123")
+		phone := loginForGenerate(t, r, "sessions@example.com", "password123")
+		var phoneID string
+		for _, s := range listSessions(t, r, phone).Sessions {
+			if s.Current {
+				phoneID = s.ID
+			}
+		}
+		require.NotEmpty(t, phoneID)
+
+		w := coachRequest(r, laptop, http.MethodDelete, "/api/auth/sessions/"+phoneID)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"deleted": true}`, w.Body.String())
+
+		w = coachRequest(r, phone, http.MethodGet, "/api/auth/me")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = coachRequest(r, laptop, http.MethodGet, "/api/auth/me")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("cannot revoke another user's session", func(t *testing.T) {
+		mine := loginForGenerate(t, r, "sessions@example.com", "password123")
+		theirs := loginForGenerate(t, r, "other-sessions@example.com", "password123")
+		var theirID string
+		for _, s := range listSessions(t, r, theirs).Sessions {
+			theirID = s.ID
+		}
+
+		w := coachRequest(r, mine, http.MethodDelete, "/api/auth/sessions/"+theirID)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = coachRequest(r, theirs, http.MethodGet, "/api/auth/me")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("logs out everywhere", func(t *testing.T) {
+		laptop := loginForGenerate(t, r, "sessions@example.com", "password123")
+		phone := loginForGenerate(t, r, "sessions@example.com", "password123")
+		other := loginForGenerate(t, r, "other-sessions@example.com", "password123")
+
+		w := coachRequest(r, laptop, http.MethodDelete, "/api/auth/sessions")
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		for _, cookies := range [][]*http.Cookie{laptop, phone} {
+			w = coachRequest(r, cookies, http.MethodGet, "/api/auth/me")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+		w = coachRequest(r, other, http.MethodGet, "/api/auth/me")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type ShareLinkController struct {
+	links  *service.ShareLinkService
+	access *service.AccessService
+}
+
+// RegisterShareLinkRoutes adds managing a plan's share links, which only
+// its owner may do, and the public view behind a link. Creating a link
+// needs a verified email when verify requires one; copying a shared plan
+// needs a login.
+func RegisterShareLinkRoutes(rg *gin.RouterGroup, links *service.ShareLinkService, access *service.AccessService, verify *service.EmailVerificationService) {
+	sc := &ShareLinkController{links: links, access: access}
+
+	plans := rg.Group("/plans")
+	plans.Use(requireAuth)
+	{
+		plans.GET("/:id/shares", sc.getLinks)
+		plans.POST("/:id/shares", requireVerifiedEmail(verify), sc.postLink)
+		plans.DELETE("/:id/shares/:shareId", sc.deleteLink)
+	}
+
+	shared := rg.Group("/shared")
+	{
+		shared.GET("/:slug", sc.getShared)
+		shared.POST("/:slug/copy", requireAuth, sc.postCopy)
+	}
+}
+
+type createShareLinkInput struct {
+	ExpiresInDays int `json:"expiresInDays"`
+}
+
+type copySharedPlanInput struct {
+	EndDate string `json:"endDate"` // optional ISO date YYYY-MM-DD
+}
+
+func (sc *ShareLinkController) getLinks(c *gin.Context) {
+	plan, ok := accessiblePlan(c, sc.access, model.TrainingPlanID(c.Param("id")), service.AccessOwner)
+	if !ok {
+		return
+	}
+	links, err := sc.links.List(plan.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list share links"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+func (sc *ShareLinkController) postLink(c *gin.Context) {
+	plan, ok := accessiblePlan(c, sc.access, model.TrainingPlanID(c.Param("id")), service.AccessOwner)
+	if !ok {
+		return
+	}
+	var req createShareLinkInput
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	link, err := sc.links.Create(plan.ID, req.ExpiresInDays)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create share link"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"link": link})
+}
+
+func (sc *ShareLinkController) deleteLink(c *gin.Context) {
+	plan, ok := accessiblePlan(c, sc.access, model.TrainingPlanID(c.Param("id")), service.AccessOwner)
+	if !ok {
+		return
+	}
+	if err := sc.links.Revoke(plan.ID, model.ShareLinkID(c.Param("shareId"))); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "share link not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke share link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+func (sc *ShareLinkController) getShared(c *gin.Context) {
+	shared, err := sc.links.Get(c.Param("slug"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shared plan not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get shared plan"})
+		return
+	}
+	// Keep shared plans out of search results even if a link is posted
+	// somewhere public.
+	c.Header("X-Robots-Tag", "noindex")
+	c.JSON(http.StatusOK, shared)
+}
+
+func (sc *ShareLinkController) postCopy(c *gin.Context) {
+	var req copySharedPlanInput
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	var endDate *time.Time
+	if req.EndDate != "" {
+		d, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "endDate must be YYYY-MM-DD"})
+			return
+		}
+		endDate = &d
+	}
+	plan, err := sc.links.Copy(model.UserID(currentUserID(c)), c.Param("slug"), endDate)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shared plan not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to copy plan"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"plan": plan})
+}
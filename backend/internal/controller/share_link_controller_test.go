@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupShareLinkTestRouter(t *testing.T) (*gin.Engine, *service.AuthService, *service.TrainingPlanService, *service.WorkoutService) {
+	gin.SetMode(gin.TestMode)
+	authSvc := service.NewAuthService(mem.NewMemUserStore())
+	planSvc := service.NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workoutSvc := service.NewWorkoutService(mem.NewMemWorkoutStore())
+	accessSvc := service.NewAccessService(planSvc, workoutSvc, nil, nil)
+
+	r := gin.New()
+	r.Use(sessions.Sessions("rp.sid", cookie.NewStore([]byte("test-secret"))))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterShareLinkRoutes(api, service.NewShareLinkService(mem.NewMemShareLinkStore(), planSvc, workoutSvc), accessSvc, nil)
+
+	return r, authSvc, planSvc, workoutSvc
+}
+
+func TestShareLinkController(t *testing.T) {
+	r, authSvc, planSvc, workoutSvc := setupShareLinkTestRouter(t)
+	user, err := authSvc.Register("sharer@example.com", "password123")
+	require.NoError(t, err)
+	_, err = authSvc.Register("friend@example.com", "password123")
+	require.NoError(t, err)
+	owner := loginForGenerate(t, r, "sharer@example.com", "password123")
+	friend := loginForGenerate(t, r, "friend@example.com", "password123")
+
+	plan := createUpcomingPlan(t, planSvc, workoutSvc, user.ID)
+	workouts, err := workoutSvc.GetByPlanID(plan.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, workouts)
+	workouts[0].Notes = "private note"
+	require.NoError(t, workoutSvc.Update(workouts[0]))
+	sharesURL := "/api/plans/" + string(plan.ID) + "/shares"
+
+	var link struct {
+		ID   string `json:"id"`
+		Slug string `json:"slug"`
+	}
+	t.Run("only the owner creates links", func(t *testing.T) {
+		w := accountRequest(r, friend, http.MethodPost, sharesURL, map[string]int{"expiresInDays": 7})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = accountRequest(r, nil, http.MethodPost, sharesURL, map[string]int{"expiresInDays": 7})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = accountRequest(r, owner, http.MethodPost, sharesURL, map[string]int{"expiresInDays": 1000})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = coachRequest(r, owner, http.MethodPost, sharesURL)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Link *struct {
+				ID   string `json:"id"`
+				Slug string `json:"slug"`
+			} `json:"link"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.NotNil(t, resp.Link)
+		link = *resp.Link
+
+		w = coachRequest(r, owner, http.MethodGet, sharesURL)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), link.Slug)
+	})
+
+	t.Run("anyone can read the shared plan", func(t *testing.T) {
+		w := coachRequest(r, nil, http.MethodGet, "/api/shared/"+link.Slug)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "noindex", w.Header().Get("X-Robots-Tag"))
+		assert.Contains(t, w.Body.String(), plan.Name)
+		assert.Contains(t, w.Body.String(), `"userId":""`)
+		assert.NotContains(t, w.Body.String(), string(user.ID))
+		assert.NotContains(t, w.Body.String(), string(plan.ID))
+		for _, workout := range workouts {
+			assert.NotContains(t, w.Body.String(), string(workout.ID))
+		}
+		assert.NotContains(t, w.Body.String(), "private note")
+
+		w = coachRequest(r, nil, http.MethodGet, "/api/shared/unknown")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("copying needs a login", func(t *testing.T) {
+		w := coachRequest(r, nil, http.MethodPost, "/api/shared/"+link.Slug+"/copy")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = accountRequest(r, friend, http.MethodPost, "/api/shared/"+link.Slug+"/copy", map[string]string{"endDate": "soon"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = coachRequest(r, friend, http.MethodPost, "/api/shared/"+link.Slug+"/copy")
+		require.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Plan struct {
+				ID     string `json:"id"`
+				UserID string `json:"userId"`
+			} `json:"plan"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.NotEqual(t, string(plan.ID), resp.Plan.ID)
+		assert.NotEqual(t, string(user.ID), resp.Plan.UserID)
+		copied, err := workoutSvc.GetByPlanID(model.TrainingPlanID(resp.Plan.ID))
+		require.NoError(t, err)
+		assert.Len(t, copied, len(workouts))
+	})
+
+	t.Run("revoking ends access", func(t *testing.T) {
+		w := coachRequest(r, friend, http.MethodDelete, sharesURL+"/"+link.ID)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = coachRequest(r, owner, http.MethodDelete, sharesURL+"/"+link.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = coachRequest(r, nil, http.MethodGet, "/api/shared/"+link.Slug)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = coachRequest(r, friend, http.MethodPost, "/api/shared/"+link.Slug+"/copy")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -8,15 +8,18 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/ratelimit"
 	"github.com/kevsommer/runplanner/internal/service"
 	"github.com/kevsommer/runplanner/internal/store"
 )
 
 type TrainingPlanController struct {
-	svc      *service.TrainingPlanService
-	workouts *service.WorkoutService
-	generate *service.GenerateService
-	auth     *service.AuthService
+	svc       *service.TrainingPlanService
+	workouts  *service.WorkoutService
+	jobs      *service.GenerateJobService
+	auth      *service.AuthService
+	validator *service.PlanValidator
+	access    *service.AccessService
 }
 
 func requireAuth(c *gin.Context) {
@@ -27,18 +30,62 @@ func requireAuth(c *gin.Context) {
 	c.Next()
 }
 
-func RegisterTrainingPlanRoutes(rg *gin.RouterGroup, svc *service.TrainingPlanService, workouts *service.WorkoutService, generate *service.GenerateService, auth *service.AuthService) {
-	tc := &TrainingPlanController{svc: svc, workouts: workouts, generate: generate, auth: auth}
+// accessiblePlan loads a plan the current user may access at the given
+// level, writing the error response if they may not.
+func accessiblePlan(c *gin.Context, access *service.AccessService, id model.TrainingPlanID, level service.Access) (*model.TrainingPlan, bool) {
+	plan, err := access.Plan(model.UserID(currentUserID(c)), id, level)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
+		return nil, false
+	}
+	return plan, true
+}
+
+// accessibleWorkout is accessiblePlan for a workout and its plan.
+func accessibleWorkout(c *gin.Context, access *service.AccessService, id model.WorkoutID, level service.Access) (*model.Workout, *model.TrainingPlan, bool) {
+	workout, plan, err := access.Workout(model.UserID(currentUserID(c)), id, level)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "workout not found"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get workout"})
+		return nil, nil, false
+	}
+	return workout, plan, true
+}
+
+// recordEdit attributes a change to someone else's plan to the current
+// user. The change has already been saved, so a failure is reported as such.
+func recordEdit(c *gin.Context, access *service.AccessService, plan *model.TrainingPlan, workoutID *model.WorkoutID, action string) bool {
+	if err := access.RecordEdit(model.UserID(currentUserID(c)), plan, workoutID, action); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "changes saved, but failed to record the edit"})
+		return false
+	}
+	return true
+}
+
+// RegisterTrainingPlanRoutes adds the plan endpoints. Coaches may view and
+// edit their athletes' plans through access. The generate limiter throttles
+// AI plan generation per user and IP; nil disables it.
+func RegisterTrainingPlanRoutes(rg *gin.RouterGroup, svc *service.TrainingPlanService, workouts *service.WorkoutService, access *service.AccessService, jobs *service.GenerateJobService, auth *service.AuthService, verify *service.EmailVerificationService, generateLimiter *ratelimit.Limiter) {
+	tc := &TrainingPlanController{svc: svc, workouts: workouts, jobs: jobs, auth: auth, validator: service.NewPlanValidator(), access: access}
 	plans := rg.Group("/plans")
 	plans.Use(requireAuth)
 	{
 		plans.POST("", tc.postCreate)
-		plans.POST("/generate", tc.postGenerate)
+		plans.POST("/generate", requireScope(model.ScopeGenerate), requireVerifiedEmail(verify), rateLimit(generateLimiter, userKey), tc.postGenerate)
 		plans.GET("", tc.getByUserID)
 		plans.GET("/:id", tc.getByID)
 		plans.PUT("/:id", tc.putUpdate)
 		plans.DELETE("/:id", tc.deletePlan)
 		plans.POST("/:id/activate", tc.postActivate)
+		plans.GET("/:id/lint", tc.getLint)
+		plans.GET("/:id/edits", tc.getEdits)
 	}
 }
 
@@ -85,19 +132,24 @@ func (t *TrainingPlanController) postCreate(c *gin.Context) {
 }
 
 func (t *TrainingPlanController) getByID(c *gin.Context) {
-	uid := currentUserID(c)
 	id := model.TrainingPlanID(c.Param("id"))
-	plan, err := t.svc.GetByID(id)
+	plan, ok := accessiblePlan(c, t.access, id, service.AccessView)
+	if !ok {
+		return
+	}
+	workouts, err := t.workouts.GetByPlanID(plan.ID)
 	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get workouts"})
 		return
 	}
-	if plan.UserID != model.UserID(uid) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
+	detail := service.BuildPlanDetail(plan, workouts)
+	c.JSON(http.StatusOK, gin.H{"plan": detail})
+}
+
+func (t *TrainingPlanController) getLint(c *gin.Context) {
+	id := model.TrainingPlanID(c.Param("id"))
+	plan, ok := accessiblePlan(c, t.access, id, service.AccessView)
+	if !ok {
 		return
 	}
 	workouts, err := t.workouts.GetByPlanID(plan.ID)
@@ -105,8 +157,7 @@ func (t *TrainingPlanController) getByID(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get workouts"})
 		return
 	}
-	detail := service.BuildPlanDetail(plan, workouts)
-	c.JSON(http.StatusOK, gin.H{"plan": detail})
+	c.JSON(http.StatusOK, gin.H{"findings": t.validator.Validate(plan, workouts)})
 }
 
 type updatePlanInput struct {
@@ -116,20 +167,10 @@ type updatePlanInput struct {
 }
 
 func (t *TrainingPlanController) putUpdate(c *gin.Context) {
-	uid := currentUserID(c)
 	id := model.TrainingPlanID(c.Param("id"))
 
-	plan, err := t.svc.GetByID(id)
-	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
-		return
-	}
-	if plan.UserID != model.UserID(uid) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
+	plan, ok := accessiblePlan(c, t.access, id, service.AccessEdit)
+	if !ok {
 		return
 	}
 
@@ -156,24 +197,16 @@ func (t *TrainingPlanController) putUpdate(c *gin.Context) {
 		}
 		return
 	}
+	if !recordEdit(c, t.access, plan, nil, service.EditPlanUpdated) {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"plan": updated})
 }
 
 func (t *TrainingPlanController) deletePlan(c *gin.Context) {
-	uid := currentUserID(c)
 	id := model.TrainingPlanID(c.Param("id"))
 
-	plan, err := t.svc.GetByID(id)
-	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
-		return
-	}
-	if plan.UserID != model.UserID(uid) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
+	if _, ok := accessiblePlan(c, t.access, id, service.AccessOwner); !ok {
 		return
 	}
 
@@ -184,9 +217,23 @@ func (t *TrainingPlanController) deletePlan(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"deleted": true})
 }
 
+// getByUserID lists the current user's plans, or with ?userId= those of an
+// athlete they coach.
 func (t *TrainingPlanController) getByUserID(c *gin.Context) {
-	uid := currentUserID(c)
-	plans, err := t.svc.GetByUserID(model.UserID(uid))
+	uid := model.UserID(currentUserID(c))
+	if owner := model.UserID(c.Query("userId")); owner != "" && owner != uid {
+		ok, err := t.access.CanAccessUser(uid, owner)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check access"})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		uid = owner
+	}
+	plans, err := t.svc.GetByUserID(uid)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -203,6 +250,20 @@ func (t *TrainingPlanController) getByUserID(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"plans": summaries})
 }
 
+// getEdits lists the changes coaches have made to the plan.
+func (t *TrainingPlanController) getEdits(c *gin.Context) {
+	id := model.TrainingPlanID(c.Param("id"))
+	if _, ok := accessiblePlan(c, t.access, id, service.AccessView); !ok {
+		return
+	}
+	edits, err := t.access.Edits(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get edits"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"edits": edits})
+}
+
 type generatePlanInput struct {
 	Name          string  `json:"name" binding:"required"`
 	EndDate       string  `json:"endDate" binding:"required"`
@@ -210,23 +271,14 @@ type generatePlanInput struct {
 	BaseKmPerWeek float64 `json:"baseKmPerWeek" binding:"required"`
 	RunsPerWeek   int     `json:"runsPerWeek" binding:"required"`
 	RaceGoal      string  `json:"raceGoal" binding:"required"`
+	Strategy      string  `json:"strategy"` // "ai" (default) or "rules"
 }
 
 func (t *TrainingPlanController) postActivate(c *gin.Context) {
 	uid := model.UserID(currentUserID(c))
 	id := model.TrainingPlanID(c.Param("id"))
 
-	plan, err := t.svc.GetByID(id)
-	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
-		return
-	}
-	if plan.UserID != uid {
-		c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
+	if _, ok := accessiblePlan(c, t.access, id, service.AccessOwner); !ok {
 		return
 	}
 
@@ -269,22 +321,27 @@ func (t *TrainingPlanController) postGenerate(c *gin.Context) {
 		BaseKmPerWeek: req.BaseKmPerWeek,
 		RunsPerWeek:   req.RunsPerWeek,
 		RaceGoal:      req.RaceGoal,
+		Strategy:      req.Strategy,
 	}
 
-	plan, workouts, err := t.generate.Generate(c.Request.Context(), model.UserID(uid), input)
+	job, err := t.jobs.Start(model.UserID(uid), input)
 	if err != nil {
+		var quotaErr *service.QuotaError
 		switch {
+		case errors.As(err, &quotaErr):
+			writeQuotaExceeded(c, quotaErr)
 		case errors.Is(err, service.ErrAINotConfigured):
-			c.JSON(http.StatusBadRequest, gin.H{"error": "AI generation is not configured on the server"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "AI generation is not configured on the server, use strategy \"rules\" instead"})
 		case errors.Is(err, service.ErrInvalidInput):
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		case errors.Is(err, service.ErrAIGeneration):
-			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate plan"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start plan generation"})
 		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"plan": plan, "workouts": workouts})
+	// Generation can take longer than proxies wait, so the plan is built in
+	// the background; follow the job until it has a planId.
+	c.Header("Location", "/api/generate-jobs/"+string(job.ID))
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
 }
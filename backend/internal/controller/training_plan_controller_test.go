@@ -39,8 +39,8 @@ func setupPlansTestRouter(t *testing.T) (*gin.Engine, *service.AuthService, *ser
 	r.Use(sessions.Sessions("rp.sid", storeCookie))
 
 	api := r.Group("/api")
-	RegisterAuthRoutes(api, authSvc)
-	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, nil, nil)
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil), nil, nil, nil, nil)
 
 	return r, authSvc, planSvc, workoutSvc
 }
@@ -549,3 +549,50 @@ func TestTrainingPlanController_GetByUserID_WithKm(t *testing.T) {
 		}
 	})
 }
+
+func TestTrainingPlanController_Lint(t *testing.T) {
+	r, authSvc, planSvc, workoutSvc := setupPlansTestRouter(t)
+	u, _ := authSvc.Register("plans@example.com", "password123")
+	other, _ := authSvc.Register("other-lint@example.com", "password123")
+	plan, _ := planSvc.Create(u.ID, "My Plan", mustParseDate("2025-05-04"), 6)
+	otherPlan, _ := planSvc.Create(other.ID, "Other Plan", mustParseDate("2025-05-04"), 6)
+	cookies := loginAndGetCookies(t, r)
+
+	// A long run in race week and a workout after race day.
+	_, err := workoutSvc.Create(plan.ID, "long_run", mustParseDate("2025-05-03"), "", 15)
+	require.NoError(t, err)
+	_, err = workoutSvc.Create(plan.ID, "easy_run", mustParseDate("2025-05-06"), "", 5)
+	require.NoError(t, err)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("returns findings", func(t *testing.T) {
+		w := get("/api/plans/" + string(plan.ID) + "/lint")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Findings []service.LintFinding `json:"findings"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		rules := map[string]service.LintFinding{}
+		for _, f := range resp.Findings {
+			rules[f.Rule] = f
+		}
+		assert.Contains(t, rules, "missing_long_run")
+		assert.Equal(t, 6, rules["race_week"].Week)
+		assert.Equal(t, "2025-05-03", rules["race_week"].Day)
+		assert.Equal(t, service.LintError, rules["outside_range"].Severity)
+	})
+
+	t.Run("hides other users' plans", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get("/api/plans/"+string(otherPlan.ID)+"/lint").Code)
+	})
+}
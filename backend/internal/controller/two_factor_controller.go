@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/service"
+)
+
+type TwoFactorController struct {
+	twoFactor *service.TwoFactorService
+}
+
+// RegisterTwoFactorRoutes adds enrolling in and disabling TOTP two-factor
+// authentication. API tokens cannot change it.
+func RegisterTwoFactorRoutes(rg *gin.RouterGroup, twoFactor *service.TwoFactorService) {
+	tc := &TwoFactorController{twoFactor: twoFactor}
+	group := rg.Group("/auth/2fa")
+	group.Use(requireAuth, requireSession)
+	{
+		group.GET("", tc.getStatus)
+		group.POST("/setup", tc.postSetup)
+		group.POST("/confirm", tc.postConfirm)
+		group.POST("/disable", tc.postDisable)
+	}
+}
+
+func (tc *TwoFactorController) getStatus(c *gin.Context) {
+	status, err := tc.twoFactor.Status(model.UserID(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get two-factor status"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (tc *TwoFactorController) postSetup(c *gin.Context) {
+	enrolment, err := tc.twoFactor.Begin(model.UserID(currentUserID(c)))
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrolment)
+}
+
+func (tc *TwoFactorController) postConfirm(c *gin.Context) {
+	var req twoFactorCodeInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code required"})
+		return
+	}
+	codes, err := tc.twoFactor.Confirm(model.UserID(currentUserID(c)), req.Code)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+func (tc *TwoFactorController) postDisable(c *gin.Context) {
+	var req twoFactorCodeInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code required"})
+		return
+	}
+	if err := tc.twoFactor.Disable(model.UserID(currentUserID(c)), req.Code); err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTwoFactorEnabled), errors.Is(err, service.ErrTwoFactorNotEnabled),
+		errors.Is(err, service.ErrTwoFactorNotStarted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update two-factor authentication"})
+	}
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/kevsommer/runplanner/internal/service"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/kevsommer/runplanner/internal/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTwoFactorTestRouter(t *testing.T) (*gin.Engine, *service.AuthService) {
+	gin.SetMode(gin.TestMode)
+	userStore := mem.NewMemUserStore()
+	authSvc := service.NewAuthService(userStore)
+	twoFactorSvc := service.NewTwoFactorService(userStore, mem.NewMemTwoFactorStore())
+
+	r := gin.New()
+	storeCookie := cookie.NewStore([]byte("test-secret"))
+	r.Use(sessions.Sessions("rp.sid", storeCookie))
+
+	api := r.Group("/api")
+	RegisterAuthRoutes(api, authSvc, nil, twoFactorSvc, nil)
+	RegisterTwoFactorRoutes(api, twoFactorSvc)
+
+	return r, authSvc
+}
+
+// nextCode returns the code of the next time step, which is still
+// accepted but not yet used.
+func nextCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(time.Now())+1)
+	require.NoError(t, err)
+	return code
+}
+
+func TestTwoFactorController(t *testing.T) {
+	r, authSvc := setupTwoFactorTestRouter(t)
+	_, err := authSvc.Register("2fa@example.com", "password123")
+	require.NoError(t, err)
+	cookies := loginForGenerate(t, r, "2fa@example.com", "password123")
+	credentials := map[string]interface{}{"email": "2fa@example.com", "password": "password123"}
+
+	t.Run("starts disabled", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodGet, "/api/auth/2fa")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"enabled": false, "recoveryCodesLeft": 0}`, w.Body.String())
+	})
+
+	var secret string
+	var recoveryCodes []string
+	t.Run("enrols with a confirmation code", func(t *testing.T) {
+		w := coachRequest(r, cookies, http.MethodPost, "/api/auth/2fa/setup")
+		require.Equal(t, http.StatusOK, w.Code)
+		var enrolment service.TwoFactorEnrolment
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &enrolment))
+		assert.Contains(t, enrolment.URI, "otpauth://totp/")
+		secret = enrolment.Secret
+
+		w = postReplan(r, cookies, "/api/auth/2fa/confirm", map[string]interface{}{"code": "000000"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		code, err := totp.Code(secret, totp.Step(time.Now()))
+		require.NoError(t, err)
+		w = postReplan(r, cookies, "/api/auth/2fa/confirm", map[string]interface{}{"code": code})
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			RecoveryCodes []string `json:"recoveryCodes"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.RecoveryCodes, 10)
+		recoveryCodes = resp.RecoveryCodes
+
+		w = coachRequest(r, cookies, http.MethodPost, "/api/auth/2fa/setup")
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("password alone does not log in", func(t *testing.T) {
+		w := postReplan(r, nil, "/api/auth/login", credentials)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"twoFactorRequired": true}`, w.Body.String())
+
+		me := coachRequest(r, w.Result().Cookies(), http.MethodGet, "/api/auth/me")
+		assert.Equal(t, http.StatusUnauthorized, me.Code)
+	})
+
+	t.Run("code step finishes the login", func(t *testing.T) {
+		w := postReplan(r, nil, "/api/auth/login", credentials)
+		pending := w.Result().Cookies()
+
+		w = postReplan(r, pending, "/api/auth/login/2fa", map[string]interface{}{"code": "000000"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = postReplan(r, pending, "/api/auth/login/2fa", map[string]interface{}{"code": nextCode(t, secret)})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "2fa@example.com")
+
+		me := coachRequest(r, w.Result().Cookies(), http.MethodGet, "/api/auth/me")
+		assert.Equal(t, http.StatusOK, me.Code)
+	})
+
+	t.Run("recovery code finishes the login", func(t *testing.T) {
+		w := postReplan(r, nil, "/api/auth/login", credentials)
+		w = postReplan(r, w.Result().Cookies(), "/api/auth/login/2fa", map[string]interface{}{"code": recoveryCodes[0]})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		status := coachRequest(r, cookies, http.MethodGet, "/api/auth/2fa")
+		assert.Contains(t, status.Body.String(), `"recoveryCodesLeft":9`)
+	})
+
+	t.Run("code step needs a password login first", func(t *testing.T) {
+		w := postReplan(r, nil, "/api/auth/login/2fa", map[string]interface{}{"code": recoveryCodes[1]})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("disables with a code", func(t *testing.T) {
+		w := postReplan(r, cookies, "/api/auth/2fa/disable", map[string]interface{}{"code": "000000"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = postReplan(r, cookies, "/api/auth/2fa/disable", map[string]interface{}{"code": recoveryCodes[1]})
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		w = postReplan(r, nil, "/api/auth/login", credentials)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"user"`)
+	})
+
+	t.Run("unauthenticated returns 401", func(t *testing.T) {
+		w := coachRequest(r, nil, http.MethodGet, "/api/auth/2fa")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
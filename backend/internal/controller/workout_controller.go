@@ -1,25 +1,29 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/kevsommer/runplanner/internal/model"
 	"github.com/kevsommer/runplanner/internal/service"
-	"github.com/kevsommer/runplanner/internal/store"
 )
 
 type WorkoutController struct {
 	workouts *service.WorkoutService
-	plans    *service.TrainingPlanService
+	access   *service.AccessService
 }
 
-func RegisterWorkoutRoutes(rg *gin.RouterGroup, workouts *service.WorkoutService, plans *service.TrainingPlanService) {
+// RegisterWorkoutRoutes adds the workout endpoints. Coaches may use them on
+// their athletes' plans; their changes are recorded.
+func RegisterWorkoutRoutes(rg *gin.RouterGroup, workouts *service.WorkoutService, access *service.AccessService) {
 	wc := &WorkoutController{
 		workouts: workouts,
-		plans:    plans,
+		access:   access,
 	}
 
 	ws := rg.Group("/workouts")
@@ -29,6 +33,7 @@ func RegisterWorkoutRoutes(rg *gin.RouterGroup, workouts *service.WorkoutService
 		ws.GET("/:id", wc.getByID)
 		ws.PUT("/:id", wc.update)
 		ws.DELETE("/:id", wc.delete)
+		ws.GET("/:id/export.fit", wc.getExportFIT)
 	}
 
 	plansGroup := rg.Group("/plans")
@@ -36,20 +41,20 @@ func RegisterWorkoutRoutes(rg *gin.RouterGroup, workouts *service.WorkoutService
 	{
 		plansGroup.GET("/:id/workouts", wc.getByPlanID)
 		plansGroup.POST("/:id/workouts/bulk", wc.postBulkCreate)
+		plansGroup.GET("/:id/weeks/:week/export.zip", wc.getWeekExportZip)
 	}
 }
 
 type createWorkoutInput struct {
-	PlanID      string  `json:"planId" binding:"required"`
-	RunType     string  `json:"runType" binding:"required"`
-	Day         string  `json:"day" binding:"required"` // ISO date YYYY-MM-DD
-	Description string  `json:"description"`
-	Distance    float64 `json:"distance"`
+	PlanID      string              `json:"planId" binding:"required"`
+	RunType     string              `json:"runType" binding:"required"`
+	Day         string              `json:"day" binding:"required"` // ISO date YYYY-MM-DD
+	Description string              `json:"description"`
+	Distance    float64             `json:"distance"`
+	Steps       []model.WorkoutStep `json:"steps"`
 }
 
 func (w *WorkoutController) postCreate(c *gin.Context) {
-	uid := currentUserID(c)
-
 	var req createWorkoutInput
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "planId, runType, day, description and distance are required"})
@@ -62,21 +67,12 @@ func (w *WorkoutController) postCreate(c *gin.Context) {
 		return
 	}
 
-	plan, err := w.plans.GetByID(model.TrainingPlanID(req.PlanID))
-	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
-		return
-	}
-	if plan.UserID != model.UserID(uid) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
+	plan, ok := accessiblePlan(c, w.access, model.TrainingPlanID(req.PlanID), service.AccessEdit)
+	if !ok {
 		return
 	}
 
-	workout, err := w.workouts.Create(plan.ID, req.RunType, day, req.Description, req.Distance)
+	workout, err := w.workouts.CreateWithSteps(plan.ID, req.RunType, day, req.Description, req.Distance, req.Steps)
 	if err != nil {
 		switch err {
 		case service.ErrInvalidDistance:
@@ -93,35 +89,18 @@ func (w *WorkoutController) postCreate(c *gin.Context) {
 			return
 		}
 	}
+	if !recordEdit(c, w.access, plan, &workout.ID, service.EditWorkoutCreated) {
+		return
+	}
 
 	c.JSON(http.StatusCreated, gin.H{"workout": workout})
 }
 
 func (w *WorkoutController) getByID(c *gin.Context) {
-	uid := currentUserID(c)
 	id := model.WorkoutID(c.Param("id"))
 
-	workout, err := w.workouts.GetByID(id)
-	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "workout not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get workout"})
-		return
-	}
-
-	plan, err := w.plans.GetByID(workout.PlanID)
-	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "workout not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
-		return
-	}
-	if plan.UserID != model.UserID(uid) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "workout not found"})
+	workout, _, ok := accessibleWorkout(c, w.access, id, service.AccessView)
+	if !ok {
 		return
 	}
 
@@ -129,20 +108,9 @@ func (w *WorkoutController) getByID(c *gin.Context) {
 }
 
 func (w *WorkoutController) getByPlanID(c *gin.Context) {
-	uid := currentUserID(c)
 	planID := model.TrainingPlanID(c.Param("id"))
 
-	plan, err := w.plans.GetByID(planID)
-	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
-		return
-	}
-	if plan.UserID != model.UserID(uid) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
+	if _, ok := accessiblePlan(c, w.access, planID, service.AccessView); !ok {
 		return
 	}
 
@@ -156,11 +124,12 @@ func (w *WorkoutController) getByPlanID(c *gin.Context) {
 
 
 type bulkWorkoutItem struct {
-	RunType     string  `json:"runType" binding:"required"`
-	Week        int     `json:"week" binding:"required"`
-	DayOfWeek   int     `json:"dayOfWeek" binding:"required"`
-	Description string  `json:"description"`
-	Distance    float64 `json:"distance"`
+	RunType     string              `json:"runType" binding:"required"`
+	Week        int                 `json:"week" binding:"required"`
+	DayOfWeek   int                 `json:"dayOfWeek" binding:"required"`
+	Description string              `json:"description"`
+	Distance    float64             `json:"distance"`
+	Steps       []model.WorkoutStep `json:"steps"`
 }
 
 type bulkCreateWorkoutsInput struct {
@@ -168,20 +137,10 @@ type bulkCreateWorkoutsInput struct {
 }
 
 func (w *WorkoutController) postBulkCreate(c *gin.Context) {
-	uid := currentUserID(c)
 	planID := model.TrainingPlanID(c.Param("id"))
 
-	plan, err := w.plans.GetByID(planID)
-	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
-		return
-	}
-	if plan.UserID != model.UserID(uid) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "plan not found"})
+	plan, ok := accessiblePlan(c, w.access, planID, service.AccessEdit)
+	if !ok {
 		return
 	}
 
@@ -199,6 +158,7 @@ func (w *WorkoutController) postBulkCreate(c *gin.Context) {
 			DayOfWeek:   wi.DayOfWeek,
 			Description: wi.Description,
 			Distance:    wi.Distance,
+			Steps:       wi.Steps,
 		}
 	}
 
@@ -211,44 +171,30 @@ func (w *WorkoutController) postBulkCreate(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create workouts"})
 		return
 	}
+	for _, created := range workouts {
+		if !recordEdit(c, w.access, plan, &created.ID, service.EditWorkoutCreated) {
+			return
+		}
+	}
 
 	c.JSON(http.StatusCreated, gin.H{"workouts": workouts})
 }
 
 type updateWorkoutInput struct {
-	RunType     *string  `json:"runType"`
-	Day         *string  `json:"day"`
-	Description *string  `json:"description"`
-	Notes       *string  `json:"notes"`
-	Status      *string  `json:"status"`
-	Distance    *float64 `json:"distance"`
+	RunType     *string              `json:"runType"`
+	Day         *string              `json:"day"`
+	Description *string              `json:"description"`
+	Notes       *string              `json:"notes"`
+	Status      *string              `json:"status"`
+	Distance    *float64             `json:"distance"`
+	Steps       *[]model.WorkoutStep `json:"steps"`
 }
 
 func (w *WorkoutController) update(c *gin.Context) {
-	uid := currentUserID(c)
 	id := model.WorkoutID(c.Param("id"))
 
-	workout, err := w.workouts.GetByID(id)
-	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "workout not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get workout"})
-		return
-	}
-
-	plan, err := w.plans.GetByID(workout.PlanID)
-	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "workout not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
-		return
-	}
-	if plan.UserID != model.UserID(uid) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "workout not found"})
+	workout, plan, ok := accessibleWorkout(c, w.access, id, service.AccessEdit)
+	if !ok {
 		return
 	}
 
@@ -281,8 +227,15 @@ func (w *WorkoutController) update(c *gin.Context) {
 	if req.Distance != nil {
 		workout.Distance = *req.Distance
 	}
+	if req.Steps != nil {
+		workout.Steps = *req.Steps
+	}
 
 	if err := w.workouts.Update(workout); err != nil {
+		if errors.Is(err, service.ErrInvalidSteps) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		switch err {
 		case service.ErrInvalidDistance:
 			c.JSON(http.StatusBadRequest, gin.H{"error": "distance cannot be negative"})
@@ -297,42 +250,87 @@ func (w *WorkoutController) update(c *gin.Context) {
 		}
 		return
 	}
+	if !recordEdit(c, w.access, plan, &workout.ID, service.EditWorkoutUpdated) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"workout": workout})
 }
 
 func (w *WorkoutController) delete(c *gin.Context) {
-	uid := currentUserID(c)
 	id := model.WorkoutID(c.Param("id"))
 
-	workout, err := w.workouts.GetByID(id)
+	_, plan, ok := accessibleWorkout(c, w.access, id, service.AccessEdit)
+	if !ok {
+		return
+	}
+
+	if err := w.workouts.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete workout"})
+		return
+	}
+	if !recordEdit(c, w.access, plan, &id, service.EditWorkoutDeleted) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+func (w *WorkoutController) getExportFIT(c *gin.Context) {
+	id := model.WorkoutID(c.Param("id"))
+
+	workout, _, ok := accessibleWorkout(c, w.access, id, service.AccessView)
+	if !ok {
+		return
+	}
+
+	data, err := service.EncodeWorkoutFIT(workout)
 	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "workout not found"})
+		if err == service.ErrNotExportable {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get workout"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export workout"})
 		return
 	}
 
-	plan, err := w.plans.GetByID(workout.PlanID)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", service.WorkoutFITFilename(workout)))
+	c.Data(http.StatusOK, "application/vnd.ant.fit", data)
+}
+
+func (w *WorkoutController) getWeekExportZip(c *gin.Context) {
+	planID := model.TrainingPlanID(c.Param("id"))
+
+	week, err := strconv.Atoi(c.Param("week"))
 	if err != nil {
-		if err == store.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "workout not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get plan"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "week must be a number"})
 		return
 	}
-	if plan.UserID != model.UserID(uid) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "workout not found"})
+
+	plan, ok := accessiblePlan(c, w.access, planID, service.AccessView)
+	if !ok {
 		return
 	}
 
-	if err := w.workouts.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete workout"})
+	workouts, err := w.workouts.GetByPlanID(planID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get workouts"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"deleted": true})
+	data, err := service.EncodeWeekFITZip(plan, workouts, week)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidWeek:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case service.ErrNothingToExport:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export week"})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"week-%d.zip\"", week))
+	c.Data(http.StatusOK, "application/zip", data)
 }
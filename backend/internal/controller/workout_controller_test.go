@@ -31,9 +31,9 @@ func setupWorkoutsTestRouter(t *testing.T) (*gin.Engine, *service.AuthService, *
 	r.Use(sessions.Sessions("rp.sid", storeCookie))
 
 	api := r.Group("/api")
-	RegisterAuthRoutes(api, authSvc)
-	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, nil, nil)
-	RegisterWorkoutRoutes(api, workoutSvc, planSvc)
+	RegisterAuthRoutes(api, authSvc, nil, nil, nil)
+	RegisterTrainingPlanRoutes(api, planSvc, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil), nil, nil, nil, nil)
+	RegisterWorkoutRoutes(api, workoutSvc, service.NewAccessService(planSvc, workoutSvc, nil, nil))
 
 	return r, authSvc, planSvc, workoutSvc
 }
@@ -718,3 +718,155 @@ func TestWorkoutController_Delete(t *testing.T) {
 	})
 }
 
+
+func TestWorkoutController_Steps(t *testing.T) {
+	r, authSvc, planSvc, _ := setupWorkoutsTestRouter(t)
+	u, _ := authSvc.Register("steps@example.com", "password123")
+	plan, _ := planSvc.Create(u.ID, "My Plan", mustParseDate("2025-05-01"), 8)
+	cookies := loginAndGetWorkoutCookies(t, r, "steps@example.com", "password123")
+
+	send := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, url, bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	steps := []map[string]interface{}{
+		{"type": "warmup", "distance": 2},
+		{"type": "repeat", "repeat": 6, "steps": []map[string]interface{}{
+			{"type": "work", "distance": 0.5, "target": map[string]int{"minPace": 225, "maxPace": 235}},
+			{"type": "recovery", "distance": 0.25},
+		}},
+		{"type": "cooldown", "distance": 2},
+	}
+
+	var workoutID string
+	t.Run("creates workout with steps", func(t *testing.T) {
+		w := send(http.MethodPost, "/api/workouts", map[string]interface{}{
+			"planId":  string(plan.ID),
+			"runType": "intervals",
+			"day":     "2025-04-01",
+			"steps":   steps,
+		})
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 8.5, resp["workout"]["distance"])
+		assert.Equal(t, "2km Warm-up\n6x (500m Work @ 3:45-3:55/km, 250m Recovery)\n2km Cool-down", resp["workout"]["description"])
+		assert.Len(t, resp["workout"]["steps"], 3)
+		workoutID = resp["workout"]["id"].(string)
+	})
+
+	t.Run("rejects invalid steps on create", func(t *testing.T) {
+		w := send(http.MethodPost, "/api/workouts", map[string]interface{}{
+			"planId":  string(plan.ID),
+			"runType": "intervals",
+			"day":     "2025-04-01",
+			"steps":   []map[string]interface{}{{"type": "work"}},
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid workout steps")
+	})
+
+	t.Run("updates steps", func(t *testing.T) {
+		w := send(http.MethodPut, "/api/workouts/"+workoutID, map[string]interface{}{
+			"steps": []map[string]interface{}{{"type": "work", "distance": 10}},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 10.0, resp["workout"]["distance"])
+	})
+
+	t.Run("rejects invalid steps on update", func(t *testing.T) {
+		w := send(http.MethodPut, "/api/workouts/"+workoutID, map[string]interface{}{
+			"steps": []map[string]interface{}{{"type": "repeat", "repeat": 3}},
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("bulk create accepts steps", func(t *testing.T) {
+		w := send(http.MethodPost, "/api/plans/"+string(plan.ID)+"/workouts/bulk", map[string]interface{}{
+			"workouts": []map[string]interface{}{
+				{"runType": "intervals", "week": 2, "dayOfWeek": 2, "steps": steps},
+			},
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp map[string][]map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp["workouts"], 1)
+		assert.Equal(t, 8.5, resp["workouts"][0]["distance"])
+	})
+}
+
+func TestWorkoutController_Export(t *testing.T) {
+	r, authSvc, planSvc, workoutSvc := setupWorkoutsTestRouter(t)
+	u, _ := authSvc.Register("export@example.com", "password123")
+	other, _ := authSvc.Register("other-export@example.com", "password123")
+	plan, _ := planSvc.Create(u.ID, "My Plan", mustParseDate("2025-05-01"), 8)
+	otherPlan, _ := planSvc.Create(other.ID, "Other Plan", mustParseDate("2025-05-01"), 8)
+	cookies := loginAndGetWorkoutCookies(t, r, "export@example.com", "password123")
+
+	tempo, _ := workoutSvc.Create(plan.ID, "tempo_run", plan.StartDate, "4k Easy\n3k Tempo\n3k Easy", 10)
+	strength, _ := workoutSvc.Create(plan.ID, "strength_training", plan.StartDate.AddDate(0, 0, 1), "", 0)
+	otherWorkout, _ := workoutSvc.Create(otherPlan.ID, "easy_run", otherPlan.StartDate, "", 5)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("exports workout as FIT", func(t *testing.T) {
+		w := get("/api/workouts/" + string(tempo.ID) + "/export.fit")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/vnd.ant.fit", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), ".fit")
+		assert.Equal(t, ".FIT", string(w.Body.Bytes()[8:12]))
+	})
+
+	t.Run("rejects strength training", func(t *testing.T) {
+		w := get("/api/workouts/" + string(strength.ID) + "/export.fit")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("hides other users' workouts", func(t *testing.T) {
+		w := get("/api/workouts/" + string(otherWorkout.ID) + "/export.fit")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("exports week as zip", func(t *testing.T) {
+		w := get("/api/plans/" + string(plan.ID) + "/weeks/1/export.zip")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+		assert.Equal(t, "PK", string(w.Body.Bytes()[:2]))
+	})
+
+	t.Run("week without runs", func(t *testing.T) {
+		w := get("/api/plans/" + string(plan.ID) + "/weeks/2/export.zip")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid week", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("/api/plans/"+string(plan.ID)+"/weeks/9/export.zip").Code)
+		assert.Equal(t, http.StatusBadRequest, get("/api/plans/"+string(plan.ID)+"/weeks/x/export.zip").Code)
+	})
+
+	t.Run("hides other users' plans", func(t *testing.T) {
+		w := get("/api/plans/" + string(otherPlan.ID) + "/weeks/1/export.zip")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
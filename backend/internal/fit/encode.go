@@ -0,0 +1,98 @@
+package fit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// protocolVersion 1.0 is understood by every device, and we only write
+// features from that version.
+const (
+	protocolVersion = 0x10
+	profileVersion  = 2132
+)
+
+// Field is a single field value to encode. String fields are written
+// zero-padded to Size bytes.
+type Field struct {
+	Num   uint8
+	Type  BaseType
+	Value int64
+	Str   string
+	Size  int
+}
+
+func (f Field) size() int {
+	if f.Type == String {
+		return f.Size
+	}
+	return f.Type.size()
+}
+
+// Encoder builds a FIT file in memory. All messages use local message type 0;
+// a new definition record is written whenever the field layout changes.
+type Encoder struct {
+	buf        bytes.Buffer
+	lastLayout string
+}
+
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+// Write appends a data message, preceded by a definition record if needed.
+func (e *Encoder) Write(num uint16, fields ...Field) {
+	var layout strings.Builder
+	fmt.Fprintf(&layout, "%d", num)
+	for _, f := range fields {
+		fmt.Fprintf(&layout, ":%d/%d/%d", f.Num, f.Type, f.size())
+	}
+	if layout.String() != e.lastLayout {
+		e.buf.WriteByte(0x40)
+		e.buf.WriteByte(0)
+		e.buf.WriteByte(0) // little endian
+		_ = binary.Write(&e.buf, binary.LittleEndian, num)
+		e.buf.WriteByte(byte(len(fields)))
+		for _, f := range fields {
+			e.buf.Write([]byte{f.Num, byte(f.size()), byte(f.Type)})
+		}
+		e.lastLayout = layout.String()
+	}
+
+	e.buf.WriteByte(0x00)
+	for _, f := range fields {
+		if f.Type == String {
+			b := make([]byte, f.Size)
+			if f.Size > 0 {
+				copy(b[:f.Size-1], f.Str)
+			}
+			e.buf.Write(b)
+			continue
+		}
+		switch f.size() {
+		case 1:
+			e.buf.WriteByte(byte(f.Value))
+		case 2:
+			_ = binary.Write(&e.buf, binary.LittleEndian, uint16(f.Value))
+		case 4:
+			_ = binary.Write(&e.buf, binary.LittleEndian, uint32(f.Value))
+		}
+	}
+}
+
+// Bytes returns the complete file including header and trailing CRC.
+func (e *Encoder) Bytes() []byte {
+	data := e.buf.Bytes()
+	header := make([]byte, 14)
+	header[0] = 14
+	header[1] = protocolVersion
+	binary.LittleEndian.PutUint16(header[2:4], profileVersion)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(data)))
+	copy(header[8:12], ".FIT")
+	binary.LittleEndian.PutUint16(header[12:14], CRC(header[:12]))
+
+	out := append(header, data...)
+	return binary.LittleEndian.AppendUint16(out, CRC(out))
+}
@@ -0,0 +1,281 @@
+// Package fit reads the binary Garmin FIT protocol. It only understands the
+// framing (header, definition and data records, CRC); interpreting message
+// and field numbers is left to the caller.
+package fit
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrInvalidFile = errors.New("invalid fit file")
+
+// Global message numbers from the FIT profile.
+const (
+	MesgFileID  uint16 = 0
+	MesgSession uint16 = 18
+	MesgLap     uint16 = 19
+	MesgRecord  uint16 = 20
+
+	MesgWorkout     uint16 = 26
+	MesgWorkoutStep uint16 = 27
+)
+
+// FieldTimestamp is the field number every message uses for its timestamp.
+const FieldTimestamp uint8 = 253
+
+// epoch is the FIT time origin; timestamps count seconds from here.
+var epoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// Time converts a FIT timestamp to UTC time.
+func Time(ts int64) time.Time {
+	return epoch.Add(time.Duration(ts) * time.Second)
+}
+
+// Message is a decoded data message. Only single-value numeric fields holding
+// a valid (non-sentinel) value are kept.
+type Message struct {
+	Num    uint16
+	Fields map[uint8]int64
+}
+
+func (m Message) Field(num uint8) (int64, bool) {
+	v, ok := m.Fields[num]
+	return v, ok
+}
+
+type fieldDef struct {
+	num      uint8
+	size     int
+	baseType uint8
+}
+
+type definition struct {
+	order   binary.ByteOrder
+	num     uint16
+	fields  []fieldDef
+	devSize int
+}
+
+// Decode reads a complete FIT file, verifies its CRC and returns all data
+// messages in file order.
+func Decode(r io.Reader) ([]Message, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 {
+		return nil, fmt.Errorf("%w: file too short", ErrInvalidFile)
+	}
+	headerSize := int(data[0])
+	if (headerSize != 12 && headerSize != 14) || len(data) < headerSize {
+		return nil, fmt.Errorf("%w: bad header size %d", ErrInvalidFile, headerSize)
+	}
+	if string(data[8:12]) != ".FIT" {
+		return nil, fmt.Errorf("%w: missing .FIT signature", ErrInvalidFile)
+	}
+	end := headerSize + int(binary.LittleEndian.Uint32(data[4:8]))
+	if len(data) < end+2 {
+		return nil, fmt.Errorf("%w: truncated", ErrInvalidFile)
+	}
+	if headerSize == 14 {
+		if crc := binary.LittleEndian.Uint16(data[12:14]); crc != 0 && crc != CRC(data[:12]) {
+			return nil, fmt.Errorf("%w: header crc mismatch", ErrInvalidFile)
+		}
+	}
+	if binary.LittleEndian.Uint16(data[end:end+2]) != CRC(data[:end]) {
+		return nil, fmt.Errorf("%w: crc mismatch", ErrInvalidFile)
+	}
+
+	defs := make(map[uint8]*definition)
+	var msgs []Message
+	var lastTimestamp int64
+	pos := headerSize
+	for pos < end {
+		header := data[pos]
+		pos++
+
+		var local uint8
+		compressed := header&0x80 != 0
+		switch {
+		case compressed:
+			// Compressed timestamp header: the low five bits replace the low
+			// five bits of the last full timestamp, rolling over if needed.
+			local = (header >> 5) & 0x03
+			offset := int64(header & 0x1F)
+			ts := (lastTimestamp &^ 0x1F) | offset
+			if offset < lastTimestamp&0x1F {
+				ts += 0x20
+			}
+			lastTimestamp = ts
+		case header&0x40 != 0:
+			def, n, err := readDefinition(data[pos:end], header&0x20 != 0)
+			if err != nil {
+				return nil, err
+			}
+			defs[header&0x0F] = def
+			pos += n
+			continue
+		default:
+			local = header & 0x0F
+		}
+
+		def, ok := defs[local]
+		if !ok {
+			return nil, fmt.Errorf("%w: data message for undefined local type %d", ErrInvalidFile, local)
+		}
+		msg := Message{Num: def.num, Fields: make(map[uint8]int64, len(def.fields))}
+		for _, f := range def.fields {
+			if pos+f.size > end {
+				return nil, fmt.Errorf("%w: truncated data message", ErrInvalidFile)
+			}
+			if v, ok := decodeValue(data[pos:pos+f.size], f.baseType, def.order); ok {
+				msg.Fields[f.num] = v
+			}
+			pos += f.size
+		}
+		pos += def.devSize
+		if pos > end {
+			return nil, fmt.Errorf("%w: truncated data message", ErrInvalidFile)
+		}
+
+		if ts, ok := msg.Fields[FieldTimestamp]; ok {
+			lastTimestamp = ts
+		} else if compressed {
+			msg.Fields[FieldTimestamp] = lastTimestamp
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+func readDefinition(b []byte, hasDevFields bool) (*definition, int, error) {
+	if len(b) < 5 {
+		return nil, 0, fmt.Errorf("%w: truncated definition", ErrInvalidFile)
+	}
+	def := &definition{order: binary.LittleEndian}
+	if b[1] == 1 {
+		def.order = binary.BigEndian
+	}
+	def.num = def.order.Uint16(b[2:4])
+	n := int(b[4])
+	pos := 5
+	if len(b) < pos+3*n {
+		return nil, 0, fmt.Errorf("%w: truncated definition", ErrInvalidFile)
+	}
+	for i := 0; i < n; i++ {
+		def.fields = append(def.fields, fieldDef{num: b[pos], size: int(b[pos+1]), baseType: b[pos+2]})
+		pos += 3
+	}
+	if hasDevFields {
+		if len(b) < pos+1 {
+			return nil, 0, fmt.Errorf("%w: truncated definition", ErrInvalidFile)
+		}
+		nd := int(b[pos])
+		pos++
+		if len(b) < pos+3*nd {
+			return nil, 0, fmt.Errorf("%w: truncated definition", ErrInvalidFile)
+		}
+		for i := 0; i < nd; i++ {
+			def.devSize += int(b[pos+1])
+			pos += 3
+		}
+	}
+	return def, pos, nil
+}
+
+// BaseType is the FIT base type byte of a field.
+type BaseType uint8
+
+const (
+	Enum    BaseType = 0x00
+	Sint8   BaseType = 0x01
+	Uint8   BaseType = 0x02
+	Sint16  BaseType = 0x83
+	Uint16  BaseType = 0x84
+	Sint32  BaseType = 0x85
+	Uint32  BaseType = 0x86
+	String  BaseType = 0x07
+	Uint8z  BaseType = 0x0A
+	Uint16z BaseType = 0x8B
+	Uint32z BaseType = 0x8C
+	Byte    BaseType = 0x0D
+)
+
+// size returns the width in bytes of a single value of the base type.
+func (t BaseType) size() int {
+	switch t {
+	case Sint16, Uint16, Uint16z:
+		return 2
+	case Sint32, Uint32, Uint32z:
+		return 4
+	}
+	return 1
+}
+
+// decodeValue returns the value of a single integer field, or false for
+// arrays, strings, floats and the protocol's "invalid" sentinel values.
+func decodeValue(b []byte, baseType uint8, order binary.ByteOrder) (int64, bool) {
+	// Older files may omit the endian-ability bit, so compare on the type
+	// number alone.
+	var t BaseType
+	for _, known := range []BaseType{Enum, Sint8, Uint8, Sint16, Uint16, Sint32, Uint32, Uint8z, Uint16z, Uint32z, Byte} {
+		if known&0x1F == BaseType(baseType&0x1F) {
+			t = known
+			break
+		}
+	}
+	if t&0x1F != BaseType(baseType&0x1F) || len(b) != t.size() {
+		return 0, false
+	}
+
+	var v uint64
+	switch len(b) {
+	case 1:
+		v = uint64(b[0])
+	case 2:
+		v = uint64(order.Uint16(b))
+	case 4:
+		v = uint64(order.Uint32(b))
+	}
+	switch t {
+	case Enum, Uint8, Byte:
+		return int64(v), v != 0xFF
+	case Uint16:
+		return int64(v), v != 0xFFFF
+	case Uint32:
+		return int64(v), v != 0xFFFFFFFF
+	case Uint8z, Uint16z, Uint32z:
+		return int64(v), v != 0
+	case Sint8:
+		return int64(int8(v)), v != 0x7F
+	case Sint16:
+		return int64(int16(v)), v != 0x7FFF
+	case Sint32:
+		return int64(int32(v)), v != 0x7FFFFFFF
+	}
+	return 0, false
+}
+
+var crcTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// CRC computes the FIT CRC-16 of data.
+func CRC(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		tmp := crcTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ crcTable[b&0xF]
+
+		tmp = crcTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ crcTable[(b>>4)&0xF]
+	}
+	return crc
+}
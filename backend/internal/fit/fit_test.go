@@ -0,0 +1,106 @@
+package fit
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRC(t *testing.T) {
+	assert.Equal(t, uint16(0), CRC(nil))
+	// A file's CRC over its own bytes plus the appended CRC is zero.
+	data := []byte("runplanner")
+	crc := CRC(data)
+	assert.Equal(t, uint16(0), CRC(append(data, byte(crc), byte(crc>>8))))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	e := NewEncoder()
+	e.Write(MesgFileID,
+		Field{Num: 0, Type: Enum, Value: 4},
+		Field{Num: 8, Type: String, Str: "runplanner", Size: 16},
+	)
+	e.Write(MesgRecord,
+		Field{Num: FieldTimestamp, Type: Uint32, Value: 1000},
+		Field{Num: 0, Type: Sint32, Value: -5},
+		Field{Num: 3, Type: Uint8, Value: 0xFF}, // invalid sentinel
+	)
+	e.Write(MesgRecord,
+		Field{Num: FieldTimestamp, Type: Uint32, Value: 1001},
+		Field{Num: 0, Type: Sint32, Value: 7},
+		Field{Num: 3, Type: Uint8, Value: 150},
+	)
+
+	msgs, err := Decode(bytes.NewReader(e.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+
+	assert.Equal(t, MesgFileID, msgs[0].Num)
+	v, ok := msgs[0].Field(0)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), v)
+	_, ok = msgs[0].Field(8)
+	assert.False(t, ok, "strings are not exposed as numeric fields")
+
+	v, _ = msgs[1].Field(0)
+	assert.Equal(t, int64(-5), v)
+	_, ok = msgs[1].Field(3)
+	assert.False(t, ok)
+
+	v, _ = msgs[2].Field(3)
+	assert.Equal(t, int64(150), v)
+}
+
+func TestDecode_CompressedTimestamp(t *testing.T) {
+	e := NewEncoder()
+	e.Write(21, Field{Num: FieldTimestamp, Type: Uint32, Value: 0x3E})
+	e.Write(MesgRecord, Field{Num: 3, Type: Uint8, Value: 140})
+	raw := e.Bytes()
+
+	// Splice a compressed-timestamp record (local type 0, offset 2) reusing
+	// the record definition above, then fix up the data size and CRCs.
+	data := append([]byte{}, raw[14:len(raw)-2]...)
+	data = append(data, 0x80|0x02, 145)
+	var out bytes.Buffer
+	header := append([]byte{}, raw[:14]...)
+	header[4] = byte(len(data))
+	header[12], header[13] = 0, 0
+	out.Write(header)
+	out.Write(data)
+	crc := CRC(out.Bytes())
+	out.Write([]byte{byte(crc), byte(crc >> 8)})
+
+	msgs, err := Decode(&out)
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	ts, ok := msgs[2].Field(FieldTimestamp)
+	require.True(t, ok)
+	// 0x3E has low bits 0x1E; offset 2 rolls over to the next 32 s window.
+	assert.Equal(t, int64(0x42), ts)
+	hr, _ := msgs[2].Field(3)
+	assert.Equal(t, int64(145), hr)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	t.Run("rejects short input", func(t *testing.T) {
+		_, err := Decode(bytes.NewReader([]byte{1, 2, 3}))
+		assert.ErrorIs(t, err, ErrInvalidFile)
+	})
+
+	t.Run("rejects bad crc", func(t *testing.T) {
+		e := NewEncoder()
+		e.Write(MesgRecord, Field{Num: 3, Type: Uint8, Value: 140})
+		raw := e.Bytes()
+		raw[len(raw)-1] ^= 0xFF
+		_, err := Decode(bytes.NewReader(raw))
+		assert.ErrorIs(t, err, ErrInvalidFile)
+	})
+}
+
+func TestTime(t *testing.T) {
+	assert.Equal(t, time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC), Time(0))
+	assert.Equal(t, time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), Time(86400))
+}
@@ -0,0 +1,47 @@
+// Package mail sends the application's transactional email.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Message is a plain-text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to a logger instead of sending them, for
+// development without a mail server.
+type LogMailer struct {
+	logger *log.Logger
+}
+
+// NewLogMailer logs to logger, or to the standard logger when nil.
+func NewLogMailer(logger *log.Logger) *LogMailer {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &LogMailer{logger: logger}
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	m.logger.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// validHeader rejects values that would inject further headers.
+func validHeader(name, v string) error {
+	if strings.ContainsAny(v, "\r\n") {
+		return fmt.Errorf("mail: invalid %s header", name)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpSink is a minimal SMTP server that accepts every message.
+type smtpSink struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	from     string
+	rcpt     []string
+	auth     string
+	messages []string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &smtpSink{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 sink ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		s.mu.Lock()
+		switch cmd {
+		case "EHLO", "HELO":
+			reply("250-sink")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			parts := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(parts[len(parts)-1])
+			s.auth = string(decoded)
+			reply("235 ok")
+		case "MAIL":
+			s.from = line
+			reply("250 ok")
+		case "RCPT":
+			s.rcpt = append(s.rcpt, line)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data bytes.Buffer
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.messages = append(s.messages, data.String())
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			s.mu.Unlock()
+			return
+		default:
+			reply("250 ok")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	sink := newSMTPSink(t)
+
+	t.Run("delivers a plain-text message", func(t *testing.T) {
+		m := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: sink.port(), From: "runplanner@example.com"})
+		err := m.Send(context.Background(), Message{To: "runner@example.com", Subject: "Reset your password", Body: "Line one\nLine two"})
+		require.NoError(t, err)
+
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		assert.Equal(t, "MAIL FROM:<runplanner@example.com>", sink.from)
+		assert.Equal(t, []string{"RCPT TO:<runner@example.com>"}, sink.rcpt)
+		require.Len(t, sink.messages, 1)
+		assert.Contains(t, sink.messages[0], "Subject: Reset your password\r\n")
+		assert.Contains(t, sink.messages[0], "To: runner@example.com\r\n")
+		assert.Contains(t, sink.messages[0], "\r\n\r\nLine one\r\nLine two")
+		assert.Empty(t, sink.auth)
+	})
+
+	t.Run("authenticates when a username is set", func(t *testing.T) {
+		m := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: sink.port(), From: "a@example.com", Username: "user", Password: "secret"})
+		require.NoError(t, m.Send(context.Background(), Message{To: "b@example.com", Subject: "Hi", Body: "Hello"}))
+
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		assert.Equal(t, "\x00user\x00secret", sink.auth)
+	})
+
+	t.Run("rejects header injection", func(t *testing.T) {
+		m := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: sink.port(), From: "a@example.com"})
+		err := m.Send(context.Background(), Message{To: "b@example.com", Subject: "Hi\r\nBcc: c@example.com", Body: "Hello"})
+		assert.Error(t, err)
+	})
+
+	t.Run("reports unreachable servers", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		port := ln.Addr().(*net.TCPAddr).Port
+		ln.Close()
+
+		m := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: port, From: "a@example.com"})
+		err = m.Send(context.Background(), Message{To: "b@example.com", Subject: "Hi", Body: "Hello"})
+		assert.ErrorContains(t, err, "smtp dial")
+	})
+}
+
+func TestLogMailer_Send(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(log.New(&buf, "", 0))
+	require.NoError(t, m.Send(context.Background(), Message{To: "runner@example.com", Subject: "Reset", Body: "https://example.com/reset"}))
+	assert.Contains(t, buf.String(), "mail to runner@example.com: Reset")
+	assert.Contains(t, buf.String(), "https://example.com/reset")
+}
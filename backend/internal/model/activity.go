@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+type ActivityID string
+
+type Activity struct {
+	ID            ActivityID    `json:"id"`
+	WorkoutID     WorkoutID     `json:"workoutId"`
+	Source        string        `json:"source"` // file format the activity was imported from: "gpx", "tcx" or "fit"
+	StartTime     time.Time     `json:"startTime"`
+	Distance      float64       `json:"distance"`      // in kilometers
+	MovingTime    int           `json:"movingTime"`    // in seconds
+	AvgPace       float64       `json:"avgPace"`       // in seconds per kilometer
+	ElevationGain float64       `json:"elevationGain"` // in meters
+	AvgHeartRate  int           `json:"avgHeartRate"`  // in bpm, 0 if not recorded
+	MaxHeartRate  int           `json:"maxHeartRate"`  // in bpm, 0 if not recorded
+	AvgCadence    int           `json:"avgCadence"`    // in steps per minute, 0 if not recorded
+	Laps          []ActivityLap `json:"laps"`
+	CreatedAt     time.Time     `json:"createdAt"`
+}
+
+type ActivityLap struct {
+	StartTime    time.Time `json:"startTime"`
+	Distance     float64   `json:"distance"` // in kilometers
+	Duration     int       `json:"duration"` // in seconds
+	AvgPace      float64   `json:"avgPace"`  // in seconds per kilometer
+	AvgHeartRate int       `json:"avgHeartRate"`
+	MaxHeartRate int       `json:"maxHeartRate"`
+	AvgCadence   int       `json:"avgCadence"`
+}
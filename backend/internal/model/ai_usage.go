@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type AIUsageID string
+
+// AIUsage records one AI operation of a user, such as a plan generation or
+// a coach reply. Requests counts the completions it took, repair attempts
+// included.
+type AIUsage struct {
+	ID           AIUsageID `json:"id"`
+	UserID       UserID    `json:"userId"`
+	Feature      string    `json:"feature"` // "generate", "replan" or "coach"
+	Requests     int       `json:"requests"`
+	InputTokens  int       `json:"inputTokens"`
+	OutputTokens int       `json:"outputTokens"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+type APITokenID string
+
+// API token scopes. Read allows GET requests, write every other method,
+// and generate the AI features on top of write.
+const (
+	ScopeRead     = "read"
+	ScopeWrite    = "write"
+	ScopeGenerate = "generate"
+)
+
+// APIToken is a personal access token for scripts, sent as
+// "Authorization: Bearer <token>". Only the SHA-256 of the secret is kept;
+// Prefix is its start, so users can tell their tokens apart.
+type APIToken struct {
+	ID         APITokenID `json:"id"`
+	UserID     UserID     `json:"userId"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `json:"-"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
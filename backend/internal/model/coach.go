@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+type CoachThreadID string
+
+type CoachMessageID string
+
+// Coach message roles.
+const (
+	CoachRoleUser      = "user"
+	CoachRoleAssistant = "assistant"
+)
+
+// Coach action states.
+const (
+	ActionProposed  = "proposed"
+	ActionAccepted  = "accepted"
+	ActionDismissed = "dismissed"
+)
+
+// CoachThread is a conversation with the coach about one plan.
+type CoachThread struct {
+	ID        CoachThreadID  `json:"id"`
+	UserID    UserID         `json:"userId"`
+	PlanID    TrainingPlanID `json:"planId"`
+	Title     string         `json:"title"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+type CoachMessage struct {
+	ID       CoachMessageID `json:"id"`
+	ThreadID CoachThreadID  `json:"threadId"`
+	Role     string         `json:"role"` // "user" or "assistant"
+	Content  string         `json:"content"`
+	// Actions are the workout edits proposed in an assistant message.
+	Actions   []CoachAction `json:"actions"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// CoachAction is a workout edit proposed by the coach. Only the fields that
+// are set change when the action is accepted.
+type CoachAction struct {
+	ID          string    `json:"id"`
+	WorkoutID   WorkoutID `json:"workoutId"`
+	Reason      string    `json:"reason"`
+	Day         *string   `json:"day,omitempty"` // YYYY-MM-DD
+	RunType     *string   `json:"runType,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Distance    *float64  `json:"distance,omitempty"` // in kilometers
+	Status      *string   `json:"status,omitempty"`   // "pending" or "skipped"
+	State       string    `json:"state"`
+}
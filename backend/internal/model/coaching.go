@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+type CoachingID string
+
+// Coaching statuses. An invitation is pending until the athlete accepts
+// it; only an active coaching gives the coach access to the athlete's
+// plans.
+const (
+	CoachingPending = "pending"
+	CoachingActive  = "active"
+)
+
+// Coaching lets CoachID view and edit the training plans of AthleteID.
+type Coaching struct {
+	ID         CoachingID `json:"id"`
+	CoachID    UserID     `json:"coachId"`
+	AthleteID  UserID     `json:"athleteId"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	AcceptedAt *time.Time `json:"acceptedAt,omitempty"`
+}
+
+type PlanEditID string
+
+// PlanEdit records a change someone other than the plan's owner made to
+// it, so athletes can see what their coach changed. WorkoutID is set for
+// changes to a single workout.
+type PlanEdit struct {
+	ID        PlanEditID     `json:"id"`
+	PlanID    TrainingPlanID `json:"planId"`
+	WorkoutID *WorkoutID     `json:"workoutId,omitempty"`
+	EditorID  UserID         `json:"editorId"`
+	Action    string         `json:"action"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type EmailVerificationID string
+
+// EmailVerification is a mailed link that confirms a user's address. Like
+// PasswordReset, only the SHA-256 of its token is stored.
+type EmailVerification struct {
+	ID        EmailVerificationID `json:"id"`
+	UserID    UserID              `json:"userId"`
+	TokenHash string              `json:"-"`
+	ExpiresAt time.Time           `json:"expiresAt"`
+	UsedAt    *time.Time          `json:"usedAt,omitempty"`
+	CreatedAt time.Time           `json:"createdAt"`
+}
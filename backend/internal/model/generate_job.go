@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+type GenerateJobID string
+
+// Generation job statuses.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// GenerateJob tracks an asynchronous plan generation.
+type GenerateJob struct {
+	ID     GenerateJobID `json:"id"`
+	UserID UserID        `json:"userId"`
+	Status string        `json:"status"`
+	// Phase is the step the job is in while running: prompting, parsing,
+	// validating or persisting. It keeps the last phase once finished.
+	Phase     string          `json:"phase"`
+	PlanID    *TrainingPlanID `json:"planId,omitempty"` // set once succeeded
+	Attempts  int             `json:"attempts"`
+	Warnings  []LintFinding   `json:"warnings"`
+	Error     string          `json:"error,omitempty"` // set once failed
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// Finished reports whether the job has stopped running.
+func (j *GenerateJob) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
+
+// LintFinding is one coaching-rule violation found in a plan. Week is
+// 1-based and 0 when the finding is not tied to a week; Day is YYYY-MM-DD.
+type LintFinding struct {
+	Rule      string    `json:"rule"`
+	Severity  string    `json:"severity"`
+	Week      int       `json:"week,omitempty"`
+	Day       string    `json:"day,omitempty"`
+	WorkoutID WorkoutID `json:"workoutId,omitempty"`
+	Message   string    `json:"message"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type IdentityID string
+
+// Identity links an account at an OpenID Connect provider to a user.
+// Subject is the provider's stable user ID; Email is what the provider
+// reported when the identity was linked.
+type Identity struct {
+	ID        IdentityID `json:"id"`
+	UserID    UserID     `json:"userId"`
+	Provider  string     `json:"provider"`
+	Subject   string     `json:"subject"`
+	Email     string     `json:"email"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
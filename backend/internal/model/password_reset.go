@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type PasswordResetID string
+
+// PasswordReset is an emailed password reset link. Only the SHA-256 of its
+// token is stored; a reset is valid once, until ExpiresAt.
+type PasswordReset struct {
+	ID        PasswordResetID `json:"id"`
+	UserID    UserID          `json:"userId"`
+	TokenHash string          `json:"-"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+	UsedAt    *time.Time      `json:"usedAt,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type SessionID string
+
+// Session is a login kept on the server. The browser holds a random token
+// whose SHA-256 is TokenHash; ID is a separate handle for listing and
+// revoking sessions. UserID is empty until someone logs in.
+type Session struct {
+	ID        SessionID `json:"id"`
+	UserID    UserID    `json:"-"`
+	TokenHash string    `json:"-"`
+	// Data holds the session values, gob encoded.
+	Data       []byte    `json:"-"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
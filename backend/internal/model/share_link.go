@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type ShareLinkID string
+
+// ShareLink makes a plan readable by anyone who has the link, without an
+// account. The slug is the random part of the URL; a link without
+// ExpiresAt works until it is revoked.
+type ShareLink struct {
+	ID        ShareLinkID    `json:"id"`
+	PlanID    TrainingPlanID `json:"planId"`
+	Slug      string         `json:"slug"`
+	ExpiresAt *time.Time     `json:"expiresAt,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// Expired reports whether the link no longer works at the given time.
+func (l *ShareLink) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
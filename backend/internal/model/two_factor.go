@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// TwoFactor is a user's TOTP enrolment. It is pending until the user
+// confirms a first code; EnabledAt is set from then on.
+type TwoFactor struct {
+	UserID UserID `json:"userId"`
+	// Secret is the base32 TOTP key shared with the authenticator app.
+	Secret    string     `json:"-"`
+	EnabledAt *time.Time `json:"enabledAt,omitempty"`
+	// LastUsedStep is the time step of the last accepted code, so a code
+	// cannot be used twice.
+	LastUsedStep int64 `json:"-"`
+	// RecoveryCodeHashes are the SHA-256 hashes of the unused recovery
+	// codes.
+	RecoveryCodeHashes []string  `json:"-"`
+	CreatedAt          time.Time `json:"createdAt"`
+}
+
+func (t *TwoFactor) Enabled() bool { return t.EnabledAt != nil }
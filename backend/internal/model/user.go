@@ -12,14 +12,21 @@ type User struct {
 	PasswordHash []byte          `json:"-"`
 	CreatedAt    time.Time       `json:"createdAt"`
 	ActivePlanID *TrainingPlanID `json:"activePlanId,omitempty"`
+	// CalendarTokenHash is the SHA-256 of the iCalendar feed token; empty
+	// when the feed is disabled.
+	CalendarTokenHash string `json:"-"`
+	// EmailVerifiedAt is set once the user opened the verification link;
+	// nil while the address is unconfirmed.
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
 }
 
 type PublicUser struct {
-	ID           UserID          `json:"id"`
-	Email        string          `json:"email"`
-	ActivePlanID *TrainingPlanID `json:"activePlanId,omitempty"`
+	ID            UserID          `json:"id"`
+	Email         string          `json:"email"`
+	ActivePlanID  *TrainingPlanID `json:"activePlanId,omitempty"`
+	EmailVerified bool            `json:"emailVerified"`
 }
 
 func (u *User) Public() PublicUser {
-	return PublicUser{ID: u.ID, Email: u.Email, ActivePlanID: u.ActivePlanID}
+	return PublicUser{ID: u.ID, Email: u.Email, ActivePlanID: u.ActivePlanID, EmailVerified: u.EmailVerifiedAt != nil}
 }
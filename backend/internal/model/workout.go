@@ -13,4 +13,25 @@ type Workout struct {
 	Notes       string         `json:"notes"`
 	Status      string         `json:"status"` // "pending", "completed", "skipped"
 	Distance    float64        `json:"distance"` // in kilometers
+	Steps       []WorkoutStep  `json:"steps,omitempty"`
+}
+
+// WorkoutStep is one block of a structured workout. A step is measured by
+// either distance or duration; "repeat" steps instead wrap child steps.
+type WorkoutStep struct {
+	Type     string        `json:"type"`               // "warmup", "work", "recovery", "cooldown", "repeat"
+	Distance float64       `json:"distance,omitempty"` // in kilometers
+	Duration int           `json:"duration,omitempty"` // in seconds
+	Target   *StepTarget   `json:"target,omitempty"`
+	Repeat   int           `json:"repeat,omitempty"` // iterations of a "repeat" step
+	Steps    []WorkoutStep `json:"steps,omitempty"`  // children of a "repeat" step
+}
+
+// StepTarget is an optional intensity range for a step, either as pace or
+// as heart rate.
+type StepTarget struct {
+	MinPace      int `json:"minPace,omitempty"` // fastest pace in seconds per kilometer
+	MaxPace      int `json:"maxPace,omitempty"` // slowest pace in seconds per kilometer
+	MinHeartRate int `json:"minHeartRate,omitempty"`
+	MaxHeartRate int `json:"maxHeartRate,omitempty"`
 }
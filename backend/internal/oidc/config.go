@@ -0,0 +1,63 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// ProviderConfig describes one identity provider. Name appears in the
+// login and callback URLs and is stored with every linked identity, so it
+// must not change once users have signed in.
+type ProviderConfig struct {
+	Name         string `json:"name"`
+	DisplayName  string `json:"displayName"`
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	// Scopes defaults to openid, email and profile; openid is always sent.
+	Scopes []string `json:"scopes"`
+}
+
+var providerNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// LoadConfig reads a JSON array of providers from path.
+func LoadConfig(path string) ([]ProviderConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig decodes and validates a JSON array of providers and fills in
+// the defaults.
+func ParseConfig(data []byte) ([]ProviderConfig, error) {
+	var providers []ProviderConfig
+	if err := json.Unmarshal(data, &providers); err != nil {
+		return nil, fmt.Errorf("parse providers: %w", err)
+	}
+	seen := map[string]bool{}
+	for i := range providers {
+		p := &providers[i]
+		switch {
+		case !providerNameRe.MatchString(p.Name):
+			return nil, fmt.Errorf("provider %d: name must be lowercase letters, digits and dashes", i+1)
+		case seen[p.Name]:
+			return nil, fmt.Errorf("provider %q is listed twice", p.Name)
+		case p.Issuer == "":
+			return nil, fmt.Errorf("provider %q: issuer is required", p.Name)
+		case p.ClientID == "":
+			return nil, fmt.Errorf("provider %q: clientId is required", p.Name)
+		}
+		seen[p.Name] = true
+		if p.DisplayName == "" {
+			p.DisplayName = p.Name
+		}
+		if len(p.Scopes) == 0 {
+			p.Scopes = []string{"openid", "email", "profile"}
+		}
+	}
+	return providers, nil
+}
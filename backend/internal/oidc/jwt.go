@@ -0,0 +1,218 @@
+package oidc
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// clockSkew is how far the provider's clock may be off from ours.
+const clockSkew = time.Minute
+
+// Claims is what a verified ID token says about the user.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type idTokenClaims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	Expiry          float64  `json:"exp"`
+	IssuedAt        float64  `json:"iat"`
+	Nonce           string   `json:"nonce"`
+	Email           string   `json:"email"`
+	EmailVerified   flexBool `json:"email_verified"`
+	Name            string   `json:"name"`
+}
+
+// audience is a JWT "aud", which is either a string or an array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// flexBool accepts "true" as well as true; some providers send
+// email_verified as a string.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch string(bytes.Trim(data, `"`)) {
+	case "true":
+		*b = true
+	case "false", "null", "":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+// parseJWT splits a compact JWS and decodes its header and payload. The
+// signature is not checked.
+func parseJWT(raw string) (header jwtHeader, payload []byte, signingInput string, sig []byte, err error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return header, nil, "", nil, fmt.Errorf("%w: malformed token", ErrInvalidIDToken)
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return header, nil, "", nil, fmt.Errorf("%w: malformed header", ErrInvalidIDToken)
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return header, nil, "", nil, fmt.Errorf("%w: malformed header", ErrInvalidIDToken)
+	}
+	payload, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return header, nil, "", nil, fmt.Errorf("%w: malformed payload", ErrInvalidIDToken)
+	}
+	sig, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return header, nil, "", nil, fmt.Errorf("%w: malformed signature", ErrInvalidIDToken)
+	}
+	return header, payload, parts[0] + "." + parts[1], sig, nil
+}
+
+// verifySignature checks an RS256 or ES256 signature, the two algorithms
+// providers use by default.
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: key does not match algorithm %s", ErrInvalidIDToken, alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: key does not match algorithm %s", ErrInvalidIDToken, alg)
+		}
+		if len(sig) != 64 {
+			return fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidIDToken, alg)
+	}
+	return nil
+}
+
+// checkClaims validates the registered claims of a signed ID token.
+func checkClaims(c *idTokenClaims, issuer, clientID, nonce string, now time.Time) error {
+	switch {
+	case c.Issuer != issuer:
+		return fmt.Errorf("%w: issuer %q does not match %q", ErrInvalidIDToken, c.Issuer, issuer)
+	case !c.Audience.contains(clientID):
+		return fmt.Errorf("%w: token was issued for another client", ErrInvalidIDToken)
+	case len(c.Audience) > 1 && c.AuthorizedParty != clientID:
+		return fmt.Errorf("%w: token was issued for another client", ErrInvalidIDToken)
+	case c.Subject == "":
+		return fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	case c.Nonce != nonce:
+		return fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if exp := time.Unix(int64(c.Expiry), 0); !now.Before(exp.Add(clockSkew)) {
+		return fmt.Errorf("%w: token expired", ErrInvalidIDToken)
+	}
+	if iat := time.Unix(int64(c.IssuedAt), 0); iat.After(now.Add(clockSkew)) {
+		return fmt.Errorf("%w: token issued in the future", ErrInvalidIDToken)
+	}
+	return nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the signing keys of a JWK set by key ID. Keys of other
+// types or uses are skipped.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse JWKS: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				return nil, fmt.Errorf("parse JWKS: bad RSA key %q", k.Kid)
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if k.Crv != "P-256" {
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("parse JWKS: bad EC key %q", k.Kid)
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
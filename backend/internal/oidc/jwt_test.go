@@ -0,0 +1,89 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signingInput(t *testing.T, alg string) string {
+	t.Helper()
+	header, err := json.Marshal(jwtHeader{Alg: alg, Kid: "k1"})
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1"}`))
+}
+
+func TestVerifySignature(t *testing.T) {
+	t.Run("RS256", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		input := signingInput(t, "RS256")
+		digest := sha256.Sum256([]byte(input))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+
+		assert.NoError(t, verifySignature("RS256", &key.PublicKey, input, sig))
+		assert.ErrorIs(t, verifySignature("RS256", &other.PublicKey, input, sig), ErrInvalidIDToken)
+		assert.ErrorIs(t, verifySignature("RS256", &key.PublicKey, input+"x", sig), ErrInvalidIDToken)
+	})
+
+	t.Run("ES256", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		input := signingInput(t, "ES256")
+		digest := sha256.Sum256([]byte(input))
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+
+		assert.NoError(t, verifySignature("ES256", &key.PublicKey, input, sig))
+		assert.ErrorIs(t, verifySignature("RS256", &key.PublicKey, input, sig), ErrInvalidIDToken)
+	})
+
+	t.Run("unsigned tokens are rejected", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		assert.ErrorIs(t, verifySignature("none", &key.PublicKey, signingInput(t, "none"), nil), ErrInvalidIDToken)
+	})
+}
+
+func TestParseJWKS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	enc := base64.RawURLEncoding.EncodeToString
+	data, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": enc(key.X.Bytes()), "y": enc(key.Y.Bytes())},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		{"kty": "oct", "kid": "sym"},
+	}})
+	require.NoError(t, err)
+
+	keys, err := parseJWKS(data)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, key.PublicKey.Equal(keys["ec"]))
+}
+
+func TestIDTokenClaims_Decoding(t *testing.T) {
+	var c idTokenClaims
+	require.NoError(t, json.Unmarshal([]byte(`{"aud": "rp", "email_verified": "true"}`), &c))
+	assert.Equal(t, audience{"rp"}, c.Audience)
+	assert.True(t, bool(c.EmailVerified))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"aud": ["rp", "other"], "email_verified": false}`), &c))
+	assert.Equal(t, audience{"rp", "other"}, c.Audience)
+	assert.False(t, bool(c.EmailVerified))
+}
@@ -0,0 +1,287 @@
+// Package oidc signs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval limits how often an unknown key ID makes us fetch
+// the provider's keys again.
+const jwksRefreshInterval = time.Minute
+
+// Provider talks to one identity provider. Discovery and the signing keys
+// are fetched on first use and cached.
+type Provider struct {
+	cfg        ProviderConfig
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu          sync.Mutex
+	meta        *discovery
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+type discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// NewProvider returns a provider for cfg. A nil httpClient uses one with a
+// 10 second timeout.
+func NewProvider(cfg ProviderConfig, httpClient *http.Client) *Provider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{cfg: cfg, httpClient: httpClient, now: time.Now}
+}
+
+func (p *Provider) Name() string        { return p.cfg.Name }
+func (p *Provider) DisplayName() string { return p.cfg.DisplayName }
+
+// AuthCodeURL returns where to send the browser to sign in. state and
+// nonce are echoed back; verifier is the PKCE code verifier, of which only
+// the S256 challenge is sent.
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {p.scope()},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the claims of the
+// verified ID token.
+func (p *Provider) Exchange(ctx context.Context, redirectURI, code, verifier, nonce string) (*Claims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+	basicAuth := p.cfg.ClientSecret != "" && supportsBasicAuth(meta.TokenAuthMethods)
+	if !basicAuth {
+		form.Set("client_id", p.cfg.ClientID)
+		if p.cfg.ClientSecret != "" {
+			form.Set("client_secret", p.cfg.ClientSecret)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basicAuth {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var tokenResp struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.getJSON(req, &tokenResp); err != nil && tokenResp.Error == "" {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	if tokenResp.Error != "" {
+		return nil, fmt.Errorf("token request: %s %s", tokenResp.Error, tokenResp.ErrorDescription)
+	}
+	if tokenResp.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+	return p.verifyIDToken(ctx, tokenResp.IDToken, nonce)
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	header, payload, signingInput, sig, err := parseJWT(raw)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, signingInput, sig); err != nil {
+		return nil, err
+	}
+	var claims idTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidIDToken)
+	}
+	if err := checkClaims(&claims, p.cfg.Issuer, p.cfg.ClientID, nonce, p.now()); err != nil {
+		return nil, err
+	}
+	return &Claims{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+func (p *Provider) scope() string {
+	scopes := []string{"openid"}
+	for _, s := range p.cfg.Scopes {
+		if s != "openid" {
+			scopes = append(scopes, s)
+		}
+	}
+	return strings.Join(scopes, " ")
+}
+
+// discover fetches the provider metadata from the issuer's well-known
+// URL. Failures are not cached, so a provider that was down is retried on
+// the next sign-in.
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	meta := p.meta
+	p.mu.Unlock()
+	if meta != nil {
+		return meta, nil
+	}
+
+	wellKnown := strings.TrimRight(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create discovery request: %w", err)
+	}
+	meta = &discovery{}
+	if err := p.getJSON(req, meta); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery: issuer %q does not match configured %q", meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery: provider metadata is incomplete")
+	}
+
+	p.mu.Lock()
+	p.meta = meta
+	p.mu.Unlock()
+	return meta, nil
+}
+
+// key returns the signing key with the given ID, fetching the key set
+// again when the ID is unknown, since providers rotate keys.
+func (p *Provider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := lookupKey(p.keys, kid)
+	stale := p.now().Sub(p.keysFetched) >= jwksRefreshInterval
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+	}
+
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create JWKS request: %w", err)
+	}
+	var raw json.RawMessage
+	if err := p.getJSON(req, &raw); err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	keys, err := parseJWKS(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetched = p.now()
+	p.mu.Unlock()
+
+	if key, ok := lookupKey(keys, kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+}
+
+// lookupKey finds a key by ID. Tokens without a key ID are accepted when
+// the provider has a single key.
+func lookupKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, true
+		}
+	}
+	k, ok := keys[kid]
+	return k, ok
+}
+
+// getJSON sends req and decodes the JSON body into v. Error responses are
+// decoded too, so callers can read an OAuth error; err is still set.
+func (p *Provider) getJSON(req *http.Request, v interface{}) error {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	decodeErr := json.Unmarshal(body, v)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("decode response: %w", decodeErr)
+	}
+	return nil
+}
+
+// supportsBasicAuth reports whether the token endpoint takes client
+// credentials as HTTP basic auth, which is the default when the provider
+// does not say.
+func supportsBasicAuth(methods []string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, m := range methods {
+		if m == "client_secret_basic" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kevsommer/runplanner/internal/oidc"
+	"github.com/kevsommer/runplanner/internal/oidc/oidctest"
+)
+
+const redirectURI = "http://app.test/api/auth/oidc/test/callback"
+
+// signIn runs the browser part of the flow and returns the code.
+func signIn(t *testing.T, idp *oidctest.Server, p *oidc.Provider, state, nonce, verifier string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), redirectURI, state, nonce, verifier)
+	require.NoError(t, err)
+	back, err := idp.Authorize(authURL)
+	require.NoError(t, err)
+	require.Equal(t, state, back.Query().Get("state"))
+	require.Empty(t, back.Query().Get("error"))
+	return back.Query().Get("code")
+}
+
+func TestProvider_SignIn(t *testing.T) {
+	idp := oidctest.NewServer("runplanner", "s3cret")
+	defer idp.Close()
+	p := oidc.NewProvider(idp.Config("test"), nil)
+
+	t.Run("auth URL carries the PKCE challenge", func(t *testing.T) {
+		authURL, err := p.AuthCodeURL(context.Background(), redirectURI, "st", "no", "verifier")
+		require.NoError(t, err)
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+		q := u.Query()
+		assert.Equal(t, idp.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+		assert.Equal(t, "code", q.Get("response_type"))
+		assert.Equal(t, "openid email profile", q.Get("scope"))
+		assert.Equal(t, oidc.CodeChallenge("verifier"), q.Get("code_challenge"))
+		assert.Equal(t, "S256", q.Get("code_challenge_method"))
+		assert.Equal(t, "no", q.Get("nonce"))
+	})
+
+	t.Run("exchanges the code for verified claims", func(t *testing.T) {
+		verifier := oidc.RandomString()
+		code := signIn(t, idp, p, "state", "nonce", verifier)
+
+		claims, err := p.Exchange(context.Background(), redirectURI, code, verifier, "nonce")
+		require.NoError(t, err)
+		assert.Equal(t, &oidc.Claims{Subject: "user-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"}, claims)
+	})
+
+	t.Run("wrong code verifier is rejected by the provider", func(t *testing.T) {
+		code := signIn(t, idp, p, "state", "nonce", oidc.RandomString())
+		_, err := p.Exchange(context.Background(), redirectURI, code, oidc.RandomString(), "nonce")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid_grant")
+	})
+
+	t.Run("code can be used once", func(t *testing.T) {
+		verifier := oidc.RandomString()
+		code := signIn(t, idp, p, "state", "nonce", verifier)
+		_, err := p.Exchange(context.Background(), redirectURI, code, verifier, "nonce")
+		require.NoError(t, err)
+		_, err = p.Exchange(context.Background(), redirectURI, code, verifier, "nonce")
+		assert.Error(t, err)
+	})
+
+	t.Run("nonce mismatch", func(t *testing.T) {
+		verifier := oidc.RandomString()
+		code := signIn(t, idp, p, "state", "nonce", verifier)
+		_, err := p.Exchange(context.Background(), redirectURI, code, verifier, "other")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+}
+
+func TestProvider_RejectsBadIDTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		hook func(claims map[string]interface{})
+	}{
+		{"other audience", func(c map[string]interface{}) { c["aud"] = "someone-else" }},
+		{"several audiences without azp", func(c map[string]interface{}) { c["aud"] = []string{"runplanner", "other"} }},
+		{"other issuer", func(c map[string]interface{}) { c["iss"] = "https://evil.test" }},
+		{"expired", func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"no subject", func(c map[string]interface{}) { delete(c, "sub") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := oidctest.NewServer("runplanner", "s3cret")
+			defer idp.Close()
+			idp.ClaimsHook = tt.hook
+			p := oidc.NewProvider(idp.Config("test"), nil)
+
+			verifier := oidc.RandomString()
+			code := signIn(t, idp, p, "state", "nonce", verifier)
+			_, err := p.Exchange(context.Background(), redirectURI, code, verifier, "nonce")
+			assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		})
+	}
+}
+
+func TestProvider_Discovery(t *testing.T) {
+	idp := oidctest.NewServer("runplanner", "")
+	defer idp.Close()
+
+	t.Run("issuer must match", func(t *testing.T) {
+		cfg := idp.Config("test")
+		cfg.Issuer = idp.URL + "/"
+		_, err := oidc.NewProvider(cfg, nil).AuthCodeURL(context.Background(), redirectURI, "s", "n", "v")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not match")
+	})
+
+	t.Run("unreachable provider", func(t *testing.T) {
+		cfg := idp.Config("test")
+		cfg.Issuer = "http://127.0.0.1:1"
+		_, err := oidc.NewProvider(cfg, nil).AuthCodeURL(context.Background(), redirectURI, "s", "n", "v")
+		assert.Error(t, err)
+	})
+
+	t.Run("public client sends its ID in the form", func(t *testing.T) {
+		p := oidc.NewProvider(idp.Config("test"), nil)
+		verifier := oidc.RandomString()
+		code := signIn(t, idp, p, "state", "nonce", verifier)
+		_, err := p.Exchange(context.Background(), redirectURI, code, verifier, "nonce")
+		assert.NoError(t, err)
+	})
+}
+
+func TestParseConfig(t *testing.T) {
+	providers, err := oidc.ParseConfig([]byte(`[{"name": "corp", "issuer": "https://id.example.com", "clientId": "rp"}]`))
+	require.NoError(t, err)
+	require.Len(t, providers, 1)
+	assert.Equal(t, "corp", providers[0].DisplayName)
+	assert.Equal(t, []string{"openid", "email", "profile"}, providers[0].Scopes)
+
+	for _, bad := range []string{
+		`[{"name": "Corp!", "issuer": "https://id.example.com", "clientId": "rp"}]`,
+		`[{"name": "corp", "clientId": "rp"}]`,
+		`[{"name": "corp", "issuer": "https://id.example.com"}]`,
+		`[{"name": "corp", "issuer": "a", "clientId": "rp"}, {"name": "corp", "issuer": "b", "clientId": "rp"}]`,
+		`{"name": "corp"}`,
+	} {
+		_, err := oidc.ParseConfig([]byte(bad))
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,178 @@
+// Package ratelimit throttles requests with in-memory token buckets and
+// locks keys out for exponentially longer after repeated failures. Keys
+// are free-form, e.g. "ip:203.0.113.7" or "email:runner@example.com".
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate allows Burst requests at once, refilling at Burst per Per.
+type Rate struct {
+	Burst int
+	Per   time.Duration
+}
+
+// ParseRate reads a rate written as "<burst>/<duration>", e.g. "10/1m".
+func ParseRate(s string) (Rate, error) {
+	n, d, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("rate %q must look like 10/1m", s)
+	}
+	burst, err := strconv.Atoi(strings.TrimSpace(n))
+	if err != nil || burst < 1 {
+		return Rate{}, fmt.Errorf("rate %q: burst must be a positive integer", s)
+	}
+	per, err := time.ParseDuration(strings.TrimSpace(d))
+	if err != nil || per <= 0 {
+		return Rate{}, fmt.Errorf("rate %q: period must be a positive duration", s)
+	}
+	return Rate{Burst: burst, Per: per}, nil
+}
+
+// Lockout locks a key once it has failed After times in a row, for Base
+// and then twice as long with every further failure, up to Max. A key's
+// failures are forgotten after Max without a new one. After 0 disables
+// lockouts.
+type Lockout struct {
+	After int
+	Base  time.Duration
+	Max   time.Duration
+}
+
+type Config struct {
+	Rate    Rate
+	Lockout Lockout
+}
+
+type entry struct {
+	tokens      float64
+	refilled    time.Time
+	failures    int
+	failedAt    time.Time
+	lockedUntil time.Time
+}
+
+// sweepEvery is how often idle keys are dropped, so that the limiter does
+// not grow with every address that ever made a request.
+const sweepEvery = time.Minute
+
+// Limiter is safe for concurrent use. Its state lives in memory, so each
+// server process limits on its own and a restart forgives everyone.
+type Limiter struct {
+	mu      sync.Mutex
+	cfg     Config
+	entries map[string]*entry
+	swept   time.Time
+	now     func() time.Time
+}
+
+func New(cfg Config) *Limiter {
+	return &Limiter{cfg: cfg, entries: map[string]*entry{}, now: time.Now}
+}
+
+// Allow takes a token for each key. If any key is locked out or has no
+// token left, nothing is taken and Allow returns false with how long to
+// wait before trying again.
+func (l *Limiter) Allow(keys ...string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	var wait time.Duration
+	for _, k := range keys {
+		e := l.entry(k, now)
+		if now.Before(e.lockedUntil) {
+			wait = max(wait, e.lockedUntil.Sub(now))
+		}
+		if e.tokens < 1 {
+			wait = max(wait, time.Duration((1-e.tokens)*float64(l.interval())))
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+	for _, k := range keys {
+		l.entries[k].tokens--
+	}
+	return true, 0
+}
+
+// Fail records a failed attempt, such as a wrong password, for each key.
+func (l *Limiter) Fail(keys ...string) {
+	lo := l.cfg.Lockout
+	if lo.After <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for _, k := range keys {
+		e := l.entry(k, now)
+		if now.Sub(e.failedAt) > lo.Max {
+			e.failures = 0
+		}
+		e.failures++
+		e.failedAt = now
+		if e.failures >= lo.After {
+			lock := time.Duration(float64(lo.Base) * math.Pow(2, float64(e.failures-lo.After)))
+			if lock > lo.Max || lock <= 0 {
+				lock = lo.Max
+			}
+			e.lockedUntil = now.Add(lock)
+		}
+	}
+}
+
+// Succeed forgets the failures of each key.
+func (l *Limiter) Succeed(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, k := range keys {
+		if e, ok := l.entries[k]; ok {
+			e.failures = 0
+			e.lockedUntil = time.Time{}
+		}
+	}
+}
+
+// interval is the time it takes to refill one token.
+func (l *Limiter) interval() time.Duration {
+	return l.cfg.Rate.Per / time.Duration(l.cfg.Rate.Burst)
+}
+
+// entry returns the key's entry with its bucket refilled up to now.
+func (l *Limiter) entry(key string, now time.Time) *entry {
+	burst := float64(l.cfg.Rate.Burst)
+	e, ok := l.entries[key]
+	if !ok {
+		e = &entry{tokens: burst, refilled: now}
+		l.entries[key] = e
+		return e
+	}
+	e.tokens = math.Min(burst, e.tokens+float64(now.Sub(e.refilled))/float64(l.interval()))
+	e.refilled = now
+	return e
+}
+
+// sweep drops keys whose bucket has refilled and whose failures no longer
+// count.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < sweepEvery {
+		return
+	}
+	l.swept = now
+	for k, e := range l.entries {
+		full := now.Sub(e.refilled) >= l.cfg.Rate.Per
+		forgiven := e.failures == 0 || now.Sub(e.failedAt) > l.cfg.Lockout.Max
+		if full && forgiven && !now.Before(e.lockedUntil) {
+			delete(l.entries, k)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(cfg Config) (*Limiter, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	l := New(cfg)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestParseRate(t *testing.T) {
+	r, err := ParseRate("10/1m")
+	require.NoError(t, err)
+	assert.Equal(t, Rate{Burst: 10, Per: time.Minute}, r)
+
+	for _, bad := range []string{"", "10", "0/1m", "x/1m", "10/soon", "10/-1s"} {
+		_, err := ParseRate(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, now := newTestLimiter(Config{Rate: Rate{Burst: 3, Per: time.Minute}})
+
+	t.Run("allows a burst, then asks to wait for the next token", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			ok, _ := l.Allow("ip:a")
+			require.True(t, ok, "request %d", i)
+		}
+		ok, wait := l.Allow("ip:a")
+		assert.False(t, ok)
+		assert.Equal(t, 20*time.Second, wait)
+	})
+
+	t.Run("keys have separate buckets", func(t *testing.T) {
+		ok, _ := l.Allow("ip:b")
+		assert.True(t, ok)
+	})
+
+	t.Run("refills over time", func(t *testing.T) {
+		*now = now.Add(20 * time.Second)
+		ok, _ := l.Allow("ip:a")
+		assert.True(t, ok)
+		ok, _ = l.Allow("ip:a")
+		assert.False(t, ok)
+	})
+
+	t.Run("takes nothing unless every key has a token", func(t *testing.T) {
+		ok, _ := l.Allow("ip:a", "email:c")
+		assert.False(t, ok)
+		for i := 0; i < 3; i++ {
+			ok, _ := l.Allow("email:c")
+			require.True(t, ok, "request %d", i)
+		}
+	})
+}
+
+func TestLimiter_Lockout(t *testing.T) {
+	l, now := newTestLimiter(Config{
+		Rate:    Rate{Burst: 100, Per: time.Minute},
+		Lockout: Lockout{After: 3, Base: time.Minute, Max: 10 * time.Minute},
+	})
+
+	t.Run("locks out after repeated failures, doubling each time", func(t *testing.T) {
+		l.Fail("email:x")
+		l.Fail("email:x")
+		ok, _ := l.Allow("email:x")
+		assert.True(t, ok)
+
+		l.Fail("email:x")
+		ok, wait := l.Allow("email:x")
+		assert.False(t, ok)
+		assert.Equal(t, time.Minute, wait)
+
+		*now = now.Add(time.Minute)
+		l.Fail("email:x")
+		_, wait = l.Allow("email:x")
+		assert.Equal(t, 2*time.Minute, wait)
+
+		*now = now.Add(2 * time.Minute)
+		l.Fail("email:x")
+		l.Fail("email:x")
+		_, wait = l.Allow("email:x")
+		assert.Equal(t, 8*time.Minute, wait)
+	})
+
+	t.Run("caps the lockout", func(t *testing.T) {
+		l.Fail("email:x")
+		_, wait := l.Allow("email:x")
+		assert.Equal(t, 10*time.Minute, wait)
+	})
+
+	t.Run("success forgives", func(t *testing.T) {
+		l.Succeed("email:x")
+		ok, _ := l.Allow("email:x")
+		assert.True(t, ok)
+		l.Fail("email:x")
+		ok, _ = l.Allow("email:x")
+		assert.True(t, ok)
+	})
+
+	t.Run("old failures are forgotten", func(t *testing.T) {
+		l.Fail("email:y")
+		l.Fail("email:y")
+		*now = now.Add(11 * time.Minute)
+		l.Fail("email:y")
+		ok, _ := l.Allow("email:y")
+		assert.True(t, ok)
+	})
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	l, now := newTestLimiter(Config{Rate: Rate{Burst: 2, Per: time.Minute}})
+	l.Allow("ip:a")
+	l.Allow("ip:b")
+	require.Len(t, l.entries, 2)
+
+	*now = now.Add(2 * time.Minute)
+	l.Allow("ip:c")
+	assert.Len(t, l.entries, 1)
+}
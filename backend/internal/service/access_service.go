@@ -0,0 +1,148 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+// Access is what a user wants to do with a plan.
+type Access int
+
+const (
+	// AccessView and AccessEdit are granted to the owner and to the
+	// owner's coaches.
+	AccessView Access = iota
+	AccessEdit
+	// AccessOwner is only granted to the owner: deleting or activating the
+	// plan, and the AI features, which count against the owner's quota.
+	AccessOwner
+)
+
+// Actions recorded for edits by someone other than the plan's owner.
+const (
+	EditPlanUpdated    = "plan.updated"
+	EditWorkoutCreated = "workout.created"
+	EditWorkoutUpdated = "workout.updated"
+	EditWorkoutDeleted = "workout.deleted"
+)
+
+// AccessService decides who may see and change a plan and its workouts,
+// and records changes coaches make to their athletes' plans. Plans a user
+// may not access are reported as store.ErrNotFound, as if they did not
+// exist. Without a coaching service only owners have access.
+type AccessService struct {
+	plans    *TrainingPlanService
+	workouts *WorkoutService
+	coaching *CoachingService
+	edits    store.PlanEditStore
+	now      func() time.Time
+}
+
+// NewAccessService takes the coaching service and the edit store, both of
+// which may be nil.
+func NewAccessService(plans *TrainingPlanService, workouts *WorkoutService, coaching *CoachingService, edits store.PlanEditStore) *AccessService {
+	return &AccessService{plans: plans, workouts: workouts, coaching: coaching, edits: edits, now: time.Now}
+}
+
+// CanAccessUser reports whether userID may view and edit the plans of
+// ownerID: they are the same user or userID coaches ownerID.
+func (s *AccessService) CanAccessUser(userID, ownerID model.UserID) (bool, error) {
+	if userID == ownerID {
+		return true, nil
+	}
+	if s.coaching == nil {
+		return false, nil
+	}
+	return s.coaching.Coaches(userID, ownerID)
+}
+
+func (s *AccessService) can(userID model.UserID, plan *model.TrainingPlan, access Access) (bool, error) {
+	if access == AccessOwner {
+		return plan.UserID == userID, nil
+	}
+	return s.CanAccessUser(userID, plan.UserID)
+}
+
+// Plan loads a plan the user may access.
+func (s *AccessService) Plan(userID model.UserID, id model.TrainingPlanID, access Access) (*model.TrainingPlan, error) {
+	plan, err := s.plans.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.can(userID, plan, access)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return plan, nil
+}
+
+// Workout loads a workout the user may access, together with its plan.
+func (s *AccessService) Workout(userID model.UserID, id model.WorkoutID, access Access) (*model.Workout, *model.TrainingPlan, error) {
+	workout, err := s.workouts.GetByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan, err := s.Plan(userID, workout.PlanID, access)
+	if err != nil {
+		return nil, nil, err
+	}
+	return workout, plan, nil
+}
+
+// RecordEdit notes a change to the plan if the editor is not its owner.
+// workoutID may be nil.
+func (s *AccessService) RecordEdit(editorID model.UserID, plan *model.TrainingPlan, workoutID *model.WorkoutID, action string) error {
+	if editorID == plan.UserID || s.edits == nil {
+		return nil
+	}
+	return s.edits.Create(&model.PlanEdit{
+		ID:        model.PlanEditID(newPlanEditID()),
+		PlanID:    plan.ID,
+		WorkoutID: workoutID,
+		EditorID:  editorID,
+		Action:    action,
+		CreatedAt: s.now().UTC(),
+	})
+}
+
+// PlanEditView adds the editor's email address; it is empty if the
+// editor has since deleted their account.
+type PlanEditView struct {
+	*model.PlanEdit
+	EditorEmail string `json:"editorEmail"`
+}
+
+// Edits lists the recorded changes to the plan, newest first.
+func (s *AccessService) Edits(planID model.TrainingPlanID) ([]PlanEditView, error) {
+	out := []PlanEditView{}
+	if s.edits == nil {
+		return out, nil
+	}
+	edits, err := s.edits.ListByPlan(planID)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range edits {
+		v := PlanEditView{PlanEdit: e}
+		if s.coaching != nil {
+			if v.EditorEmail, err = s.coaching.email(e.EditorID); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func newPlanEditID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
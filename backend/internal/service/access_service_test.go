@@ -0,0 +1,101 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessService(t *testing.T) {
+	users := mem.NewMemUserStore()
+	plans := NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workouts := NewWorkoutService(mem.NewMemWorkoutStore())
+	coaching := NewCoachingService(users, mem.NewMemCoachingStore())
+	access := NewAccessService(plans, workouts, coaching, mem.NewMemPlanEditStore())
+
+	athlete, err := users.CreateUser("athlete@example.com", []byte("hash"))
+	require.NoError(t, err)
+	coach, err := users.CreateUser("coach@example.com", []byte("hash"))
+	require.NoError(t, err)
+	stranger, err := users.CreateUser("stranger@example.com", []byte("hash"))
+	require.NoError(t, err)
+
+	plan, err := plans.Create(athlete.ID, "Spring marathon", time.Now().AddDate(0, 0, 56), 8)
+	require.NoError(t, err)
+	workout, err := workouts.Create(plan.ID, "easy_run", plan.StartDate, "", 8)
+	require.NoError(t, err)
+
+	invitation, err := coaching.Invite(coach.ID, "athlete@example.com")
+	require.NoError(t, err)
+
+	t.Run("a pending invitation grants nothing", func(t *testing.T) {
+		_, err := access.Plan(coach.ID, plan.ID, AccessView)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	_, err = coaching.Accept(athlete.ID, invitation.ID)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		user   model.UserID
+		access Access
+		want   bool
+	}{
+		{"owner views", athlete.ID, AccessView, true},
+		{"owner edits", athlete.ID, AccessEdit, true},
+		{"owner manages", athlete.ID, AccessOwner, true},
+		{"coach views", coach.ID, AccessView, true},
+		{"coach edits", coach.ID, AccessEdit, true},
+		{"coach cannot manage", coach.ID, AccessOwner, false},
+		{"stranger cannot view", stranger.ID, AccessView, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, gotPlan, err := access.Workout(tc.user, workout.ID, tc.access)
+			if !tc.want {
+				assert.ErrorIs(t, err, store.ErrNotFound)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, workout.ID, got.ID)
+			assert.Equal(t, plan.ID, gotPlan.ID)
+		})
+	}
+
+	t.Run("records edits by coaches only", func(t *testing.T) {
+		require.NoError(t, access.RecordEdit(athlete.ID, plan, &workout.ID, EditWorkoutUpdated))
+		require.NoError(t, access.RecordEdit(coach.ID, plan, &workout.ID, EditWorkoutUpdated))
+		require.NoError(t, access.RecordEdit(coach.ID, plan, nil, EditPlanUpdated))
+
+		edits, err := access.Edits(plan.ID)
+		require.NoError(t, err)
+		require.Len(t, edits, 2)
+		for _, e := range edits {
+			assert.Equal(t, coach.ID, e.EditorID)
+			assert.Equal(t, "coach@example.com", e.EditorEmail)
+		}
+	})
+
+	t.Run("ending the coaching revokes access", func(t *testing.T) {
+		require.NoError(t, coaching.End(athlete.ID, invitation.ID))
+		_, err := access.Plan(coach.ID, plan.ID, AccessView)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		ok, err := access.CanAccessUser(coach.ID, athlete.ID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("without coaching only owners have access", func(t *testing.T) {
+		ownerOnly := NewAccessService(plans, workouts, nil, nil)
+		_, err := ownerOnly.Plan(athlete.ID, plan.ID, AccessOwner)
+		assert.NoError(t, err)
+		_, err = ownerOnly.Plan(coach.ID, plan.ID, AccessView)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		assert.NoError(t, ownerOnly.RecordEdit(coach.ID, plan, nil, EditPlanUpdated))
+	})
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+// AccountStores are the stores holding per-user data. Deleting an account
+// has to visit every one of them, so a store added for user data belongs
+// here too.
+type AccountStores struct {
+	Users              store.UserStore
+	Plans              store.TrainingPlanStore
+	Workouts           store.WorkoutStore
+	Activities         store.ActivityStore
+	Coach              store.CoachStore
+	GenerateJobs       store.GenerateJobStore
+	AIUsage            store.AIUsageStore
+	PasswordResets     store.PasswordResetStore
+	EmailVerifications store.EmailVerificationStore
+	APITokens          store.APITokenStore
+	Identities         store.IdentityStore
+	TwoFactor          store.TwoFactorStore
+	Sessions           store.SessionStore
+	Coachings          store.CoachingStore
+	PlanEdits          store.PlanEditStore
+	ShareLinks         store.ShareLinkStore
+}
+
+// AccountService deletes and exports whole accounts.
+type AccountService struct {
+	stores AccountStores
+	plans  *TrainingPlanService
+	now    func() time.Time
+}
+
+func NewAccountService(stores AccountStores) *AccountService {
+	return &AccountService{
+		stores: stores,
+		plans:  NewTrainingPlanServiceWithDependents(stores.Plans, stores.PlanDependents()),
+		now:    time.Now,
+	}
+}
+
+// PlanDependents returns the stores a plan's deletion has to clear.
+func (st AccountStores) PlanDependents() PlanDependents {
+	return PlanDependents{
+		Workouts:   st.Workouts,
+		Activities: st.Activities,
+		Coach:      st.Coach,
+		PlanEdits:  st.PlanEdits,
+		ShareLinks: st.ShareLinks,
+	}
+}
+
+// AccountExport is everything stored about a user, as handed out for data
+// requests. Secrets are left out by the models' JSON tags; one-time links
+// and generation jobs are not included.
+type AccountExport struct {
+	ExportedAt time.Time         `json:"exportedAt"`
+	User       *model.User       `json:"user"`
+	Plans      []PlanExport      `json:"plans"`
+	AIUsage    []*model.AIUsage  `json:"aiUsage"`
+	APITokens  []*model.APIToken `json:"apiTokens"`
+	Identities []*model.Identity `json:"identities"`
+	Sessions   []*model.Session  `json:"sessions"`
+	Coachings  []*model.Coaching `json:"coachings"`
+	TwoFactor  *TwoFactorStatus  `json:"twoFactor"`
+}
+
+type PlanExport struct {
+	Plan         *model.TrainingPlan `json:"plan"`
+	Workouts     []WorkoutExport     `json:"workouts"`
+	CoachThreads []CoachThreadExport `json:"coachThreads"`
+	Edits        []*model.PlanEdit   `json:"edits"`
+	ShareLinks   []*model.ShareLink  `json:"shareLinks"`
+}
+
+type WorkoutExport struct {
+	*model.Workout
+	Activity *model.Activity `json:"activity,omitempty"`
+}
+
+type CoachThreadExport struct {
+	*model.CoachThread
+	Messages []*model.CoachMessage `json:"messages"`
+}
+
+// Export collects the user's data. Everything is read before anything is
+// written, so a failure can still be reported as an error response.
+func (s *AccountService) Export(userID model.UserID) (*AccountExport, error) {
+	st := s.stores
+	u, err := st.Users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	out := &AccountExport{ExportedAt: s.now().UTC(), User: u, Plans: []PlanExport{}}
+
+	plans, err := st.Plans.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, plan := range plans {
+		p, err := s.exportPlan(plan)
+		if err != nil {
+			return nil, err
+		}
+		out.Plans = append(out.Plans, *p)
+	}
+
+	if out.AIUsage, err = st.AIUsage.ListByUserSince(userID, time.Time{}); err != nil {
+		return nil, err
+	}
+	if out.APITokens, err = st.APITokens.ListByUser(userID); err != nil {
+		return nil, err
+	}
+	if out.Identities, err = st.Identities.ListByUser(userID); err != nil {
+		return nil, err
+	}
+	if out.Sessions, err = st.Sessions.ListByUser(userID); err != nil {
+		return nil, err
+	}
+	for _, list := range []func(model.UserID) ([]*model.Coaching, error){st.Coachings.ListByCoach, st.Coachings.ListByAthlete} {
+		coachings, err := list(userID)
+		if err != nil {
+			return nil, err
+		}
+		out.Coachings = append(out.Coachings, coachings...)
+	}
+	out.TwoFactor = &TwoFactorStatus{}
+	tf, err := st.TwoFactor.Get(userID)
+	switch {
+	case err == nil && tf.Enabled():
+		out.TwoFactor = &TwoFactorStatus{Enabled: true, EnabledAt: tf.EnabledAt, RecoveryCodesLeft: len(tf.RecoveryCodeHashes)}
+	case err != nil && !errors.Is(err, store.ErrNotFound):
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *AccountService) exportPlan(plan *model.TrainingPlan) (*PlanExport, error) {
+	st := s.stores
+	p := &PlanExport{Plan: plan, Workouts: []WorkoutExport{}, CoachThreads: []CoachThreadExport{}}
+	workouts, err := st.Workouts.GetByPlanID(plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range workouts {
+		activity, err := st.Activities.GetByWorkoutID(w.ID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+		p.Workouts = append(p.Workouts, WorkoutExport{Workout: w, Activity: activity})
+	}
+	threads, err := st.Coach.ListThreadsByPlan(plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range threads {
+		messages, err := st.Coach.ListMessages(t.ID)
+		if err != nil {
+			return nil, err
+		}
+		p.CoachThreads = append(p.CoachThreads, CoachThreadExport{CoachThread: t, Messages: emptyIfNil(messages)})
+	}
+	edits, err := st.PlanEdits.ListByPlan(plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	p.Edits = emptyIfNil(edits)
+	links, err := st.ShareLinks.ListByPlan(plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	p.ShareLinks = emptyIfNil(links)
+	return p, nil
+}
+
+// WriteZip writes the export as a zip with one JSON file per kind of data.
+func (e *AccountExport) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"account.json", map[string]interface{}{"exportedAt": e.ExportedAt, "user": e.User, "twoFactor": e.TwoFactor}},
+		{"plans.json", e.Plans},
+		{"ai_usage.json", emptyIfNil(e.AIUsage)},
+		{"api_tokens.json", emptyIfNil(e.APITokens)},
+		{"identities.json", emptyIfNil(e.Identities)},
+		{"sessions.json", emptyIfNil(e.Sessions)},
+		{"coachings.json", emptyIfNil(e.Coachings)},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: e.ExportedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// emptyIfNil makes an empty list encode as [] rather than null.
+func emptyIfNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
+
+// Delete removes the user and all of their data. SQLite does not enforce
+// the schema's cascades, so every store is cleared explicitly; the user
+// row goes last, so a failed deletion can simply be retried.
+func (s *AccountService) Delete(userID model.UserID) error {
+	st := s.stores
+	if _, err := st.Users.GetUserByID(userID); err != nil {
+		return err
+	}
+	plans, err := st.Plans.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for _, plan := range plans {
+		if err := s.deletePlan(plan.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, deleteByUser := range []func(model.UserID) error{
+		st.GenerateJobs.DeleteByUser,
+		st.AIUsage.DeleteByUser,
+		st.PasswordResets.DeleteByUser,
+		st.EmailVerifications.DeleteByUser,
+		st.APITokens.DeleteByUser,
+		st.Identities.DeleteByUser,
+		st.Sessions.DeleteByUser,
+		st.Coachings.DeleteByUser,
+	} {
+		if err := deleteByUser(userID); err != nil {
+			return err
+		}
+	}
+	if err := st.TwoFactor.Delete(userID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	return st.Users.DeleteUser(userID)
+}
+
+func (s *AccountService) deletePlan(planID model.TrainingPlanID) error {
+	if err := s.plans.Delete(planID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	sqliteStore "github.com/kevsommer/runplanner/internal/store/sqlite"
+	goose "github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMemAccountStores() AccountStores {
+	return AccountStores{
+		Users:              mem.NewMemUserStore(),
+		Plans:              mem.NewMemTrainingPlanStore(),
+		Workouts:           mem.NewMemWorkoutStore(),
+		Activities:         mem.NewMemActivityStore(),
+		Coach:              mem.NewMemCoachStore(),
+		GenerateJobs:       mem.NewMemGenerateJobStore(),
+		AIUsage:            mem.NewMemAIUsageStore(),
+		PasswordResets:     mem.NewMemPasswordResetStore(),
+		EmailVerifications: mem.NewMemEmailVerificationStore(),
+		APITokens:          mem.NewMemAPITokenStore(),
+		Identities:         mem.NewMemIdentityStore(),
+		TwoFactor:          mem.NewMemTwoFactorStore(),
+		Sessions:           mem.NewMemSessionStore(),
+		Coachings:          mem.NewMemCoachingStore(),
+		PlanEdits:          mem.NewMemPlanEditStore(),
+		ShareLinks:         mem.NewMemShareLinkStore(),
+	}
+}
+
+// seedAccount gives the user one row in every store, prefixing IDs with
+// the email so that two accounts do not collide.
+func seedAccount(t *testing.T, st AccountStores, email string) *model.User {
+	t.Helper()
+	now := time.Now().UTC()
+	u, err := st.Users.CreateUser(email, []byte("hash"))
+	require.NoError(t, err)
+	id := func(suffix string) string { return email + "-" + suffix }
+
+	plan := &model.TrainingPlan{ID: model.TrainingPlanID(id("plan")), UserID: u.ID, Name: "Marathon", Weeks: 8, CreatedAt: now}
+	require.NoError(t, st.Plans.Create(plan))
+	workout := &model.Workout{ID: model.WorkoutID(id("workout")), PlanID: plan.ID, RunType: "easy_run", Day: now, Status: "completed", Distance: 8}
+	require.NoError(t, st.Workouts.Create(workout))
+	require.NoError(t, st.Activities.Create(&model.Activity{ID: model.ActivityID(id("activity")), WorkoutID: workout.ID, Source: "gpx", Distance: 8.1}))
+	thread := &model.CoachThread{ID: model.CoachThreadID(id("thread")), UserID: u.ID, PlanID: plan.ID, Title: "Taper", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, st.Coach.CreateThread(thread))
+	require.NoError(t, st.ShareLinks.Create(&model.ShareLink{ID: model.ShareLinkID(id("share")), PlanID: plan.ID, Slug: id("slug"), CreatedAt: now}))
+	require.NoError(t, st.Coach.AddMessage(&model.CoachMessage{ID: model.CoachMessageID(id("message")), ThreadID: thread.ID, Role: "user", Content: "How long should I taper?", CreatedAt: now}))
+
+	require.NoError(t, st.GenerateJobs.Create(&model.GenerateJob{ID: model.GenerateJobID(id("job")), UserID: u.ID, Status: model.JobSucceeded}))
+	require.NoError(t, st.AIUsage.Create(&model.AIUsage{ID: model.AIUsageID(id("usage")), UserID: u.ID, Feature: "coach", Requests: 1, CreatedAt: now}))
+	require.NoError(t, st.PasswordResets.Create(&model.PasswordReset{ID: model.PasswordResetID(id("reset")), UserID: u.ID, TokenHash: id("reset"), ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, st.EmailVerifications.Create(&model.EmailVerification{ID: model.EmailVerificationID(id("verify")), UserID: u.ID, TokenHash: id("verify"), ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, st.APITokens.Create(&model.APIToken{ID: model.APITokenID(id("token")), UserID: u.ID, Name: "sync", Scopes: []string{"read"}, TokenHash: id("token-hash"), CreatedAt: now}))
+	require.NoError(t, st.Identities.Create(&model.Identity{ID: model.IdentityID(id("identity")), UserID: u.ID, Provider: "google", Subject: id("subject"), Email: email, CreatedAt: now}))
+	enabledAt := now
+	require.NoError(t, st.TwoFactor.Save(&model.TwoFactor{UserID: u.ID, Secret: "SECRET", EnabledAt: &enabledAt, RecoveryCodeHashes: []string{"a", "b"}}))
+	require.NoError(t, st.Sessions.Create(&model.Session{ID: model.SessionID(id("session")), UserID: u.ID, TokenHash: id("session"), Data: []byte{}, IP: "203.0.113.7", LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}))
+	return u
+}
+
+func TestAccountService_Delete(t *testing.T) {
+	st := newMemAccountStores()
+	accounts := NewAccountService(st)
+	u := seedAccount(t, st, "leaving@example.com")
+	other := seedAccount(t, st, "staying@example.com")
+	require.NoError(t, st.Coachings.Create(&model.Coaching{ID: "coaching", CoachID: other.ID, AthleteID: u.ID, Status: model.CoachingActive}))
+	require.NoError(t, st.PlanEdits.Create(&model.PlanEdit{ID: "edit", PlanID: "leaving@example.com-plan", EditorID: other.ID, Action: EditPlanUpdated}))
+
+	require.NoError(t, accounts.Delete(u.ID))
+
+	t.Run("removes the user and all their data", func(t *testing.T) {
+		_, err := st.Users.GetUserByID(u.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = st.Users.GetUserByEmail("leaving@example.com")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		plans, err := st.Plans.GetByUserID(u.ID)
+		require.NoError(t, err)
+		assert.Empty(t, plans)
+		_, err = st.Workouts.GetByID("leaving@example.com-workout")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = st.Activities.GetByWorkoutID("leaving@example.com-workout")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = st.Coach.GetThread("leaving@example.com-thread")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = st.GenerateJobs.GetByID("leaving@example.com-job")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		usage, err := st.AIUsage.ListByUserSince(u.ID, time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, usage)
+		_, err = st.PasswordResets.GetByTokenHash("leaving@example.com-reset")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = st.EmailVerifications.GetByTokenHash("leaving@example.com-verify")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = st.APITokens.GetByHash("leaving@example.com-token-hash")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = st.Identities.GetBySubject("google", "leaving@example.com-subject")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = st.TwoFactor.Get(u.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		sessions, err := st.Sessions.ListByUser(u.ID)
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+		_, err = st.Coachings.GetByID("coaching")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		edits, err := st.PlanEdits.ListByPlan("leaving@example.com-plan")
+		require.NoError(t, err)
+		assert.Empty(t, edits)
+		_, err = st.ShareLinks.GetBySlug("leaving@example.com-slug")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("leaves other accounts alone", func(t *testing.T) {
+		export, err := accounts.Export(other.ID)
+		require.NoError(t, err)
+		require.Len(t, export.Plans, 1)
+		assert.Len(t, export.Plans[0].Workouts, 1)
+		assert.Len(t, export.Plans[0].CoachThreads, 1)
+		assert.Len(t, export.Plans[0].ShareLinks, 1)
+		assert.Len(t, export.APITokens, 1)
+		assert.Len(t, export.Sessions, 1)
+		_, err = st.GenerateJobs.GetByID("staying@example.com-job")
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown user returns ErrNotFound", func(t *testing.T) {
+		assert.ErrorIs(t, accounts.Delete(u.ID), store.ErrNotFound)
+	})
+}
+
+// newSQLiteAccountStores migrates a fresh in-memory database and returns
+// its stores along with the connection.
+func newSQLiteAccountStores(t *testing.T) (AccountStores, *sql.DB) {
+	t.Helper()
+	db, err := sqliteStore.Open("file:" + t.Name() + "?mode=memory&cache=shared")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, goose.SetDialect("sqlite3"))
+	goose.SetLogger(goose.NopLogger())
+	require.NoError(t, goose.Up(db, "../../db/migrations"))
+
+	return AccountStores{
+		Users:              sqliteStore.NewUserStore(db),
+		Plans:              sqliteStore.NewTrainingPlanStore(db),
+		Workouts:           sqliteStore.NewWorkoutStore(db),
+		Activities:         sqliteStore.NewActivityStore(db),
+		Coach:              sqliteStore.NewCoachStore(db),
+		GenerateJobs:       sqliteStore.NewGenerateJobStore(db),
+		AIUsage:            sqliteStore.NewAIUsageStore(db),
+		PasswordResets:     sqliteStore.NewPasswordResetStore(db),
+		EmailVerifications: sqliteStore.NewEmailVerificationStore(db),
+		APITokens:          sqliteStore.NewAPITokenStore(db),
+		Identities:         sqliteStore.NewIdentityStore(db),
+		TwoFactor:          sqliteStore.NewTwoFactorStore(db),
+		Sessions:           sqliteStore.NewSessionStore(db),
+		Coachings:          sqliteStore.NewCoachingStore(db),
+		PlanEdits:          sqliteStore.NewPlanEditStore(db),
+		ShareLinks:         sqliteStore.NewShareLinkStore(db),
+	}, db
+}
+
+func TestAccountService_DeleteAfterPlanDeletion(t *testing.T) {
+	st, db := newSQLiteAccountStores(t)
+	accounts := NewAccountService(st)
+	plans := NewTrainingPlanServiceWithDependents(st.Plans, st.PlanDependents())
+	u := seedAccount(t, st, "leaving@example.com")
+	require.NoError(t, st.PlanEdits.Create(&model.PlanEdit{ID: "edit", PlanID: "leaving@example.com-plan", EditorID: u.ID, Action: EditPlanUpdated}))
+
+	// Deleting the plan on its own must not orphan what hangs off it,
+	// since account deletion only finds data through the user's plans.
+	require.NoError(t, plans.Delete("leaving@example.com-plan"))
+	require.NoError(t, accounts.Delete(u.ID))
+
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'goose_db_version'`)
+	require.NoError(t, err)
+	var tables []string
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		tables = append(tables, name)
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	require.NotEmpty(t, tables)
+
+	for _, table := range tables {
+		var n int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM `+table).Scan(&n))
+		assert.Zero(t, n, "rows left in %s", table)
+	}
+}
+
+func TestAccountService_Export(t *testing.T) {
+	st := newMemAccountStores()
+	accounts := NewAccountService(st)
+	u := seedAccount(t, st, "export@example.com")
+	seedAccount(t, st, "someone-else@example.com")
+
+	export, err := accounts.Export(u.ID)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, export.WriteZip(&buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = data
+	}
+	assert.ElementsMatch(t, []string{"account.json", "plans.json", "ai_usage.json", "api_tokens.json", "identities.json", "sessions.json", "coachings.json"},
+		mapKeys(files))
+
+	t.Run("account holds the user and two-factor status", func(t *testing.T) {
+		var account struct {
+			User      map[string]interface{} `json:"user"`
+			TwoFactor TwoFactorStatus        `json:"twoFactor"`
+		}
+		require.NoError(t, json.Unmarshal(files["account.json"], &account))
+		assert.Equal(t, "export@example.com", account.User["email"])
+		assert.NotContains(t, account.User, "passwordHash")
+		assert.True(t, account.TwoFactor.Enabled)
+		assert.Equal(t, 2, account.TwoFactor.RecoveryCodesLeft)
+		assert.NotContains(t, string(files["account.json"]), "SECRET")
+	})
+
+	t.Run("plans nest workouts, activities and coach threads", func(t *testing.T) {
+		var plans []struct {
+			Plan     model.TrainingPlan `json:"plan"`
+			Workouts []struct {
+				ID       model.WorkoutID `json:"id"`
+				Activity *model.Activity `json:"activity"`
+			} `json:"workouts"`
+			CoachThreads []struct {
+				Title    string               `json:"title"`
+				Messages []model.CoachMessage `json:"messages"`
+			} `json:"coachThreads"`
+		}
+		require.NoError(t, json.Unmarshal(files["plans.json"], &plans))
+		require.Len(t, plans, 1)
+		assert.Equal(t, "Marathon", plans[0].Plan.Name)
+		require.Len(t, plans[0].Workouts, 1)
+		require.NotNil(t, plans[0].Workouts[0].Activity)
+		assert.Equal(t, 8.1, plans[0].Workouts[0].Activity.Distance)
+		require.Len(t, plans[0].CoachThreads, 1)
+		require.Len(t, plans[0].CoachThreads[0].Messages, 1)
+		assert.Equal(t, "How long should I taper?", plans[0].CoachThreads[0].Messages[0].Content)
+	})
+
+	t.Run("secrets are left out", func(t *testing.T) {
+		assert.NotContains(t, string(files["api_tokens.json"]), "token-hash")
+		assert.NotContains(t, string(files["sessions.json"]), "tokenHash")
+		assert.Contains(t, string(files["sessions.json"]), "203.0.113.7")
+		for _, data := range files {
+			assert.NotContains(t, string(data), "someone-else")
+		}
+	})
+}
+
+func mapKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
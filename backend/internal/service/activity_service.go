@@ -0,0 +1,146 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/activityimport"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+var (
+	ErrInvalidActivityFile = errors.New("invalid activity file")
+	ErrNoMatchingWorkout   = errors.New("no workout found for the activity date")
+)
+
+type ActivityService struct {
+	activities store.ActivityStore
+	workouts   *WorkoutService
+}
+
+func NewActivityService(activities store.ActivityStore, workouts *WorkoutService) *ActivityService {
+	return &ActivityService{activities: activities, workouts: workouts}
+}
+
+// Import decodes a GPX, TCX or FIT file, stores the resulting activity for
+// the workout (replacing any previous one) and marks the workout as
+// completed. The filename is used to pick the decoder.
+func (s *ActivityService) Import(workout *model.Workout, filename string, r io.Reader) (*model.Activity, error) {
+	decoded, err := activityimport.Decode(filename, r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidActivityFile, err)
+	}
+	return s.attach(workout, decoded.Format, decoded.Summarize())
+}
+
+// ImportForPlan decodes an activity file and attaches it to the plan's
+// workout on the activity's start date. If day is non-nil it overrides that
+// date.
+func (s *ActivityService) ImportForPlan(plan *model.TrainingPlan, filename string, r io.Reader, day *time.Time) (*model.Activity, *model.Workout, error) {
+	decoded, err := activityimport.Decode(filename, r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidActivityFile, err)
+	}
+	summary := decoded.Summarize()
+
+	date := summary.StartTime
+	if day != nil {
+		date = *day
+	}
+	workouts, err := s.workouts.GetByPlanID(plan.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	workout := MatchWorkout(workouts, date)
+	if workout == nil {
+		return nil, nil, ErrNoMatchingWorkout
+	}
+
+	activity, err := s.attach(workout, decoded.Format, summary)
+	if err != nil {
+		return nil, nil, err
+	}
+	return activity, workout, nil
+}
+
+// MatchWorkout picks the running workout scheduled on the given date,
+// preferring one that is still pending. Strength sessions are never matched.
+func MatchWorkout(workouts []*model.Workout, day time.Time) *model.Workout {
+	dateStr := day.Format("2006-01-02")
+	var match *model.Workout
+	for _, w := range workouts {
+		if w.RunType == "strength_training" || w.Day.Format("2006-01-02") != dateStr {
+			continue
+		}
+		if w.Status == "pending" {
+			return w
+		}
+		if match == nil {
+			match = w
+		}
+	}
+	return match
+}
+
+func (s *ActivityService) GetByWorkoutID(workoutID model.WorkoutID) (*model.Activity, error) {
+	return s.activities.GetByWorkoutID(workoutID)
+}
+
+func (s *ActivityService) attach(workout *model.Workout, source string, summary activityimport.Summary) (*model.Activity, error) {
+	laps := make([]model.ActivityLap, 0, len(summary.Laps))
+	for _, l := range summary.Laps {
+		lap := model.ActivityLap{
+			StartTime:    l.StartTime,
+			Distance:     math.Round(l.Distance*100) / 100,
+			Duration:     int(l.Duration.Seconds()),
+			AvgHeartRate: l.AvgHeartRate,
+			MaxHeartRate: l.MaxHeartRate,
+			AvgCadence:   l.AvgCadence,
+		}
+		if l.Distance > 0 {
+			lap.AvgPace = math.Round(l.Duration.Seconds() / l.Distance)
+		}
+		laps = append(laps, lap)
+	}
+
+	activity := &model.Activity{
+		ID:            model.ActivityID(newActivityID()),
+		WorkoutID:     workout.ID,
+		Source:        source,
+		StartTime:     summary.StartTime,
+		Distance:      math.Round(summary.Distance*100) / 100,
+		MovingTime:    int(summary.MovingTime.Seconds()),
+		AvgPace:       math.Round(summary.AvgPace()),
+		ElevationGain: math.Round(summary.ElevationGain),
+		AvgHeartRate:  summary.AvgHeartRate,
+		MaxHeartRate:  summary.MaxHeartRate,
+		AvgCadence:    summary.AvgCadence,
+		Laps:          laps,
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	if err := s.activities.DeleteByWorkoutID(workout.ID); err != nil {
+		return nil, err
+	}
+	if err := s.activities.Create(activity); err != nil {
+		return nil, err
+	}
+
+	workout.Status = "completed"
+	if err := s.workouts.Update(workout); err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+func newActivityID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
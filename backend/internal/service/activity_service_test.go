@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGPX = `<gpx version="1.1">
+  <trk><trkseg>
+    <trkpt lat="52.5200" lon="13.4050"><ele>30</ele><time>2025-06-02T06:00:00Z</time></trkpt>
+    <trkpt lat="52.5290" lon="13.4050"><ele>40</ele><time>2025-06-02T06:05:00Z</time></trkpt>
+  </trkseg></trk>
+</gpx>`
+
+func setupActivityTest(t *testing.T) (*ActivityService, *WorkoutService) {
+	workoutSvc := NewWorkoutService(mem.NewMemWorkoutStore())
+	return NewActivityService(mem.NewMemActivityStore(), workoutSvc), workoutSvc
+}
+
+func TestActivityService_Import(t *testing.T) {
+	svc, workoutSvc := setupActivityTest(t)
+	workout, err := workoutSvc.Create("plan-1", "easy_run", time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), "Easy", 5)
+	require.NoError(t, err)
+
+	t.Run("stores activity and completes workout", func(t *testing.T) {
+		activity, err := svc.Import(workout, "run.gpx", strings.NewReader(testGPX))
+		require.NoError(t, err)
+		assert.Equal(t, workout.ID, activity.WorkoutID)
+		assert.Equal(t, "gpx", activity.Source)
+		assert.InDelta(t, 1.0, activity.Distance, 0.01)
+		assert.Equal(t, 300, activity.MovingTime)
+		assert.Equal(t, 10.0, activity.ElevationGain)
+		require.Len(t, activity.Laps, 1)
+		assert.Equal(t, 300, activity.Laps[0].Duration)
+		assert.Equal(t, 300.0, activity.Laps[0].AvgPace)
+
+		stored, err := workoutSvc.GetByID(workout.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "completed", stored.Status)
+
+		found, err := svc.GetByWorkoutID(workout.ID)
+		require.NoError(t, err)
+		assert.Equal(t, activity.ID, found.ID)
+	})
+
+	t.Run("replaces an earlier activity", func(t *testing.T) {
+		first, err := svc.GetByWorkoutID(workout.ID)
+		require.NoError(t, err)
+		second, err := svc.Import(workout, "run.gpx", strings.NewReader(testGPX))
+		require.NoError(t, err)
+		assert.NotEqual(t, first.ID, second.ID)
+
+		found, err := svc.GetByWorkoutID(workout.ID)
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, found.ID)
+	})
+
+	t.Run("invalid file returns ErrInvalidActivityFile", func(t *testing.T) {
+		_, err := svc.Import(workout, "run.gpx", strings.NewReader("garbage"))
+		assert.ErrorIs(t, err, ErrInvalidActivityFile)
+	})
+}
+
+func TestActivityService_ImportForPlan(t *testing.T) {
+	svc, workoutSvc := setupActivityTest(t)
+	plan := &model.TrainingPlan{ID: "plan-1", Weeks: 1, StartDate: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)}
+	monday := plan.StartDate
+	_, err := workoutSvc.Create(plan.ID, "strength_training", monday, "Gym", 0)
+	require.NoError(t, err)
+	run, err := workoutSvc.Create(plan.ID, "easy_run", monday, "Easy", 5)
+	require.NoError(t, err)
+
+	t.Run("matches the running workout on the activity date", func(t *testing.T) {
+		activity, workout, err := svc.ImportForPlan(plan, "run.gpx", strings.NewReader(testGPX), nil)
+		require.NoError(t, err)
+		assert.Equal(t, run.ID, workout.ID)
+		assert.Equal(t, run.ID, activity.WorkoutID)
+		assert.Equal(t, "completed", workout.Status)
+	})
+
+	t.Run("date override without workout returns ErrNoMatchingWorkout", func(t *testing.T) {
+		tuesday := monday.AddDate(0, 0, 1)
+		_, _, err := svc.ImportForPlan(plan, "run.gpx", strings.NewReader(testGPX), &tuesday)
+		assert.ErrorIs(t, err, ErrNoMatchingWorkout)
+
+		_, err = svc.GetByWorkoutID("missing")
+		assert.Equal(t, store.ErrNotFound, err)
+	})
+}
+
+func TestMatchWorkout(t *testing.T) {
+	day := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	done := &model.Workout{ID: "done", RunType: "easy_run", Day: day, Status: "completed"}
+	pending := &model.Workout{ID: "pending", RunType: "tempo_run", Day: day, Status: "pending"}
+	other := &model.Workout{ID: "other", RunType: "easy_run", Day: day.AddDate(0, 0, 1), Status: "pending"}
+
+	assert.Equal(t, pending, MatchWorkout([]*model.Workout{done, pending, other}, day))
+	assert.Equal(t, done, MatchWorkout([]*model.Workout{done, other}, day))
+	assert.Nil(t, MatchWorkout([]*model.Workout{other}, day.AddDate(0, 0, 2)))
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+var ErrQuotaExceeded = errors.New("AI quota exceeded")
+
+// Features recorded in AI usage rows.
+const (
+	FeatureGenerate = "generate"
+	FeatureReplan   = "replan"
+	FeatureCoach    = "coach"
+)
+
+// AIQuota limits how many AI operations a user may start per UTC day and
+// per calendar month. Zero means unlimited.
+type AIQuota struct {
+	Daily   int
+	Monthly int
+}
+
+// QuotaError is returned when a user has used up a quota. It matches
+// ErrQuotaExceeded.
+type QuotaError struct {
+	Period  string // "daily" or "monthly"
+	Limit   int
+	ResetAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s AI quota of %d requests reached, try again after %s", e.Period, e.Limit, e.ResetAt.Format(time.RFC3339))
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// AIUsagePeriod is a user's usage within one quota period.
+type AIUsagePeriod struct {
+	Used         int       `json:"used"`
+	Limit        int       `json:"limit"` // 0 means unlimited
+	Requests     int       `json:"requests"`
+	InputTokens  int       `json:"inputTokens"`
+	OutputTokens int       `json:"outputTokens"`
+	ResetsAt     time.Time `json:"resetsAt"`
+}
+
+type AIUsageSummary struct {
+	Day   AIUsagePeriod `json:"day"`
+	Month AIUsagePeriod `json:"month"`
+	// ByFeature counts this month's operations per feature.
+	ByFeature map[string]int `json:"byFeature"`
+}
+
+// AIUsageService records what each user spends on the shared AI provider
+// and enforces the quotas.
+type AIUsageService struct {
+	usage store.AIUsageStore
+	quota AIQuota
+	now   func() time.Time
+
+	// mu guards inFlight, the operations per user that were reserved but
+	// not recorded yet. They count towards the quota, so operations
+	// started together, such as several generation jobs, cannot overrun
+	// it. Reservations live in memory, which suffices for one server
+	// process.
+	mu       sync.Mutex
+	inFlight map[model.UserID]int
+}
+
+func NewAIUsageService(usage store.AIUsageStore, quota AIQuota) *AIUsageService {
+	return &AIUsageService{usage: usage, quota: quota, now: time.Now, inFlight: map[model.UserID]int{}}
+}
+
+// Check fails with a *QuotaError if the user may not start another AI
+// operation. Operations already running count as used.
+func (s *AIUsageService) Check(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.check(userID)
+}
+
+// reserve is Check for an operation about to call the AI. It holds a place
+// in the quota until the operation's finish, which must follow.
+func (s *AIUsageService) reserve(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.check(userID); err != nil {
+		return err
+	}
+	s.inFlight[userID]++
+	return nil
+}
+
+// check is Check with s.mu held.
+func (s *AIUsageService) check(userID model.UserID) error {
+	summary, err := s.Summary(userID)
+	if err != nil {
+		return err
+	}
+	running := s.inFlight[userID]
+	if p := summary.Day; p.Limit > 0 && p.Used+running >= p.Limit {
+		return &QuotaError{Period: "daily", Limit: p.Limit, ResetAt: p.ResetsAt}
+	}
+	if p := summary.Month; p.Limit > 0 && p.Used+running >= p.Limit {
+		return &QuotaError{Period: "monthly", Limit: p.Limit, ResetAt: p.ResetsAt}
+	}
+	return nil
+}
+
+// Record stores one operation that made requests completions. Operations
+// that never reached the provider are not recorded.
+func (s *AIUsageService) Record(userID model.UserID, feature string, requests int, usage ai.Usage) error {
+	if requests == 0 {
+		return nil
+	}
+	return s.usage.Create(&model.AIUsage{
+		ID:           model.AIUsageID(newAIUsageID()),
+		UserID:       userID,
+		Feature:      feature,
+		Requests:     requests,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		CreatedAt:    s.now().UTC(),
+	})
+}
+
+// Summary returns the user's usage for the current day and month.
+func (s *AIUsageService) Summary(userID model.UserID) (*AIUsageSummary, error) {
+	now := s.now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	rows, err := s.usage.ListByUserSince(userID, month)
+	if err != nil {
+		return nil, err
+	}
+	summary := &AIUsageSummary{
+		Day:       AIUsagePeriod{Limit: s.quota.Daily, ResetsAt: day.AddDate(0, 0, 1)},
+		Month:     AIUsagePeriod{Limit: s.quota.Monthly, ResetsAt: month.AddDate(0, 1, 0)},
+		ByFeature: map[string]int{},
+	}
+	for _, u := range rows {
+		summary.Month.add(u)
+		if !u.CreatedAt.Before(day) {
+			summary.Day.add(u)
+		}
+		summary.ByFeature[u.Feature]++
+	}
+	return summary, nil
+}
+
+// finish records the operation measured by meter, releases its
+// reservation and returns opErr. A failed operation is recorded too, since
+// its completions were paid for; the recording error is only reported when
+// the operation succeeded. The row is written before the reservation is
+// released, so the operation never stops counting in between.
+func (s *AIUsageService) finish(userID model.UserID, feature string, meter *aiMeter, opErr error) error {
+	err := s.Record(userID, feature, meter.requests, meter.usage)
+	s.mu.Lock()
+	if s.inFlight[userID]--; s.inFlight[userID] <= 0 {
+		delete(s.inFlight, userID)
+	}
+	s.mu.Unlock()
+	if opErr != nil {
+		return opErr
+	}
+	return err
+}
+
+// aiMeter sums the completions of one operation, repair attempts included,
+// so they are recorded as a single usage row.
+type aiMeter struct {
+	client   ai.Client
+	requests int
+	usage    ai.Usage
+}
+
+func (m *aiMeter) complete(ctx context.Context, req ai.CompletionRequest) (string, error) {
+	m.requests++
+	resp, err := m.client.Complete(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	m.usage.Add(resp.Usage)
+	return resp.Content, nil
+}
+
+func (p *AIUsagePeriod) add(u *model.AIUsage) {
+	p.Used++
+	p.Requests += u.Requests
+	p.InputTokens += u.InputTokens
+	p.OutputTokens += u.OutputTokens
+}
+
+func newAIUsageID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
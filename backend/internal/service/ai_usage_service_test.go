@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unlimitedUsage() *AIUsageService {
+	return NewAIUsageService(mem.NewMemAIUsageStore(), AIQuota{})
+}
+
+// setupUsageTest returns a usage service whose clock reads now.
+func setupUsageTest(quota AIQuota, now time.Time) *AIUsageService {
+	usage := NewAIUsageService(mem.NewMemAIUsageStore(), quota)
+	usage.now = func() time.Time { return now }
+	return usage
+}
+
+func TestAIUsageService_Summary(t *testing.T) {
+	now := time.Date(2025, 5, 14, 15, 0, 0, 0, time.UTC)
+	usage := setupUsageTest(AIQuota{Daily: 5, Monthly: 50}, now)
+	user := model.UserID("user1")
+
+	for _, at := range []time.Time{
+		time.Date(2025, 4, 30, 23, 0, 0, 0, time.UTC), // last month
+		time.Date(2025, 5, 2, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, 5, 14, 8, 0, 0, 0, time.UTC),
+	} {
+		usage.now = func() time.Time { return at }
+		require.NoError(t, usage.Record(user, FeatureGenerate, 2, ai.Usage{InputTokens: 100, OutputTokens: 10}))
+	}
+	usage.now = func() time.Time { return now }
+	require.NoError(t, usage.Record(user, FeatureCoach, 1, ai.Usage{InputTokens: 40, OutputTokens: 4}))
+	require.NoError(t, usage.Record("user2", FeatureCoach, 1, ai.Usage{InputTokens: 40}))
+	require.NoError(t, usage.Record(user, FeatureCoach, 0, ai.Usage{}), "operations without requests are not recorded")
+
+	summary, err := usage.Summary(user)
+	require.NoError(t, err)
+	assert.Equal(t, AIUsagePeriod{
+		Used: 2, Limit: 5, Requests: 3, InputTokens: 140, OutputTokens: 14,
+		ResetsAt: time.Date(2025, 5, 15, 0, 0, 0, 0, time.UTC),
+	}, summary.Day)
+	assert.Equal(t, AIUsagePeriod{
+		Used: 3, Limit: 50, Requests: 5, InputTokens: 240, OutputTokens: 24,
+		ResetsAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+	}, summary.Month)
+	assert.Equal(t, map[string]int{FeatureGenerate: 2, FeatureCoach: 1}, summary.ByFeature)
+}
+
+func TestAIUsageService_Check(t *testing.T) {
+	now := time.Date(2025, 5, 14, 15, 0, 0, 0, time.UTC)
+	user := model.UserID("user1")
+
+	t.Run("unlimited by default", func(t *testing.T) {
+		usage := setupUsageTest(AIQuota{}, now)
+		for i := 0; i < 20; i++ {
+			require.NoError(t, usage.Record(user, FeatureCoach, 1, ai.Usage{}))
+		}
+		assert.NoError(t, usage.Check(user))
+	})
+
+	t.Run("daily quota", func(t *testing.T) {
+		usage := setupUsageTest(AIQuota{Daily: 2}, now)
+		require.NoError(t, usage.Record(user, FeatureGenerate, 3, ai.Usage{}))
+		assert.NoError(t, usage.Check(user))
+		require.NoError(t, usage.Record(user, FeatureCoach, 1, ai.Usage{}))
+
+		err := usage.Check(user)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		var quotaErr *QuotaError
+		require.True(t, errors.As(err, &quotaErr))
+		assert.Equal(t, "daily", quotaErr.Period)
+		assert.Equal(t, time.Date(2025, 5, 15, 0, 0, 0, 0, time.UTC), quotaErr.ResetAt)
+		assert.NoError(t, usage.Check("user2"))
+
+		usage.now = func() time.Time { return now.AddDate(0, 0, 1) }
+		assert.NoError(t, usage.Check(user))
+	})
+
+	t.Run("monthly quota", func(t *testing.T) {
+		usage := setupUsageTest(AIQuota{Daily: 5, Monthly: 2}, now)
+		usage.now = func() time.Time { return now.AddDate(0, 0, -3) }
+		require.NoError(t, usage.Record(user, FeatureGenerate, 1, ai.Usage{}))
+		require.NoError(t, usage.Record(user, FeatureReplan, 1, ai.Usage{}))
+		usage.now = func() time.Time { return now }
+
+		var quotaErr *QuotaError
+		require.True(t, errors.As(usage.Check(user), &quotaErr))
+		assert.Equal(t, "monthly", quotaErr.Period)
+		assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), quotaErr.ResetAt)
+		assert.Contains(t, quotaErr.Error(), "monthly AI quota of 2 requests reached")
+	})
+
+	t.Run("running operations count until they finish", func(t *testing.T) {
+		usage := setupUsageTest(AIQuota{Daily: 2}, now)
+		require.NoError(t, usage.reserve(user))
+		require.NoError(t, usage.reserve(user))
+		assert.ErrorIs(t, usage.Check(user), ErrQuotaExceeded)
+		assert.ErrorIs(t, usage.reserve(user), ErrQuotaExceeded)
+
+		// One never reached the provider, so it frees its place.
+		require.NoError(t, usage.finish(user, FeatureCoach, &aiMeter{}, nil))
+		require.NoError(t, usage.Check(user))
+		// The other is recorded and keeps counting.
+		require.NoError(t, usage.finish(user, FeatureCoach, &aiMeter{requests: 1}, nil))
+		require.NoError(t, usage.reserve(user))
+		assert.ErrorIs(t, usage.Check(user), ErrQuotaExceeded)
+	})
+}
+
+func TestAIUsageService_ConcurrentOperations(t *testing.T) {
+	usage := NewAIUsageService(mem.NewMemAIUsageStore(), AIQuota{Daily: 2})
+	client := &blockingAIClient{response: validAIResponse(), started: make(chan struct{}, 16), release: make(chan struct{})}
+	_, planSvc, workoutSvc := setupGenerateTest(nil)
+	gen := NewGenerateService(client, usage, planSvc, workoutSvc)
+
+	const started = 5
+	errs := make(chan error, started)
+	for i := 0; i < started; i++ {
+		go func() {
+			_, err := gen.GenerateWithReport(context.Background(), "user1", validInput())
+			errs <- err
+		}()
+	}
+
+	// Only the quota's worth may start while the others are in flight.
+	for i := 0; i < started-2; i++ {
+		assert.ErrorIs(t, <-errs, ErrQuotaExceeded)
+	}
+	close(client.release)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, <-errs)
+	}
+
+	summary, err := usage.Summary("user1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Day.Used)
+	assert.ErrorIs(t, usage.Check("user1"), ErrQuotaExceeded)
+}
+
+func TestAIUsageService_Recording(t *testing.T) {
+	t.Run("generation records every attempt as one operation", func(t *testing.T) {
+		usage := unlimitedUsage()
+		mock := &mockAIClient{response: validAIResponse(), usage: ai.Usage{InputTokens: 500, OutputTokens: 200}}
+		_, planSvc, workoutSvc := setupGenerateTest(nil)
+		gen := NewGenerateService(mock, usage, planSvc, workoutSvc)
+
+		result, err := gen.GenerateWithReport(context.Background(), "user1", validInput())
+		require.NoError(t, err)
+
+		summary, err := usage.Summary("user1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Month.Used)
+		assert.Equal(t, result.Attempts, summary.Month.Requests)
+		assert.Equal(t, 500*result.Attempts, summary.Month.InputTokens)
+		assert.Equal(t, 200*result.Attempts, summary.Month.OutputTokens)
+		assert.Equal(t, 1, summary.ByFeature[FeatureGenerate])
+	})
+
+	t.Run("rule-based generation is free", func(t *testing.T) {
+		usage := NewAIUsageService(mem.NewMemAIUsageStore(), AIQuota{Daily: 1})
+		_, planSvc, workoutSvc := setupGenerateTest(nil)
+		gen := NewGenerateService(nil, usage, planSvc, workoutSvc)
+		input := validInput()
+		input.Strategy = StrategyRules
+
+		for i := 0; i < 2; i++ {
+			_, err := gen.GenerateWithReport(context.Background(), "user1", input)
+			require.NoError(t, err)
+		}
+		summary, err := usage.Summary("user1")
+		require.NoError(t, err)
+		assert.Equal(t, 0, summary.Month.Used)
+	})
+
+	t.Run("failed operations are recorded", func(t *testing.T) {
+		usage := unlimitedUsage()
+		_, planSvc, workoutSvc := setupGenerateTest(nil)
+		gen := NewGenerateService(&mockAIClient{response: "not json"}, usage, planSvc, workoutSvc)
+
+		_, err := gen.GenerateWithReport(context.Background(), "user1", validInput())
+		assert.ErrorIs(t, err, ErrAIGeneration)
+		summary, err := usage.Summary("user1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Month.Used)
+		assert.Equal(t, maxGenerateAttempts, summary.Month.Requests)
+	})
+
+	t.Run("quota stops generation before the AI is called", func(t *testing.T) {
+		usage := NewAIUsageService(mem.NewMemAIUsageStore(), AIQuota{Daily: 1})
+		require.NoError(t, usage.Record("user1", FeatureCoach, 1, ai.Usage{}))
+		client := &sequenceAIClient{responses: []string{validAIResponse()}}
+		_, planSvc, workoutSvc := setupGenerateTest(nil)
+		gen := NewGenerateService(client, usage, planSvc, workoutSvc)
+
+		_, err := gen.GenerateWithReport(context.Background(), "user1", validInput())
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.Empty(t, client.prompts)
+
+		_, err = gen.GenerateWithReport(context.Background(), "user2", validInput())
+		assert.NoError(t, err)
+	})
+
+	t.Run("replan and coach count towards the quota", func(t *testing.T) {
+		client := &recordingAIClient{responses: []string{replanResponse}}
+		replan, _, plan := setupReplanTest(t, nil)
+		usage := NewAIUsageService(mem.NewMemAIUsageStore(), AIQuota{Daily: 1})
+		replan.ai, replan.usage = client, usage
+
+		_, err := replan.Preview(context.Background(), plan, ReplanInput{FromWeek: 3, ToWeek: 4, Today: replanToday(plan)})
+		require.NoError(t, err)
+		_, err = replan.Preview(context.Background(), plan, ReplanInput{FromWeek: 3, ToWeek: 4, Today: replanToday(plan)})
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+		coach := NewCoachService(client, usage, mem.NewMemCoachStore(), replan.workouts)
+		thread, err := coach.CreateThread(plan, "")
+		require.NoError(t, err)
+		_, _, err = coach.SendMessage(context.Background(), thread, plan, "Hello", replanToday(plan))
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.Len(t, client.requests, 1)
+
+		summary, err := usage.Summary(plan.UserID)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{FeatureReplan: 1}, summary.ByFeature)
+	})
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+var ErrInvalidAPIToken = errors.New("invalid or expired API token")
+
+const (
+	apiTokenSecretPrefix   = "rp_"
+	defaultAPITokenDays    = 90
+	maxAPITokenDays        = 365
+	maxAPITokenNameLen     = 100
+	apiTokenLastUsedPeriod = time.Minute
+)
+
+// apiTokenScopes lists the valid scopes in the order they are stored.
+var apiTokenScopes = []string{model.ScopeRead, model.ScopeWrite, model.ScopeGenerate}
+
+type CreateAPITokenInput struct {
+	Name   string
+	Scopes []string
+	// ExpiresInDays defaults to 90 and may be at most 365.
+	ExpiresInDays int
+}
+
+// APITokenService issues personal API tokens. The secret is returned once
+// on creation; afterwards only its hash is known.
+type APITokenService struct {
+	tokens store.APITokenStore
+	now    func() time.Time
+}
+
+func NewAPITokenService(tokens store.APITokenStore) *APITokenService {
+	return &APITokenService{tokens: tokens, now: time.Now}
+}
+
+// Create issues a token and returns it together with its secret.
+func (s *APITokenService) Create(userID model.UserID, input CreateAPITokenInput) (*model.APIToken, string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > maxAPITokenNameLen {
+		return nil, "", fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidInput, maxAPITokenNameLen)
+	}
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	days := input.ExpiresInDays
+	if days == 0 {
+		days = defaultAPITokenDays
+	}
+	if days < 1 || days > maxAPITokenDays {
+		return nil, "", fmt.Errorf("%w: expiresInDays must be between 1 and %d", ErrInvalidInput, maxAPITokenDays)
+	}
+
+	secret := apiTokenSecretPrefix + newSecretToken()
+	now := s.now().UTC()
+	token := &model.APIToken{
+		ID:        model.APITokenID(newAPITokenID()),
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		Prefix:    secret[:len(apiTokenSecretPrefix)+6],
+		TokenHash: hashSecretToken(secret),
+		ExpiresAt: now.AddDate(0, 0, days),
+		CreatedAt: now,
+	}
+	if err := s.tokens.Create(token); err != nil {
+		return nil, "", err
+	}
+	return token, secret, nil
+}
+
+func (s *APITokenService) List(userID model.UserID) ([]*model.APIToken, error) {
+	return s.tokens.ListByUser(userID)
+}
+
+// Revoke deletes one of the user's tokens; other users' tokens are
+// reported as store.ErrNotFound.
+func (s *APITokenService) Revoke(userID model.UserID, id model.APITokenID) error {
+	tokens, err := s.tokens.ListByUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if t.ID == id {
+			return s.tokens.Delete(id)
+		}
+	}
+	return store.ErrNotFound
+}
+
+// Authenticate resolves a bearer secret to its token and records the use.
+// Unknown and expired secrets fail with ErrInvalidAPIToken.
+func (s *APITokenService) Authenticate(secret string) (*model.APIToken, error) {
+	if !strings.HasPrefix(secret, apiTokenSecretPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+	token, err := s.tokens.GetByHash(hashSecretToken(secret))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	if !now.Before(token.ExpiresAt) {
+		return nil, ErrInvalidAPIToken
+	}
+	// Scripts make many requests in a row; the timestamp only needs to be
+	// roughly right.
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenLastUsedPeriod {
+		if err := s.tokens.SetLastUsed(token.ID, now); err != nil {
+			return nil, err
+		}
+		token.LastUsedAt = &now
+	}
+	return token, nil
+}
+
+// normalizeScopes validates scopes and returns them deduplicated in
+// canonical order.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidInput)
+	}
+	want := map[string]bool{}
+	for _, sc := range scopes {
+		valid := false
+		for _, known := range apiTokenScopes {
+			if sc == known {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("%w: unknown scope %q (valid: %s)", ErrInvalidInput, sc, strings.Join(apiTokenScopes, ", "))
+		}
+		want[sc] = true
+	}
+	if want[model.ScopeGenerate] && !want[model.ScopeWrite] {
+		return nil, fmt.Errorf("%w: the generate scope needs the write scope", ErrInvalidInput)
+	}
+	var out []string
+	for _, sc := range apiTokenScopes {
+		if want[sc] {
+			out = append(out, sc)
+		}
+	}
+	return out, nil
+}
+
+func newAPITokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
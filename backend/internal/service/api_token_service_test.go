@@ -0,0 +1,109 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokenService_Create(t *testing.T) {
+	tokens := NewAPITokenService(mem.NewMemAPITokenStore())
+
+	t.Run("returns the secret once and stores its hash", func(t *testing.T) {
+		token, secret, err := tokens.Create("user1", CreateAPITokenInput{Name: " bulk loader ", Scopes: []string{"write", "read", "write"}})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(secret, "rp_"))
+		assert.True(t, strings.HasPrefix(secret, token.Prefix))
+		assert.Equal(t, "bulk loader", token.Name)
+		assert.Equal(t, []string{"read", "write"}, token.Scopes)
+		assert.Equal(t, hashSecretToken(secret), token.TokenHash)
+		assert.NotContains(t, token.TokenHash, secret)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, defaultAPITokenDays), token.ExpiresAt, time.Minute)
+		assert.Nil(t, token.LastUsedAt)
+	})
+
+	for _, tc := range []struct {
+		name  string
+		input CreateAPITokenInput
+		want  string
+	}{
+		{"missing name", CreateAPITokenInput{Scopes: []string{"read"}}, "name must be"},
+		{"long name", CreateAPITokenInput{Name: strings.Repeat("x", maxAPITokenNameLen+1), Scopes: []string{"read"}}, "name must be"},
+		{"no scopes", CreateAPITokenInput{Name: "t"}, "at least one scope"},
+		{"unknown scope", CreateAPITokenInput{Name: "t", Scopes: []string{"admin"}}, `unknown scope "admin"`},
+		{"generate without write", CreateAPITokenInput{Name: "t", Scopes: []string{"read", "generate"}}, "needs the write scope"},
+		{"expiry too long", CreateAPITokenInput{Name: "t", Scopes: []string{"read"}, ExpiresInDays: maxAPITokenDays + 1}, "expiresInDays"},
+		{"negative expiry", CreateAPITokenInput{Name: "t", Scopes: []string{"read"}, ExpiresInDays: -1}, "expiresInDays"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := tokens.Create("user1", tc.input)
+			assert.ErrorIs(t, err, ErrInvalidInput)
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+}
+
+func TestAPITokenService_Authenticate(t *testing.T) {
+	tokens := NewAPITokenService(mem.NewMemAPITokenStore())
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	tokens.now = func() time.Time { return now }
+	created, secret, err := tokens.Create("user1", CreateAPITokenInput{Name: "script", Scopes: []string{"read"}, ExpiresInDays: 7})
+	require.NoError(t, err)
+
+	t.Run("resolves the secret and records the use", func(t *testing.T) {
+		token, err := tokens.Authenticate(secret)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, token.ID)
+		assert.Equal(t, model.UserID("user1"), token.UserID)
+		require.NotNil(t, token.LastUsedAt)
+		assert.Equal(t, now, *token.LastUsedAt)
+	})
+
+	t.Run("last use is updated at most once a minute", func(t *testing.T) {
+		tokens.now = func() time.Time { return now.Add(30 * time.Second) }
+		token, err := tokens.Authenticate(secret)
+		require.NoError(t, err)
+		assert.Equal(t, now, *token.LastUsedAt)
+
+		tokens.now = func() time.Time { return now.Add(2 * time.Minute) }
+		_, err = tokens.Authenticate(secret)
+		require.NoError(t, err)
+		list, err := tokens.List("user1")
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(2*time.Minute), *list[0].LastUsedAt)
+	})
+
+	t.Run("unknown secrets are rejected", func(t *testing.T) {
+		for _, s := range []string{"", "rp_unknown", secret[3:], secret + "x"} {
+			_, err := tokens.Authenticate(s)
+			assert.ErrorIs(t, err, ErrInvalidAPIToken)
+		}
+	})
+
+	t.Run("expired tokens are rejected", func(t *testing.T) {
+		tokens.now = func() time.Time { return now.AddDate(0, 0, 7) }
+		_, err := tokens.Authenticate(secret)
+		assert.ErrorIs(t, err, ErrInvalidAPIToken)
+	})
+}
+
+func TestAPITokenService_Revoke(t *testing.T) {
+	tokens := NewAPITokenService(mem.NewMemAPITokenStore())
+	token, secret, err := tokens.Create("user1", CreateAPITokenInput{Name: "script", Scopes: []string{"read"}})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, tokens.Revoke("user2", token.ID), store.ErrNotFound)
+	require.NoError(t, tokens.Revoke("user1", token.ID))
+	_, err = tokens.Authenticate(secret)
+	assert.ErrorIs(t, err, ErrInvalidAPIToken)
+
+	list, err := tokens.List("user1")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
@@ -20,17 +20,18 @@ var (
 	errInvalidEmail   = errors.New("invalid email")
 	errWeakPassword   = errors.New("password must be at least 8 chars")
 	errBadCredentials = errors.New("invalid email or password")
+
+	ErrWrongPassword = errors.New("current password is incorrect")
+	// ErrNoPassword is for accounts created through a sign-in provider,
+	// which have to set a password through the reset flow first.
+	ErrNoPassword = errors.New("this account has no password yet; set one with the password reset link")
 )
 
 func (s *AuthService) Register(email, password string) (*model.User, error) {
 	if !isEmail(email) {
 		return nil, errInvalidEmail
 	}
-	if len(password) < 8 {
-		return nil, errWeakPassword
-	}
-
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +62,61 @@ func (s *AuthService) SetActivePlan(userID model.UserID, planID *model.TrainingP
 	return s.users.SetActivePlan(userID, planID)
 }
 
+// CheckPassword confirms a sensitive change with the user's password.
+func (s *AuthService) CheckPassword(userID model.UserID, password string) (*model.User, error) {
+	u, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(u.PasswordHash) == 0 {
+		return nil, ErrNoPassword
+	}
+	if bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)) != nil {
+		return nil, ErrWrongPassword
+	}
+	return u, nil
+}
+
+// ChangePassword replaces the password after checking the current one.
+// Ending the user's other sessions is up to the caller.
+func (s *AuthService) ChangePassword(userID model.UserID, current, next string) error {
+	if _, err := s.CheckPassword(userID, current); err != nil {
+		return err
+	}
+	hash, err := hashPassword(next)
+	if err != nil {
+		return err
+	}
+	return s.users.SetPasswordHash(userID, hash)
+}
+
+// ChangeEmail moves the account to a new address, which is unverified
+// until the user confirms it again.
+func (s *AuthService) ChangeEmail(userID model.UserID, password, email string) (*model.User, error) {
+	u, err := s.CheckPassword(userID, password)
+	if err != nil {
+		return nil, err
+	}
+	if !isEmail(email) {
+		return nil, errInvalidEmail
+	}
+	if email == u.Email {
+		return u, nil
+	}
+	if err := s.users.SetEmail(userID, email); err != nil {
+		return nil, err
+	}
+	return s.users.GetUserByID(userID)
+}
+
+// hashPassword checks the password policy and returns the bcrypt hash.
+func hashPassword(password string) ([]byte, error) {
+	if len(password) < 8 {
+		return nil, errWeakPassword
+	}
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
 var emailRe = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
 
 func isEmail(s string) bool { return emailRe.MatchString(s) }
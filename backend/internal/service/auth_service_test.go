@@ -2,6 +2,7 @@ package service
 
 import (
 	"testing"
+	"time"
 
 	"github.com/kevsommer/runplanner/internal/store"
 	"github.com/kevsommer/runplanner/internal/store/mem"
@@ -139,3 +140,74 @@ func TestAuthService_GetUser(t *testing.T) {
 		assert.Nil(t, user)
 	})
 }
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	svc := setupTest(t)
+	u, err := svc.Register("change@example.com", "password123")
+	require.NoError(t, err)
+
+	t.Run("wrong current password returns ErrWrongPassword", func(t *testing.T) {
+		err := svc.ChangePassword(u.ID, "wrong-password", "new-password")
+		assert.ErrorIs(t, err, ErrWrongPassword)
+	})
+
+	t.Run("weak new password returns errWeakPassword", func(t *testing.T) {
+		err := svc.ChangePassword(u.ID, "password123", "short")
+		assert.Equal(t, errWeakPassword, err)
+	})
+
+	t.Run("replaces the password", func(t *testing.T) {
+		require.NoError(t, svc.ChangePassword(u.ID, "password123", "new-password"))
+
+		_, err := svc.Login("change@example.com", "password123")
+		assert.Equal(t, errBadCredentials, err)
+		_, err = svc.Login("change@example.com", "new-password")
+		assert.NoError(t, err)
+	})
+
+	t.Run("account without a password returns ErrNoPassword", func(t *testing.T) {
+		users := mem.NewMemUserStore()
+		oidcUser, err := users.CreateUser("oidc@example.com", []byte{})
+		require.NoError(t, err)
+		err = NewAuthService(users).ChangePassword(oidcUser.ID, "", "new-password")
+		assert.ErrorIs(t, err, ErrNoPassword)
+	})
+}
+
+func TestAuthService_ChangeEmail(t *testing.T) {
+	svc := setupTest(t)
+	u, err := svc.Register("old@example.com", "password123")
+	require.NoError(t, err)
+	_, err = svc.Register("taken@example.com", "password123")
+	require.NoError(t, err)
+	require.NoError(t, svc.users.SetEmailVerified(u.ID, time.Now()))
+
+	t.Run("wrong password returns ErrWrongPassword", func(t *testing.T) {
+		_, err := svc.ChangeEmail(u.ID, "wrong-password", "new@example.com")
+		assert.ErrorIs(t, err, ErrWrongPassword)
+	})
+
+	t.Run("invalid email returns errInvalidEmail", func(t *testing.T) {
+		_, err := svc.ChangeEmail(u.ID, "password123", "not-an-email")
+		assert.Equal(t, errInvalidEmail, err)
+	})
+
+	t.Run("address of another account returns ErrEmailTaken", func(t *testing.T) {
+		_, err := svc.ChangeEmail(u.ID, "password123", "taken@example.com")
+		assert.ErrorIs(t, err, store.ErrEmailTaken)
+	})
+
+	t.Run("moves the account and needs verifying again", func(t *testing.T) {
+		changed, err := svc.ChangeEmail(u.ID, "password123", "new@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "new@example.com", changed.Email)
+		assert.Nil(t, changed.EmailVerifiedAt)
+
+		_, err = svc.Login("old@example.com", "password123")
+		assert.Equal(t, errBadCredentials, err)
+		_, err = svc.Login("new@example.com", "password123")
+		assert.NoError(t, err)
+		_, err = svc.Register("old@example.com", "password123")
+		assert.NoError(t, err, "the old address is free again")
+	})
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+var ErrCalendarTokenExists = errors.New("calendar feed already enabled")
+
+// CalendarService manages the per-user iCalendar feed. Only a hash of the
+// feed token is stored, so a lost URL has to be rotated.
+type CalendarService struct {
+	users    store.UserStore
+	plans    *TrainingPlanService
+	workouts *WorkoutService
+}
+
+func NewCalendarService(users store.UserStore, plans *TrainingPlanService, workouts *WorkoutService) *CalendarService {
+	return &CalendarService{users: users, plans: plans, workouts: workouts}
+}
+
+// Enabled reports whether the user has a feed token.
+func (s *CalendarService) Enabled(userID model.UserID) (bool, error) {
+	u, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return u.CalendarTokenHash != "", nil
+}
+
+// CreateToken enables the feed and returns its token. It fails with
+// ErrCalendarTokenExists if the feed is already enabled.
+func (s *CalendarService) CreateToken(userID model.UserID) (string, error) {
+	enabled, err := s.Enabled(userID)
+	if err != nil {
+		return "", err
+	}
+	if enabled {
+		return "", ErrCalendarTokenExists
+	}
+	return s.RotateToken(userID)
+}
+
+// RotateToken issues a new token; the previous feed URL stops working.
+func (s *CalendarService) RotateToken(userID model.UserID) (string, error) {
+	token := newCalendarToken()
+	if err := s.users.SetCalendarTokenHash(userID, hashCalendarToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *CalendarService) DeleteToken(userID model.UserID) error {
+	return s.users.SetCalendarTokenHash(userID, "")
+}
+
+// Feed renders the calendar for a feed token. It covers the active plan, or
+// every plan when allPlans is set or no plan is active. Unknown tokens
+// return store.ErrNotFound.
+func (s *CalendarService) Feed(token string, allPlans bool) (string, error) {
+	if token == "" {
+		return "", store.ErrNotFound
+	}
+	u, err := s.users.GetUserByCalendarTokenHash(hashCalendarToken(token))
+	if err != nil {
+		return "", err
+	}
+
+	var plans []*model.TrainingPlan
+	if u.ActivePlanID != nil && !allPlans {
+		plan, err := s.plans.GetByID(*u.ActivePlanID)
+		if err != nil && err != store.ErrNotFound {
+			return "", err
+		}
+		if plan != nil {
+			plans = append(plans, plan)
+		}
+	}
+	if plans == nil {
+		plans, err = s.plans.GetByUserID(u.ID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var workouts []*model.Workout
+	for _, p := range plans {
+		ws, err := s.workouts.GetByPlanID(p.ID)
+		if err != nil {
+			return "", err
+		}
+		workouts = append(workouts, ws...)
+	}
+	return BuildICS(workouts, time.Now()), nil
+}
+
+// BuildICS renders workouts as all-day VEVENTs. UIDs derive from the workout
+// ID so calendar apps update events in place on refresh.
+func BuildICS(workouts []*model.Workout, now time.Time) string {
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//runplanner//runplanner//EN")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	writeICSLine(&b, "X-WR-CALNAME:Runplanner")
+	writeICSLine(&b, "REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	writeICSLine(&b, "X-PUBLISHED-TTL:PT1H")
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, w := range workouts {
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:"+string(w.ID)+"@runplanner")
+		writeICSLine(&b, "DTSTAMP:"+stamp)
+		writeICSLine(&b, "DTSTART;VALUE=DATE:"+w.Day.Format("20060102"))
+		writeICSLine(&b, "DTEND;VALUE=DATE:"+w.Day.AddDate(0, 0, 1).Format("20060102"))
+		writeICSLine(&b, "SUMMARY:"+escapeICSText(workoutSummary(w)))
+		writeICSLine(&b, "DESCRIPTION:"+escapeICSText(workoutDetails(w)))
+		writeICSLine(&b, "CATEGORIES:"+escapeICSText(w.RunType))
+		if w.Status == "skipped" {
+			writeICSLine(&b, "STATUS:CANCELLED")
+		} else {
+			writeICSLine(&b, "STATUS:CONFIRMED")
+		}
+		writeICSLine(&b, "TRANSP:TRANSPARENT")
+		writeICSLine(&b, "END:VEVENT")
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// workoutSummary returns e.g. "Tempo Run 10 km", prefixed with a check mark
+// once completed.
+func workoutSummary(w *model.Workout) string {
+	name := runTypeNames[w.RunType]
+	if name == "" {
+		name = w.RunType
+	}
+	if w.Distance > 0 {
+		name += fmt.Sprintf(" %g km", w.Distance)
+	}
+	if w.Status == "completed" {
+		name = "✓ " + name
+	}
+	return name
+}
+
+func workoutDetails(w *model.Workout) string {
+	var parts []string
+	if w.Description != "" {
+		parts = append(parts, w.Description)
+	}
+	if w.Notes != "" {
+		parts = append(parts, "Notes: "+w.Notes)
+	}
+	parts = append(parts, "Status: "+w.Status)
+	return strings.Join(parts, "\n\n")
+}
+
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine writes a content line folded at 75 octets as RFC 5545
+// requires, without splitting UTF-8 sequences.
+func writeICSLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func newCalendarToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCalendarTest(t *testing.T) (*CalendarService, *AuthService, *TrainingPlanService, *WorkoutService) {
+	t.Helper()
+	users := mem.NewMemUserStore()
+	plans := NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workouts := NewWorkoutService(mem.NewMemWorkoutStore())
+	return NewCalendarService(users, plans, workouts), NewAuthService(users), plans, workouts
+}
+
+func TestCalendarService_Tokens(t *testing.T) {
+	cal, auth, _, _ := setupCalendarTest(t)
+	u, err := auth.Register("cal@example.com", "password123")
+	require.NoError(t, err)
+
+	enabled, err := cal.Enabled(u.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	token, err := cal.CreateToken(u.ID)
+	require.NoError(t, err)
+	assert.Len(t, token, 43)
+
+	stored, _ := auth.GetUser(u.ID)
+	assert.NotEqual(t, token, stored.CalendarTokenHash, "only the hash is stored")
+	enabled, _ = cal.Enabled(u.ID)
+	assert.True(t, enabled)
+
+	_, err = cal.CreateToken(u.ID)
+	assert.Equal(t, ErrCalendarTokenExists, err)
+
+	rotated, err := cal.RotateToken(u.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, rotated)
+	_, err = cal.Feed(token, false)
+	assert.Equal(t, store.ErrNotFound, err)
+	_, err = cal.Feed(rotated, false)
+	assert.NoError(t, err)
+
+	require.NoError(t, cal.DeleteToken(u.ID))
+	_, err = cal.Feed(rotated, false)
+	assert.Equal(t, store.ErrNotFound, err)
+	_, err = cal.Feed("", false)
+	assert.Equal(t, store.ErrNotFound, err)
+}
+
+func TestCalendarService_Feed(t *testing.T) {
+	cal, auth, plans, workouts := setupCalendarTest(t)
+	u, _ := auth.Register("feed@example.com", "password123")
+	active, _ := plans.Create(u.ID, "Marathon", time.Date(2025, 10, 12, 0, 0, 0, 0, time.UTC), 12)
+	other, _ := plans.Create(u.ID, "10k", time.Date(2025, 5, 4, 0, 0, 0, 0, time.UTC), 6)
+	require.NoError(t, auth.SetActivePlan(u.ID, &active.ID))
+
+	tempo, _ := workouts.Create(active.ID, "tempo_run", time.Date(2025, 8, 5, 0, 0, 0, 0, time.UTC), "4k Easy\n3k Tempo\n3k Easy", 10)
+	tempo.Status = "completed"
+	require.NoError(t, workouts.Update(tempo))
+	otherRun, _ := workouts.Create(other.ID, "easy_run", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), "", 6)
+
+	token, err := cal.CreateToken(u.ID)
+	require.NoError(t, err)
+
+	t.Run("active plan only", func(t *testing.T) {
+		ics, err := cal.Feed(token, false)
+		require.NoError(t, err)
+		assert.Contains(t, ics, "UID:"+string(tempo.ID)+"@runplanner\r\n")
+		assert.NotContains(t, ics, string(otherRun.ID))
+		assert.Contains(t, ics, "DTSTART;VALUE=DATE:20250805\r\n")
+		assert.Contains(t, ics, "SUMMARY:✓ Tempo Run 10 km\r\n")
+		assert.Contains(t, ics, `DESCRIPTION:4k Easy\n3k Tempo\n3k Easy\n\nStatus: completed`)
+	})
+
+	t.Run("all plans", func(t *testing.T) {
+		ics, err := cal.Feed(token, true)
+		require.NoError(t, err)
+		assert.Contains(t, ics, string(tempo.ID))
+		assert.Contains(t, ics, string(otherRun.ID))
+	})
+
+	t.Run("no active plan falls back to all plans", func(t *testing.T) {
+		require.NoError(t, auth.SetActivePlan(u.ID, nil))
+		ics, err := cal.Feed(token, false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(ics, "BEGIN:VEVENT"))
+	})
+}
+
+func TestBuildICS(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	w := &model.Workout{
+		ID:          "w1",
+		RunType:     "long_run",
+		Day:         time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC),
+		Description: strings.Repeat("Easy, relaxed; ", 10),
+		Status:      "skipped",
+		Distance:    24,
+	}
+
+	ics := BuildICS([]*model.Workout{w}, now)
+
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+	assert.Contains(t, ics, "DTSTAMP:20250102T030405Z\r\n")
+	assert.Contains(t, ics, "DTEND;VALUE=DATE:20250310\r\n")
+	assert.Contains(t, ics, "STATUS:CANCELLED\r\n")
+	assert.Contains(t, ics, `Easy\, relaxed\;`)
+	for _, line := range strings.Split(ics, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	assert.Contains(t, unfolded, "DESCRIPTION:"+strings.Repeat(`Easy\, relaxed\; `, 10))
+}
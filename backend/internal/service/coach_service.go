@@ -0,0 +1,486 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+var (
+	ErrActionNotFound = errors.New("action not found")
+	ErrActionResolved = errors.New("action was already accepted or dismissed")
+	ErrStaleAction    = errors.New("the workout changed since the action was proposed")
+)
+
+const (
+	// coachHistoryMessages is how many earlier messages of a thread are
+	// sent with a new question.
+	coachHistoryMessages = 20
+	maxCoachMessageLen   = 4000
+	maxCoachTitleLen     = 60
+	// The coach sees every workout from coachRecentDays before today to
+	// coachUpcomingDays after it.
+	coachRecentDays   = 14
+	coachUpcomingDays = 21
+)
+
+// CoachService runs chat threads about a plan. The coach answers questions
+// and may propose workout edits, which only change the plan once the user
+// accepts them.
+type CoachService struct {
+	ai       ai.Client
+	usage    *AIUsageService
+	coach    store.CoachStore
+	workouts *WorkoutService
+	// mu serializes accepting and dismissing so an action is resolved once.
+	mu sync.Mutex
+}
+
+func NewCoachService(aiClient ai.Client, usage *AIUsageService, coach store.CoachStore, workouts *WorkoutService) *CoachService {
+	return &CoachService{ai: aiClient, usage: usage, coach: coach, workouts: workouts}
+}
+
+func (s *CoachService) CreateThread(plan *model.TrainingPlan, title string) (*model.CoachThread, error) {
+	now := time.Now().UTC()
+	thread := &model.CoachThread{
+		ID:        model.CoachThreadID(newCoachID()),
+		UserID:    plan.UserID,
+		PlanID:    plan.ID,
+		Title:     truncateTitle(title),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.coach.CreateThread(thread); err != nil {
+		return nil, err
+	}
+	return thread, nil
+}
+
+func (s *CoachService) GetThread(id model.CoachThreadID) (*model.CoachThread, error) {
+	return s.coach.GetThread(id)
+}
+
+func (s *CoachService) ListThreads(planID model.TrainingPlanID) ([]*model.CoachThread, error) {
+	threads, err := s.coach.ListThreadsByPlan(planID)
+	if err != nil {
+		return nil, err
+	}
+	if threads == nil {
+		threads = []*model.CoachThread{}
+	}
+	return threads, nil
+}
+
+func (s *CoachService) ListMessages(threadID model.CoachThreadID) ([]*model.CoachMessage, error) {
+	return s.coach.ListMessages(threadID)
+}
+
+func (s *CoachService) DeleteThread(id model.CoachThreadID) error {
+	return s.coach.DeleteThread(id)
+}
+
+// SendMessage asks the coach a question in a thread and stores the
+// question together with the answer. Nothing is stored if the AI fails.
+func (s *CoachService) SendMessage(ctx context.Context, thread *model.CoachThread, plan *model.TrainingPlan, content string, today time.Time) (question, answer *model.CoachMessage, err error) {
+	if s.ai == nil {
+		return nil, nil, ErrAINotConfigured
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, nil, fmt.Errorf("%w: message is empty", ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(content) > maxCoachMessageLen {
+		return nil, nil, fmt.Errorf("%w: message must be at most %d characters", ErrInvalidInput, maxCoachMessageLen)
+	}
+	messages, err := s.coach.ListMessages(thread.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	workouts, err := s.workouts.GetByPlanID(plan.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[model.WorkoutID]*model.Workout, len(workouts))
+	for _, w := range workouts {
+		byID[w.ID] = w
+	}
+
+	req := ai.CompletionRequest{
+		SystemPrompt: buildCoachSystemPrompt(plan, workouts, today),
+		History:      buildCoachHistory(messages),
+		UserPrompt:   content,
+	}
+	if err := s.usage.reserve(thread.UserID); err != nil {
+		return nil, nil, err
+	}
+	meter := &aiMeter{client: s.ai}
+	reply, err := s.ask(ctx, meter, req, func(actions []model.CoachAction) []string {
+		return validateCoachActions(plan, byID, actions, today)
+	})
+	if err := s.usage.finish(thread.UserID, FeatureCoach, meter, err); err != nil {
+		return nil, nil, err
+	}
+	return s.storeExchange(thread, content, reply)
+}
+
+// ask runs the AI repair loop for SendMessage until the reply parses and
+// validate finds no problems with its actions.
+func (s *CoachService) ask(ctx context.Context, meter *aiMeter, req ai.CompletionRequest, validate func([]model.CoachAction) []string) (*coachReply, error) {
+	content := req.UserPrompt
+	var problems []string
+	for attempt := 1; attempt <= maxGenerateAttempts; attempt++ {
+		raw, err := meter.complete(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrAIGeneration, err)
+		}
+
+		reply, err := parseCoachReply(raw)
+		if err != nil {
+			problems = []string{fmt.Sprintf("failed to parse AI response: %v", err)}
+		} else {
+			problems = validate(reply.Actions)
+		}
+		if len(problems) == 0 {
+			return reply, nil
+		}
+		req.UserPrompt = buildRepairPrompt(content, raw, problems)
+	}
+	return nil, fmt.Errorf("%w: still invalid after %d attempts: %s", ErrAIGeneration, maxGenerateAttempts, strings.Join(problems, "; "))
+}
+
+func (s *CoachService) storeExchange(thread *model.CoachThread, content string, reply *coachReply) (*model.CoachMessage, *model.CoachMessage, error) {
+	now := time.Now().UTC()
+	actions := make([]model.CoachAction, len(reply.Actions))
+	for i, a := range reply.Actions {
+		a.ID = newCoachID()
+		a.State = model.ActionProposed
+		actions[i] = a
+	}
+	question := &model.CoachMessage{
+		ID:        model.CoachMessageID(newCoachID()),
+		ThreadID:  thread.ID,
+		Role:      model.CoachRoleUser,
+		Content:   content,
+		Actions:   []model.CoachAction{},
+		CreatedAt: now,
+	}
+	answer := &model.CoachMessage{
+		ID:        model.CoachMessageID(newCoachID()),
+		ThreadID:  thread.ID,
+		Role:      model.CoachRoleAssistant,
+		Content:   reply.Reply,
+		Actions:   actions,
+		CreatedAt: now,
+	}
+	if err := s.coach.AddMessage(question); err != nil {
+		return nil, nil, err
+	}
+	if err := s.coach.AddMessage(answer); err != nil {
+		return nil, nil, err
+	}
+
+	if thread.Title == "" {
+		thread.Title = truncateTitle(content)
+	}
+	thread.UpdatedAt = now
+	if err := s.coach.UpdateThread(thread); err != nil {
+		return nil, nil, err
+	}
+	return question, answer, nil
+}
+
+// AcceptAction applies a proposed edit to its workout through
+// WorkoutService.Update. It fails with ErrStaleAction if the edit is no
+// longer valid, e.g. because the workout was completed in the meantime.
+func (s *CoachService) AcceptAction(thread *model.CoachThread, plan *model.TrainingPlan, actionID string, today time.Time) (*model.Workout, *model.CoachMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, action, err := s.findProposedAction(thread.ID, actionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	workout, err := s.workouts.GetByID(action.WorkoutID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && workout.PlanID != plan.ID) {
+		return nil, nil, fmt.Errorf("%w: the workout no longer exists", ErrStaleAction)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if problems := checkCoachAction(plan, workout, *action, today); len(problems) > 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrStaleAction, strings.Join(problems, "; "))
+	}
+
+	if action.Day != nil {
+		day, _ := time.Parse("2006-01-02", *action.Day)
+		workout.Day = day
+	}
+	if action.RunType != nil {
+		workout.RunType = *action.RunType
+	}
+	if action.Description != nil {
+		workout.Description = *action.Description
+	}
+	if action.Distance != nil {
+		workout.Distance = *action.Distance
+	}
+	if action.Status != nil {
+		workout.Status = *action.Status
+	}
+	if err := s.workouts.Update(workout); err != nil {
+		return nil, nil, err
+	}
+
+	action.State = model.ActionAccepted
+	if err := s.coach.UpdateMessage(message); err != nil {
+		return nil, nil, err
+	}
+	return workout, message, nil
+}
+
+// DismissAction marks a proposed edit as declined without changing the
+// plan.
+func (s *CoachService) DismissAction(thread *model.CoachThread, actionID string) (*model.CoachMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, action, err := s.findProposedAction(thread.ID, actionID)
+	if err != nil {
+		return nil, err
+	}
+	action.State = model.ActionDismissed
+	if err := s.coach.UpdateMessage(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// findProposedAction returns the message holding an action together with a
+// pointer into its Actions, so updating the action updates the message.
+func (s *CoachService) findProposedAction(threadID model.CoachThreadID, actionID string) (*model.CoachMessage, *model.CoachAction, error) {
+	messages, err := s.coach.ListMessages(threadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range messages {
+		for i := range m.Actions {
+			if m.Actions[i].ID != actionID {
+				continue
+			}
+			if m.Actions[i].State != model.ActionProposed {
+				return nil, nil, ErrActionResolved
+			}
+			return m, &m.Actions[i], nil
+		}
+	}
+	return nil, nil, ErrActionNotFound
+}
+
+// coachReply is the JSON object the coach answers with.
+type coachReply struct {
+	Reply   string              `json:"reply"`
+	Actions []model.CoachAction `json:"actions"`
+}
+
+func parseCoachReply(raw string) (*coachReply, error) {
+	var reply coachReply
+	if err := json.Unmarshal([]byte(raw), &reply); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if strings.TrimSpace(reply.Reply) == "" {
+		return nil, fmt.Errorf("reply is empty")
+	}
+	return &reply, nil
+}
+
+func validateCoachActions(plan *model.TrainingPlan, workouts map[model.WorkoutID]*model.Workout, actions []model.CoachAction, today time.Time) []string {
+	var problems []string
+	for i, a := range actions {
+		w, ok := workouts[a.WorkoutID]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("action %d: workout %q is not in the plan", i+1, a.WorkoutID))
+			continue
+		}
+		for _, p := range checkCoachAction(plan, w, a, today) {
+			problems = append(problems, fmt.Sprintf("action %d: %s", i+1, p))
+		}
+	}
+	return problems
+}
+
+// checkCoachAction reports why an action cannot be applied to w.
+func checkCoachAction(plan *model.TrainingPlan, w *model.Workout, a model.CoachAction, today time.Time) []string {
+	var problems []string
+	if w.Status == "completed" {
+		problems = append(problems, "completed workouts cannot be changed")
+	}
+	if w.RunType == "race" {
+		problems = append(problems, "the race cannot be changed")
+	}
+	if a.Day == nil && a.RunType == nil && a.Description == nil && a.Distance == nil && a.Status == nil {
+		problems = append(problems, "no change given")
+	}
+
+	if a.Day != nil {
+		day, err := time.Parse("2006-01-02", *a.Day)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("day %q must be YYYY-MM-DD", *a.Day))
+		case day.Before(dateOnly(today)):
+			problems = append(problems, fmt.Sprintf("day %s is in the past, the earliest day is %s", *a.Day, dateOnly(today).Format("2006-01-02")))
+		case day.Before(plan.StartDate) || !day.Before(dateOnly(plan.EndDate)):
+			problems = append(problems, fmt.Sprintf("day %s must be between %s and the day before the race", *a.Day, plan.StartDate.Format("2006-01-02")))
+		}
+	}
+
+	runType, distance := w.RunType, w.Distance
+	if a.RunType != nil {
+		runType = *a.RunType
+		if !isValidRunType(runType) || runType == "race" {
+			problems = append(problems, fmt.Sprintf("runType %q is not allowed, use easy_run, intervals, long_run, tempo_run or strength_training", runType))
+		}
+	}
+	if a.Distance != nil {
+		distance = *a.Distance
+		if distance < 0 {
+			problems = append(problems, "distance cannot be negative")
+		}
+	}
+	if runType == "strength_training" && distance != 0 {
+		problems = append(problems, "strength_training must have a distance of 0")
+	}
+	if a.Status != nil && *a.Status != "pending" && *a.Status != "skipped" {
+		problems = append(problems, fmt.Sprintf("status %q is not allowed, use pending or skipped", *a.Status))
+	}
+	return problems
+}
+
+// buildCoachHistory sends the latest messages as conversation turns.
+// Assistant turns are replayed in the reply format, with the state of each
+// proposed action, so the coach knows what was accepted.
+func buildCoachHistory(messages []*model.CoachMessage) []ai.Message {
+	if len(messages) > coachHistoryMessages {
+		messages = messages[len(messages)-coachHistoryMessages:]
+	}
+	history := make([]ai.Message, 0, len(messages))
+	for _, m := range messages {
+		if m.Role != model.CoachRoleAssistant {
+			history = append(history, ai.Message{Role: ai.RoleUser, Content: m.Content})
+			continue
+		}
+		actions := m.Actions
+		if actions == nil {
+			actions = []model.CoachAction{}
+		}
+		content, _ := json.Marshal(coachReply{Reply: m.Content, Actions: actions})
+		history = append(history, ai.Message{Role: ai.RoleAssistant, Content: string(content)})
+	}
+	return history
+}
+
+// coachPromptWorkout is a workout as the coach sees it; the ID is what
+// actions refer to.
+type coachPromptWorkout struct {
+	ID          model.WorkoutID `json:"id"`
+	Date        string          `json:"date"`
+	Week        int             `json:"week"`
+	DayOfWeek   int             `json:"dayOfWeek"`
+	RunType     string          `json:"runType"`
+	Distance    float64         `json:"distance"`
+	Description string          `json:"description,omitempty"`
+	Status      string          `json:"status"`
+	Notes       string          `json:"notes,omitempty"`
+}
+
+type coachPromptWeek struct {
+	Week      int     `json:"week"`
+	PlannedKm float64 `json:"plannedKm"`
+	DoneKm    float64 `json:"doneKm"`
+}
+
+func buildCoachSystemPrompt(plan *model.TrainingPlan, workouts []*model.Workout, today time.Time) string {
+	detail := BuildPlanDetail(plan, workouts)
+	weeks := make([]coachPromptWeek, 0, len(detail.WeeksSummary))
+	for _, week := range detail.WeeksSummary {
+		weeks = append(weeks, coachPromptWeek{Week: week.Number, PlannedKm: week.PlannedKm, DoneKm: week.DoneKm})
+	}
+	weeksJSON, _ := json.Marshal(weeks)
+
+	from := dateOnly(today).AddDate(0, 0, -coachRecentDays)
+	to := dateOnly(today).AddDate(0, 0, coachUpcomingDays)
+	window := []coachPromptWorkout{}
+	for _, w := range workouts {
+		if w.Day.Before(from) || w.Day.After(to) {
+			continue
+		}
+		offset := int(w.Day.Sub(plan.StartDate).Hours() / 24)
+		window = append(window, coachPromptWorkout{
+			ID:          w.ID,
+			Date:        w.Day.Format("2006-01-02"),
+			Week:        offset/7 + 1,
+			DayOfWeek:   offset%7 + 1,
+			RunType:     w.RunType,
+			Distance:    w.Distance,
+			Description: w.Description,
+			Status:      w.Status,
+			Notes:       w.Notes,
+		})
+	}
+	windowJSON, _ := json.Marshal(window)
+
+	var b strings.Builder
+	b.WriteString(`You are a running coach chatting with an athlete about their training plan. You output JSON only.
+Answer questions about the plan, recent training and how to adjust it. Keep replies short and practical, and take the athlete's notes and missed workouts into account.
+When a change to the plan would help, propose it as actions. The athlete decides whether to apply each one, so describe the change in the reply as well.
+
+Rules for actions:
+- Only edit workouts listed below, referring to them by id
+- Only edit pending or skipped workouts, never completed workouts or the race
+- Do not move workouts before today or on or after race day
+- Do not schedule hard sessions (intervals, tempo_run, long_run) on consecutive days
+- Valid run types: easy_run, intervals, long_run, tempo_run, strength_training; strength_training has distance 0
+- status may only be "pending" or "skipped"
+
+Respond with a JSON object: {"reply": string, "actions": [...]}
+Each action: {"workoutId": string, "reason": string} plus only the fields that change: "day" (YYYY-MM-DD), "runType", "description", "distance" (kilometers), "status".
+Use an empty actions array when you propose no change. Do not include any text outside the JSON object.
+
+`)
+	writePlanIntro(&b, plan, today)
+	b.WriteString("\nWeekly volume:\n")
+	b.Write(weeksJSON)
+	fmt.Fprintf(&b, "\n\nWorkouts from %s to %s with status and the athlete's notes:\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	b.Write(windowJSON)
+	return b.String()
+}
+
+// truncateTitle shortens a thread title to its first line and
+// maxCoachTitleLen characters.
+func truncateTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	if utf8.RuneCountInString(title) > maxCoachTitleLen {
+		title = string([]rune(title)[:maxCoachTitleLen-1]) + "…"
+	}
+	return title
+}
+
+func newCoachID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
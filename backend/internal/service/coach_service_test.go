@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAIClient answers with responses in order and keeps every
+// request.
+type recordingAIClient struct {
+	responses []string
+	err       error
+	requests  []ai.CompletionRequest
+}
+
+func (m *recordingAIClient) Complete(_ context.Context, req ai.CompletionRequest) (*ai.CompletionResponse, error) {
+	m.requests = append(m.requests, req)
+	if m.err != nil {
+		return nil, m.err
+	}
+	i := len(m.requests) - 1
+	if i >= len(m.responses) {
+		i = len(m.responses) - 1
+	}
+	return &ai.CompletionResponse{Content: m.responses[i]}, nil
+}
+
+// setupCoachTest reuses the replan plan: week 1 completed, week 2 skipped
+// with a note, and today on Wednesday of week 3. It returns the first
+// pending workout after today.
+func setupCoachTest(t *testing.T, client *recordingAIClient) (*CoachService, *WorkoutService, *model.TrainingPlan, *model.Workout) {
+	t.Helper()
+	_, workoutSvc, plan := setupReplanTest(t, nil)
+	var aiClient ai.Client
+	if client != nil {
+		aiClient = client
+	}
+	coach := NewCoachService(aiClient, unlimitedUsage(), mem.NewMemCoachStore(), workoutSvc)
+
+	workouts, err := workoutSvc.GetByPlanID(plan.ID)
+	require.NoError(t, err)
+	for _, w := range workouts {
+		if w.Status == "pending" && w.Day.After(replanToday(plan)) {
+			return coach, workoutSvc, plan, w
+		}
+	}
+	t.Fatal("no pending workout after today")
+	return nil, nil, nil, nil
+}
+
+func moveReply(w *model.Workout, day string) string {
+	return fmt.Sprintf(`{"reply": "Move it to %s.", "actions": [{"workoutId": %q, "reason": "more rest", "day": %q, "distance": 4}]}`, day, w.ID, day)
+}
+
+func TestCoachService_SendMessage(t *testing.T) {
+	t.Run("stores the exchange with proposed actions", func(t *testing.T) {
+		client := &recordingAIClient{}
+		coach, _, plan, target := setupCoachTest(t, client)
+		today := replanToday(plan)
+		day := target.Day.AddDate(0, 0, 1).Format("2006-01-02")
+		client.responses = []string{moveReply(target, day)}
+
+		thread, err := coach.CreateThread(plan, "")
+		require.NoError(t, err)
+		question, answer, err := coach.SendMessage(context.Background(), thread, plan, "  Should I move my next run?\nMy calf is sore.  ", today)
+		require.NoError(t, err)
+
+		assert.Equal(t, model.CoachRoleUser, question.Role)
+		assert.Equal(t, "Should I move my next run?\nMy calf is sore.", question.Content)
+		assert.Equal(t, model.CoachRoleAssistant, answer.Role)
+		assert.Equal(t, "Move it to "+day+".", answer.Content)
+		require.Len(t, answer.Actions, 1)
+		assert.NotEmpty(t, answer.Actions[0].ID)
+		assert.Equal(t, model.ActionProposed, answer.Actions[0].State)
+		assert.Equal(t, target.ID, answer.Actions[0].WorkoutID)
+		assert.Equal(t, day, *answer.Actions[0].Day)
+		assert.Nil(t, answer.Actions[0].RunType)
+
+		assert.Equal(t, "Should I move my next run?", thread.Title)
+		stored, err := coach.GetThread(thread.ID)
+		require.NoError(t, err)
+		assert.Equal(t, thread.Title, stored.Title)
+		messages, err := coach.ListMessages(thread.ID)
+		require.NoError(t, err)
+		assert.Len(t, messages, 2)
+
+		req := client.requests[0]
+		assert.Empty(t, req.History)
+		assert.Equal(t, question.Content, req.UserPrompt)
+		assert.Contains(t, req.SystemPrompt, "Today is "+today.Format("2006-01-02")+" (week 3, dayOfWeek 3)")
+		assert.Contains(t, req.SystemPrompt, `"id":"`+string(target.ID)+`"`)
+		assert.Contains(t, req.SystemPrompt, `"status":"skipped"`)
+		assert.Contains(t, req.SystemPrompt, `"notes":"calf strain"`)
+		// Week 1 is more than two weeks ago, so only its volume is sent.
+		assert.NotContains(t, req.SystemPrompt, plan.StartDate.Format("2006-01-02")+`","week"`)
+		assert.Contains(t, req.SystemPrompt, `{"week":1,"plannedKm"`)
+	})
+
+	t.Run("sends earlier messages as history", func(t *testing.T) {
+		client := &recordingAIClient{}
+		coach, _, plan, target := setupCoachTest(t, client)
+		client.responses = []string{moveReply(target, target.Day.AddDate(0, 0, 1).Format("2006-01-02"))}
+		thread, err := coach.CreateThread(plan, "Calf")
+		require.NoError(t, err)
+
+		_, first, err := coach.SendMessage(context.Background(), thread, plan, "First question", replanToday(plan))
+		require.NoError(t, err)
+		_, err = coach.DismissAction(thread, first.Actions[0].ID)
+		require.NoError(t, err)
+		_, _, err = coach.SendMessage(context.Background(), thread, plan, "Second question", replanToday(plan))
+		require.NoError(t, err)
+
+		assert.Equal(t, "Calf", thread.Title)
+		history := client.requests[1].History
+		require.Len(t, history, 2)
+		assert.Equal(t, ai.Message{Role: ai.RoleUser, Content: "First question"}, history[0])
+		assert.Equal(t, ai.RoleAssistant, history[1].Role)
+		assert.Contains(t, history[1].Content, `"reply":"Move it to`)
+		assert.Contains(t, history[1].Content, `"state":"dismissed"`)
+		assert.Equal(t, "Second question", client.requests[1].UserPrompt)
+	})
+
+	t.Run("keeps only the latest messages in history", func(t *testing.T) {
+		var messages []*model.CoachMessage
+		for i := 0; i < coachHistoryMessages+6; i++ {
+			messages = append(messages, &model.CoachMessage{Role: model.CoachRoleUser, Content: fmt.Sprint(i)})
+		}
+		history := buildCoachHistory(messages)
+		require.Len(t, history, coachHistoryMessages)
+		assert.Equal(t, "6", history[0].Content)
+	})
+
+	t.Run("re-prompts when actions are invalid", func(t *testing.T) {
+		client := &recordingAIClient{}
+		coach, _, plan, target := setupCoachTest(t, client)
+		client.responses = []string{
+			`{"reply": "Move it.", "actions": [{"workoutId": "nope", "reason": "x", "day": "2025-05-08"}]}`,
+			moveReply(target, target.Day.AddDate(0, 0, 1).Format("2006-01-02")),
+		}
+		thread, err := coach.CreateThread(plan, "")
+		require.NoError(t, err)
+
+		_, answer, err := coach.SendMessage(context.Background(), thread, plan, "Move it", replanToday(plan))
+		require.NoError(t, err)
+		require.Len(t, client.requests, 2)
+		assert.Contains(t, client.requests[1].UserPrompt, `action 1: workout "nope" is not in the plan`)
+		assert.Len(t, answer.Actions, 1)
+
+		messages, err := coach.ListMessages(thread.ID)
+		require.NoError(t, err)
+		assert.Len(t, messages, 2)
+	})
+
+	t.Run("stores nothing when the AI fails", func(t *testing.T) {
+		client := &recordingAIClient{err: errors.New("timeout")}
+		coach, _, plan, _ := setupCoachTest(t, client)
+		thread, err := coach.CreateThread(plan, "")
+		require.NoError(t, err)
+
+		_, _, err = coach.SendMessage(context.Background(), thread, plan, "Hello", replanToday(plan))
+		assert.ErrorIs(t, err, ErrAIGeneration)
+		messages, err := coach.ListMessages(thread.ID)
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+		assert.Empty(t, thread.Title)
+	})
+
+	t.Run("gives up after repeated invalid replies", func(t *testing.T) {
+		client := &recordingAIClient{responses: []string{`{"reply": ""}`}}
+		coach, _, plan, _ := setupCoachTest(t, client)
+		thread, err := coach.CreateThread(plan, "")
+		require.NoError(t, err)
+
+		_, _, err = coach.SendMessage(context.Background(), thread, plan, "Hello", replanToday(plan))
+		assert.ErrorIs(t, err, ErrAIGeneration)
+		assert.Len(t, client.requests, maxGenerateAttempts)
+	})
+
+	t.Run("validates the message", func(t *testing.T) {
+		client := &recordingAIClient{responses: []string{`{"reply": "Hi", "actions": []}`}}
+		coach, _, plan, _ := setupCoachTest(t, client)
+		thread, err := coach.CreateThread(plan, "")
+		require.NoError(t, err)
+
+		_, _, err = coach.SendMessage(context.Background(), thread, plan, "   ", replanToday(plan))
+		assert.ErrorIs(t, err, ErrInvalidInput)
+		_, _, err = coach.SendMessage(context.Background(), thread, plan, strings.Repeat("a", maxCoachMessageLen+1), replanToday(plan))
+		assert.ErrorIs(t, err, ErrInvalidInput)
+		assert.Empty(t, client.requests)
+	})
+
+	t.Run("requires an AI client", func(t *testing.T) {
+		coach, _, plan, _ := setupCoachTest(t, nil)
+		thread, err := coach.CreateThread(plan, "")
+		require.NoError(t, err)
+		_, _, err = coach.SendMessage(context.Background(), thread, plan, "Hello", replanToday(plan))
+		assert.ErrorIs(t, err, ErrAINotConfigured)
+	})
+}
+
+func TestCoachService_Actions(t *testing.T) {
+	propose := func(t *testing.T) (*CoachService, *WorkoutService, *model.TrainingPlan, *model.CoachThread, *model.Workout, model.CoachAction) {
+		t.Helper()
+		client := &recordingAIClient{}
+		coach, workoutSvc, plan, target := setupCoachTest(t, client)
+		client.responses = []string{moveReply(target, target.Day.AddDate(0, 0, 1).Format("2006-01-02"))}
+		thread, err := coach.CreateThread(plan, "")
+		require.NoError(t, err)
+		_, answer, err := coach.SendMessage(context.Background(), thread, plan, "Move it", replanToday(plan))
+		require.NoError(t, err)
+		return coach, workoutSvc, plan, thread, target, answer.Actions[0]
+	}
+
+	t.Run("accept updates the workout", func(t *testing.T) {
+		coach, workoutSvc, plan, thread, target, action := propose(t)
+		// The memory store hands out the stored workout, so keep the day.
+		day := target.Day
+
+		workout, message, err := coach.AcceptAction(thread, plan, action.ID, replanToday(plan))
+		require.NoError(t, err)
+		assert.Equal(t, day.AddDate(0, 0, 1), workout.Day)
+		assert.Equal(t, 4.0, workout.Distance)
+		assert.Equal(t, target.RunType, workout.RunType)
+		assert.Equal(t, model.ActionAccepted, message.Actions[0].State)
+
+		stored, err := workoutSvc.GetByID(target.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 4.0, stored.Distance)
+		messages, err := coach.ListMessages(thread.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ActionAccepted, messages[1].Actions[0].State)
+
+		_, _, err = coach.AcceptAction(thread, plan, action.ID, replanToday(plan))
+		assert.ErrorIs(t, err, ErrActionResolved)
+		_, err = coach.DismissAction(thread, action.ID)
+		assert.ErrorIs(t, err, ErrActionResolved)
+	})
+
+	t.Run("refuses a stale action", func(t *testing.T) {
+		coach, workoutSvc, plan, thread, target, action := propose(t)
+		w, err := workoutSvc.GetByID(target.ID)
+		require.NoError(t, err)
+		w.Status = "completed"
+		require.NoError(t, workoutSvc.Update(w))
+
+		_, _, err = coach.AcceptAction(thread, plan, action.ID, replanToday(plan))
+		assert.ErrorIs(t, err, ErrStaleAction)
+
+		require.NoError(t, workoutSvc.Delete(target.ID))
+		_, _, err = coach.AcceptAction(thread, plan, action.ID, replanToday(plan))
+		assert.ErrorIs(t, err, ErrStaleAction)
+
+		messages, err := coach.ListMessages(thread.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ActionProposed, messages[1].Actions[0].State)
+	})
+
+	t.Run("dismiss leaves the workout alone", func(t *testing.T) {
+		coach, workoutSvc, _, thread, target, action := propose(t)
+		day, distance := target.Day, target.Distance
+
+		message, err := coach.DismissAction(thread, action.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ActionDismissed, message.Actions[0].State)
+		stored, err := workoutSvc.GetByID(target.ID)
+		require.NoError(t, err)
+		assert.Equal(t, day, stored.Day)
+		assert.Equal(t, distance, stored.Distance)
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		coach, _, plan, thread, _, _ := propose(t)
+		_, _, err := coach.AcceptAction(thread, plan, "missing", replanToday(plan))
+		assert.ErrorIs(t, err, ErrActionNotFound)
+		_, err = coach.DismissAction(thread, "missing")
+		assert.ErrorIs(t, err, ErrActionNotFound)
+	})
+}
+
+func TestCheckCoachAction(t *testing.T) {
+	plan := &model.TrainingPlan{
+		StartDate: time.Date(2025, 4, 21, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC),
+		Weeks:     8,
+	}
+	today := time.Date(2025, 5, 7, 15, 30, 0, 0, time.UTC)
+	pending := &model.Workout{RunType: "tempo_run", Status: "pending", Distance: 8, Day: time.Date(2025, 5, 8, 0, 0, 0, 0, time.UTC)}
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name    string
+		workout *model.Workout
+		action  model.CoachAction
+		want    string
+	}{
+		{"valid move", pending, model.CoachAction{Day: str("2025-05-07")}, ""},
+		{"skip", pending, model.CoachAction{Status: str("skipped")}, ""},
+		{"no change", pending, model.CoachAction{Reason: "x"}, "no change given"},
+		{"past day", pending, model.CoachAction{Day: str("2025-05-06")}, "is in the past"},
+		{"bad day", pending, model.CoachAction{Day: str("May 9")}, "must be YYYY-MM-DD"},
+		{"race day", pending, model.CoachAction{Day: str("2025-06-15")}, "the day before the race"},
+		{"completed", &model.Workout{RunType: "easy_run", Status: "completed"}, model.CoachAction{Distance: num(5)}, "completed workouts cannot be changed"},
+		{"race", &model.Workout{RunType: "race", Status: "pending"}, model.CoachAction{Distance: num(5)}, "the race cannot be changed"},
+		{"invalid run type", pending, model.CoachAction{RunType: str("race")}, `runType "race" is not allowed`},
+		{"negative distance", pending, model.CoachAction{Distance: num(-1)}, "distance cannot be negative"},
+		{"strength keeps distance", pending, model.CoachAction{RunType: str("strength_training")}, "distance of 0"},
+		{"strength without distance", pending, model.CoachAction{RunType: str("strength_training"), Distance: num(0)}, ""},
+		{"complete by chat", pending, model.CoachAction{Status: str("completed")}, `status "completed" is not allowed`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := checkCoachAction(plan, tt.workout, tt.action, today)
+			if tt.want == "" {
+				assert.Empty(t, problems)
+				return
+			}
+			require.Len(t, problems, 1)
+			assert.Contains(t, problems[0], tt.want)
+		})
+	}
+}
+
+func TestTruncateTitle(t *testing.T) {
+	assert.Equal(t, "Move Thursday?", truncateTitle("  Move Thursday?\nIt is raining "))
+	long := truncateTitle(strings.Repeat("ü", 100))
+	assert.Equal(t, maxCoachTitleLen, len([]rune(long)))
+	assert.True(t, strings.HasSuffix(long, "…"))
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+var (
+	ErrCoachSelf          = errors.New("you cannot coach yourself")
+	ErrNoAthleteAccount   = errors.New("no account with that email")
+	ErrAlreadyCoaching    = errors.New("you already coach or have invited this athlete")
+	ErrInvitationAccepted = errors.New("invitation already accepted")
+)
+
+// CoachingService manages coaches and their athletes. A coach invites an
+// athlete by email and, once the athlete accepts, may view and edit the
+// athlete's plans; either of them can end the coaching.
+type CoachingService struct {
+	users     store.UserStore
+	coachings store.CoachingStore
+	now       func() time.Time
+}
+
+func NewCoachingService(users store.UserStore, coachings store.CoachingStore) *CoachingService {
+	return &CoachingService{users: users, coachings: coachings, now: time.Now}
+}
+
+// CoachingView adds both parties' email addresses, which is how they
+// know each other.
+type CoachingView struct {
+	*model.Coaching
+	CoachEmail   string `json:"coachEmail"`
+	AthleteEmail string `json:"athleteEmail"`
+}
+
+// CoachingList is the user's coachings from both sides: the athletes they
+// coach or invited and the coaches who coach or invited them.
+type CoachingList struct {
+	Athletes []CoachingView `json:"athletes"`
+	Coaches  []CoachingView `json:"coaches"`
+}
+
+// Invite asks the account with the given email to be coached by coachID.
+func (s *CoachingService) Invite(coachID model.UserID, athleteEmail string) (*CoachingView, error) {
+	athlete, err := s.users.GetUserByEmail(strings.TrimSpace(athleteEmail))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNoAthleteAccount
+		}
+		return nil, err
+	}
+	if athlete.ID == coachID {
+		return nil, ErrCoachSelf
+	}
+	coaching := &model.Coaching{
+		ID:        model.CoachingID(newCoachingID()),
+		CoachID:   coachID,
+		AthleteID: athlete.ID,
+		Status:    model.CoachingPending,
+		CreatedAt: s.now().UTC(),
+	}
+	if err := s.coachings.Create(coaching); err != nil {
+		if errors.Is(err, store.ErrCoachingExists) {
+			return nil, ErrAlreadyCoaching
+		}
+		return nil, err
+	}
+	return s.view(coaching)
+}
+
+// Accept starts a coaching the athlete was invited to. Invitations for
+// someone else are reported as not found.
+func (s *CoachingService) Accept(athleteID model.UserID, id model.CoachingID) (*CoachingView, error) {
+	coaching, err := s.coachings.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if coaching.AthleteID != athleteID {
+		return nil, store.ErrNotFound
+	}
+	if coaching.Status != model.CoachingPending {
+		return nil, ErrInvitationAccepted
+	}
+	now := s.now().UTC()
+	if err := s.coachings.Accept(id, now); err != nil {
+		return nil, err
+	}
+	coaching.Status = model.CoachingActive
+	coaching.AcceptedAt = &now
+	return s.view(coaching)
+}
+
+// End removes a coaching or invitation. The coach and the athlete may both
+// end it; for anyone else it is not found.
+func (s *CoachingService) End(userID model.UserID, id model.CoachingID) error {
+	coaching, err := s.coachings.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if coaching.CoachID != userID && coaching.AthleteID != userID {
+		return store.ErrNotFound
+	}
+	return s.coachings.Delete(id)
+}
+
+func (s *CoachingService) List(userID model.UserID) (*CoachingList, error) {
+	out := &CoachingList{Athletes: []CoachingView{}, Coaches: []CoachingView{}}
+	athletes, err := s.coachings.ListByCoach(userID)
+	if err != nil {
+		return nil, err
+	}
+	coaches, err := s.coachings.ListByAthlete(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, list := range []struct {
+		coachings []*model.Coaching
+		dst       *[]CoachingView
+	}{{athletes, &out.Athletes}, {coaches, &out.Coaches}} {
+		for _, c := range list.coachings {
+			v, err := s.view(c)
+			if err != nil {
+				return nil, err
+			}
+			*list.dst = append(*list.dst, *v)
+		}
+	}
+	return out, nil
+}
+
+// Coaches reports whether coachID has an accepted coaching of athleteID.
+func (s *CoachingService) Coaches(coachID, athleteID model.UserID) (bool, error) {
+	coaching, err := s.coachings.Get(coachID, athleteID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return coaching.Status == model.CoachingActive, nil
+}
+
+func (s *CoachingService) view(c *model.Coaching) (*CoachingView, error) {
+	v := &CoachingView{Coaching: c}
+	for _, p := range []struct {
+		id  model.UserID
+		dst *string
+	}{{c.CoachID, &v.CoachEmail}, {c.AthleteID, &v.AthleteEmail}} {
+		email, err := s.email(p.id)
+		if err != nil {
+			return nil, err
+		}
+		*p.dst = email
+	}
+	return v, nil
+}
+
+// email returns the user's address, or "" for a deleted account.
+func (s *CoachingService) email(id model.UserID) (string, error) {
+	u, err := s.users.GetUserByID(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return u.Email, nil
+}
+
+func newCoachingID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCoachingService(t *testing.T) (*CoachingService, store.UserStore) {
+	t.Helper()
+	users := mem.NewMemUserStore()
+	return NewCoachingService(users, mem.NewMemCoachingStore()), users
+}
+
+func TestCoachingService_Invite(t *testing.T) {
+	coaching, users := newTestCoachingService(t)
+	coach, err := users.CreateUser("coach@example.com", []byte("hash"))
+	require.NoError(t, err)
+	athlete, err := users.CreateUser("athlete@example.com", []byte("hash"))
+	require.NoError(t, err)
+
+	t.Run("creates a pending invitation", func(t *testing.T) {
+		v, err := coaching.Invite(coach.ID, " athlete@example.com ")
+		require.NoError(t, err)
+		assert.Equal(t, model.CoachingPending, v.Status)
+		assert.Equal(t, athlete.ID, v.AthleteID)
+		assert.Equal(t, "coach@example.com", v.CoachEmail)
+		assert.Equal(t, "athlete@example.com", v.AthleteEmail)
+		assert.Nil(t, v.AcceptedAt)
+
+		ok, err := coaching.Coaches(coach.ID, athlete.ID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("twice is refused", func(t *testing.T) {
+		_, err := coaching.Invite(coach.ID, "athlete@example.com")
+		assert.ErrorIs(t, err, ErrAlreadyCoaching)
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		_, err := coaching.Invite(coach.ID, "nobody@example.com")
+		assert.ErrorIs(t, err, ErrNoAthleteAccount)
+	})
+
+	t.Run("yourself", func(t *testing.T) {
+		_, err := coaching.Invite(coach.ID, "coach@example.com")
+		assert.ErrorIs(t, err, ErrCoachSelf)
+	})
+}
+
+func TestCoachingService_AcceptAndEnd(t *testing.T) {
+	coaching, users := newTestCoachingService(t)
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	coaching.now = func() time.Time { return now }
+	coach, err := users.CreateUser("coach@example.com", []byte("hash"))
+	require.NoError(t, err)
+	athlete, err := users.CreateUser("athlete@example.com", []byte("hash"))
+	require.NoError(t, err)
+	stranger, err := users.CreateUser("stranger@example.com", []byte("hash"))
+	require.NoError(t, err)
+	invitation, err := coaching.Invite(coach.ID, "athlete@example.com")
+	require.NoError(t, err)
+
+	t.Run("only the athlete can accept", func(t *testing.T) {
+		_, err := coaching.Accept(coach.ID, invitation.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = coaching.Accept(stranger.ID, invitation.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("accepting starts the coaching", func(t *testing.T) {
+		v, err := coaching.Accept(athlete.ID, invitation.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.CoachingActive, v.Status)
+		require.NotNil(t, v.AcceptedAt)
+		assert.Equal(t, now, *v.AcceptedAt)
+
+		ok, err := coaching.Coaches(coach.ID, athlete.ID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = coaching.Coaches(athlete.ID, coach.ID)
+		require.NoError(t, err)
+		assert.False(t, ok, "coaching is one way")
+
+		_, err = coaching.Accept(athlete.ID, invitation.ID)
+		assert.ErrorIs(t, err, ErrInvitationAccepted)
+	})
+
+	t.Run("lists both sides", func(t *testing.T) {
+		list, err := coaching.List(coach.ID)
+		require.NoError(t, err)
+		require.Len(t, list.Athletes, 1)
+		assert.Equal(t, "athlete@example.com", list.Athletes[0].AthleteEmail)
+		assert.Empty(t, list.Coaches)
+
+		list, err = coaching.List(athlete.ID)
+		require.NoError(t, err)
+		assert.Empty(t, list.Athletes)
+		require.Len(t, list.Coaches, 1)
+		assert.Equal(t, "coach@example.com", list.Coaches[0].CoachEmail)
+	})
+
+	t.Run("strangers cannot end it", func(t *testing.T) {
+		assert.ErrorIs(t, coaching.End(stranger.ID, invitation.ID), store.ErrNotFound)
+	})
+
+	t.Run("the athlete can end it", func(t *testing.T) {
+		require.NoError(t, coaching.End(athlete.ID, invitation.ID))
+		ok, err := coaching.Coaches(coach.ID, athlete.ID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.ErrorIs(t, coaching.End(coach.ID, invitation.ID), store.ErrNotFound)
+	})
+}
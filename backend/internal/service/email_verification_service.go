@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/mail"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailAlreadyVerified     = errors.New("email already verified")
+	ErrEmailNotVerified         = errors.New("email not verified")
+)
+
+const emailVerificationTTL = 48 * time.Hour
+
+// EmailVerificationService confirms that users own their email address.
+// When required, unverified users may not use the features RequireVerified
+// guards.
+type EmailVerificationService struct {
+	users         store.UserStore
+	verifications store.EmailVerificationStore
+	mailer        mail.Mailer
+	appURL        string
+	required      bool
+	now           func() time.Time
+}
+
+// NewEmailVerificationService builds verification links on appURL, the
+// frontend's base URL. required switches on RequireVerified.
+func NewEmailVerificationService(users store.UserStore, verifications store.EmailVerificationStore, mailer mail.Mailer, appURL string, required bool) *EmailVerificationService {
+	return &EmailVerificationService{
+		users:         users,
+		verifications: verifications,
+		mailer:        mailer,
+		appURL:        strings.TrimRight(appURL, "/"),
+		required:      required,
+		now:           time.Now,
+	}
+}
+
+// Required reports whether verification is enforced.
+func (s *EmailVerificationService) Required() bool {
+	return s.required
+}
+
+// Send mails a verification link to the user. Earlier links stay valid
+// until they expire.
+func (s *EmailVerificationService) Send(ctx context.Context, u *model.User) error {
+	if u.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	token := newSecretToken()
+	now := s.now().UTC()
+	v := &model.EmailVerification{
+		ID:        model.EmailVerificationID(newVerificationID()),
+		UserID:    u.ID,
+		TokenHash: hashSecretToken(token),
+		ExpiresAt: now.Add(emailVerificationTTL),
+		CreatedAt: now,
+	}
+	if err := s.verifications.Create(v); err != nil {
+		return err
+	}
+
+	link := s.appURL + "/verify-email?token=" + url.QueryEscape(token)
+	err := s.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Confirm your RunPlanner email address",
+		Body: fmt.Sprintf("Welcome to RunPlanner!\n\n"+
+			"Open this link within %d hours to confirm your email address:\n%s\n\n"+
+			"If you did not create an account, ignore this email.\n",
+			int(emailVerificationTTL.Hours()), link),
+	})
+	if err != nil {
+		return fmt.Errorf("send verification email: %w", err)
+	}
+	return nil
+}
+
+// Resend mails a new verification link to a signed-in user.
+func (s *EmailVerificationService) Resend(ctx context.Context, userID model.UserID) error {
+	u, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	return s.Send(ctx, u)
+}
+
+// Restart is for a changed address: links sent to the old one stop
+// working and a new one is mailed.
+func (s *EmailVerificationService) Restart(ctx context.Context, u *model.User) error {
+	if err := s.verifications.InvalidateUser(u.ID, s.now().UTC()); err != nil {
+		return err
+	}
+	return s.Send(ctx, u)
+}
+
+// Verify consumes a token from Send and marks the user's email verified.
+func (s *EmailVerificationService) Verify(token string) (*model.User, error) {
+	if token == "" {
+		return nil, ErrInvalidVerificationToken
+	}
+	v, err := s.verifications.GetByTokenHash(hashSecretToken(token))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	if v.UsedAt != nil || !now.Before(v.ExpiresAt) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if err := s.verifications.MarkUsed(v.ID, now); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+	u, err := s.users.GetUserByID(v.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u.EmailVerifiedAt == nil {
+		if err := s.users.SetEmailVerified(u.ID, now); err != nil {
+			return nil, err
+		}
+		u.EmailVerifiedAt = &now
+	}
+	return u, nil
+}
+
+// RequireVerified fails with ErrEmailNotVerified if verification is
+// required and the user has not verified their address.
+func (s *EmailVerificationService) RequireVerified(userID model.UserID) error {
+	if !s.required {
+		return nil
+	}
+	u, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+func newVerificationID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
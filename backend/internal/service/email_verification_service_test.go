@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupEmailVerificationTest(t *testing.T, required bool) (*EmailVerificationService, *model.User, *recordingMailer) {
+	users := mem.NewMemUserStore()
+	mailer := &recordingMailer{}
+	verify := NewEmailVerificationService(users, mem.NewMemEmailVerificationStore(), mailer, "https://runplanner.example", required)
+	u, err := NewAuthService(users).Register("new@example.com", "password123")
+	require.NoError(t, err)
+	return verify, u, mailer
+}
+
+func TestEmailVerificationService_Verify(t *testing.T) {
+	t.Run("new accounts start unverified", func(t *testing.T) {
+		_, u, _ := setupEmailVerificationTest(t, false)
+		assert.Nil(t, u.EmailVerifiedAt)
+		assert.False(t, u.Public().EmailVerified)
+	})
+
+	t.Run("verifies with the mailed token once", func(t *testing.T) {
+		verify, u, mailer := setupEmailVerificationTest(t, false)
+		require.NoError(t, verify.Send(context.Background(), u))
+		require.Len(t, mailer.sent, 1)
+		assert.Equal(t, "new@example.com", mailer.sent[0].To)
+		assert.Contains(t, mailer.sent[0].Body, "https://runplanner.example/verify-email?token=")
+		token := mailedToken(t, mailer)
+
+		verified, err := verify.Verify(token)
+		require.NoError(t, err)
+		assert.Equal(t, u.ID, verified.ID)
+		assert.True(t, verified.Public().EmailVerified)
+
+		_, err = verify.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+		assert.ErrorIs(t, verify.Send(context.Background(), verified), ErrEmailAlreadyVerified)
+	})
+
+	t.Run("an older link still works", func(t *testing.T) {
+		verify, u, mailer := setupEmailVerificationTest(t, false)
+		require.NoError(t, verify.Send(context.Background(), u))
+		first := mailedToken(t, mailer)
+		require.NoError(t, verify.Resend(context.Background(), u.ID))
+		require.Len(t, mailer.sent, 2)
+
+		_, err := verify.Verify(first)
+		assert.NoError(t, err)
+	})
+
+	t.Run("restarting for a new address voids older links", func(t *testing.T) {
+		verify, u, mailer := setupEmailVerificationTest(t, false)
+		require.NoError(t, verify.Send(context.Background(), u))
+		first := mailedToken(t, mailer)
+		u.Email = "moved@example.com"
+		require.NoError(t, verify.Restart(context.Background(), u))
+		require.Len(t, mailer.sent, 2)
+		assert.Equal(t, "moved@example.com", mailer.sent[1].To)
+
+		_, err := verify.Verify(first)
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+		_, err = verify.Verify(mailedToken(t, mailer))
+		assert.NoError(t, err)
+	})
+
+	t.Run("expired token is rejected", func(t *testing.T) {
+		verify, u, mailer := setupEmailVerificationTest(t, false)
+		require.NoError(t, verify.Send(context.Background(), u))
+		token := mailedToken(t, mailer)
+
+		verify.now = func() time.Time { return time.Now().Add(emailVerificationTTL + time.Minute) }
+		_, err := verify.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+
+	t.Run("unknown token is rejected", func(t *testing.T) {
+		verify, _, _ := setupEmailVerificationTest(t, false)
+		_, err := verify.Verify("")
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+		_, err = verify.Verify("nope")
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+}
+
+func TestEmailVerificationService_RequireVerified(t *testing.T) {
+	t.Run("not enforced by default", func(t *testing.T) {
+		verify, u, _ := setupEmailVerificationTest(t, false)
+		assert.False(t, verify.Required())
+		assert.NoError(t, verify.RequireVerified(u.ID))
+	})
+
+	t.Run("enforced when required", func(t *testing.T) {
+		verify, u, mailer := setupEmailVerificationTest(t, true)
+		assert.ErrorIs(t, verify.RequireVerified(u.ID), ErrEmailNotVerified)
+
+		require.NoError(t, verify.Send(context.Background(), u))
+		_, err := verify.Verify(mailedToken(t, mailer))
+		require.NoError(t, err)
+		assert.NoError(t, verify.RequireVerified(u.ID))
+	})
+}
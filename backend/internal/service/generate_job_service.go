@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+// GenerateJobService runs plan generation in the background. Job state goes
+// through the store so it can be polled, and every change is also pushed to
+// subscribers for streaming.
+type GenerateJobService struct {
+	jobs     store.GenerateJobStore
+	generate *GenerateService
+
+	mu          sync.Mutex
+	subscribers map[model.GenerateJobID][]chan model.GenerateJob
+	running     sync.WaitGroup
+}
+
+func NewGenerateJobService(jobs store.GenerateJobStore, generate *GenerateService) *GenerateJobService {
+	return &GenerateJobService{
+		jobs:        jobs,
+		generate:    generate,
+		subscribers: make(map[model.GenerateJobID][]chan model.GenerateJob),
+	}
+}
+
+// Start validates the input and queues the generation. Input errors are
+// returned right away, like GenerateWithReport does; everything after that
+// is reported through the job.
+func (s *GenerateJobService) Start(userID model.UserID, input GenerateInput) (*model.GenerateJob, error) {
+	if err := s.generate.checkInput(userID, input); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	job := &model.GenerateJob{
+		ID:        model.GenerateJobID(newGenerateJobID()),
+		UserID:    userID,
+		Status:    model.JobPending,
+		Warnings:  []LintFinding{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.jobs.Create(job); err != nil {
+		return nil, err
+	}
+
+	s.running.Add(1)
+	go s.run(*job, input)
+	return job, nil
+}
+
+func (s *GenerateJobService) GetByID(id model.GenerateJobID) (*model.GenerateJob, error) {
+	return s.jobs.GetByID(id)
+}
+
+// Subscribe returns a channel receiving the job after every change. The
+// channel is closed once the job finishes; call cancel to stop early.
+// Updates are dropped for subscribers that fall behind, so readers should
+// fetch the job again when the channel closes.
+func (s *GenerateJobService) Subscribe(id model.GenerateJobID) (updates <-chan model.GenerateJob, cancel func()) {
+	ch := make(chan model.GenerateJob, 16)
+	s.mu.Lock()
+	s.subscribers[id] = append(s.subscribers[id], ch)
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		subs := s.subscribers[id]
+		for i, c := range subs {
+			if c == ch {
+				s.subscribers[id] = append(subs[:i], subs[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+		if len(s.subscribers[id]) == 0 {
+			delete(s.subscribers, id)
+		}
+	}
+}
+
+// FailInterrupted marks jobs left unfinished by a previous process as
+// failed. Call it once at startup, before starting new jobs.
+func (s *GenerateJobService) FailInterrupted() error {
+	return s.jobs.FailUnfinished("generation was interrupted by a server restart", time.Now().UTC())
+}
+
+// Wait blocks until every started job has finished.
+func (s *GenerateJobService) Wait() {
+	s.running.Wait()
+}
+
+func (s *GenerateJobService) run(job model.GenerateJob, input GenerateInput) {
+	defer s.running.Done()
+
+	job.Status = model.JobRunning
+	result, err := s.generate.generate(context.Background(), job.UserID, input, func(phase string) {
+		job.Phase = phase
+		s.save(&job)
+	})
+	if err != nil {
+		job.Status = model.JobFailed
+		job.Error = "failed to generate plan"
+		if errors.Is(err, ErrAIGeneration) || errors.Is(err, ErrQuotaExceeded) {
+			job.Error = err.Error()
+		}
+	} else {
+		job.Status = model.JobSucceeded
+		job.PlanID = &result.Plan.ID
+		job.Attempts = result.Attempts
+		if result.Warnings != nil {
+			job.Warnings = result.Warnings
+		}
+	}
+	s.save(&job)
+}
+
+// save stores the job and notifies subscribers. The store is written first,
+// so a subscriber that reads the job after its channel closed sees the final
+// state.
+func (s *GenerateJobService) save(job *model.GenerateJob) {
+	job.UpdatedAt = time.Now().UTC()
+	_ = s.jobs.Update(job)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ch := range s.subscribers[job.ID] {
+		select {
+		case ch <- *job:
+		default:
+		}
+		if job.Finished() {
+			close(ch)
+		}
+	}
+	if job.Finished() {
+		delete(s.subscribers, job.ID)
+	}
+}
+
+func newGenerateJobID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
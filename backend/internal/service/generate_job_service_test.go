@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingAIClient signals each call on started and waits for release
+// before answering.
+type blockingAIClient struct {
+	response string
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (m *blockingAIClient) Complete(_ context.Context, _ ai.CompletionRequest) (*ai.CompletionResponse, error) {
+	m.started <- struct{}{}
+	<-m.release
+	return &ai.CompletionResponse{Content: m.response}, nil
+}
+
+func setupGenerateJobTest(mockClient ai.Client) (*GenerateJobService, *TrainingPlanService) {
+	genSvc, planSvc, _ := setupGenerateTest(mockClient)
+	return NewGenerateJobService(mem.NewMemGenerateJobStore(), genSvc), planSvc
+}
+
+func TestGenerateJobService_Start(t *testing.T) {
+	t.Run("runs generation in the background", func(t *testing.T) {
+		input := validInput()
+		input.Strategy = StrategyRules
+		jobs, planSvc := setupGenerateJobTest(nil)
+
+		job, err := jobs.Start(model.UserID("user1"), input)
+		require.NoError(t, err)
+		assert.Equal(t, model.JobPending, job.Status)
+		assert.NotEmpty(t, job.ID)
+
+		jobs.Wait()
+		done, err := jobs.GetByID(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.JobSucceeded, done.Status)
+		assert.Equal(t, PhasePersisting, done.Phase)
+		assert.Equal(t, 1, done.Attempts)
+		assert.Empty(t, done.Error)
+		require.NotNil(t, done.PlanID)
+
+		plan, err := planSvc.GetByID(*done.PlanID)
+		require.NoError(t, err)
+		assert.Equal(t, "Marathon Plan", plan.Name)
+		assert.Equal(t, model.UserID("user1"), plan.UserID)
+	})
+
+	t.Run("returns input errors without creating a job", func(t *testing.T) {
+		jobs, _ := setupGenerateJobTest(nil)
+
+		_, err := jobs.Start(model.UserID("user1"), validInput())
+		assert.ErrorIs(t, err, ErrAINotConfigured)
+
+		input := validInput()
+		input.Strategy = StrategyRules
+		input.Weeks = 2
+		_, err = jobs.Start(model.UserID("user1"), input)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	})
+
+	t.Run("records AI failures on the job", func(t *testing.T) {
+		jobs, _ := setupGenerateJobTest(&mockAIClient{err: errors.New("rate limited")})
+
+		job, err := jobs.Start(model.UserID("user1"), validInput())
+		require.NoError(t, err)
+		jobs.Wait()
+
+		done, err := jobs.GetByID(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.JobFailed, done.Status)
+		assert.Contains(t, done.Error, "rate limited")
+		assert.Nil(t, done.PlanID)
+	})
+}
+
+func TestGenerateJobService_Subscribe(t *testing.T) {
+	input := validInput()
+	input.EndDate = time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+	mock := &blockingAIClient{
+		response: cleanAIResponse(t, input),
+		started:  make(chan struct{}, 1),
+		release:  make(chan struct{}),
+	}
+	jobs, _ := setupGenerateJobTest(mock)
+
+	job, err := jobs.Start(model.UserID("user1"), input)
+	require.NoError(t, err)
+	<-mock.started
+
+	updates, cancel := jobs.Subscribe(job.ID)
+	defer cancel()
+	close(mock.release)
+
+	var phases []string
+	var last model.GenerateJob
+	for update := range updates {
+		phases = append(phases, update.Phase)
+		last = update
+	}
+	assert.Equal(t, []string{PhaseParsing, PhaseValidating, PhasePersisting, PhasePersisting}, phases)
+	assert.Equal(t, model.JobSucceeded, last.Status)
+	assert.NotNil(t, last.PlanID)
+}
+
+func TestGenerateJobService_FailInterrupted(t *testing.T) {
+	store := mem.NewMemGenerateJobStore()
+	genSvc, _, _ := setupGenerateTest(nil)
+	jobs := NewGenerateJobService(store, genSvc)
+
+	now := time.Now().UTC()
+	require.NoError(t, store.Create(&model.GenerateJob{ID: "running", UserID: "user1", Status: model.JobRunning, Phase: PhasePrompting, CreatedAt: now, UpdatedAt: now}))
+	require.NoError(t, store.Create(&model.GenerateJob{ID: "done", UserID: "user1", Status: model.JobSucceeded, CreatedAt: now, UpdatedAt: now}))
+
+	require.NoError(t, jobs.FailInterrupted())
+
+	running, err := jobs.GetByID("running")
+	require.NoError(t, err)
+	assert.Equal(t, model.JobFailed, running.Status)
+	assert.Contains(t, running.Error, "interrupted")
+
+	done, err := jobs.GetByID("done")
+	require.NoError(t, err)
+	assert.Equal(t, model.JobSucceeded, done.Status)
+}
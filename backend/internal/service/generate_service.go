@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/kevsommer/runplanner/internal/ai"
@@ -18,6 +19,13 @@ var (
 	ErrInvalidInput    = errors.New("invalid input")
 )
 
+// Generation strategies. StrategyAI is the default; StrategyRules builds
+// the plan locally and needs no AI client.
+const (
+	StrategyAI    = "ai"
+	StrategyRules = "rules"
+)
+
 type GenerateInput struct {
 	Name          string
 	EndDate       time.Time
@@ -25,66 +33,256 @@ type GenerateInput struct {
 	BaseKmPerWeek float64
 	RunsPerWeek   int
 	RaceGoal      string
+	Strategy      string // StrategyAI or StrategyRules; empty means StrategyAI
 }
 
 type GenerateService struct {
 	ai       ai.Client
+	usage    *AIUsageService
 	plans    *TrainingPlanService
 	workouts *WorkoutService
 }
 
-func NewGenerateService(aiClient ai.Client, plans *TrainingPlanService, workouts *WorkoutService) *GenerateService {
+func NewGenerateService(aiClient ai.Client, usage *AIUsageService, plans *TrainingPlanService, workouts *WorkoutService) *GenerateService {
 	return &GenerateService{
 		ai:       aiClient,
+		usage:    usage,
 		plans:    plans,
 		workouts: workouts,
 	}
 }
 
+// Generation phases, reported in order to the progress callback. Prompting
+// and parsing repeat for every AI attempt; StrategyRules skips them.
+const (
+	PhasePrompting  = "prompting"
+	PhaseParsing    = "parsing"
+	PhaseValidating = "validating"
+	PhasePersisting = "persisting"
+)
+
+// maxGenerateAttempts bounds how often the AI is asked to repair a plan
+// that fails validation.
+const maxGenerateAttempts = 3
+
+// GenerateResult is a generated plan together with how it was obtained.
+type GenerateResult struct {
+	Plan     *model.TrainingPlan
+	Workouts []*model.Workout
+	// Attempts is the number of AI completions used; 1 for StrategyRules.
+	Attempts int
+	// Warnings are coaching-rule findings still present in the stored plan.
+	Warnings []LintFinding
+}
+
 func (s *GenerateService) Generate(ctx context.Context, userID model.UserID, input GenerateInput) (*model.TrainingPlan, []*model.Workout, error) {
-	if s.ai == nil {
-		return nil, nil, ErrAINotConfigured
+	result, err := s.GenerateWithReport(ctx, userID, input)
+	if err != nil {
+		return nil, nil, err
 	}
+	return result.Plan, result.Workouts, nil
+}
 
+func (s *GenerateService) GenerateWithReport(ctx context.Context, userID model.UserID, input GenerateInput) (*GenerateResult, error) {
+	return s.generate(ctx, userID, input, func(string) {})
+}
+
+// checkInput reports the errors a request fails with before any work is
+// done. Only AI generation counts towards the user's quota.
+func (s *GenerateService) checkInput(userID model.UserID, input GenerateInput) error {
+	if input.Strategy != StrategyRules && s.ai == nil {
+		return ErrAINotConfigured
+	}
 	if err := validateGenerateInput(input); err != nil {
-		return nil, nil, err
+		return err
 	}
+	if input.Strategy != StrategyRules {
+		return s.usage.Check(userID)
+	}
+	return nil
+}
 
-	systemPrompt := buildSystemPrompt()
-	userPrompt := buildUserPrompt(input)
-
-	raw, err := s.ai.Complete(ctx, ai.CompletionRequest{
-		SystemPrompt: systemPrompt,
-		UserPrompt:   userPrompt,
-	})
-	if err != nil {
-		return nil, nil, fmt.Errorf("%w: %v", ErrAIGeneration, err)
+func (s *GenerateService) generate(ctx context.Context, userID model.UserID, input GenerateInput, progress func(phase string)) (*GenerateResult, error) {
+	if err := s.checkInput(userID, input); err != nil {
+		return nil, err
 	}
 
-	items, err := parseWorkouts(raw)
-	if err != nil {
-		return nil, nil, fmt.Errorf("%w: failed to parse AI response: %v", ErrAIGeneration, err)
+	var items []BulkWorkoutInput
+	var found []generatedWarning
+	attempts := 1
+	if input.Strategy == StrategyRules {
+		items = BuildRuleBasedWorkouts(input)
+		progress(PhaseValidating)
+		_, found = validateGeneratedWorkouts(input, items)
+	} else {
+		if err := s.usage.reserve(userID); err != nil {
+			return nil, err
+		}
+		meter := &aiMeter{client: s.ai}
+		var err error
+		items, found, attempts, err = s.generateWithAI(ctx, meter, input, progress)
+		if err := s.usage.finish(userID, FeatureGenerate, meter, err); err != nil {
+			return nil, err
+		}
 	}
 
+	progress(PhasePersisting)
 	plan, err := s.plans.Create(userID, input.Name, input.EndDate, input.Weeks)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	workouts, err := s.workouts.CreateBatch(plan, items)
 	if err != nil {
 		_ = s.plans.Delete(plan.ID)
-		return nil, nil, fmt.Errorf("failed to create workouts: %w", err)
+		return nil, fmt.Errorf("failed to create workouts: %w", err)
 	}
 
 	raceWorkout, err := s.workouts.CreateRaceWorkout(plan, input.RaceGoal)
 	if err != nil {
 		_ = s.plans.Delete(plan.ID)
-		return nil, nil, fmt.Errorf("failed to create race workout: %w", err)
+		return nil, fmt.Errorf("failed to create race workout: %w", err)
 	}
 	workouts = append(workouts, raceWorkout)
 
-	return plan, workouts, nil
+	// CreateBatch keeps the order of items and the race comes last, as in
+	// the validated plan, so positions point at the stored workouts.
+	var warnings []LintFinding
+	for _, w := range found {
+		f := w.LintFinding
+		if w.workout >= 0 && w.workout < len(workouts) {
+			f.WorkoutID = workouts[w.workout].ID
+		}
+		warnings = append(warnings, f)
+	}
+
+	return &GenerateResult{Plan: plan, Workouts: workouts, Attempts: attempts, Warnings: warnings}, nil
+}
+
+// generateWithAI asks the AI for a plan and re-prompts with the problems it
+// finds, up to maxGenerateAttempts times. Invalid workouts fail generation;
+// coaching-rule findings left after the last attempt are returned as
+// warnings.
+func (s *GenerateService) generateWithAI(ctx context.Context, meter *aiMeter, input GenerateInput, progress func(phase string)) ([]BulkWorkoutInput, []generatedWarning, int, error) {
+	userPrompt := buildUserPrompt(input)
+	prompt := userPrompt
+	var problems []string
+	for attempt := 1; attempt <= maxGenerateAttempts; attempt++ {
+		progress(PhasePrompting)
+		raw, err := meter.complete(ctx, ai.CompletionRequest{
+			SystemPrompt: buildSystemPrompt(),
+			UserPrompt:   prompt,
+		})
+		if err != nil {
+			return nil, nil, attempt, fmt.Errorf("%w: %v", ErrAIGeneration, err)
+		}
+
+		progress(PhaseParsing)
+		var findings []generatedWarning
+		items, err := parseWorkouts(raw)
+		if err != nil {
+			problems = []string{fmt.Sprintf("failed to parse AI response: %v", err)}
+		} else {
+			progress(PhaseValidating)
+			problems, findings = validateGeneratedWorkouts(input, items)
+		}
+		if len(problems) == 0 && (len(findings) == 0 || attempt == maxGenerateAttempts) {
+			return items, findings, attempt, nil
+		}
+
+		for _, f := range findings {
+			problems = append(problems, f.Message)
+		}
+		prompt = buildRepairPrompt(userPrompt, raw, problems)
+	}
+	return nil, nil, maxGenerateAttempts, fmt.Errorf("%w: still invalid after %d attempts: %s", ErrAIGeneration, maxGenerateAttempts, strings.Join(problems, "; "))
+}
+
+// generatedWarning is a linter finding on a generated plan. The finding's
+// WorkoutID is left empty, since the plan is not stored yet; workout is the
+// position of the workout it is about in the stored plan, items first and
+// the race last, or -1 if it is not about a single workout.
+type generatedWarning struct {
+	LintFinding
+	workout int
+}
+
+// validateGeneratedWorkouts checks AI output. problems are violations that
+// would make CreateBatch fail or store a broken plan; warnings are the plan
+// linter's findings for the plan as it would be stored, race included.
+func validateGeneratedWorkouts(input GenerateInput, items []BulkWorkoutInput) (problems []string, warnings []generatedWarning) {
+	if problems := validateWorkoutItems(items, 1, input.Weeks); len(problems) > 0 {
+		return problems, nil
+	}
+
+	plan := &model.TrainingPlan{
+		EndDate:   input.EndDate,
+		Weeks:     input.Weeks,
+		StartDate: StartDateFor(input.EndDate, input.Weeks),
+	}
+	// The linter needs IDs; positions maps them back.
+	positions := map[model.WorkoutID]int{}
+	placeholder := func(i int) model.WorkoutID {
+		id := model.WorkoutID(fmt.Sprintf("workout-%d", i+1))
+		positions[id] = i
+		return id
+	}
+	workouts := make([]*model.Workout, 0, len(items)+1)
+	for i, item := range items {
+		workouts = append(workouts, &model.Workout{
+			ID:       placeholder(i),
+			RunType:  item.RunType,
+			Day:      plan.StartDate.AddDate(0, 0, (item.Week-1)*7+(item.DayOfWeek-1)),
+			Status:   "pending",
+			Distance: item.Distance,
+		})
+	}
+	workouts = append(workouts, &model.Workout{
+		ID:      placeholder(len(items)),
+		RunType: "race",
+		Day:     input.EndDate,
+		Status:  "pending",
+	})
+
+	for _, f := range NewPlanValidator().Validate(plan, workouts) {
+		pos, ok := positions[f.WorkoutID]
+		if !ok {
+			pos = -1
+		}
+		f.WorkoutID = ""
+		warnings = append(warnings, generatedWarning{LintFinding: f, workout: pos})
+	}
+	return nil, warnings
+}
+
+// validateWorkoutItems checks the fields of AI-proposed workouts, which must
+// fall in weeks fromWeek to toWeek. Workouts are numbered from 1 in the
+// messages, matching the order of the response.
+func validateWorkoutItems(items []BulkWorkoutInput, fromWeek, toWeek int) []string {
+	var problems []string
+	for i, item := range items {
+		n := i + 1
+		switch item.RunType {
+		case "easy_run", "intervals", "long_run", "tempo_run":
+		default:
+			problems = append(problems, fmt.Sprintf("workout %d: runType %q is not allowed, use easy_run, intervals, long_run or tempo_run", n, item.RunType))
+		}
+		if item.Week < fromWeek || item.Week > toWeek {
+			problems = append(problems, fmt.Sprintf("workout %d: week %d must be between %d and %d", n, item.Week, fromWeek, toWeek))
+		}
+		if item.DayOfWeek < 1 || item.DayOfWeek > 7 {
+			problems = append(problems, fmt.Sprintf("workout %d: dayOfWeek %d must be between 1 (Monday) and 7 (Sunday)", n, item.DayOfWeek))
+		}
+		if item.Distance <= 0 && len(item.Steps) == 0 {
+			problems = append(problems, fmt.Sprintf("workout %d: distance must be a positive whole number", n))
+		}
+		if len(item.Steps) > 0 {
+			if err := validateSteps(item.Steps); err != nil {
+				problems = append(problems, fmt.Sprintf("workout %d: %v", n, err))
+			}
+		}
+	}
+	return problems
 }
 
 func validateGenerateInput(input GenerateInput) error {
@@ -103,6 +301,9 @@ func validateGenerateInput(input GenerateInput) error {
 	if _, ok := RaceGoalDistances[input.RaceGoal]; !ok {
 		return fmt.Errorf("%w: raceGoal must be one of: 5k, 10k, halfmarathon, marathon", ErrInvalidInput)
 	}
+	if input.Strategy != "" && input.Strategy != StrategyAI && input.Strategy != StrategyRules {
+		return fmt.Errorf("%w: strategy must be one of: ai, rules", ErrInvalidInput)
+	}
 	return nil
 }
 
@@ -142,6 +343,21 @@ Each workout: {"runType": string, "week": int, "dayOfWeek": int, "description":
 Distance is in kilometers as whole integers. Do not include any text outside the JSON object.`
 }
 
+// buildRepairPrompt repeats the request with the previous answer and what
+// was wrong with it, since completions carry no conversation history.
+func buildRepairPrompt(userPrompt, previous string, problems []string) string {
+	var b strings.Builder
+	b.WriteString(userPrompt)
+	b.WriteString("\n\nYour previous response was:\n")
+	b.WriteString(previous)
+	b.WriteString("\n\nIt has these problems:\n")
+	for _, p := range problems {
+		b.WriteString("- " + p + "\n")
+	}
+	b.WriteString("Return the complete corrected plan as JSON, following all rules.")
+	return b.String()
+}
+
 func buildUserPrompt(input GenerateInput) string {
 	return fmt.Sprintf(
 		"Create a %d-week training plan with %d runs per week. Base weekly volume: %.1f km. "+
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,11 +17,15 @@ import (
 
 type mockAIClient struct {
 	response string
+	usage    ai.Usage
 	err      error
 }
 
-func (m *mockAIClient) Complete(_ context.Context, _ ai.CompletionRequest) (string, error) {
-	return m.response, m.err
+func (m *mockAIClient) Complete(_ context.Context, _ ai.CompletionRequest) (*ai.CompletionResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &ai.CompletionResponse{Content: m.response, Usage: m.usage}, nil
 }
 
 func validAIResponse() string {
@@ -46,7 +52,7 @@ func setupGenerateTest(mockClient ai.Client) (*GenerateService, *TrainingPlanSer
 	workoutStore := mem.NewMemWorkoutStore()
 	planSvc := NewTrainingPlanService(planStore)
 	workoutSvc := NewWorkoutService(workoutStore)
-	genSvc := NewGenerateService(mockClient, planSvc, workoutSvc)
+	genSvc := NewGenerateService(mockClient, unlimitedUsage(), planSvc, workoutSvc)
 	return genSvc, planSvc, workoutSvc
 }
 
@@ -249,3 +255,163 @@ func TestParseWorkouts(t *testing.T) {
 		assert.Equal(t, 18.0, items[1].Distance)
 	})
 }
+
+// sequenceAIClient returns responses in order, repeating the last one, and
+// records the prompts it received.
+type sequenceAIClient struct {
+	responses []string
+	prompts   []string
+}
+
+func (m *sequenceAIClient) Complete(_ context.Context, req ai.CompletionRequest) (*ai.CompletionResponse, error) {
+	m.prompts = append(m.prompts, req.UserPrompt)
+	i := len(m.prompts) - 1
+	if i >= len(m.responses) {
+		i = len(m.responses) - 1
+	}
+	return &ai.CompletionResponse{Content: m.responses[i]}, nil
+}
+
+// cleanAIResponse is a plan that passes every coaching rule.
+func cleanAIResponse(t *testing.T, input GenerateInput) string {
+	t.Helper()
+	in := input
+	in.Strategy = StrategyRules
+	raw, err := json.Marshal(aiResponse{Workouts: BuildRuleBasedWorkouts(in)})
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func TestGenerateService_RepairLoop(t *testing.T) {
+	input := validInput()
+	input.EndDate = time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC) // Sunday
+
+	t.Run("valid plan needs one attempt", func(t *testing.T) {
+		mock := &sequenceAIClient{responses: []string{cleanAIResponse(t, input)}}
+		genSvc, _, _ := setupGenerateTest(mock)
+
+		result, err := genSvc.GenerateWithReport(context.Background(), model.UserID("user1"), input)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Attempts)
+		assert.Empty(t, result.Warnings)
+		assert.Len(t, mock.prompts, 1)
+	})
+
+	t.Run("re-prompts with errors until valid", func(t *testing.T) {
+		invalid := `{"workouts": [
+			{"runType": "easy_run", "week": 0, "dayOfWeek": 9, "description": "", "distance": 5},
+			{"runType": "race", "week": 1, "dayOfWeek": 6, "description": "", "distance": 10}
+		]}`
+		mock := &sequenceAIClient{responses: []string{"not json", invalid, cleanAIResponse(t, input)}}
+		genSvc, _, _ := setupGenerateTest(mock)
+
+		result, err := genSvc.GenerateWithReport(context.Background(), model.UserID("user1"), input)
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Attempts)
+		assert.Empty(t, result.Warnings)
+
+		require.Len(t, mock.prompts, 3)
+		assert.Contains(t, mock.prompts[1], "failed to parse AI response")
+		assert.Contains(t, mock.prompts[1], "not json")
+		assert.Contains(t, mock.prompts[2], "workout 1: week 0 must be between 1 and 8")
+		assert.Contains(t, mock.prompts[2], "workout 1: dayOfWeek 9 must be between 1 (Monday) and 7 (Sunday)")
+		assert.Contains(t, mock.prompts[2], `workout 2: runType "race" is not allowed`)
+		assert.True(t, strings.HasPrefix(mock.prompts[2], buildUserPrompt(input)))
+	})
+
+	t.Run("fails when workouts stay invalid", func(t *testing.T) {
+		mock := &sequenceAIClient{responses: []string{`{"workouts": [{"runType": "easy_run", "week": 12, "dayOfWeek": 1, "distance": 5}]}`}}
+		genSvc, planSvc, _ := setupGenerateTest(mock)
+
+		_, err := genSvc.GenerateWithReport(context.Background(), model.UserID("user1"), input)
+		assert.ErrorIs(t, err, ErrAIGeneration)
+		assert.Contains(t, err.Error(), "after 3 attempts")
+		assert.Contains(t, err.Error(), "week 12 must be between 1 and 8")
+		assert.Len(t, mock.prompts, maxGenerateAttempts)
+
+		plans, _ := planSvc.GetByUserID(model.UserID("user1"))
+		assert.Empty(t, plans)
+	})
+
+	t.Run("keeps plan with remaining rule warnings", func(t *testing.T) {
+		// Only week 1 has a long run, so the other weeks break the weekend
+		// long run rule on every attempt.
+		mock := &sequenceAIClient{responses: []string{validAIResponse()}}
+		genSvc, _, _ := setupGenerateTest(mock)
+
+		result, err := genSvc.GenerateWithReport(context.Background(), model.UserID("user1"), input)
+		require.NoError(t, err)
+		assert.Equal(t, maxGenerateAttempts, result.Attempts)
+		require.NotEmpty(t, result.Warnings)
+		assert.Contains(t, mock.prompts[1], "has no weekend long run")
+
+		ids := map[model.WorkoutID]bool{}
+		for _, w := range result.Workouts {
+			ids[w.ID] = true
+		}
+		for _, f := range result.Warnings {
+			if f.WorkoutID != "" {
+				assert.True(t, ids[f.WorkoutID], "warning %q refers to a stored workout", f.Message)
+			}
+		}
+	})
+
+	t.Run("warnings point at the stored workout", func(t *testing.T) {
+		mock := &sequenceAIClient{responses: []string{`{"workouts": [
+			{"runType": "easy_run", "week": 1, "dayOfWeek": 1, "distance": 5.0},
+			{"runType": "tempo_run", "week": 1, "dayOfWeek": 3, "distance": 8.0},
+			{"runType": "intervals", "week": 1, "dayOfWeek": 4, "distance": 8.0},
+			{"runType": "long_run", "week": 1, "dayOfWeek": 6, "distance": 12.0}
+		]}`}}
+		genSvc, _, _ := setupGenerateTest(mock)
+
+		result, err := genSvc.GenerateWithReport(context.Background(), model.UserID("user1"), input)
+		require.NoError(t, err)
+		byID := map[model.WorkoutID]*model.Workout{}
+		for _, w := range result.Workouts {
+			byID[w.ID] = w
+		}
+		var hardDays []LintFinding
+		for _, f := range result.Warnings {
+			if f.Rule == "consecutive_hard_days" {
+				hardDays = append(hardDays, f)
+			}
+		}
+		require.Len(t, hardDays, 1)
+		require.Contains(t, byID, hardDays[0].WorkoutID)
+		assert.Equal(t, "intervals", byID[hardDays[0].WorkoutID].RunType)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		mock := &mockAIClient{err: errors.New("timeout")}
+		genSvc, _, _ := setupGenerateTest(mock)
+
+		_, err := genSvc.GenerateWithReport(context.Background(), model.UserID("user1"), input)
+		assert.ErrorIs(t, err, ErrAIGeneration)
+	})
+}
+
+// TestGenerateService_SyntheticFixture runs generation through the replay
+// client. Its fixture in testdata/synthetic-ai-fixtures is not model output:
+// the response is BuildRuleBasedWorkouts' plan for validInput, with zero
+// usage, so the test covers the replay and parsing path rather than how a
+// real model answers. When a prompt changes, rebuild the fixture the same
+// way, or replace it with a real recording made by running the server with
+// AI_FIXTURES=record and generating a plan with validInput's values.
+func TestGenerateService_SyntheticFixture(t *testing.T) {
+	replay := ai.NewReplayClient("testdata/synthetic-ai-fixtures")
+	genSvc, _, _ := setupGenerateTest(replay)
+
+	result, err := genSvc.GenerateWithReport(context.Background(), model.UserID("user1"), validInput())
+	if errors.Is(err, ai.ErrFixtureNotFound) {
+		t.Fatalf("prompt has no fixture, rebuild it: %v", err)
+	}
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Empty(t, result.Warnings)
+	assert.Len(t, result.Workouts, 24)
+	assert.Equal(t, "race", result.Workouts[len(result.Workouts)-1].RunType)
+	for _, w := range result.Workouts[:len(result.Workouts)-1] {
+		assert.True(t, w.Day.Before(result.Plan.EndDate), "%s on %s is before race day", w.RunType, w.Day)
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/oidc"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+var (
+	ErrUnknownOIDCProvider = errors.New("unknown sign-in provider")
+	ErrOIDCLogin           = errors.New("sign-in with the provider failed")
+	ErrOIDCEmailUnverified = errors.New("the provider did not confirm your email address")
+	// ErrOIDCAccountUnverified guards against linking to an account someone
+	// registered with another person's address before they signed in.
+	ErrOIDCAccountUnverified = errors.New("an account with this email exists but is not verified; sign in with your password and verify the address first")
+)
+
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// OIDCLogin is a sign-in in progress. The controller keeps it in the
+// session until the provider redirects back.
+type OIDCLogin struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// URL is where to send the browser.
+	URL string `json:"-"`
+}
+
+// OIDCService signs users in with the configured OpenID Connect providers.
+// An identity is linked to the account with the same verified email, and a
+// new account without a password is created when there is none.
+type OIDCService struct {
+	providers  []*oidc.Provider
+	users      store.UserStore
+	identities store.IdentityStore
+	now        func() time.Time
+}
+
+func NewOIDCService(providers []*oidc.Provider, users store.UserStore, identities store.IdentityStore) *OIDCService {
+	return &OIDCService{providers: providers, users: users, identities: identities, now: time.Now}
+}
+
+// Providers lists the providers in configuration order.
+func (s *OIDCService) Providers() []OIDCProviderInfo {
+	out := make([]OIDCProviderInfo, 0, len(s.providers))
+	for _, p := range s.providers {
+		out = append(out, OIDCProviderInfo{Name: p.Name(), DisplayName: p.DisplayName()})
+	}
+	return out
+}
+
+func (s *OIDCService) provider(name string) (*oidc.Provider, error) {
+	for _, p := range s.providers {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, ErrUnknownOIDCProvider
+}
+
+// Begin starts a sign-in with the named provider. redirectURI is the
+// callback the provider sends the browser back to.
+func (s *OIDCService) Begin(ctx context.Context, name, redirectURI string) (*OIDCLogin, error) {
+	p, err := s.provider(name)
+	if err != nil {
+		return nil, err
+	}
+	login := &OIDCLogin{
+		Provider: name,
+		State:    oidc.RandomString(),
+		Nonce:    oidc.RandomString(),
+		Verifier: oidc.RandomString(),
+	}
+	login.URL, err = p.AuthCodeURL(ctx, redirectURI, login.State, login.Nonce, login.Verifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLogin, err)
+	}
+	return login, nil
+}
+
+// Complete redeems the code from the provider's redirect and returns the
+// signed-in user, linking or creating the account on first sign-in.
+func (s *OIDCService) Complete(ctx context.Context, login *OIDCLogin, redirectURI, code string) (*model.User, error) {
+	p, err := s.provider(login.Provider)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := p.Exchange(ctx, redirectURI, code, login.Verifier, login.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLogin, err)
+	}
+
+	identity, err := s.identities.GetBySubject(login.Provider, claims.Subject)
+	switch {
+	case err == nil:
+		return s.users.GetUserByID(identity.UserID)
+	case !errors.Is(err, store.ErrNotFound):
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailUnverified
+	}
+	user, err := s.users.GetUserByEmail(claims.Email)
+	switch {
+	case err == nil:
+		if user.EmailVerifiedAt == nil {
+			return nil, ErrOIDCAccountUnverified
+		}
+	case errors.Is(err, store.ErrNotFound):
+		// The account has no password; one can be set through the reset
+		// flow.
+		user, err = s.users.CreateUser(claims.Email, []byte{})
+		if err != nil {
+			return nil, err
+		}
+		now := s.now().UTC()
+		if err := s.users.SetEmailVerified(user.ID, now); err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	default:
+		return nil, err
+	}
+
+	err = s.identities.Create(&model.Identity{
+		ID:        model.IdentityID(newIdentityID()),
+		UserID:    user.ID,
+		Provider:  login.Provider,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		CreatedAt: s.now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Identities lists the provider accounts linked to the user.
+func (s *OIDCService) Identities(userID model.UserID) ([]*model.Identity, error) {
+	return s.identities.ListByUser(userID)
+}
+
+func newIdentityID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
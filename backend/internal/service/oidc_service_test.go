@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/oidc"
+	"github.com/kevsommer/runplanner/internal/oidc/oidctest"
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const oidcRedirectURI = "http://app.test/api/auth/oidc/corp/callback"
+
+func setupOIDCTest(t *testing.T) (*OIDCService, *oidctest.Server, store.UserStore) {
+	idp := oidctest.NewServer("runplanner", "s3cret")
+	t.Cleanup(idp.Close)
+	users := mem.NewMemUserStore()
+	svc := NewOIDCService([]*oidc.Provider{oidc.NewProvider(idp.Config("corp"), nil)}, users, mem.NewMemIdentityStore())
+	return svc, idp, users
+}
+
+// oidcSignIn runs a whole sign-in as the IdP's current user.
+func oidcSignIn(t *testing.T, svc *OIDCService, idp *oidctest.Server) (*model.User, error) {
+	t.Helper()
+	login, err := svc.Begin(context.Background(), "corp", oidcRedirectURI)
+	require.NoError(t, err)
+	back, err := idp.Authorize(login.URL)
+	require.NoError(t, err)
+	require.Equal(t, login.State, back.Query().Get("state"))
+	return svc.Complete(context.Background(), login, oidcRedirectURI, back.Query().Get("code"))
+}
+
+func TestOIDCService_Providers(t *testing.T) {
+	svc, _, _ := setupOIDCTest(t)
+	assert.Equal(t, []OIDCProviderInfo{{Name: "corp", DisplayName: "Test provider"}}, svc.Providers())
+
+	_, err := svc.Begin(context.Background(), "other", oidcRedirectURI)
+	assert.ErrorIs(t, err, ErrUnknownOIDCProvider)
+}
+
+func TestOIDCService_Complete(t *testing.T) {
+	t.Run("creates a verified account without a password", func(t *testing.T) {
+		svc, idp, users := setupOIDCTest(t)
+		idp.SetUser(oidctest.User{Subject: "abc", Email: "new@corp.example", EmailVerified: true})
+
+		u, err := oidcSignIn(t, svc, idp)
+		require.NoError(t, err)
+		assert.Equal(t, "new@corp.example", u.Email)
+		assert.NotNil(t, u.EmailVerifiedAt)
+
+		stored, err := users.GetUserByEmail("new@corp.example")
+		require.NoError(t, err)
+		assert.NotNil(t, stored.EmailVerifiedAt)
+		_, err = NewAuthService(users).Login("new@corp.example", "")
+		assert.Error(t, err, "no password login")
+
+		identities, err := svc.Identities(u.ID)
+		require.NoError(t, err)
+		require.Len(t, identities, 1)
+		assert.Equal(t, "corp", identities[0].Provider)
+		assert.Equal(t, "abc", identities[0].Subject)
+	})
+
+	t.Run("links to the existing account by email", func(t *testing.T) {
+		svc, idp, users := setupOIDCTest(t)
+		existing, err := NewAuthService(users).Register("runner@corp.example", "password123")
+		require.NoError(t, err)
+		require.NoError(t, users.SetEmailVerified(existing.ID, time.Now()))
+		idp.SetUser(oidctest.User{Subject: "abc", Email: "runner@corp.example", EmailVerified: true})
+
+		u, err := oidcSignIn(t, svc, idp)
+		require.NoError(t, err)
+		assert.Equal(t, existing.ID, u.ID)
+		_, err = NewAuthService(users).Login("runner@corp.example", "password123")
+		assert.NoError(t, err, "password still works")
+	})
+
+	t.Run("later sign-ins use the linked identity", func(t *testing.T) {
+		svc, idp, _ := setupOIDCTest(t)
+		idp.SetUser(oidctest.User{Subject: "abc", Email: "first@corp.example", EmailVerified: true})
+		first, err := oidcSignIn(t, svc, idp)
+		require.NoError(t, err)
+
+		// The address changed at the provider; the subject is what counts.
+		idp.SetUser(oidctest.User{Subject: "abc", Email: "renamed@corp.example", EmailVerified: true})
+		again, err := oidcSignIn(t, svc, idp)
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, again.ID)
+	})
+
+	t.Run("unverified provider email is refused", func(t *testing.T) {
+		svc, idp, _ := setupOIDCTest(t)
+		idp.SetUser(oidctest.User{Subject: "abc", Email: "new@corp.example", EmailVerified: false})
+		_, err := oidcSignIn(t, svc, idp)
+		assert.ErrorIs(t, err, ErrOIDCEmailUnverified)
+	})
+
+	t.Run("unverified local account is not linked", func(t *testing.T) {
+		svc, idp, users := setupOIDCTest(t)
+		_, err := NewAuthService(users).Register("squatter@corp.example", "password123")
+		require.NoError(t, err)
+		idp.SetUser(oidctest.User{Subject: "abc", Email: "squatter@corp.example", EmailVerified: true})
+
+		_, err = oidcSignIn(t, svc, idp)
+		assert.ErrorIs(t, err, ErrOIDCAccountUnverified)
+	})
+
+	t.Run("provider errors are wrapped", func(t *testing.T) {
+		svc, idp, _ := setupOIDCTest(t)
+		login, err := svc.Begin(context.Background(), "corp", oidcRedirectURI)
+		require.NoError(t, err)
+		_, err = idp.Authorize(login.URL)
+		require.NoError(t, err)
+
+		_, err = svc.Complete(context.Background(), login, oidcRedirectURI, "bogus")
+		assert.ErrorIs(t, err, ErrOIDCLogin)
+	})
+}
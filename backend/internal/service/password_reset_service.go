@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/mail"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+const passwordResetTTL = time.Hour
+
+// PasswordResetService recovers accounts through emailed, single-use reset
+// links. Only a hash of each token is stored.
+type PasswordResetService struct {
+	users    store.UserStore
+	resets   store.PasswordResetStore
+	sessions *SessionService
+	mailer   mail.Mailer
+	appURL   string
+	now      func() time.Time
+}
+
+// NewPasswordResetService builds reset links on appURL, the frontend's
+// base URL.
+func NewPasswordResetService(users store.UserStore, resets store.PasswordResetStore, sessions *SessionService, mailer mail.Mailer, appURL string) *PasswordResetService {
+	return &PasswordResetService{
+		users:    users,
+		resets:   resets,
+		sessions: sessions,
+		mailer:   mailer,
+		appURL:   strings.TrimRight(appURL, "/"),
+		now:      time.Now,
+	}
+}
+
+// RequestReset emails a reset link to the account registered with email.
+// Unknown addresses succeed without sending anything, so callers cannot
+// tell which addresses are registered.
+func (s *PasswordResetService) RequestReset(ctx context.Context, email string) error {
+	u, err := s.users.GetUserByEmail(strings.TrimSpace(email))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token := newSecretToken()
+	now := s.now().UTC()
+	reset := &model.PasswordReset{
+		ID:        model.PasswordResetID(newResetID()),
+		UserID:    u.ID,
+		TokenHash: hashSecretToken(token),
+		ExpiresAt: now.Add(passwordResetTTL),
+		CreatedAt: now,
+	}
+	if err := s.resets.Create(reset); err != nil {
+		return err
+	}
+
+	link := s.appURL + "/reset-password?token=" + url.QueryEscape(token)
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Reset your RunPlanner password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your RunPlanner account.\n\n"+
+			"Open this link within %d minutes to choose a new password:\n%s\n\n"+
+			"If it wasn't you, ignore this email; your password stays unchanged.\n",
+			int(passwordResetTTL.Minutes()), link),
+	})
+	if err != nil {
+		return fmt.Errorf("send reset email: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with a token from RequestReset. The
+// token is consumed, the user's other outstanding tokens stop working and
+// every session is logged out.
+func (s *PasswordResetService) ResetPassword(token, password string) error {
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+	reset, err := s.resets.GetByTokenHash(hashSecretToken(token))
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	if reset.UsedAt != nil || !now.Before(reset.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	hash, err := hashPassword(password)
+	if errors.Is(err, errWeakPassword) {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.resets.MarkUsed(reset.ID, now); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	if err := s.users.SetPasswordHash(reset.UserID, hash); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeAll(reset.UserID); err != nil {
+		return err
+	}
+	return s.resets.InvalidateUser(reset.UserID, now)
+}
+
+func newResetID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/mail"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMailer keeps sent messages instead of delivering them.
+type recordingMailer struct {
+	sent []mail.Message
+	err  error
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg mail.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var resetLinkRe = regexp.MustCompile(`https?://\S+`)
+
+// mailedToken extracts the token from the link in the last email.
+func mailedToken(t *testing.T, mailer *recordingMailer) string {
+	t.Helper()
+	require.NotEmpty(t, mailer.sent)
+	link := resetLinkRe.FindString(mailer.sent[len(mailer.sent)-1].Body)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	return u.Query().Get("token")
+}
+
+func setupPasswordResetTest(t *testing.T) (*PasswordResetService, *AuthService, *recordingMailer) {
+	users := mem.NewMemUserStore()
+	mailer := &recordingMailer{}
+	resets := NewPasswordResetService(users, mem.NewMemPasswordResetStore(), NewSessionService(mem.NewMemSessionStore()), mailer, "https://runplanner.example/")
+	auth := NewAuthService(users)
+	_, err := auth.Register("runner@example.com", "password123")
+	require.NoError(t, err)
+	return resets, auth, mailer
+}
+
+func TestPasswordResetService_RequestReset(t *testing.T) {
+	t.Run("emails a reset link", func(t *testing.T) {
+		resets, _, mailer := setupPasswordResetTest(t)
+		require.NoError(t, resets.RequestReset(context.Background(), "runner@example.com"))
+
+		require.Len(t, mailer.sent, 1)
+		assert.Equal(t, "runner@example.com", mailer.sent[0].To)
+		assert.Contains(t, mailer.sent[0].Body, "https://runplanner.example/reset-password?token=")
+		assert.Len(t, mailedToken(t, mailer), 43)
+	})
+
+	t.Run("unknown email sends nothing", func(t *testing.T) {
+		resets, _, mailer := setupPasswordResetTest(t)
+		require.NoError(t, resets.RequestReset(context.Background(), "nobody@example.com"))
+		assert.Empty(t, mailer.sent)
+	})
+
+	t.Run("mail failures are reported", func(t *testing.T) {
+		resets, _, mailer := setupPasswordResetTest(t)
+		mailer.err = errors.New("connection refused")
+		err := resets.RequestReset(context.Background(), "runner@example.com")
+		assert.ErrorContains(t, err, "connection refused")
+	})
+}
+
+func TestPasswordResetService_ResetPassword(t *testing.T) {
+	t.Run("sets the new password once", func(t *testing.T) {
+		resets, auth, mailer := setupPasswordResetTest(t)
+		require.NoError(t, resets.RequestReset(context.Background(), "runner@example.com"))
+		token := mailedToken(t, mailer)
+
+		require.NoError(t, resets.ResetPassword(token, "new-password"))
+		_, err := auth.Login("runner@example.com", "new-password")
+		assert.NoError(t, err)
+		_, err = auth.Login("runner@example.com", "password123")
+		assert.Error(t, err)
+
+		assert.ErrorIs(t, resets.ResetPassword(token, "another-password"), ErrInvalidResetToken)
+	})
+
+	t.Run("logs out every session", func(t *testing.T) {
+		resets, auth, mailer := setupPasswordResetTest(t)
+		u, err := auth.Login("runner@example.com", "password123")
+		require.NoError(t, err)
+		for _, id := range []model.SessionID{"laptop", "phone"} {
+			require.NoError(t, resets.sessions.sessions.Create(&model.Session{
+				ID: id, UserID: u.ID, TokenHash: string(id), ExpiresAt: time.Now().Add(time.Hour),
+			}))
+		}
+		require.NoError(t, resets.RequestReset(context.Background(), "runner@example.com"))
+
+		require.NoError(t, resets.ResetPassword(mailedToken(t, mailer), "new-password"))
+		sessions, err := resets.sessions.List(u.ID)
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+
+	t.Run("invalidates the user's other tokens", func(t *testing.T) {
+		resets, _, mailer := setupPasswordResetTest(t)
+		require.NoError(t, resets.RequestReset(context.Background(), "runner@example.com"))
+		first := mailedToken(t, mailer)
+		require.NoError(t, resets.RequestReset(context.Background(), "runner@example.com"))
+		second := mailedToken(t, mailer)
+		require.NotEqual(t, first, second)
+
+		require.NoError(t, resets.ResetPassword(second, "new-password"))
+		assert.ErrorIs(t, resets.ResetPassword(first, "other-password"), ErrInvalidResetToken)
+	})
+
+	t.Run("expired token is rejected", func(t *testing.T) {
+		resets, _, mailer := setupPasswordResetTest(t)
+		require.NoError(t, resets.RequestReset(context.Background(), "runner@example.com"))
+		token := mailedToken(t, mailer)
+
+		resets.now = func() time.Time { return time.Now().Add(passwordResetTTL + time.Minute) }
+		assert.ErrorIs(t, resets.ResetPassword(token, "new-password"), ErrInvalidResetToken)
+	})
+
+	t.Run("unknown token is rejected", func(t *testing.T) {
+		resets, _, _ := setupPasswordResetTest(t)
+		assert.ErrorIs(t, resets.ResetPassword("", "new-password"), ErrInvalidResetToken)
+		assert.ErrorIs(t, resets.ResetPassword("not-a-token", "new-password"), ErrInvalidResetToken)
+	})
+
+	t.Run("weak password keeps the token usable", func(t *testing.T) {
+		resets, _, mailer := setupPasswordResetTest(t)
+		require.NoError(t, resets.RequestReset(context.Background(), "runner@example.com"))
+		token := mailedToken(t, mailer)
+
+		err := resets.ResetPassword(token, "short")
+		assert.ErrorIs(t, err, ErrInvalidInput)
+		assert.Contains(t, err.Error(), "at least 8 chars")
+		assert.NoError(t, resets.ResetPassword(token, "long-enough"))
+	})
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintFinding is one rule violation. It lives in model so generation jobs
+// can store the findings of the plan they produced.
+type LintFinding = model.LintFinding
+
+// PlanValidator checks a plan against the coaching rules used for
+// generation: progression, deloads, taper, long runs and hard-day spacing.
+type PlanValidator struct {
+	// MaxWeeklyIncrease is the allowed volume growth over the previous
+	// normal week, e.g. 0.10 for 10%.
+	MaxWeeklyIncrease float64
+	// RoundingKm is added to the allowed increase, since plans use whole km.
+	RoundingKm float64
+	// DeloadEvery is the longest stretch of weeks allowed without a deload.
+	DeloadEvery int
+	// DeloadDrop is the minimum volume reduction that counts as a deload.
+	DeloadDrop float64
+	// TaperWeeks at the end of the plan are exempt from progression checks.
+	TaperWeeks int
+}
+
+func NewPlanValidator() *PlanValidator {
+	return &PlanValidator{
+		MaxWeeklyIncrease: weeklyProgression - 1,
+		RoundingKm:        1,
+		DeloadEvery:       deloadEvery,
+		DeloadDrop:        0.20,
+		TaperWeeks:        taperWeeks,
+	}
+}
+
+var hardRunTypes = map[string]bool{
+	"intervals": true,
+	"tempo_run": true,
+	"long_run":  true,
+	"race":      true,
+}
+
+// Validate returns the findings for a plan, ordered by week and day.
+func (v *PlanValidator) Validate(plan *model.TrainingPlan, workouts []*model.Workout) []LintFinding {
+	detail := BuildPlanDetail(plan, workouts)
+	findings := v.checkOutsideRange(plan, workouts)
+	findings = append(findings, v.checkVolume(detail)...)
+	findings = append(findings, v.checkLongRuns(detail)...)
+	findings = append(findings, v.checkRaceWeek(detail)...)
+	findings = append(findings, v.checkHardDays(detail)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Week != findings[j].Week {
+			return findings[i].Week < findings[j].Week
+		}
+		return findings[i].Day < findings[j].Day
+	})
+	if findings == nil {
+		findings = []LintFinding{}
+	}
+	return findings
+}
+
+func (v *PlanValidator) checkOutsideRange(plan *model.TrainingPlan, workouts []*model.Workout) []LintFinding {
+	start := plan.StartDate.Format("2006-01-02")
+	end := plan.EndDate.Format("2006-01-02")
+
+	var findings []LintFinding
+	for _, w := range workouts {
+		day := w.Day.Format("2006-01-02")
+		if day >= start && day <= end {
+			continue
+		}
+		week := int(w.Day.Sub(plan.StartDate).Hours()/24)/7 + 1
+		if day < start || week > plan.Weeks {
+			week = 0
+		}
+		findings = append(findings, LintFinding{
+			Rule:      "outside_range",
+			Severity:  LintError,
+			Week:      week,
+			Day:       day,
+			WorkoutID: w.ID,
+			Message:   fmt.Sprintf("workout on %s is outside the plan (%s to %s)", day, start, end),
+		})
+	}
+	return findings
+}
+
+// checkVolume flags weekly increases above MaxWeeklyIncrease and stretches
+// without a deload. A week after a deload is compared with the week before
+// the deload, so returning to normal volume is not a jump.
+func (v *PlanValidator) checkVolume(detail *PlanDetail) []LintFinding {
+	var findings []LintFinding
+	lastBuild := len(detail.WeeksSummary) - v.TaperWeeks
+	reference := 0.0
+	streak := 0
+	for i, week := range detail.WeeksSummary {
+		if i >= lastBuild {
+			break
+		}
+		km := week.PlannedKm
+		if km == 0 {
+			streak = 0
+			continue
+		}
+
+		if reference > 0 && km <= reference*(1-v.DeloadDrop) {
+			streak = 0
+			continue
+		}
+		if limit := reference*(1+v.MaxWeeklyIncrease) + v.RoundingKm; reference > 0 && km > limit {
+			findings = append(findings, LintFinding{
+				Rule:     "volume_jump",
+				Severity: LintWarning,
+				Week:     week.Number,
+				Message:  fmt.Sprintf("week %d has %g km, up %.0f%% from %g km", week.Number, km, (km/reference-1)*100, reference),
+			})
+		}
+		reference = km
+
+		streak++
+		if streak == v.DeloadEvery {
+			findings = append(findings, LintFinding{
+				Rule:     "missing_deload",
+				Severity: LintWarning,
+				Week:     week.Number,
+				Message:  fmt.Sprintf("no deload week in weeks %d-%d", week.Number-v.DeloadEvery+1, week.Number),
+			})
+			streak = 0
+		}
+	}
+	return findings
+}
+
+// checkLongRuns requires a long run on Saturday or Sunday in every week but
+// the race week.
+func (v *PlanValidator) checkLongRuns(detail *PlanDetail) []LintFinding {
+	var findings []LintFinding
+	for _, week := range detail.WeeksSummary[:max(len(detail.WeeksSummary)-1, 0)] {
+		var weekday []*model.Workout
+		weekend := false
+		for dayIdx, day := range week.Days {
+			for _, w := range day.Workouts {
+				if w.RunType != "long_run" {
+					continue
+				}
+				if dayIdx >= 5 {
+					weekend = true
+				} else {
+					weekday = append(weekday, w)
+				}
+			}
+		}
+		switch {
+		case weekend:
+		case len(weekday) > 0:
+			findings = append(findings, LintFinding{
+				Rule:      "missing_long_run",
+				Severity:  LintWarning,
+				Week:      week.Number,
+				Day:       weekday[0].Day.Format("2006-01-02"),
+				WorkoutID: weekday[0].ID,
+				Message:   fmt.Sprintf("week %d has its long run on a weekday instead of the weekend", week.Number),
+			})
+		default:
+			findings = append(findings, LintFinding{
+				Rule:     "missing_long_run",
+				Severity: LintWarning,
+				Week:     week.Number,
+				Message:  fmt.Sprintf("week %d has no weekend long run", week.Number),
+			})
+		}
+	}
+	return findings
+}
+
+func (v *PlanValidator) checkRaceWeek(detail *PlanDetail) []LintFinding {
+	if len(detail.WeeksSummary) == 0 {
+		return nil
+	}
+	week := detail.WeeksSummary[len(detail.WeeksSummary)-1]
+
+	var findings []LintFinding
+	for _, day := range week.Days {
+		for _, w := range day.Workouts {
+			if w.RunType != "long_run" && w.RunType != "tempo_run" && w.RunType != "intervals" {
+				continue
+			}
+			findings = append(findings, LintFinding{
+				Rule:      "race_week",
+				Severity:  LintError,
+				Week:      week.Number,
+				Day:       day.Date,
+				WorkoutID: w.ID,
+				Message:   fmt.Sprintf("race week should only have easy runs, found %s on %s", w.RunType, day.DayName),
+			})
+		}
+	}
+	return findings
+}
+
+// checkHardDays flags hard sessions on consecutive days, including across
+// week boundaries. Skipped workouts are ignored.
+func (v *PlanValidator) checkHardDays(detail *PlanDetail) []LintFinding {
+	var findings []LintFinding
+	var prev *model.Workout
+	for _, week := range detail.WeeksSummary {
+		for _, day := range week.Days {
+			var hard *model.Workout
+			for _, w := range day.Workouts {
+				if hardRunTypes[w.RunType] && w.Status != "skipped" {
+					hard = w
+					break
+				}
+			}
+			if hard != nil && prev != nil {
+				findings = append(findings, LintFinding{
+					Rule:      "consecutive_hard_days",
+					Severity:  LintWarning,
+					Week:      week.Number,
+					Day:       day.Date,
+					WorkoutID: hard.ID,
+					Message:   fmt.Sprintf("%s on %s follows %s the day before", hard.RunType, day.DayName, prev.RunType),
+				})
+			}
+			prev = hard
+		}
+	}
+	return findings
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lintPlan() *model.TrainingPlan {
+	return &model.TrainingPlan{
+		ID:        "plan-1",
+		StartDate: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),  // Monday
+		EndDate:   time.Date(2025, 4, 13, 0, 0, 0, 0, time.UTC), // Sunday of week 6
+		Weeks:     6,
+	}
+}
+
+func lintWorkout(plan *model.TrainingPlan, week, dayOfWeek int, runType string, km float64) *model.Workout {
+	return &model.Workout{
+		ID:       model.WorkoutID(runType + "-" + string(rune('0'+week)) + string(rune('0'+dayOfWeek))),
+		PlanID:   plan.ID,
+		RunType:  runType,
+		Day:      plan.StartDate.AddDate(0, 0, (week-1)*7+dayOfWeek-1),
+		Status:   "pending",
+		Distance: km,
+	}
+}
+
+// wellFormedWeeks returns a six-week plan that passes every rule: build
+// weeks 1-3, taper 4-5 and race week 6.
+func wellFormedWeeks(plan *model.TrainingPlan) []*model.Workout {
+	var ws []*model.Workout
+	for week, km := range map[int][3]float64{1: {8, 6, 12}, 2: {9, 6, 13}, 3: {9, 7, 14}, 4: {7, 5, 11}, 5: {5, 4, 9}} {
+		ws = append(ws,
+			lintWorkout(plan, week, 2, "tempo_run", km[0]),
+			lintWorkout(plan, week, 4, "easy_run", km[1]),
+			lintWorkout(plan, week, 7, "long_run", km[2]),
+		)
+	}
+	return append(ws,
+		lintWorkout(plan, 6, 2, "easy_run", 4),
+		lintWorkout(plan, 6, 4, "easy_run", 4),
+		lintWorkout(plan, 6, 7, "race", 21),
+	)
+}
+
+func rulesOf(findings []LintFinding) []string {
+	var rules []string
+	for _, f := range findings {
+		rules = append(rules, f.Rule)
+	}
+	return rules
+}
+
+func TestPlanValidator(t *testing.T) {
+	v := NewPlanValidator()
+	plan := lintPlan()
+
+	t.Run("well formed plan has no findings", func(t *testing.T) {
+		findings := v.Validate(plan, wellFormedWeeks(plan))
+		assert.Empty(t, findings)
+		assert.NotNil(t, findings)
+	})
+
+	t.Run("volume jump", func(t *testing.T) {
+		ws := append(wellFormedWeeks(plan), lintWorkout(plan, 3, 5, "easy_run", 6))
+		findings := v.Validate(plan, ws)
+		require.Equal(t, []string{"volume_jump"}, rulesOf(findings))
+		assert.Equal(t, 3, findings[0].Week)
+		assert.Equal(t, LintWarning, findings[0].Severity)
+	})
+
+	t.Run("missing deload", func(t *testing.T) {
+		long := lintPlan()
+		long.Weeks = 9
+		long.EndDate = long.StartDate.AddDate(0, 0, 9*7-1)
+		var ws []*model.Workout
+		for week := 1; week <= 6; week++ {
+			ws = append(ws,
+				lintWorkout(long, week, 2, "easy_run", 10),
+				lintWorkout(long, week, 7, "long_run", 15))
+		}
+		findings := v.Validate(long, ws)
+		var deload []LintFinding
+		for _, f := range findings {
+			if f.Rule == "missing_deload" {
+				deload = append(deload, f)
+			}
+		}
+		require.Len(t, deload, 1)
+		assert.Equal(t, 4, deload[0].Week)
+		assert.Equal(t, "no deload week in weeks 1-4", deload[0].Message)
+	})
+
+	t.Run("week after deload is not a jump", func(t *testing.T) {
+		long := lintPlan()
+		long.Weeks = 8
+		long.EndDate = long.StartDate.AddDate(0, 0, 8*7-1)
+		var ws []*model.Workout
+		for week, km := range []float64{20, 22, 24, 14, 26} {
+			ws = append(ws,
+				lintWorkout(long, week+1, 3, "easy_run", km-12),
+				lintWorkout(long, week+1, 6, "long_run", 12))
+		}
+		assert.NotContains(t, rulesOf(v.Validate(long, ws)), "volume_jump")
+	})
+
+	t.Run("race week with long run and speed session", func(t *testing.T) {
+		ws := append(wellFormedWeeks(plan),
+			lintWorkout(plan, 6, 3, "intervals", 6),
+			lintWorkout(plan, 6, 6, "long_run", 12))
+		findings := v.Validate(plan, ws)
+		assert.Equal(t, []string{"race_week", "race_week", "consecutive_hard_days"}, rulesOf(findings))
+		assert.Equal(t, LintError, findings[0].Severity)
+		assert.Equal(t, "2025-04-09", findings[0].Day)
+	})
+
+	t.Run("consecutive hard days across weeks", func(t *testing.T) {
+		ws := append(wellFormedWeeks(plan), lintWorkout(plan, 3, 1, "intervals", 0))
+		findings := v.Validate(plan, ws)
+		require.Equal(t, []string{"consecutive_hard_days", "consecutive_hard_days"}, rulesOf(findings))
+		assert.Equal(t, "2025-03-17", findings[0].Day)
+		assert.Equal(t, "2025-03-18", findings[1].Day)
+	})
+
+	t.Run("skipped workouts are not hard days", func(t *testing.T) {
+		extra := lintWorkout(plan, 3, 1, "intervals", 0)
+		extra.Status = "skipped"
+		assert.Empty(t, v.Validate(plan, append(wellFormedWeeks(plan), extra)))
+	})
+
+	t.Run("long run missing or on a weekday", func(t *testing.T) {
+		// Week 1 runs easy on Sunday, week 2 has its long run on Friday.
+		ws := wellFormedWeeks(plan)
+		for _, w := range ws {
+			if w.RunType != "long_run" {
+				continue
+			}
+			switch w.Day.Format("2006-01-02") {
+			case "2025-03-09":
+				w.RunType = "easy_run"
+			case "2025-03-16":
+				w.Day = w.Day.AddDate(0, 0, -2)
+			}
+		}
+		findings := v.Validate(plan, ws)
+		require.Equal(t, []string{"missing_long_run", "missing_long_run"}, rulesOf(findings))
+		assert.Equal(t, 1, findings[0].Week)
+		assert.Empty(t, findings[0].Day)
+		assert.Equal(t, 2, findings[1].Week)
+		assert.Contains(t, findings[1].Message, "weekday")
+	})
+
+	t.Run("workouts outside the plan", func(t *testing.T) {
+		before := lintWorkout(plan, 1, 1, "easy_run", 5)
+		before.Day = plan.StartDate.AddDate(0, 0, -1)
+		before.ID = "before"
+		after := lintWorkout(plan, 7, 1, "easy_run", 5)
+		after.ID = "after"
+		findings := v.Validate(plan, append(wellFormedWeeks(plan), before, after))
+		require.Equal(t, []string{"outside_range", "outside_range"}, rulesOf(findings))
+		assert.Equal(t, model.WorkoutID("before"), findings[0].WorkoutID)
+		assert.Equal(t, 0, findings[0].Week)
+		assert.Equal(t, LintError, findings[1].Severity)
+	})
+}
+
+func TestPlanValidator_RuleBasedPlans(t *testing.T) {
+	for _, runs := range []int{3, 4, 5, 6} {
+		for _, goal := range []string{"5k", "10k", "halfmarathon", "marathon"} {
+			genSvc, _, _ := setupGenerateTest(nil)
+			input := ruleInput()
+			input.RunsPerWeek = runs
+			input.RaceGoal = goal
+			plan, workouts, err := genSvc.Generate(context.Background(), model.UserID("user1"), input)
+			require.NoError(t, err)
+
+			assert.Empty(t, NewPlanValidator().Validate(plan, workouts), "%d runs, %s", runs, goal)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+var ErrStaleReplan = errors.New("the plan changed since the preview")
+
+// ReplanInput selects the weeks whose pending workouts are rebuilt.
+type ReplanInput struct {
+	FromWeek int
+	ToWeek   int
+	Reason   string
+	// Today is the first day new workouts may be scheduled on.
+	Today time.Time
+}
+
+// ReplanWorkout is a workout proposed by a replan.
+type ReplanWorkout struct {
+	RunType     string              `json:"runType"`
+	Week        int                 `json:"week"`
+	DayOfWeek   int                 `json:"dayOfWeek"`
+	Day         string              `json:"day"` // YYYY-MM-DD
+	Description string              `json:"description"`
+	Distance    float64             `json:"distance"`
+	Steps       []model.WorkoutStep `json:"steps,omitempty"`
+}
+
+// ReplanDay lists what changes on one day.
+type ReplanDay struct {
+	Date    string           `json:"date"`
+	Week    int              `json:"week"`
+	DayName string           `json:"dayName"`
+	Removed []*model.Workout `json:"removed"`
+	Added   []ReplanWorkout  `json:"added"`
+}
+
+type ReplanWeek struct {
+	Number   int     `json:"number"`
+	BeforeKm float64 `json:"beforeKm"`
+	AfterKm  float64 `json:"afterKm"`
+}
+
+// ReplanPreview is a proposed change to a plan. Nothing is stored until the
+// preview is passed to Apply.
+type ReplanPreview struct {
+	FromWeek int    `json:"fromWeek"`
+	ToWeek   int    `json:"toWeek"`
+	Reason   string `json:"reason"`
+	// Replace lists the pending workouts the change deletes. Apply refuses
+	// the change if they no longer match the plan.
+	Replace  []model.WorkoutID `json:"replace"`
+	Workouts []ReplanWorkout   `json:"workouts"`
+	Days     []ReplanDay       `json:"days"` // only days that change
+	Weeks    []ReplanWeek      `json:"weeks"`
+	Attempts int               `json:"attempts"`
+	// Warnings are coaching-rule findings in the replanned weeks.
+	Warnings []LintFinding `json:"warnings"`
+}
+
+// ReplanService asks the AI to rework part of an existing plan, e.g. after
+// an injury or a missed week. Completed and skipped workouts are never
+// touched.
+type ReplanService struct {
+	ai       ai.Client
+	usage    *AIUsageService
+	workouts *WorkoutService
+}
+
+func NewReplanService(aiClient ai.Client, usage *AIUsageService, workouts *WorkoutService) *ReplanService {
+	return &ReplanService{ai: aiClient, usage: usage, workouts: workouts}
+}
+
+// Preview proposes new workouts for the pending days of the week range.
+func (s *ReplanService) Preview(ctx context.Context, plan *model.TrainingPlan, input ReplanInput) (*ReplanPreview, error) {
+	if s.ai == nil {
+		return nil, ErrAINotConfigured
+	}
+	if err := validateReplanInput(plan, input); err != nil {
+		return nil, err
+	}
+	workouts, err := s.workouts.GetByPlanID(plan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.usage.reserve(plan.UserID); err != nil {
+		return nil, err
+	}
+	meter := &aiMeter{client: s.ai}
+	preview, err := s.propose(ctx, meter, plan, workouts, input)
+	if err := s.usage.finish(plan.UserID, FeatureReplan, meter, err); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// propose runs the AI repair loop for Preview.
+func (s *ReplanService) propose(ctx context.Context, meter *aiMeter, plan *model.TrainingPlan, workouts []*model.Workout, input ReplanInput) (*ReplanPreview, error) {
+	replace := replaceableWorkouts(plan, workouts, input)
+	userPrompt := buildReplanPrompt(plan, workouts, input)
+	prompt := userPrompt
+	var problems []string
+	for attempt := 1; attempt <= maxGenerateAttempts; attempt++ {
+		raw, err := meter.complete(ctx, ai.CompletionRequest{
+			SystemPrompt: buildReplanSystemPrompt(),
+			UserPrompt:   prompt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrAIGeneration, err)
+		}
+
+		items, err := parseWorkouts(raw)
+		if err != nil {
+			problems = []string{fmt.Sprintf("failed to parse AI response: %v", err)}
+		} else {
+			problems = validateReplanWorkouts(plan, input, items)
+		}
+		if len(problems) == 0 {
+			preview := buildReplanPreview(plan, workouts, replace, items, input)
+			preview.Attempts = attempt
+			return preview, nil
+		}
+		prompt = buildRepairPrompt(userPrompt, raw, problems)
+	}
+	return nil, fmt.Errorf("%w: still invalid after %d attempts: %s", ErrAIGeneration, maxGenerateAttempts, strings.Join(problems, "; "))
+}
+
+// Apply stores a previewed change: the new workouts are created first and
+// the replaced ones deleted afterwards, so a failure leaves the old plan in
+// place. It fails with ErrStaleReplan if the pending workouts in the range
+// differ from replace.
+func (s *ReplanService) Apply(plan *model.TrainingPlan, input ReplanInput, replace []model.WorkoutID, items []BulkWorkoutInput) ([]*model.Workout, error) {
+	if err := validateReplanInput(plan, input); err != nil {
+		return nil, err
+	}
+	workouts, err := s.workouts.GetByPlanID(plan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := replaceableWorkouts(plan, workouts, input)
+	if len(current) != len(replace) {
+		return nil, ErrStaleReplan
+	}
+	want := map[model.WorkoutID]bool{}
+	for _, id := range replace {
+		want[id] = true
+	}
+	for _, w := range current {
+		if !want[w.ID] {
+			return nil, ErrStaleReplan
+		}
+	}
+
+	if problems := validateReplanWorkouts(plan, input, items); len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidInput, strings.Join(problems, "; "))
+	}
+
+	created, err := s.workouts.CreateBatch(plan, items)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range current {
+		if err := s.workouts.Delete(w.ID); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+func validateReplanInput(plan *model.TrainingPlan, input ReplanInput) error {
+	if input.FromWeek < 1 || input.ToWeek > plan.Weeks || input.FromWeek > input.ToWeek {
+		return fmt.Errorf("%w: weeks must be a range within 1 and %d", ErrInvalidInput, plan.Weeks)
+	}
+	if input.Today.IsZero() {
+		return fmt.Errorf("%w: today is required", ErrInvalidInput)
+	}
+	if lastDay := plan.StartDate.AddDate(0, 0, input.ToWeek*7-1); dateOnly(input.Today).After(lastDay) {
+		return fmt.Errorf("%w: week %d is already over", ErrInvalidInput, input.ToWeek)
+	}
+	return nil
+}
+
+// replaceableWorkouts returns the pending workouts in the week range from
+// today on. The race itself is never replaced.
+func replaceableWorkouts(plan *model.TrainingPlan, workouts []*model.Workout, input ReplanInput) []*model.Workout {
+	from := plan.StartDate.AddDate(0, 0, (input.FromWeek-1)*7)
+	if today := dateOnly(input.Today); today.After(from) {
+		from = today
+	}
+	end := plan.StartDate.AddDate(0, 0, input.ToWeek*7)
+
+	var out []*model.Workout
+	for _, w := range workouts {
+		if w.Status != "pending" || w.RunType == "race" || w.Day.Before(from) || !w.Day.Before(end) {
+			continue
+		}
+		out = append(out, w)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Day.Before(out[j].Day) })
+	return out
+}
+
+// validateReplanWorkouts extends the generation checks with the replan
+// range: nothing before today and nothing on or after race day.
+func validateReplanWorkouts(plan *model.TrainingPlan, input ReplanInput, items []BulkWorkoutInput) []string {
+	problems := validateWorkoutItems(items, input.FromWeek, input.ToWeek)
+	today := dateOnly(input.Today)
+	for i, item := range items {
+		if item.Week < input.FromWeek || item.Week > input.ToWeek || item.DayOfWeek < 1 || item.DayOfWeek > 7 {
+			continue
+		}
+		day := plan.StartDate.AddDate(0, 0, (item.Week-1)*7+(item.DayOfWeek-1))
+		if day.Before(today) {
+			problems = append(problems, fmt.Sprintf("workout %d: week %d day %d is in the past, the earliest day is %s", i+1, item.Week, item.DayOfWeek, today.Format("2006-01-02")))
+		}
+		if !day.Before(dateOnly(plan.EndDate)) {
+			problems = append(problems, fmt.Sprintf("workout %d: week %d day %d is not before race day", i+1, item.Week, item.DayOfWeek))
+		}
+	}
+	return problems
+}
+
+func buildReplanPreview(plan *model.TrainingPlan, workouts, replace []*model.Workout, items []BulkWorkoutInput, input ReplanInput) *ReplanPreview {
+	preview := &ReplanPreview{
+		FromWeek: input.FromWeek,
+		ToWeek:   input.ToWeek,
+		Reason:   input.Reason,
+		Replace:  []model.WorkoutID{},
+		Workouts: []ReplanWorkout{},
+		Days:     []ReplanDay{},
+		Warnings: []LintFinding{},
+	}
+
+	days := map[string]*ReplanDay{}
+	dayFor := func(day time.Time) *ReplanDay {
+		key := day.Format("2006-01-02")
+		if d, ok := days[key]; ok {
+			return d
+		}
+		offset := int(day.Sub(plan.StartDate).Hours() / 24)
+		d := &ReplanDay{Date: key, Week: offset/7 + 1, DayName: dayNames[offset%7], Removed: []*model.Workout{}, Added: []ReplanWorkout{}}
+		days[key] = d
+		return d
+	}
+
+	removed := map[model.WorkoutID]bool{}
+	for _, w := range replace {
+		removed[w.ID] = true
+		preview.Replace = append(preview.Replace, w.ID)
+		d := dayFor(w.Day)
+		d.Removed = append(d.Removed, w)
+	}
+
+	// The plan after the change, for the weekly totals and the linter. New
+	// workouts get placeholder IDs.
+	after := make([]*model.Workout, 0, len(workouts)+len(items))
+	for _, w := range workouts {
+		if !removed[w.ID] {
+			after = append(after, w)
+		}
+	}
+	for i, item := range items {
+		day := plan.StartDate.AddDate(0, 0, (item.Week-1)*7+(item.DayOfWeek-1))
+		rw := ReplanWorkout{
+			RunType:     item.RunType,
+			Week:        item.Week,
+			DayOfWeek:   item.DayOfWeek,
+			Day:         day.Format("2006-01-02"),
+			Description: item.Description,
+			Distance:    item.Distance,
+			Steps:       item.Steps,
+		}
+		preview.Workouts = append(preview.Workouts, rw)
+		d := dayFor(day)
+		d.Added = append(d.Added, rw)
+		after = append(after, &model.Workout{
+			ID:       model.WorkoutID(fmt.Sprintf("new-%d", i+1)),
+			PlanID:   plan.ID,
+			RunType:  item.RunType,
+			Day:      day,
+			Status:   "pending",
+			Distance: item.Distance,
+		})
+	}
+
+	for _, d := range days {
+		preview.Days = append(preview.Days, *d)
+	}
+	sort.Slice(preview.Days, func(i, j int) bool { return preview.Days[i].Date < preview.Days[j].Date })
+
+	before := BuildPlanDetail(plan, workouts)
+	changed := BuildPlanDetail(plan, after)
+	for week := input.FromWeek; week <= input.ToWeek; week++ {
+		preview.Weeks = append(preview.Weeks, ReplanWeek{
+			Number:   week,
+			BeforeKm: before.WeeksSummary[week-1].PlannedKm,
+			AfterKm:  changed.WeeksSummary[week-1].PlannedKm,
+		})
+	}
+
+	for _, f := range NewPlanValidator().Validate(plan, after) {
+		if f.Week >= input.FromWeek && f.Week <= input.ToWeek {
+			preview.Warnings = append(preview.Warnings, f)
+		}
+	}
+	return preview
+}
+
+func buildReplanSystemPrompt() string {
+	return `You are a running coach that adjusts existing training plans. You output JSON only.
+You receive the current plan with the athlete's completion history and a reason for the change, for example an injury, illness or a missed week.
+Return ONLY the new workouts for the requested weeks; they replace every pending workout in those weeks from today on. Completed and skipped workouts stay as they are and count towards the week.
+
+Rules:
+- Respect the reason: after an injury or illness rebuild gradually with easy running before any speed session
+- Do not increase weekly volume by more than 10% over the previous normal week
+- Keep deload weeks (every 4th week) and the taper before the race
+- Every week except the race week needs exactly one long_run on a weekend (Saturday=6 or Sunday=7)
+- Do not schedule hard sessions (intervals, tempo_run, long_run) on consecutive days
+- Do not schedule workouts before today or on or after race day
+- All distances MUST be whole integers (e.g. 8, 12, 15), never decimals
+- Each workout needs a brief description
+
+Valid run types: easy_run, intervals, long_run, tempo_run
+DayOfWeek: 1=Monday through 7=Sunday
+
+Respond with a JSON object: {"workouts": [...]}
+Each workout: {"runType": string, "week": int, "dayOfWeek": int, "description": string, "distance": number}
+Distance is in kilometers as whole integers. Do not include any text outside the JSON object.`
+}
+
+// replanPromptWeek is the compact view of a plan week sent to the AI.
+type replanPromptWeek struct {
+	Week      int                   `json:"week"`
+	PlannedKm float64               `json:"plannedKm"`
+	DoneKm    float64               `json:"doneKm"`
+	Workouts  []replanPromptWorkout `json:"workouts"`
+}
+
+type replanPromptWorkout struct {
+	DayOfWeek   int     `json:"dayOfWeek"`
+	Date        string  `json:"date"`
+	RunType     string  `json:"runType"`
+	Distance    float64 `json:"distance"`
+	Description string  `json:"description,omitempty"`
+	Status      string  `json:"status"`
+	Notes       string  `json:"notes,omitempty"`
+}
+
+func buildReplanPrompt(plan *model.TrainingPlan, workouts []*model.Workout, input ReplanInput) string {
+	detail := BuildPlanDetail(plan, workouts)
+	weeks := make([]replanPromptWeek, 0, len(detail.WeeksSummary))
+	for _, week := range detail.WeeksSummary {
+		pw := replanPromptWeek{Week: week.Number, PlannedKm: week.PlannedKm, DoneKm: week.DoneKm, Workouts: []replanPromptWorkout{}}
+		for dayIdx, day := range week.Days {
+			for _, w := range day.Workouts {
+				pw.Workouts = append(pw.Workouts, replanPromptWorkout{
+					DayOfWeek:   dayIdx + 1,
+					Date:        day.Date,
+					RunType:     w.RunType,
+					Distance:    w.Distance,
+					Description: w.Description,
+					Status:      w.Status,
+					Notes:       w.Notes,
+				})
+			}
+		}
+		weeks = append(weeks, pw)
+	}
+	current, _ := json.Marshal(weeks)
+
+	reason := input.Reason
+	if reason == "" {
+		reason = "not given"
+	}
+
+	var b strings.Builder
+	writePlanIntro(&b, plan, input.Today)
+	fmt.Fprintf(&b, "Reason for the change: %s\n", reason)
+	fmt.Fprintf(&b, "Create new workouts for weeks %d to %d.\n\n", input.FromWeek, input.ToWeek)
+	b.WriteString("Current plan with completion history:\n")
+	b.Write(current)
+	return b.String()
+}
+
+// writePlanIntro describes the plan's dates and where today falls in it.
+func writePlanIntro(b *strings.Builder, plan *model.TrainingPlan, today time.Time) {
+	today = dateOnly(today)
+	offset := int(today.Sub(plan.StartDate).Hours() / 24)
+	fmt.Fprintf(b, "Plan %q has %d weeks. Week 1 starts on Monday %s and the race is on %s (week %d).\n",
+		plan.Name, plan.Weeks, plan.StartDate.Format("2006-01-02"), plan.EndDate.Format("2006-01-02"), plan.Weeks)
+	if offset >= 0 {
+		fmt.Fprintf(b, "Today is %s (week %d, dayOfWeek %d).\n", today.Format("2006-01-02"), offset/7+1, offset%7+1)
+	} else {
+		fmt.Fprintf(b, "Today is %s, before the plan starts.\n", today.Format("2006-01-02"))
+	}
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/ai"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupReplanTest creates an 8-week rule-based plan ending Sunday 2025-06-15
+// with week 1 completed and week 2 skipped.
+func setupReplanTest(t *testing.T, client *sequenceAIClient) (*ReplanService, *WorkoutService, *model.TrainingPlan) {
+	t.Helper()
+	_, planSvc, workoutSvc := setupGenerateTest(nil)
+	// A nil *sequenceAIClient must stay a nil ai.Client.
+	var aiClient ai.Client
+	if client != nil {
+		aiClient = client
+	}
+	replan := NewReplanService(aiClient, unlimitedUsage(), workoutSvc)
+
+	input := validInput()
+	input.Strategy = StrategyRules
+	input.RunsPerWeek = 4
+	plan, err := planSvc.Create(model.UserID("user1"), input.Name, input.EndDate, input.Weeks)
+	require.NoError(t, err)
+	workouts, err := workoutSvc.CreateBatch(plan, BuildRuleBasedWorkouts(input))
+	require.NoError(t, err)
+	_, err = workoutSvc.CreateRaceWorkout(plan, input.RaceGoal)
+	require.NoError(t, err)
+
+	for _, w := range workouts {
+		switch week := int(w.Day.Sub(plan.StartDate).Hours()/24)/7 + 1; week {
+		case 1:
+			w.Status = "completed"
+		case 2:
+			w.Status = "skipped"
+			w.Notes = "calf strain"
+		default:
+			continue
+		}
+		require.NoError(t, workoutSvc.Update(w))
+	}
+	return replan, workoutSvc, plan
+}
+
+// replanToday is Wednesday of week 3.
+func replanToday(plan *model.TrainingPlan) time.Time {
+	return plan.StartDate.AddDate(0, 0, 16)
+}
+
+const replanResponse = `{"workouts": [
+	{"runType": "easy_run", "week": 3, "dayOfWeek": 4, "description": "Easy, check the calf", "distance": 5},
+	{"runType": "long_run", "week": 3, "dayOfWeek": 7, "description": "All Easy", "distance": 10},
+	{"runType": "easy_run", "week": 4, "dayOfWeek": 2, "description": "Easy", "distance": 6},
+	{"runType": "long_run", "week": 4, "dayOfWeek": 7, "description": "All Easy", "distance": 11}
+]}`
+
+func TestReplanService_Preview(t *testing.T) {
+	t.Run("proposes replacements for pending workouts in range", func(t *testing.T) {
+		client := &sequenceAIClient{responses: []string{replanResponse}}
+		replan, workoutSvc, plan := setupReplanTest(t, client)
+		today := replanToday(plan)
+		before, err := workoutSvc.GetByPlanID(plan.ID)
+		require.NoError(t, err)
+
+		preview, err := replan.Preview(context.Background(), plan, ReplanInput{FromWeek: 3, ToWeek: 4, Reason: "Calf strain, missed week 2", Today: today})
+		require.NoError(t, err)
+		assert.Equal(t, 1, preview.Attempts)
+
+		var expected []model.WorkoutID
+		for _, w := range before {
+			if w.Status == "pending" && !w.Day.Before(today) && w.Day.Before(plan.StartDate.AddDate(0, 0, 28)) {
+				expected = append(expected, w.ID)
+			}
+		}
+		assert.ElementsMatch(t, expected, preview.Replace)
+		require.Len(t, preview.Workouts, 4)
+		assert.Equal(t, plan.StartDate.AddDate(0, 0, 17).Format("2006-01-02"), preview.Workouts[0].Day)
+
+		require.Len(t, preview.Weeks, 2)
+		assert.Equal(t, 3, preview.Weeks[0].Number)
+		assert.NotEqual(t, preview.Weeks[0].BeforeKm, preview.Weeks[0].AfterKm)
+		assert.Equal(t, 17.0, preview.Weeks[1].AfterKm)
+
+		for i := 1; i < len(preview.Days); i++ {
+			assert.Less(t, preview.Days[i-1].Date, preview.Days[i].Date)
+		}
+		for _, d := range preview.Days {
+			assert.GreaterOrEqual(t, d.Date, today.Format("2006-01-02"))
+		}
+
+		prompt := client.prompts[0]
+		assert.Contains(t, prompt, "Calf strain, missed week 2")
+		assert.Contains(t, prompt, "Today is "+today.Format("2006-01-02")+" (week 3, dayOfWeek 3)")
+		assert.Contains(t, prompt, "weeks 3 to 4")
+		assert.Contains(t, prompt, `"status":"skipped"`)
+		assert.Contains(t, prompt, `"notes":"calf strain"`)
+
+		after, err := workoutSvc.GetByPlanID(plan.ID)
+		require.NoError(t, err)
+		assert.Len(t, after, len(before), "preview must not change the plan")
+	})
+
+	t.Run("re-prompts when workouts fall outside the range", func(t *testing.T) {
+		outside := `{"workouts": [
+			{"runType": "easy_run", "week": 3, "dayOfWeek": 1, "description": "", "distance": 5},
+			{"runType": "easy_run", "week": 5, "dayOfWeek": 2, "description": "", "distance": 5}
+		]}`
+		client := &sequenceAIClient{responses: []string{outside, replanResponse}}
+		replan, _, plan := setupReplanTest(t, client)
+
+		preview, err := replan.Preview(context.Background(), plan, ReplanInput{FromWeek: 3, ToWeek: 4, Today: replanToday(plan)})
+		require.NoError(t, err)
+		assert.Equal(t, 2, preview.Attempts)
+		require.Len(t, client.prompts, 2)
+		assert.Contains(t, client.prompts[1], "workout 1: week 3 day 1 is in the past")
+		assert.Contains(t, client.prompts[1], "workout 2: week 5 must be between 3 and 4")
+	})
+
+	t.Run("rejects invalid ranges", func(t *testing.T) {
+		client := &sequenceAIClient{responses: []string{replanResponse}}
+		replan, _, plan := setupReplanTest(t, client)
+		today := replanToday(plan)
+
+		for _, in := range []ReplanInput{
+			{FromWeek: 0, ToWeek: 3, Today: today},
+			{FromWeek: 4, ToWeek: 3, Today: today},
+			{FromWeek: 3, ToWeek: 9, Today: today},
+			{FromWeek: 1, ToWeek: 2, Today: today},
+		} {
+			_, err := replan.Preview(context.Background(), plan, in)
+			assert.ErrorIs(t, err, ErrInvalidInput, "%+v", in)
+		}
+		assert.Empty(t, client.prompts)
+	})
+
+	t.Run("requires an AI client", func(t *testing.T) {
+		replan, _, plan := setupReplanTest(t, nil)
+		_, err := replan.Preview(context.Background(), plan, ReplanInput{FromWeek: 3, ToWeek: 3, Today: replanToday(plan)})
+		assert.ErrorIs(t, err, ErrAINotConfigured)
+	})
+
+	t.Run("AI errors are not retried", func(t *testing.T) {
+		_, _, workoutSvc := setupGenerateTest(nil)
+		replan := NewReplanService(&mockAIClient{err: errors.New("timeout")}, unlimitedUsage(), workoutSvc)
+		plan := &model.TrainingPlan{ID: "p", Weeks: 8, StartDate: time.Date(2025, 4, 21, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)}
+		_, err := replan.Preview(context.Background(), plan, ReplanInput{FromWeek: 3, ToWeek: 3, Today: replanToday(plan)})
+		assert.ErrorIs(t, err, ErrAIGeneration)
+	})
+}
+
+func TestReplanService_Apply(t *testing.T) {
+	t.Run("replaces pending workouts and keeps history", func(t *testing.T) {
+		client := &sequenceAIClient{responses: []string{replanResponse}}
+		replan, workoutSvc, plan := setupReplanTest(t, client)
+		input := ReplanInput{FromWeek: 3, ToWeek: 4, Today: replanToday(plan)}
+		preview, err := replan.Preview(context.Background(), plan, input)
+		require.NoError(t, err)
+		before, err := workoutSvc.GetByPlanID(plan.ID)
+		require.NoError(t, err)
+
+		items, err := parseWorkouts(replanResponse)
+		require.NoError(t, err)
+		created, err := replan.Apply(plan, input, preview.Replace, items)
+		require.NoError(t, err)
+		assert.Len(t, created, 4)
+
+		after, err := workoutSvc.GetByPlanID(plan.ID)
+		require.NoError(t, err)
+		assert.Len(t, after, len(before)-len(preview.Replace)+4)
+		for _, id := range preview.Replace {
+			_, err := workoutSvc.GetByID(id)
+			assert.Error(t, err)
+		}
+		var completed, skipped, races int
+		for _, w := range after {
+			switch {
+			case w.RunType == "race":
+				races++
+			case w.Status == "completed":
+				completed++
+			case w.Status == "skipped":
+				skipped++
+			}
+		}
+		assert.Equal(t, 4, completed)
+		assert.Equal(t, 4, skipped)
+		assert.Equal(t, 1, races)
+	})
+
+	t.Run("refuses a stale preview", func(t *testing.T) {
+		client := &sequenceAIClient{responses: []string{replanResponse}}
+		replan, workoutSvc, plan := setupReplanTest(t, client)
+		input := ReplanInput{FromWeek: 3, ToWeek: 4, Today: replanToday(plan)}
+		preview, err := replan.Preview(context.Background(), plan, input)
+		require.NoError(t, err)
+
+		w, err := workoutSvc.GetByID(preview.Replace[0])
+		require.NoError(t, err)
+		w.Status = "completed"
+		require.NoError(t, workoutSvc.Update(w))
+
+		items, _ := parseWorkouts(replanResponse)
+		_, err = replan.Apply(plan, input, preview.Replace, items)
+		assert.ErrorIs(t, err, ErrStaleReplan)
+	})
+
+	t.Run("validates the workouts", func(t *testing.T) {
+		replan, workoutSvc, plan := setupReplanTest(t, nil)
+		input := ReplanInput{FromWeek: 3, ToWeek: 3, Today: replanToday(plan)}
+		workouts, err := workoutSvc.GetByPlanID(plan.ID)
+		require.NoError(t, err)
+		var replace []model.WorkoutID
+		for _, w := range replaceableWorkouts(plan, workouts, input) {
+			replace = append(replace, w.ID)
+		}
+
+		_, err = replan.Apply(plan, input, replace, []BulkWorkoutInput{{RunType: "easy_run", Week: 3, DayOfWeek: 1, Distance: 5}})
+		assert.ErrorIs(t, err, ErrInvalidInput)
+
+		after, err := workoutSvc.GetByPlanID(plan.ID)
+		require.NoError(t, err)
+		assert.Len(t, after, len(workouts))
+	})
+}
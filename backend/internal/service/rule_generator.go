@@ -0,0 +1,262 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// The coaching rules below mirror the ones spelled out in buildSystemPrompt,
+// so both strategies produce plans of the same shape.
+const (
+	weeklyProgression = 1.10
+	deloadFactor      = 0.60
+	deloadEvery       = 4
+	taperWeeks        = 3
+	minEasyRunKm      = 3
+	maxShakeOutRuns   = 3
+	speedDay          = 2 // Tuesday
+	longRunDay        = 7 // Sunday
+)
+
+// taperFactors scale the peak weekly volume for weeks N-2, N-1 and N.
+var taperFactors = [taperWeeks]float64{0.75, 0.50, 0.20}
+
+// peakVolumeByGoal and peakLongRunByGoal cap the build-up, in km. A base
+// volume above the cap is kept rather than reduced.
+var peakVolumeByGoal = map[string]float64{
+	"5k":           50,
+	"10k":          60,
+	"halfmarathon": 75,
+	"marathon":     100,
+}
+
+var peakLongRunByGoal = map[string]float64{
+	"5k":           14,
+	"10k":          18,
+	"halfmarathon": 22,
+	"marathon":     32,
+}
+
+// trainingDays lists the days (1=Monday) used for each runs-per-week
+// setting. Every set contains Tuesday for the speed session and Sunday for
+// the long run.
+var trainingDays = map[int][]int{
+	2: {3, 7},
+	3: {2, 4, 7},
+	4: {2, 3, 5, 7},
+	5: {1, 2, 4, 5, 7},
+	6: {1, 2, 3, 4, 5, 7},
+	7: {1, 2, 3, 4, 5, 6, 7},
+}
+
+// easyRunWeights vary the easy days so they are not all the same distance.
+var easyRunWeights = []float64{1.15, 0.85, 1.0, 0.9, 1.1}
+
+// BuildRuleBasedWorkouts creates the workouts for a plan without an AI
+// model. The result only depends on the input, so the same request always
+// produces the same plan. The race workout itself is not included.
+func BuildRuleBasedWorkouts(input GenerateInput) []BulkWorkoutInput {
+	n := input.Weeks
+	buildWeeks := n - taperWeeks
+	volumeCap := math.Max(peakVolumeByGoal[input.RaceGoal], input.BaseKmPerWeek)
+	longRunCap := peakLongRunByGoal[input.RaceGoal]
+	// The long run takes a bigger share of the week when there are fewer runs.
+	longRunShare := 1.75 / float64(input.RunsPerWeek+1)
+	longRunFor := func(volume float64) float64 {
+		return math.Max(minEasyRunKm, math.Min(math.Round(volume*longRunShare), longRunCap))
+	}
+
+	// Progression and deloads follow the volume actually scheduled, which
+	// can fall short of the target when long or easy runs hit their caps.
+	var items []BulkWorkoutInput
+	lastNormal, peak, peakLong := 0.0, 0.0, 0.0
+	speedSessions := 0
+	for week := 1; week <= buildWeeks; week++ {
+		deload := week%deloadEvery == 0
+		volume := input.BaseKmPerWeek
+		switch {
+		case deload:
+			volume = lastNormal * deloadFactor
+		case week > 1:
+			volume = math.Min(lastNormal*weeklyProgression, volumeCap)
+		}
+
+		long := longRunFor(volume)
+		var speed *BulkWorkoutInput
+		if !deload && input.RunsPerWeek >= 3 {
+			speed = speedSession(week, speedSessions, volume)
+			speedSessions++
+		}
+		weekItems := buildWeek(input.RunsPerWeek, week, volume, long, speed, deload)
+		items = append(items, weekItems...)
+
+		if !deload {
+			lastNormal = totalDistance(weekItems)
+			peak = math.Max(peak, lastNormal)
+			peakLong = math.Max(peakLong, long)
+		}
+	}
+
+	for i, factor := range taperFactors[:taperWeeks-1] {
+		week := buildWeeks + i + 1
+		volume := peak * factor
+		long := math.Min(longRunFor(volume), peakLong-2)
+		if i == 1 {
+			long = math.Min(long, 15)
+		}
+		long = math.Max(long, minEasyRunKm)
+		items = append(items, buildWeek(input.RunsPerWeek, week, volume, long, nil, false)...)
+	}
+
+	return append(items, raceWeek(input, peak*taperFactors[taperWeeks-1])...)
+}
+
+// buildWeek lays out one week: the long run on Sunday, the speed session (if
+// any) on Tuesday and easy runs filling the remaining volume.
+func buildWeek(runsPerWeek, week int, volume, long float64, speed *BulkWorkoutInput, deload bool) []BulkWorkoutInput {
+	var easyDays []int
+	for _, day := range trainingDays[runsPerWeek] {
+		if day == longRunDay || (speed != nil && day == speedDay) {
+			continue
+		}
+		easyDays = append(easyDays, day)
+	}
+
+	remaining := volume - long
+	if speed != nil {
+		remaining -= speed.Distance
+	}
+	easy := splitEasyVolume(remaining, len(easyDays), long-2)
+
+	var items []BulkWorkoutInput
+	if speed != nil {
+		items = append(items, *speed)
+	}
+	for i, day := range easyDays {
+		desc := "Easy pace"
+		switch {
+		case deload:
+			desc = "Easy pace, deload week"
+		case i == 0 && runsPerWeek >= 4:
+			desc = "+4x 20s Strides"
+		}
+		items = append(items, BulkWorkoutInput{RunType: "easy_run", Week: week, DayOfWeek: day, Description: desc, Distance: easy[i]})
+	}
+	items = append(items, BulkWorkoutInput{RunType: "long_run", Week: week, DayOfWeek: longRunDay, Description: "All Easy", Distance: long})
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DayOfWeek < items[j].DayOfWeek })
+	return items
+}
+
+// speedSession alternates tempo runs and intervals, starting with tempo.
+func speedSession(week, previous int, volume float64) *BulkWorkoutInput {
+	distance := math.Max(6, math.Min(math.Round(volume*0.2), 14))
+	if previous%2 == 0 {
+		warmup := 2.0
+		if distance >= 10 {
+			warmup = 3
+		}
+		return &BulkWorkoutInput{
+			RunType:     "tempo_run",
+			Week:        week,
+			DayOfWeek:   speedDay,
+			Description: fmt.Sprintf("%gk Easy\n%gk Tempo\n%gk Easy", warmup, distance-2*warmup, warmup),
+			Distance:    distance,
+		}
+	}
+
+	// 2km warm-up and cool-down around 800m reps with 400m recovery.
+	reps := int((distance - 4) / 1.2)
+	if reps < 3 {
+		reps = 3
+	}
+	return &BulkWorkoutInput{
+		RunType:     "intervals",
+		Week:        week,
+		DayOfWeek:   speedDay,
+		Description: fmt.Sprintf("2k Warm-up\n%dx (800m Work, 400m Recovery)\n2k Cool-down", reps),
+		Distance:    distance,
+	}
+}
+
+// splitEasyVolume divides km over count runs in whole kilometers, weighted
+// so neighbouring days differ. Rounding is balanced so the runs add up to
+// km. No run exceeds max, keeping the long run the longest of the week.
+func splitEasyVolume(km float64, count int, max float64) []float64 {
+	if count == 0 {
+		return nil
+	}
+	var total float64
+	for i := 0; i < count; i++ {
+		total += easyRunWeights[i%len(easyRunWeights)]
+	}
+
+	out := make([]float64, count)
+	fractions := make([]float64, count)
+	left := math.Round(km)
+	for i := range out {
+		exact := km * easyRunWeights[i%len(easyRunWeights)] / total
+		out[i] = math.Floor(exact)
+		fractions[i] = exact - out[i]
+		left -= out[i]
+	}
+	order := make([]int, count)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return fractions[order[a]] > fractions[order[b]] })
+	for _, i := range order {
+		if left < 1 {
+			break
+		}
+		out[i]++
+		left--
+	}
+
+	for i := range out {
+		out[i] = math.Max(minEasyRunKm, math.Min(out[i], max))
+	}
+	return out
+}
+
+func totalDistance(items []BulkWorkoutInput) float64 {
+	var km float64
+	for _, it := range items {
+		km += it.Distance
+	}
+	return km
+}
+
+// raceWeek schedules short shake-out runs on the training days before race
+// day; there is no long run or speed session.
+func raceWeek(input GenerateInput, volume float64) []BulkWorkoutInput {
+	raceDay := isoWeekday(input.EndDate)
+	var days []int
+	for _, day := range trainingDays[input.RunsPerWeek] {
+		if day < raceDay && day != longRunDay {
+			days = append(days, day)
+		}
+	}
+	if len(days) > maxShakeOutRuns {
+		days = days[len(days)-maxShakeOutRuns:]
+	}
+	if len(days) == 0 {
+		return nil
+	}
+
+	distance := math.Max(3, math.Min(math.Round(volume/float64(len(days))), 5))
+	items := make([]BulkWorkoutInput, 0, len(days))
+	for _, day := range days {
+		items = append(items, BulkWorkoutInput{RunType: "easy_run", Week: input.Weeks, DayOfWeek: day, Description: "Shake-out run", Distance: distance})
+	}
+	return items
+}
+
+// isoWeekday returns 1 for Monday through 7 for Sunday.
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ruleInput() GenerateInput {
+	return GenerateInput{
+		Name:          "Rules Plan",
+		EndDate:       time.Date(2025, 10, 12, 0, 0, 0, 0, time.UTC), // Sunday
+		Weeks:         12,
+		BaseKmPerWeek: 40,
+		RunsPerWeek:   4,
+		RaceGoal:      "marathon",
+		Strategy:      StrategyRules,
+	}
+}
+
+func byWeek(items []BulkWorkoutInput) map[int][]BulkWorkoutInput {
+	weeks := map[int][]BulkWorkoutInput{}
+	for _, it := range items {
+		weeks[it.Week] = append(weeks[it.Week], it)
+	}
+	return weeks
+}
+
+func TestBuildRuleBasedWorkouts(t *testing.T) {
+	input := ruleInput()
+	items := BuildRuleBasedWorkouts(input)
+	weeks := byWeek(items)
+
+	t.Run("is deterministic", func(t *testing.T) {
+		assert.Equal(t, items, BuildRuleBasedWorkouts(input))
+	})
+
+	t.Run("uses whole kilometers and valid days", func(t *testing.T) {
+		for _, it := range items {
+			assert.Equal(t, math.Round(it.Distance), it.Distance)
+			assert.GreaterOrEqual(t, it.Distance, 3.0)
+			assert.True(t, it.DayOfWeek >= 1 && it.DayOfWeek <= 7)
+			assert.True(t, isValidRunType(it.RunType))
+		}
+	})
+
+	t.Run("runs per week and weekend long run", func(t *testing.T) {
+		for week := 1; week < input.Weeks; week++ {
+			require.Len(t, weeks[week], input.RunsPerWeek, "week %d", week)
+			var long []BulkWorkoutInput
+			for _, it := range weeks[week] {
+				if it.RunType == "long_run" {
+					long = append(long, it)
+				}
+			}
+			require.Len(t, long, 1, "week %d", week)
+			assert.Equal(t, 7, long[0].DayOfWeek)
+			for _, it := range weeks[week] {
+				if it.RunType != "long_run" {
+					assert.Less(t, it.Distance, long[0].Distance, "week %d", week)
+				}
+			}
+		}
+	})
+
+	t.Run("progression, deloads and taper", func(t *testing.T) {
+		// Weeks 1-9 build with deloads in weeks 4 and 8; 10-12 taper.
+		assert.InDelta(t, 40, totalDistance(weeks[1]), 3)
+		for _, week := range []int{2, 3, 6, 7} {
+			prev := totalDistance(weeks[week-1])
+			assert.LessOrEqual(t, totalDistance(weeks[week]), prev*1.1+3, "week %d", week)
+			assert.Greater(t, totalDistance(weeks[week]), prev, "week %d", week)
+		}
+		for _, week := range []int{4, 8} {
+			assert.Less(t, totalDistance(weeks[week]), totalDistance(weeks[week-1])*0.7, "week %d", week)
+		}
+		peak := totalDistance(weeks[9])
+		assert.Less(t, totalDistance(weeks[10]), peak)
+		assert.Less(t, totalDistance(weeks[11]), totalDistance(weeks[10]))
+		assert.Less(t, totalDistance(weeks[12]), totalDistance(weeks[11]))
+	})
+
+	t.Run("speed sessions alternate outside deload and taper", func(t *testing.T) {
+		var speed []string
+		for week := 1; week <= input.Weeks; week++ {
+			for _, it := range weeks[week] {
+				if it.RunType == "tempo_run" || it.RunType == "intervals" {
+					assert.NotContains(t, []int{4, 8, 10, 11, 12}, week)
+					_, ok := ParseDescriptionSteps(it.Description, it.Distance)
+					assert.True(t, ok, it.Description)
+					speed = append(speed, it.RunType)
+				}
+			}
+		}
+		assert.Equal(t, []string{"tempo_run", "intervals", "tempo_run", "intervals", "tempo_run", "intervals", "tempo_run"}, speed)
+	})
+
+	t.Run("race week has only short easy runs before race day", func(t *testing.T) {
+		require.NotEmpty(t, weeks[12])
+		for _, it := range weeks[12] {
+			assert.Equal(t, "easy_run", it.RunType)
+			assert.LessOrEqual(t, it.Distance, 5.0)
+			assert.Less(t, it.DayOfWeek, 7)
+		}
+	})
+
+	t.Run("long run is capped by race goal", func(t *testing.T) {
+		in := ruleInput()
+		in.RaceGoal = "5k"
+		in.BaseKmPerWeek = 60
+		for _, it := range BuildRuleBasedWorkouts(in) {
+			if it.RunType == "long_run" {
+				assert.LessOrEqual(t, it.Distance, 14.0)
+			}
+		}
+	})
+
+	t.Run("two runs per week have no speed session", func(t *testing.T) {
+		in := ruleInput()
+		in.RunsPerWeek = 2
+		for _, it := range BuildRuleBasedWorkouts(in) {
+			assert.Contains(t, []string{"easy_run", "long_run"}, it.RunType)
+		}
+	})
+
+	t.Run("Saturday race skips Saturday shake-out", func(t *testing.T) {
+		in := ruleInput()
+		in.EndDate = time.Date(2025, 10, 11, 0, 0, 0, 0, time.UTC)
+		in.RunsPerWeek = 7
+		race := byWeek(BuildRuleBasedWorkouts(in))[12]
+		require.Len(t, race, 3)
+		assert.Equal(t, []int{3, 4, 5}, []int{race[0].DayOfWeek, race[1].DayOfWeek, race[2].DayOfWeek})
+	})
+}
+
+func TestGenerateService_RulesStrategy(t *testing.T) {
+	t.Run("generates without AI client", func(t *testing.T) {
+		genSvc, _, workoutSvc := setupGenerateTest(nil)
+
+		plan, workouts, err := genSvc.Generate(context.Background(), model.UserID("user1"), ruleInput())
+		require.NoError(t, err)
+		assert.Equal(t, "race", workouts[len(workouts)-1].RunType)
+
+		stored, err := workoutSvc.GetByPlanID(plan.ID)
+		require.NoError(t, err)
+		assert.Len(t, stored, len(workouts))
+	})
+
+	t.Run("AI strategy still requires client", func(t *testing.T) {
+		genSvc, _, _ := setupGenerateTest(nil)
+		in := ruleInput()
+		in.Strategy = StrategyAI
+		_, _, err := genSvc.Generate(context.Background(), model.UserID("user1"), in)
+		assert.ErrorIs(t, err, ErrAINotConfigured)
+	})
+
+	t.Run("rejects unknown strategy", func(t *testing.T) {
+		genSvc, _, _ := setupGenerateTest(&mockAIClient{response: validAIResponse()})
+		in := ruleInput()
+		in.Strategy = "magic"
+		_, _, err := genSvc.Generate(context.Background(), model.UserID("user1"), in)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	})
+}
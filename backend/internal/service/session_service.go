@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+// SessionService lets users see where they are logged in and end those
+// sessions.
+type SessionService struct {
+	sessions store.SessionStore
+	now      func() time.Time
+}
+
+func NewSessionService(sessions store.SessionStore) *SessionService {
+	return &SessionService{sessions: sessions, now: time.Now}
+}
+
+// List returns the user's unexpired sessions, most recently seen first.
+func (s *SessionService) List(userID model.UserID) ([]*model.Session, error) {
+	sessions, err := s.sessions.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	active := make([]*model.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if now.Before(session.ExpiresAt) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+// Revoke ends one of the user's sessions. Another user's session is
+// reported as store.ErrNotFound.
+func (s *SessionService) Revoke(userID model.UserID, id model.SessionID) error {
+	session, err := s.sessions.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return store.ErrNotFound
+	}
+	return s.sessions.Delete(id)
+}
+
+// RevokeAll logs the user out everywhere.
+func (s *SessionService) RevokeAll(userID model.UserID) error {
+	return s.sessions.DeleteByUser(userID)
+}
+
+// RevokeOthers logs the user out everywhere except the session keep.
+func (s *SessionService) RevokeOthers(userID model.UserID, keep model.SessionID) error {
+	sessions, err := s.sessions.ListByUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == keep {
+			continue
+		}
+		if err := s.sessions.Delete(session.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneExpired deletes expired sessions and returns how many there were.
+func (s *SessionService) PruneExpired() (int, error) {
+	return s.sessions.DeleteExpired(s.now())
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSessionTest(t *testing.T) (*SessionService, store.SessionStore) {
+	t.Helper()
+	sessionStore := mem.NewMemSessionStore()
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	svc := NewSessionService(sessionStore)
+	svc.now = func() time.Time { return now }
+
+	for _, s := range []*model.Session{
+		{ID: "laptop", UserID: "user1", TokenHash: "a", LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{ID: "phone", UserID: "user1", TokenHash: "b", LastSeenAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+		{ID: "old", UserID: "user1", TokenHash: "c", LastSeenAt: now.Add(-48 * time.Hour), ExpiresAt: now.Add(-time.Hour)},
+		{ID: "other", UserID: "user2", TokenHash: "d", LastSeenAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: "anonymous", TokenHash: "e", LastSeenAt: now, ExpiresAt: now.Add(-time.Minute)},
+	} {
+		require.NoError(t, sessionStore.Create(s))
+	}
+	return svc, sessionStore
+}
+
+func sessionIDs(sessions []*model.Session) []model.SessionID {
+	ids := make([]model.SessionID, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func TestSessionService_List(t *testing.T) {
+	svc, _ := setupSessionTest(t)
+
+	sessions, err := svc.List("user1")
+	require.NoError(t, err)
+	assert.Equal(t, []model.SessionID{"phone", "laptop"}, sessionIDs(sessions))
+}
+
+func TestSessionService_Revoke(t *testing.T) {
+	t.Run("ends the session", func(t *testing.T) {
+		svc, sessionStore := setupSessionTest(t)
+		require.NoError(t, svc.Revoke("user1", "laptop"))
+		_, err := sessionStore.GetByID("laptop")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("another user's session is not found", func(t *testing.T) {
+		svc, sessionStore := setupSessionTest(t)
+		assert.ErrorIs(t, svc.Revoke("user1", "other"), store.ErrNotFound)
+		_, err := sessionStore.GetByID("other")
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		svc, _ := setupSessionTest(t)
+		assert.ErrorIs(t, svc.Revoke("user1", "missing"), store.ErrNotFound)
+	})
+}
+
+func TestSessionService_RevokeAll(t *testing.T) {
+	svc, sessionStore := setupSessionTest(t)
+
+	require.NoError(t, svc.RevokeAll("user1"))
+	remaining, err := sessionStore.ListByUser("user1")
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+	others, err := svc.List("user2")
+	require.NoError(t, err)
+	assert.Len(t, others, 1)
+}
+
+func TestSessionService_PruneExpired(t *testing.T) {
+	svc, sessionStore := setupSessionTest(t)
+
+	n, err := svc.PruneExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	_, err = sessionStore.GetByID("old")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	_, err = sessionStore.GetByID("anonymous")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	_, err = sessionStore.GetByID("laptop")
+	assert.NoError(t, err)
+}
+
+func TestSessionService_RevokeOthers(t *testing.T) {
+	svc, sessionStore := setupSessionTest(t)
+
+	require.NoError(t, svc.RevokeOthers("user1", "phone"))
+	remaining, err := sessionStore.ListByUser("user1")
+	require.NoError(t, err)
+	assert.Equal(t, []model.SessionID{"phone"}, sessionIDs(remaining))
+	_, err = sessionStore.GetByID("other")
+	assert.NoError(t, err)
+}
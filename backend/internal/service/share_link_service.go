@@ -0,0 +1,178 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+const maxShareLinkDays = 365
+
+// ShareLinkService manages read-only links to a plan and serves the plan to
+// whoever opens one. Expired and revoked links are reported as
+// store.ErrNotFound, like slugs that never existed.
+type ShareLinkService struct {
+	links    store.ShareLinkStore
+	plans    *TrainingPlanService
+	workouts *WorkoutService
+	now      func() time.Time
+}
+
+func NewShareLinkService(links store.ShareLinkStore, plans *TrainingPlanService, workouts *WorkoutService) *ShareLinkService {
+	return &ShareLinkService{links: links, plans: plans, workouts: workouts, now: time.Now}
+}
+
+// Create adds a link to the plan. expiresInDays of 0 means it works until
+// revoked; otherwise it may be at most 365.
+func (s *ShareLinkService) Create(planID model.TrainingPlanID, expiresInDays int) (*model.ShareLink, error) {
+	if expiresInDays < 0 || expiresInDays > maxShareLinkDays {
+		return nil, fmt.Errorf("%w: expiresInDays must be between 0 and %d", ErrInvalidInput, maxShareLinkDays)
+	}
+	now := s.now().UTC()
+	link := &model.ShareLink{
+		ID:        model.ShareLinkID(newShareLinkID()),
+		PlanID:    planID,
+		Slug:      newShareSlug(),
+		CreatedAt: now,
+	}
+	if expiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, expiresInDays)
+		link.ExpiresAt = &expiresAt
+	}
+	if err := s.links.Create(link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// List returns the plan's links, newest first, including expired ones.
+func (s *ShareLinkService) List(planID model.TrainingPlanID) ([]*model.ShareLink, error) {
+	links, err := s.links.ListByPlan(planID)
+	if err != nil {
+		return nil, err
+	}
+	return emptyIfNil(links), nil
+}
+
+// Revoke deletes one of the plan's links.
+func (s *ShareLinkService) Revoke(planID model.TrainingPlanID, id model.ShareLinkID) error {
+	link, err := s.links.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if link.PlanID != planID {
+		return store.ErrNotFound
+	}
+	return s.links.Delete(id)
+}
+
+// SharedPlan is what a share link shows.
+type SharedPlan struct {
+	Plan      *PlanDetail `json:"plan"`
+	ExpiresAt *time.Time  `json:"expiresAt,omitempty"`
+}
+
+// Get returns the plan behind a slug. It has the shape of BuildPlanDetail
+// without internal IDs, workout notes or progress: every workout reads as
+// pending.
+func (s *ShareLinkService) Get(slug string) (*SharedPlan, error) {
+	link, plan, workouts, err := s.resolve(slug)
+	if err != nil {
+		return nil, err
+	}
+	stripped := make([]*model.Workout, 0, len(workouts))
+	for _, w := range workouts {
+		c := *w
+		c.ID = ""
+		c.PlanID = ""
+		c.Notes = ""
+		c.Status = "pending"
+		stripped = append(stripped, &c)
+	}
+	detail := BuildPlanDetail(plan, stripped)
+	detail.ID = ""
+	detail.UserID = ""
+	return &SharedPlan{Plan: detail, ExpiresAt: link.ExpiresAt}, nil
+}
+
+// Copy creates a plan for userID with the shared plan's workouts, without
+// notes or progress. With an endDate the schedule is moved so the last week
+// ends on it; otherwise the dates are kept.
+func (s *ShareLinkService) Copy(userID model.UserID, slug string, endDate *time.Time) (*model.TrainingPlan, error) {
+	_, plan, workouts, err := s.resolve(slug)
+	if err != nil {
+		return nil, err
+	}
+	end := plan.EndDate
+	if endDate != nil {
+		end = *endDate
+	}
+	copied, err := s.plans.Create(userID, plan.Name, end, plan.Weeks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Workouts keep their place in the plan: week and day of the week.
+	items := make([]BulkWorkoutInput, 0, len(workouts))
+	for _, w := range workouts {
+		if w.Day.Before(plan.StartDate) {
+			continue
+		}
+		offset := int(w.Day.Sub(plan.StartDate).Hours() / 24)
+		if offset >= plan.Weeks*7 {
+			continue
+		}
+		items = append(items, BulkWorkoutInput{
+			RunType:     w.RunType,
+			Week:        offset/7 + 1,
+			DayOfWeek:   offset%7 + 1,
+			Description: w.Description,
+			Distance:    w.Distance,
+			Steps:       w.Steps,
+		})
+	}
+	if len(items) > 0 {
+		if _, err := s.workouts.CreateBatch(copied, items); err != nil {
+			_ = s.plans.Delete(copied.ID)
+			return nil, err
+		}
+	}
+	return copied, nil
+}
+
+func (s *ShareLinkService) resolve(slug string) (*model.ShareLink, *model.TrainingPlan, []*model.Workout, error) {
+	link, err := s.links.GetBySlug(slug)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if link.Expired(s.now()) {
+		return nil, nil, nil, store.ErrNotFound
+	}
+	plan, err := s.plans.GetByID(link.PlanID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	workouts, err := s.workouts.GetByPlanID(plan.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return link, plan, workouts, nil
+}
+
+func newShareLinkID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// newShareSlug returns 128 random bits, URL-safe, so links cannot be guessed.
+func newShareSlug() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
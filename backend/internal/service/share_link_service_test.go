@@ -0,0 +1,148 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestShareLinkService(t *testing.T) (*ShareLinkService, *TrainingPlanService, *WorkoutService, *model.TrainingPlan) {
+	t.Helper()
+	plans := NewTrainingPlanService(mem.NewMemTrainingPlanStore())
+	workouts := NewWorkoutService(mem.NewMemWorkoutStore())
+	links := NewShareLinkService(mem.NewMemShareLinkStore(), plans, workouts)
+
+	plan, err := plans.Create("owner", "Club marathon", time.Date(2030, 4, 28, 0, 0, 0, 0, time.UTC), 2)
+	require.NoError(t, err)
+	tempo, err := workouts.Create(plan.ID, "tempo_run", plan.StartDate.AddDate(0, 0, 1), "Tempo", 10)
+	require.NoError(t, err)
+	tempo.Notes = "Legs felt heavy"
+	tempo.Status = "completed"
+	require.NoError(t, workouts.Update(tempo))
+	_, err = workouts.Create(plan.ID, "long_run", plan.StartDate.AddDate(0, 0, 13), "Long", 25)
+	require.NoError(t, err)
+	return links, plans, workouts, plan
+}
+
+func TestShareLinkService_Get(t *testing.T) {
+	links, _, _, plan := newTestShareLinkService(t)
+	now := time.Date(2030, 3, 1, 12, 0, 0, 0, time.UTC)
+	links.now = func() time.Time { return now }
+
+	link, err := links.Create(plan.ID, 0)
+	require.NoError(t, err)
+	assert.Len(t, link.Slug, 22)
+	assert.Nil(t, link.ExpiresAt)
+
+	t.Run("strips IDs, notes and progress", func(t *testing.T) {
+		shared, err := links.Get(link.Slug)
+		require.NoError(t, err)
+		assert.Empty(t, shared.Plan.ID)
+		assert.Empty(t, shared.Plan.UserID)
+		assert.Equal(t, "Club marathon", shared.Plan.Name)
+		require.Len(t, shared.Plan.WeeksSummary, 2)
+		week := shared.Plan.WeeksSummary[0]
+		assert.Equal(t, 10.0, week.PlannedKm)
+		assert.Zero(t, week.DoneKm)
+		require.Len(t, week.Days[1].Workouts, 1)
+		w := week.Days[1].Workouts[0]
+		assert.Equal(t, "Tempo", w.Description)
+		assert.Empty(t, w.ID)
+		assert.Empty(t, w.PlanID)
+		assert.Empty(t, w.Notes)
+		assert.Equal(t, "pending", w.Status)
+	})
+
+	t.Run("unknown slug", func(t *testing.T) {
+		_, err := links.Get("nope")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("expired links stop working", func(t *testing.T) {
+		expiring, err := links.Create(plan.ID, 7)
+		require.NoError(t, err)
+		require.NotNil(t, expiring.ExpiresAt)
+		_, err = links.Get(expiring.Slug)
+		require.NoError(t, err)
+
+		now = now.AddDate(0, 0, 7)
+		_, err = links.Get(expiring.Slug)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = links.Get(link.Slug)
+		assert.NoError(t, err, "links without expiry keep working")
+	})
+
+	t.Run("expiry is limited", func(t *testing.T) {
+		_, err := links.Create(plan.ID, maxShareLinkDays+1)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+		_, err = links.Create(plan.ID, -1)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	})
+}
+
+func TestShareLinkService_Revoke(t *testing.T) {
+	links, plans, _, plan := newTestShareLinkService(t)
+	other, err := plans.Create("owner", "Other", time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC), 4)
+	require.NoError(t, err)
+	link, err := links.Create(plan.ID, 0)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, links.Revoke(other.ID, link.ID), store.ErrNotFound)
+	require.NoError(t, links.Revoke(plan.ID, link.ID))
+	_, err = links.Get(link.Slug)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	list, err := links.List(plan.ID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestShareLinkService_Copy(t *testing.T) {
+	links, _, workouts, plan := newTestShareLinkService(t)
+	link, err := links.Create(plan.ID, 0)
+	require.NoError(t, err)
+
+	t.Run("keeps the dates", func(t *testing.T) {
+		copied, err := links.Copy("friend", link.Slug, nil)
+		require.NoError(t, err)
+		assert.Equal(t, model.UserID("friend"), copied.UserID)
+		assert.NotEqual(t, plan.ID, copied.ID)
+		assert.Equal(t, plan.StartDate, copied.StartDate)
+
+		got, err := workouts.GetByPlanID(copied.ID)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		for _, w := range got {
+			assert.Empty(t, w.Notes)
+			assert.Equal(t, "pending", w.Status)
+		}
+	})
+
+	t.Run("moves the schedule to a new end date", func(t *testing.T) {
+		end := plan.EndDate.AddDate(0, 0, 28)
+		copied, err := links.Copy("friend", link.Slug, &end)
+		require.NoError(t, err)
+		assert.Equal(t, plan.StartDate.AddDate(0, 0, 28), copied.StartDate)
+
+		got, err := workouts.GetByPlanID(copied.ID)
+		require.NoError(t, err)
+		days := map[string]string{}
+		for _, w := range got {
+			days[w.Day.Format("2006-01-02")] = w.RunType
+		}
+		assert.Equal(t, map[string]string{
+			copied.StartDate.AddDate(0, 0, 1).Format("2006-01-02"):  "tempo_run",
+			copied.StartDate.AddDate(0, 0, 13).Format("2006-01-02"): "long_run",
+		}, days)
+	})
+
+	t.Run("revoked links cannot be copied", func(t *testing.T) {
+		require.NoError(t, links.Revoke(plan.ID, link.ID))
+		_, err := links.Copy("friend", link.Slug, nil)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
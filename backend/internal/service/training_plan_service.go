@@ -16,13 +16,32 @@ var (
 )
 
 type TrainingPlanService struct {
-	plans store.TrainingPlanStore
+	plans      store.TrainingPlanStore
+	dependents PlanDependents
+}
+
+// PlanDependents are the stores holding data that belongs to a plan.
+// SQLite does not enforce the schema's cascades, so Delete clears them
+// explicitly; nil stores are skipped.
+type PlanDependents struct {
+	Workouts   store.WorkoutStore
+	Activities store.ActivityStore
+	Coach      store.CoachStore
+	PlanEdits  store.PlanEditStore
+	ShareLinks store.ShareLinkStore
 }
 
 func NewTrainingPlanService(plans store.TrainingPlanStore) *TrainingPlanService {
 	return &TrainingPlanService{plans: plans}
 }
 
+// NewTrainingPlanServiceWithDependents returns a service whose Delete also
+// removes the plan's workouts, activities, coach threads, edits and share
+// links.
+func NewTrainingPlanServiceWithDependents(plans store.TrainingPlanStore, dependents PlanDependents) *TrainingPlanService {
+	return &TrainingPlanService{plans: plans, dependents: dependents}
+}
+
 func StartDateFor(endDate time.Time, weeks int) time.Time {
 	weekday := endDate.Weekday()
 	daysSinceMonday := int(weekday) - 1
@@ -205,7 +224,47 @@ func (s *TrainingPlanService) Update(id model.TrainingPlanID, name string, endDa
 	return plan, nil
 }
 
+// Delete removes the plan and its dependent data. The plan row goes last,
+// so a failed deletion can simply be retried.
 func (s *TrainingPlanService) Delete(id model.TrainingPlanID) error {
+	d := s.dependents
+	if d.Workouts != nil {
+		workouts, err := d.Workouts.GetByPlanID(id)
+		if err != nil {
+			return err
+		}
+		for _, w := range workouts {
+			if d.Activities != nil {
+				if err := d.Activities.DeleteByWorkoutID(w.ID); err != nil {
+					return err
+				}
+			}
+			if err := d.Workouts.Delete(w.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+		}
+	}
+	if d.Coach != nil {
+		threads, err := d.Coach.ListThreadsByPlan(id)
+		if err != nil {
+			return err
+		}
+		for _, t := range threads {
+			if err := d.Coach.DeleteThread(t.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+		}
+	}
+	if d.PlanEdits != nil {
+		if err := d.PlanEdits.DeleteByPlan(id); err != nil {
+			return err
+		}
+	}
+	if d.ShareLinks != nil {
+		if err := d.ShareLinks.DeleteByPlan(id); err != nil {
+			return err
+		}
+	}
 	return s.plans.Delete(id)
 }
 
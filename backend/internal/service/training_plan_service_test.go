@@ -318,7 +318,7 @@ func setupSQLiteTestDB(t *testing.T) (*TrainingPlanService, store.WorkoutStore)
 		plan_id TEXT NOT NULL REFERENCES training_plans(id) ON DELETE CASCADE,
 		runType TEXT NOT NULL, day TEXT NOT NULL, description TEXT NOT NULL,
 		notes TEXT NOT NULL DEFAULT '', status TEXT NOT NULL DEFAULT 'pending',
-		distance REAL NOT NULL)`)
+		distance REAL NOT NULL, steps TEXT NOT NULL DEFAULT '[]')`)
 	require.NoError(t, err)
 	_, err = db.Exec(`INSERT INTO users (id) VALUES ('user-1')`)
 	require.NoError(t, err)
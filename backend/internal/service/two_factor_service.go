@@ -0,0 +1,192 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/totp"
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotStarted  = errors.New("start two-factor setup first")
+	ErrInvalidTwoFactorCode = errors.New("invalid authentication code")
+)
+
+const (
+	// twoFactorIssuer names the account in authenticator apps.
+	twoFactorIssuer   = "RunPlanner"
+	recoveryCodeCount = 10
+)
+
+// TwoFactorEnrolment is what the user scans into an authenticator app.
+type TwoFactorEnrolment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabledAt,omitempty"`
+	RecoveryCodesLeft int        `json:"recoveryCodesLeft"`
+}
+
+// TwoFactorService manages optional TOTP two-factor authentication. Each
+// recovery code can replace one authenticator code; only their hashes are
+// stored.
+type TwoFactorService struct {
+	users     store.UserStore
+	twoFactor store.TwoFactorStore
+	now       func() time.Time
+}
+
+func NewTwoFactorService(users store.UserStore, twoFactor store.TwoFactorStore) *TwoFactorService {
+	return &TwoFactorService{users: users, twoFactor: twoFactor, now: time.Now}
+}
+
+func (s *TwoFactorService) Status(userID model.UserID) (*TwoFactorStatus, error) {
+	tf, err := s.twoFactor.Get(userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return &TwoFactorStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !tf.Enabled() {
+		return &TwoFactorStatus{}, nil
+	}
+	return &TwoFactorStatus{Enabled: true, EnabledAt: tf.EnabledAt, RecoveryCodesLeft: len(tf.RecoveryCodeHashes)}, nil
+}
+
+// Enabled reports whether logging in needs a second factor.
+func (s *TwoFactorService) Enabled(userID model.UserID) (bool, error) {
+	status, err := s.Status(userID)
+	if err != nil {
+		return false, err
+	}
+	return status.Enabled, nil
+}
+
+// Begin starts enrolment with a new secret. Calling it again before
+// confirming replaces the secret.
+func (s *TwoFactorService) Begin(userID model.UserID) (*TwoFactorEnrolment, error) {
+	if enabled, err := s.Enabled(userID); err != nil {
+		return nil, err
+	} else if enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	u, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	secret := totp.GenerateSecret()
+	err = s.twoFactor.Save(&model.TwoFactor{UserID: userID, Secret: secret, CreatedAt: s.now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorEnrolment{Secret: secret, URI: totp.URI(twoFactorIssuer, u.Email, secret)}, nil
+}
+
+// Confirm enables two-factor authentication once the user proves the app
+// works, and returns the recovery codes. They are shown only this once.
+func (s *TwoFactorService) Confirm(userID model.UserID, code string) ([]string, error) {
+	tf, err := s.twoFactor.Get(userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrTwoFactorNotStarted
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	now := s.now()
+	step, ok := totp.Validate(tf.Secret, code, now)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	tf.RecoveryCodeHashes = make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = newRecoveryCode()
+		tf.RecoveryCodeHashes[i] = hashRecoveryCode(codes[i])
+	}
+	enabledAt := now.UTC()
+	tf.EnabledAt = &enabledAt
+	tf.LastUsedStep = step
+	if err := s.twoFactor.Save(tf); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a login code, which is either a code from the app or an
+// unused recovery code. Both work only once.
+func (s *TwoFactorService) Verify(userID model.UserID, code string) error {
+	tf, err := s.twoFactor.Get(userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if !tf.Enabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := totp.Validate(tf.Secret, code, s.now()); ok {
+		if step <= tf.LastUsedStep {
+			return ErrInvalidTwoFactorCode
+		}
+		tf.LastUsedStep = step
+		return s.twoFactor.Save(tf)
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, h := range tf.RecoveryCodeHashes {
+		if h == hash {
+			tf.RecoveryCodeHashes = append(tf.RecoveryCodeHashes[:i], tf.RecoveryCodeHashes[i+1:]...)
+			return s.twoFactor.Save(tf)
+		}
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+// Disable turns two-factor authentication off; it takes a valid code so a
+// hijacked session alone cannot remove it.
+func (s *TwoFactorService) Disable(userID model.UserID, code string) error {
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+	return s.twoFactor.Delete(userID)
+}
+
+// newRecoveryCode returns a code like "k3v9q-7mxa2" (50 random bits).
+func newRecoveryCode() string {
+	b := make([]byte, 7)
+	_, _ = rand.Read(b)
+	s := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+	return s[:5] + "-" + s[5:]
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, which users tend to
+// get wrong when typing a code from paper.
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
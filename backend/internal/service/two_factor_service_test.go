@@ -0,0 +1,129 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+	"github.com/kevsommer/runplanner/internal/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTwoFactorTest(t *testing.T) (*TwoFactorService, *model.User, *time.Time) {
+	users := mem.NewMemUserStore()
+	svc := NewTwoFactorService(users, mem.NewMemTwoFactorStore())
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	u, err := NewAuthService(users).Register("totp@example.com", "password123")
+	require.NoError(t, err)
+	return svc, u, &now
+}
+
+func currentCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(now))
+	require.NoError(t, err)
+	return code
+}
+
+// enableTwoFactor enrols the user and returns the secret and recovery
+// codes.
+func enableTwoFactor(t *testing.T, svc *TwoFactorService, userID model.UserID, now time.Time) (string, []string) {
+	t.Helper()
+	enrolment, err := svc.Begin(userID)
+	require.NoError(t, err)
+	codes, err := svc.Confirm(userID, currentCode(t, enrolment.Secret, now))
+	require.NoError(t, err)
+	return enrolment.Secret, codes
+}
+
+func TestTwoFactorService_Enrolment(t *testing.T) {
+	t.Run("setup returns an otpauth URI", func(t *testing.T) {
+		svc, u, _ := setupTwoFactorTest(t)
+		enrolment, err := svc.Begin(u.ID)
+		require.NoError(t, err)
+		uri, err := url.Parse(enrolment.URI)
+		require.NoError(t, err)
+		assert.Equal(t, "otpauth", uri.Scheme)
+		assert.Contains(t, uri.Path, "totp@example.com")
+		assert.Equal(t, enrolment.Secret, uri.Query().Get("secret"))
+
+		enabled, err := svc.Enabled(u.ID)
+		require.NoError(t, err)
+		assert.False(t, enabled, "pending until confirmed")
+	})
+
+	t.Run("confirm needs a valid code", func(t *testing.T) {
+		svc, u, _ := setupTwoFactorTest(t)
+		_, err := svc.Confirm(u.ID, "123456")
+		assert.ErrorIs(t, err, ErrTwoFactorNotStarted)
+
+		_, err = svc.Begin(u.ID)
+		require.NoError(t, err)
+		_, err = svc.Confirm(u.ID, "000000")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("confirm enables it and returns recovery codes", func(t *testing.T) {
+		svc, u, now := setupTwoFactorTest(t)
+		_, codes := enableTwoFactor(t, svc, u.ID, *now)
+		assert.Len(t, codes, recoveryCodeCount)
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, codes[0])
+
+		status, err := svc.Status(u.ID)
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.Equal(t, recoveryCodeCount, status.RecoveryCodesLeft)
+
+		_, err = svc.Begin(u.ID)
+		assert.ErrorIs(t, err, ErrTwoFactorEnabled)
+	})
+}
+
+func TestTwoFactorService_Verify(t *testing.T) {
+	t.Run("accepts an app code once", func(t *testing.T) {
+		svc, u, now := setupTwoFactorTest(t)
+		secret, _ := enableTwoFactor(t, svc, u.ID, *now)
+
+		// The confirmation code is spent; the next step's code works.
+		assert.ErrorIs(t, svc.Verify(u.ID, currentCode(t, secret, *now)), ErrInvalidTwoFactorCode)
+		*now = now.Add(totp.Period)
+		code := currentCode(t, secret, *now)
+		assert.NoError(t, svc.Verify(u.ID, code))
+		assert.ErrorIs(t, svc.Verify(u.ID, code), ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("recovery codes work once and ignore formatting", func(t *testing.T) {
+		svc, u, now := setupTwoFactorTest(t)
+		_, codes := enableTwoFactor(t, svc, u.ID, *now)
+
+		assert.NoError(t, svc.Verify(u.ID, " "+codes[0]+" "))
+		assert.ErrorIs(t, svc.Verify(u.ID, codes[0]), ErrInvalidTwoFactorCode)
+		typed := codes[1][:5] + codes[1][6:]
+		assert.NoError(t, svc.Verify(u.ID, typed))
+
+		status, err := svc.Status(u.ID)
+		require.NoError(t, err)
+		assert.Equal(t, recoveryCodeCount-2, status.RecoveryCodesLeft)
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		svc, u, _ := setupTwoFactorTest(t)
+		assert.ErrorIs(t, svc.Verify(u.ID, "123456"), ErrTwoFactorNotEnabled)
+	})
+
+	t.Run("disable takes a code", func(t *testing.T) {
+		svc, u, now := setupTwoFactorTest(t)
+		_, codes := enableTwoFactor(t, svc, u.ID, *now)
+		assert.ErrorIs(t, svc.Disable(u.ID, "000000"), ErrInvalidTwoFactorCode)
+		require.NoError(t, svc.Disable(u.ID, codes[3]))
+
+		enabled, err := svc.Enabled(u.ID)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+		assert.ErrorIs(t, svc.Disable(u.ID, codes[4]), ErrTwoFactorNotEnabled)
+	})
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/fit"
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+var (
+	ErrNotExportable   = errors.New("strength training cannot be exported")
+	ErrInvalidWeek     = errors.New("week is outside the plan")
+	ErrNothingToExport = errors.New("no workouts to export")
+)
+
+// FIT profile values used by workout files.
+const (
+	fitFileWorkout         = 5
+	fitManufacturerDev     = 255
+	fitSportRunning        = 1
+	fitDurationTime        = 0 // ms
+	fitDurationDistance    = 1 // cm
+	fitDurationOpen        = 5
+	fitDurationRepeatSteps = 6
+	fitTargetSpeed         = 0 // custom range in mm/s
+	fitTargetHeartRate     = 1 // custom range in bpm + 100
+	fitTargetOpen          = 2
+	fitIntensityActive     = 0
+	fitIntensityWarmup     = 2
+	fitIntensityCooldown   = 3
+	fitIntensityRecovery   = 4
+	fitInvalidEnum         = 0xFF
+	fitInvalidUint32       = 0xFFFFFFFF
+	fitWorkoutNameSize     = 16
+)
+
+var fitIntensities = map[string]int64{
+	"warmup":   fitIntensityWarmup,
+	"work":     fitIntensityActive,
+	"recovery": fitIntensityRecovery,
+	"cooldown": fitIntensityCooldown,
+}
+
+var runTypeNames = map[string]string{
+	"easy_run":          "Easy Run",
+	"intervals":         "Intervals",
+	"long_run":          "Long Run",
+	"tempo_run":         "Tempo Run",
+	"race":              "Race",
+	"strength_training": "Strength Training",
+}
+
+// ExportSteps returns the structure written to a workout file: the stored
+// steps, else steps parsed from the description, else a single step over the
+// whole distance. A nil result means an open-ended run.
+func ExportSteps(w *model.Workout) []model.WorkoutStep {
+	if len(w.Steps) > 0 {
+		return w.Steps
+	}
+	if steps, ok := ParseDescriptionSteps(w.Description, w.Distance); ok {
+		return steps
+	}
+	if w.Distance > 0 {
+		return []model.WorkoutStep{{Type: "work", Distance: w.Distance}}
+	}
+	return nil
+}
+
+// EncodeWorkoutFIT encodes a workout as a FIT workout file that Garmin
+// devices pick up from GARMIN/NewFiles.
+func EncodeWorkoutFIT(w *model.Workout) ([]byte, error) {
+	if w.RunType == "strength_training" {
+		return nil, ErrNotExportable
+	}
+	steps := ExportSteps(w)
+
+	e := fit.NewEncoder()
+	e.Write(fit.MesgFileID,
+		fit.Field{Num: 0, Type: fit.Enum, Value: fitFileWorkout},
+		fit.Field{Num: 1, Type: fit.Uint16, Value: fitManufacturerDev},
+		fit.Field{Num: 2, Type: fit.Uint16, Value: 0},
+		fit.Field{Num: 3, Type: fit.Uint32z, Value: int64(crc32.ChecksumIEEE([]byte(w.ID)))},
+		fit.Field{Num: 4, Type: fit.Uint32, Value: fitTimestamp(time.Now())},
+	)
+
+	numSteps := 1
+	if steps != nil {
+		numSteps = countFITSteps(steps)
+	}
+	e.Write(fit.MesgWorkout,
+		fit.Field{Num: 4, Type: fit.Enum, Value: fitSportRunning},
+		fit.Field{Num: 6, Type: fit.Uint16, Value: int64(numSteps)},
+		fit.Field{Num: 8, Type: fit.String, Str: workoutName(w), Size: fitWorkoutNameSize},
+	)
+
+	if steps == nil {
+		writeFITStep(e, 0, fitDurationOpen, 0, nil, fitIntensityActive)
+		return e.Bytes(), nil
+	}
+	index := 0
+	for _, s := range steps {
+		if s.Type != "repeat" {
+			writeSimpleFITStep(e, index, s)
+			index++
+			continue
+		}
+		first := index
+		for _, child := range s.Steps {
+			writeSimpleFITStep(e, index, child)
+			index++
+		}
+		e.Write(fit.MesgWorkoutStep,
+			fit.Field{Num: 254, Type: fit.Uint16, Value: int64(index)},
+			fit.Field{Num: 1, Type: fit.Enum, Value: fitDurationRepeatSteps},
+			fit.Field{Num: 2, Type: fit.Uint32, Value: int64(first)},
+			fit.Field{Num: 3, Type: fit.Enum, Value: fitInvalidEnum},
+			fit.Field{Num: 4, Type: fit.Uint32, Value: int64(s.Repeat)},
+			fit.Field{Num: 5, Type: fit.Uint32, Value: fitInvalidUint32},
+			fit.Field{Num: 6, Type: fit.Uint32, Value: fitInvalidUint32},
+			fit.Field{Num: 7, Type: fit.Enum, Value: fitInvalidEnum},
+		)
+		index++
+	}
+	return e.Bytes(), nil
+}
+
+func writeSimpleFITStep(e *fit.Encoder, index int, s model.WorkoutStep) {
+	if s.Distance > 0 {
+		writeFITStep(e, index, fitDurationDistance, int64(math.Round(s.Distance*100000)), s.Target, fitIntensities[s.Type])
+		return
+	}
+	writeFITStep(e, index, fitDurationTime, int64(s.Duration)*1000, s.Target, fitIntensities[s.Type])
+}
+
+// writeFITStep writes one workout_step. All steps share a single layout so
+// the encoder only emits one definition for them.
+func writeFITStep(e *fit.Encoder, index int, durationType, durationValue int64, target *model.StepTarget, intensity int64) {
+	targetType, low, high := int64(fitTargetOpen), int64(fitInvalidUint32), int64(fitInvalidUint32)
+	if t := target; t != nil {
+		if t.MinPace > 0 {
+			// The slowest pace is the lowest speed.
+			targetType = fitTargetSpeed
+			low = int64(math.Round(1e6 / float64(t.MaxPace)))
+			high = int64(math.Round(1e6 / float64(t.MinPace)))
+		} else if t.MinHeartRate > 0 {
+			targetType = fitTargetHeartRate
+			low = int64(t.MinHeartRate + 100)
+			high = int64(t.MaxHeartRate + 100)
+		}
+	}
+	e.Write(fit.MesgWorkoutStep,
+		fit.Field{Num: 254, Type: fit.Uint16, Value: int64(index)},
+		fit.Field{Num: 1, Type: fit.Enum, Value: durationType},
+		fit.Field{Num: 2, Type: fit.Uint32, Value: durationValue},
+		fit.Field{Num: 3, Type: fit.Enum, Value: targetType},
+		fit.Field{Num: 4, Type: fit.Uint32, Value: 0},
+		fit.Field{Num: 5, Type: fit.Uint32, Value: low},
+		fit.Field{Num: 6, Type: fit.Uint32, Value: high},
+		fit.Field{Num: 7, Type: fit.Enum, Value: intensity},
+	)
+}
+
+func countFITSteps(steps []model.WorkoutStep) int {
+	n := 0
+	for _, s := range steps {
+		if s.Type == "repeat" {
+			n += len(s.Steps)
+		}
+		n++
+	}
+	return n
+}
+
+// workoutName is shown on the watch, which truncates long names, so the date
+// comes first.
+func workoutName(w *model.Workout) string {
+	name := runTypeNames[w.RunType]
+	if name == "" {
+		name = "Run"
+	}
+	return w.Day.Format("01-02") + " " + name
+}
+
+func fitTimestamp(t time.Time) int64 {
+	return int64(t.Sub(fit.Time(0)) / time.Second)
+}
+
+// WorkoutFITFilename returns e.g. "2025-04-01-intervals.fit".
+func WorkoutFITFilename(w *model.Workout) string {
+	return fmt.Sprintf("%s-%s.fit", w.Day.Format("2006-01-02"), strings.ReplaceAll(w.RunType, "_", "-"))
+}
+
+// EncodeWeekFITZip bundles the FIT files of every exportable workout in the
+// given plan week (1-based) into a zip archive.
+func EncodeWeekFITZip(plan *model.TrainingPlan, workouts []*model.Workout, week int) ([]byte, error) {
+	if week < 1 || week > plan.Weeks {
+		return nil, ErrInvalidWeek
+	}
+	start := plan.StartDate.AddDate(0, 0, (week-1)*7)
+	end := start.AddDate(0, 0, 7)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	used := map[string]int{}
+	count := 0
+	for _, w := range workouts {
+		if w.RunType == "strength_training" || w.Day.Before(start) || !w.Day.Before(end) {
+			continue
+		}
+		data, err := EncodeWorkoutFIT(w)
+		if err != nil {
+			return nil, err
+		}
+		name := WorkoutFITFilename(w)
+		if n := used[name]; n > 0 {
+			name = strings.TrimSuffix(name, ".fit") + fmt.Sprintf("-%d.fit", n+1)
+		}
+		used[WorkoutFITFilename(w)]++
+
+		f, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(data); err != nil {
+			return nil, err
+		}
+		count++
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNothingToExport
+	}
+	return buf.Bytes(), nil
+}
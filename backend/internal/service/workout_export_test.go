@@ -0,0 +1,148 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/fit"
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeFIT(t *testing.T, data []byte) []fit.Message {
+	t.Helper()
+	msgs, err := fit.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return msgs
+}
+
+func fitField(t *testing.T, m fit.Message, num uint8) int64 {
+	t.Helper()
+	v, ok := m.Field(num)
+	require.True(t, ok, "field %d missing from message %d", num, m.Num)
+	return v
+}
+
+func TestEncodeWorkoutFIT(t *testing.T) {
+	t.Run("encodes structured steps with repeat", func(t *testing.T) {
+		w := &model.Workout{ID: "w1", RunType: "intervals", Day: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), Steps: intervalSteps()}
+
+		data, err := EncodeWorkoutFIT(w)
+		require.NoError(t, err)
+		msgs := decodeFIT(t, data)
+		require.Len(t, msgs, 7)
+
+		assert.Equal(t, fit.MesgFileID, msgs[0].Num)
+		assert.Equal(t, int64(fitFileWorkout), fitField(t, msgs[0], 0))
+
+		assert.Equal(t, fit.MesgWorkout, msgs[1].Num)
+		assert.Equal(t, int64(fitSportRunning), fitField(t, msgs[1], 4))
+		assert.Equal(t, int64(5), fitField(t, msgs[1], 6))
+
+		warmup := msgs[2]
+		assert.Equal(t, fit.MesgWorkoutStep, warmup.Num)
+		assert.Equal(t, int64(fitDurationDistance), fitField(t, warmup, 1))
+		assert.Equal(t, int64(200000), fitField(t, warmup, 2))
+		assert.Equal(t, int64(fitTargetOpen), fitField(t, warmup, 3))
+		assert.Equal(t, int64(fitIntensityWarmup), fitField(t, warmup, 7))
+
+		work := msgs[3]
+		assert.Equal(t, int64(1), fitField(t, work, 254))
+		assert.Equal(t, int64(40000), fitField(t, work, 2))
+		assert.Equal(t, int64(fitTargetSpeed), fitField(t, work, 3))
+		assert.Equal(t, int64(4167), fitField(t, work, 5)) // 4:00/km
+		assert.Equal(t, int64(4348), fitField(t, work, 6)) // 3:50/km
+
+		recovery := msgs[4]
+		assert.Equal(t, int64(fitDurationTime), fitField(t, recovery, 1))
+		assert.Equal(t, int64(90000), fitField(t, recovery, 2))
+		assert.Equal(t, int64(fitIntensityRecovery), fitField(t, recovery, 7))
+
+		repeat := msgs[5]
+		assert.Equal(t, int64(3), fitField(t, repeat, 254))
+		assert.Equal(t, int64(fitDurationRepeatSteps), fitField(t, repeat, 1))
+		assert.Equal(t, int64(1), fitField(t, repeat, 2))
+		assert.Equal(t, int64(5), fitField(t, repeat, 4))
+
+		assert.Equal(t, int64(fitIntensityCooldown), fitField(t, msgs[6], 7))
+	})
+
+	t.Run("falls back to parsed description", func(t *testing.T) {
+		w := &model.Workout{ID: "w2", RunType: "tempo_run", Description: "4k Easy\n3k Tempo @ 160-170 bpm\n3k Easy", Distance: 10}
+
+		data, err := EncodeWorkoutFIT(w)
+		require.NoError(t, err)
+		msgs := decodeFIT(t, data)
+		require.Len(t, msgs, 5)
+		assert.Equal(t, int64(fitTargetHeartRate), fitField(t, msgs[3], 3))
+		assert.Equal(t, int64(260), fitField(t, msgs[3], 5))
+		assert.Equal(t, int64(270), fitField(t, msgs[3], 6))
+	})
+
+	t.Run("free text becomes a single distance step", func(t *testing.T) {
+		w := &model.Workout{ID: "w3", RunType: "long_run", Description: "All Easy", Distance: 16}
+
+		msgs := decodeFIT(t, mustEncodeFIT(t, w))
+		require.Len(t, msgs, 3)
+		assert.Equal(t, int64(1600000), fitField(t, msgs[2], 2))
+		assert.Equal(t, int64(fitIntensityActive), fitField(t, msgs[2], 7))
+	})
+
+	t.Run("no distance gives an open step", func(t *testing.T) {
+		w := &model.Workout{ID: "w4", RunType: "easy_run"}
+
+		msgs := decodeFIT(t, mustEncodeFIT(t, w))
+		require.Len(t, msgs, 3)
+		assert.Equal(t, int64(fitDurationOpen), fitField(t, msgs[2], 1))
+	})
+
+	t.Run("rejects strength training", func(t *testing.T) {
+		_, err := EncodeWorkoutFIT(&model.Workout{RunType: "strength_training"})
+		assert.Equal(t, ErrNotExportable, err)
+	})
+}
+
+func mustEncodeFIT(t *testing.T, w *model.Workout) []byte {
+	t.Helper()
+	data, err := EncodeWorkoutFIT(w)
+	require.NoError(t, err)
+	return data
+}
+
+func TestEncodeWeekFITZip(t *testing.T) {
+	start := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	plan := &model.TrainingPlan{ID: "p1", StartDate: start, Weeks: 2}
+	workouts := []*model.Workout{
+		{ID: "a", RunType: "easy_run", Day: start, Distance: 8},
+		{ID: "b", RunType: "easy_run", Day: start, Distance: 5},
+		{ID: "c", RunType: "strength_training", Day: start.AddDate(0, 0, 1)},
+		{ID: "d", RunType: "long_run", Day: start.AddDate(0, 0, 6), Distance: 18},
+		{ID: "e", RunType: "tempo_run", Day: start.AddDate(0, 0, 7), Distance: 10},
+	}
+
+	t.Run("bundles the week's runs", func(t *testing.T) {
+		data, err := EncodeWeekFITZip(plan, workouts, 1)
+		require.NoError(t, err)
+
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		assert.Equal(t, []string{"2025-03-03-easy-run.fit", "2025-03-03-easy-run-2.fit", "2025-03-09-long-run.fit"}, names)
+	})
+
+	t.Run("rejects weeks outside the plan", func(t *testing.T) {
+		_, err := EncodeWeekFITZip(plan, workouts, 3)
+		assert.Equal(t, ErrInvalidWeek, err)
+	})
+
+	t.Run("empty week", func(t *testing.T) {
+		_, err := EncodeWeekFITZip(plan, workouts[2:3], 1)
+		assert.Equal(t, ErrNothingToExport, err)
+	})
+}
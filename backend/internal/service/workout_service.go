@@ -48,6 +48,7 @@ type BulkWorkoutInput struct {
 	DayOfWeek   int // 1=Monday, 7=Sunday
 	Description string
 	Distance    float64
+	Steps       []model.WorkoutStep
 }
 
 type WorkoutService struct {
@@ -71,18 +72,12 @@ func isValidRunType(runType string) bool {
 }
 
 func (s *WorkoutService) Create(planID model.TrainingPlanID, runType string, day time.Time, description string, distance float64) (*model.Workout, error) {
-	if distance < 0 {
-		return nil, ErrInvalidDistance
-	}
-
-	if !isValidRunType(runType) {
-		return nil, ErrInvalidRunType
-	}
-
-	if runType == "strength_training" && distance != 0 {
-		return nil, ErrStrengthTrainingNonZeroDist
-	}
+	return s.CreateWithSteps(planID, runType, day, description, distance, nil)
+}
 
+// CreateWithSteps creates a structured workout. When steps are given the
+// distance is derived from them; see applySteps.
+func (s *WorkoutService) CreateWithSteps(planID model.TrainingPlanID, runType string, day time.Time, description string, distance float64, steps []model.WorkoutStep) (*model.Workout, error) {
 	workout := &model.Workout{
 		ID:          model.WorkoutID(newPlanID()),
 		PlanID:      planID,
@@ -92,7 +87,24 @@ func (s *WorkoutService) Create(planID model.TrainingPlanID, runType string, day
 		Notes:       "",
 		Status:      "pending",
 		Distance:    distance,
+		Steps:       steps,
+	}
+	if err := applySteps(workout); err != nil {
+		return nil, err
+	}
+
+	if workout.Distance < 0 {
+		return nil, ErrInvalidDistance
+	}
+
+	if !isValidRunType(runType) {
+		return nil, ErrInvalidRunType
+	}
+
+	if runType == "strength_training" && workout.Distance != 0 {
+		return nil, ErrStrengthTrainingNonZeroDist
 	}
+
 	if err := s.workouts.Create(workout); err != nil {
 		return nil, err
 	}
@@ -105,6 +117,15 @@ func (s *WorkoutService) CreateBatch(plan *model.TrainingPlan, items []BulkWorko
 		if !isValidRunType(item.RunType) {
 			return nil, &BatchValidationError{Index: i, Message: "invalid run type"}
 		}
+		w := &model.Workout{
+			Description: item.Description,
+			Distance:    item.Distance,
+			Steps:       item.Steps,
+		}
+		if err := applySteps(w); err != nil {
+			return nil, &BatchValidationError{Index: i, Message: err.Error()}
+		}
+		item.Description, item.Distance = w.Description, w.Distance
 		if item.Distance < 0 {
 			return nil, &BatchValidationError{Index: i, Message: "distance cannot be negative"}
 		}
@@ -126,6 +147,7 @@ func (s *WorkoutService) CreateBatch(plan *model.TrainingPlan, items []BulkWorko
 			Description: item.Description,
 			Status:      "pending",
 			Distance:    item.Distance,
+			Steps:       item.Steps,
 		})
 	}
 
@@ -144,6 +166,10 @@ func (s *WorkoutService) GetByPlanID(planID model.TrainingPlanID) ([]*model.Work
 }
 
 func (s *WorkoutService) Update(workout *model.Workout) error {
+	if err := applySteps(workout); err != nil {
+		return err
+	}
+
 	if workout.Distance < 0 {
 		return ErrInvalidDistance
 	}
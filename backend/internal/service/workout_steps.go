@@ -0,0 +1,353 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+var ErrInvalidSteps = errors.New("invalid workout steps")
+
+// maxWorkoutSteps bounds the number of steps including repeat children, in
+// line with what watches accept for a single workout.
+const maxWorkoutSteps = 50
+
+var stepLabels = map[string]string{
+	"warmup":   "Warm-up",
+	"work":     "Work",
+	"recovery": "Recovery",
+	"cooldown": "Cool-down",
+}
+
+func validateSteps(steps []model.WorkoutStep) error {
+	count := 0
+	for i, s := range steps {
+		if s.Type == "repeat" {
+			if s.Repeat < 2 {
+				return fmt.Errorf("%w: step %d: repeat must be at least 2", ErrInvalidSteps, i+1)
+			}
+			if len(s.Steps) == 0 {
+				return fmt.Errorf("%w: step %d: repeat needs at least one step", ErrInvalidSteps, i+1)
+			}
+			if s.Distance != 0 || s.Duration != 0 || s.Target != nil {
+				return fmt.Errorf("%w: step %d: repeat cannot have distance, duration or target", ErrInvalidSteps, i+1)
+			}
+			for j, child := range s.Steps {
+				if child.Type == "repeat" {
+					return fmt.Errorf("%w: step %d.%d: repeats cannot be nested", ErrInvalidSteps, i+1, j+1)
+				}
+				if err := validateStep(child); err != nil {
+					return fmt.Errorf("%w: step %d.%d: %v", ErrInvalidSteps, i+1, j+1, err)
+				}
+			}
+			count += len(s.Steps) + 1
+			continue
+		}
+		if err := validateStep(s); err != nil {
+			return fmt.Errorf("%w: step %d: %v", ErrInvalidSteps, i+1, err)
+		}
+		count++
+	}
+	if count > maxWorkoutSteps {
+		return fmt.Errorf("%w: at most %d steps are allowed", ErrInvalidSteps, maxWorkoutSteps)
+	}
+	return nil
+}
+
+func validateStep(s model.WorkoutStep) error {
+	if _, ok := stepLabels[s.Type]; !ok {
+		return errors.New("type must be one of warmup, work, recovery, cooldown, repeat")
+	}
+	if s.Repeat != 0 || len(s.Steps) > 0 {
+		return errors.New("only repeat steps can have repeat or steps")
+	}
+	if s.Distance < 0 || s.Duration < 0 {
+		return errors.New("distance and duration cannot be negative")
+	}
+	if (s.Distance > 0) == (s.Duration > 0) {
+		return errors.New("exactly one of distance or duration is required")
+	}
+	if t := s.Target; t != nil {
+		hasPace := t.MinPace != 0 || t.MaxPace != 0
+		hasHR := t.MinHeartRate != 0 || t.MaxHeartRate != 0
+		switch {
+		case hasPace && hasHR:
+			return errors.New("target can be pace or heart rate, not both")
+		case hasPace:
+			if t.MinPace < 120 || t.MaxPace > 1200 || t.MinPace > t.MaxPace {
+				return errors.New("pace target must be between 120 and 1200 s/km with minPace <= maxPace")
+			}
+		case hasHR:
+			if t.MinHeartRate < 40 || t.MaxHeartRate > 230 || t.MinHeartRate > t.MaxHeartRate {
+				return errors.New("heart rate target must be between 40 and 230 bpm with minHeartRate <= maxHeartRate")
+			}
+		default:
+			return errors.New("target is empty")
+		}
+	}
+	return nil
+}
+
+// StepsDistance returns the total distance in kilometers of the steps.
+// Duration-based steps are converted using the middle of their pace target;
+// complete is false when some step has neither distance nor pace.
+func StepsDistance(steps []model.WorkoutStep) (km float64, complete bool) {
+	complete = true
+	for _, s := range steps {
+		switch {
+		case s.Type == "repeat":
+			d, ok := StepsDistance(s.Steps)
+			km += float64(s.Repeat) * d
+			complete = complete && ok
+		case s.Distance > 0:
+			km += s.Distance
+		case s.Target != nil && s.Target.MinPace > 0:
+			pace := float64(s.Target.MinPace+s.Target.MaxPace) / 2
+			km += float64(s.Duration) / pace
+		default:
+			complete = false
+		}
+	}
+	return km, complete
+}
+
+// applySteps validates a workout's steps and derives its distance from them.
+// If some step's distance cannot be determined the larger of the given and
+// the partially derived distance is kept. An empty description is filled
+// with a readable rendering of the steps.
+func applySteps(w *model.Workout) error {
+	if len(w.Steps) == 0 {
+		return nil
+	}
+	if err := validateSteps(w.Steps); err != nil {
+		return err
+	}
+	km, complete := StepsDistance(w.Steps)
+	km = math.Round(km*100) / 100
+	if complete || km > w.Distance {
+		w.Distance = km
+	}
+	if w.Description == "" {
+		w.Description = DescribeSteps(w.Steps)
+	}
+	return nil
+}
+
+// DescribeSteps renders steps one per line, e.g. "2km Warm-up" or
+// "5x (400m Work @ 3:50-4:00/km, 90s Recovery)".
+func DescribeSteps(steps []model.WorkoutStep) string {
+	lines := make([]string, 0, len(steps))
+	for _, s := range steps {
+		lines = append(lines, describeStep(s))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func describeStep(s model.WorkoutStep) string {
+	if s.Type == "repeat" {
+		parts := make([]string, 0, len(s.Steps))
+		for _, child := range s.Steps {
+			parts = append(parts, describeStep(child))
+		}
+		return fmt.Sprintf("%dx (%s)", s.Repeat, strings.Join(parts, ", "))
+	}
+
+	var amount string
+	switch {
+	case s.Distance >= 1:
+		amount = fmt.Sprintf("%gkm", s.Distance)
+	case s.Distance > 0:
+		amount = fmt.Sprintf("%gm", math.Round(s.Distance*1000))
+	case s.Duration%60 == 0:
+		amount = fmt.Sprintf("%dmin", s.Duration/60)
+	default:
+		amount = fmt.Sprintf("%ds", s.Duration)
+	}
+	desc := amount + " " + stepLabels[s.Type]
+
+	if t := s.Target; t != nil {
+		switch {
+		case t.MinPace > 0 && t.MinPace == t.MaxPace:
+			desc += " @ " + FormatPace(t.MinPace) + "/km"
+		case t.MinPace > 0:
+			desc += " @ " + FormatPace(t.MinPace) + "-" + FormatPace(t.MaxPace) + "/km"
+		case t.MinHeartRate > 0:
+			desc += fmt.Sprintf(" @ %d-%d bpm", t.MinHeartRate, t.MaxHeartRate)
+		}
+	}
+	return desc
+}
+
+// FormatPace formats seconds per kilometer as m:ss.
+func FormatPace(secondsPerKm int) string {
+	return fmt.Sprintf("%d:%02d", secondsPerKm/60, secondsPerKm%60)
+}
+
+var (
+	repeatGroupRe = regexp.MustCompile(`(?i)^(\d+)\s*[x×]\s*\((.+)\)$`)
+	repeatOneRe   = regexp.MustCompile(`(?i)^(\d+)\s*[x×]\s*(.+)$`)
+	amountRe      = regexp.MustCompile(`(?i)^(\d+(?:[.,]\d+)?)\s*(km|k|minutes|mins?|secs?|s|m|h)\b\s*(.*)$`)
+	paceTargetRe  = regexp.MustCompile(`@\s*(\d{1,2}):(\d{2})(?:\s*-\s*(\d{1,2}):(\d{2}))?\s*(?:min)?(?:/km)?`)
+	hrTargetRe    = regexp.MustCompile(`(?i)@\s*(\d{2,3})\s*-\s*(\d{2,3})\s*bpm`)
+)
+
+// ParseDescriptionSteps reads a free-text description such as
+// "4k Easy\n3k Tempo\n3k Easy" or "2km Warm-up\n5x (400m @ 4:00/km, 90s Jog)"
+// into steps. Easy blocks before the first hard block become the warm-up,
+// those after the last become the cool-down. Lines starting with "+" are
+// extras on top of the main run, which gets the rest of distanceKm.
+// ok is false if any line cannot be understood.
+func ParseDescriptionSteps(description string, distanceKm float64) (steps []model.WorkoutStep, ok bool) {
+	extra := false
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "+") {
+			extra = true
+			line = strings.TrimSpace(strings.TrimPrefix(line, "+"))
+		}
+		step, ok := parseStepLine(line)
+		if !ok {
+			return nil, false
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, false
+	}
+
+	first, last := -1, -1
+	for i, s := range steps {
+		if s.Type != "easy" {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+	for i := range steps {
+		if steps[i].Type != "easy" {
+			continue
+		}
+		switch {
+		case first == -1:
+			steps[i].Type = "work"
+		case i < first:
+			steps[i].Type = "warmup"
+		case i > last:
+			steps[i].Type = "cooldown"
+		default:
+			steps[i].Type = "recovery"
+		}
+	}
+
+	if extra {
+		known, _ := StepsDistance(steps)
+		if main := math.Round((distanceKm-known)*100) / 100; main > 0 {
+			steps = append([]model.WorkoutStep{{Type: "work", Distance: main}}, steps...)
+		}
+	}
+	if validateSteps(steps) != nil {
+		return nil, false
+	}
+	return steps, true
+}
+
+func parseStepLine(line string) (model.WorkoutStep, bool) {
+	var inner []string
+	var repeat int
+	if m := repeatGroupRe.FindStringSubmatch(line); m != nil {
+		repeat, _ = strconv.Atoi(m[1])
+		inner = strings.Split(m[2], ",")
+	} else if m := repeatOneRe.FindStringSubmatch(line); m != nil {
+		repeat, _ = strconv.Atoi(m[1])
+		inner = []string{m[2]}
+	}
+	if inner == nil {
+		return parseSimpleStep(line)
+	}
+
+	step := model.WorkoutStep{Type: "repeat", Repeat: repeat}
+	for _, part := range inner {
+		child, ok := parseSimpleStep(strings.TrimSpace(part))
+		if !ok {
+			return model.WorkoutStep{}, false
+		}
+		if child.Type == "easy" {
+			child.Type = "recovery"
+		}
+		step.Steps = append(step.Steps, child)
+	}
+	return step, true
+}
+
+// parseSimpleStep parses "<amount><unit> <label> [@ target]". Steps labelled
+// easy get the placeholder type "easy", resolved by the caller.
+func parseSimpleStep(s string) (model.WorkoutStep, bool) {
+	var step model.WorkoutStep
+	if m := hrTargetRe.FindStringSubmatch(s); m != nil {
+		lo, _ := strconv.Atoi(m[1])
+		hi, _ := strconv.Atoi(m[2])
+		step.Target = &model.StepTarget{MinHeartRate: lo, MaxHeartRate: hi}
+		s = strings.TrimSpace(strings.Replace(s, m[0], "", 1))
+	} else if m := paceTargetRe.FindStringSubmatch(s); m != nil {
+		minPace := atoi(m[1])*60 + atoi(m[2])
+		maxPace := minPace
+		if m[3] != "" {
+			maxPace = atoi(m[3])*60 + atoi(m[4])
+		}
+		if minPace > maxPace {
+			minPace, maxPace = maxPace, minPace
+		}
+		step.Target = &model.StepTarget{MinPace: minPace, MaxPace: maxPace}
+		s = strings.TrimSpace(strings.Replace(s, m[0], "", 1))
+	}
+
+	m := amountRe.FindStringSubmatch(s)
+	if m == nil {
+		return model.WorkoutStep{}, false
+	}
+	value, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+	if err != nil || value <= 0 {
+		return model.WorkoutStep{}, false
+	}
+	switch strings.ToLower(m[2]) {
+	case "km", "k":
+		step.Distance = value
+	case "m":
+		step.Distance = value / 1000
+	case "h":
+		step.Duration = int(value * 3600)
+	case "min", "mins", "minutes":
+		step.Duration = int(value * 60)
+	default:
+		step.Duration = int(value)
+	}
+
+	label := strings.ToLower(m[3])
+	switch {
+	case strings.Contains(label, "warm"):
+		step.Type = "warmup"
+	case strings.Contains(label, "cool"):
+		step.Type = "cooldown"
+	case strings.Contains(label, "recover"), strings.Contains(label, "rest"), strings.Contains(label, "jog"), strings.Contains(label, "float"):
+		step.Type = "recovery"
+	case strings.Contains(label, "easy"):
+		step.Type = "easy"
+	default:
+		step.Type = "work"
+	}
+	return step, true
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intervalSteps() []model.WorkoutStep {
+	return []model.WorkoutStep{
+		{Type: "warmup", Distance: 2},
+		{Type: "repeat", Repeat: 5, Steps: []model.WorkoutStep{
+			{Type: "work", Distance: 0.4, Target: &model.StepTarget{MinPace: 230, MaxPace: 240}},
+			{Type: "recovery", Duration: 90},
+		}},
+		{Type: "cooldown", Distance: 2},
+	}
+}
+
+func TestValidateSteps(t *testing.T) {
+	t.Run("accepts interval workout", func(t *testing.T) {
+		assert.NoError(t, validateSteps(intervalSteps()))
+	})
+
+	tests := []struct {
+		name  string
+		steps []model.WorkoutStep
+		msg   string
+	}{
+		{"unknown type", []model.WorkoutStep{{Type: "sprint", Distance: 1}}, "type must be one of"},
+		{"distance and duration", []model.WorkoutStep{{Type: "work", Distance: 1, Duration: 60}}, "exactly one of distance or duration"},
+		{"neither distance nor duration", []model.WorkoutStep{{Type: "work"}}, "exactly one of distance or duration"},
+		{"negative distance", []model.WorkoutStep{{Type: "work", Distance: -1}}, "cannot be negative"},
+		{"repeat once", []model.WorkoutStep{{Type: "repeat", Repeat: 1, Steps: []model.WorkoutStep{{Type: "work", Distance: 1}}}}, "at least 2"},
+		{"empty repeat", []model.WorkoutStep{{Type: "repeat", Repeat: 3}}, "at least one step"},
+		{"nested repeat", []model.WorkoutStep{{Type: "repeat", Repeat: 2, Steps: []model.WorkoutStep{
+			{Type: "repeat", Repeat: 2, Steps: []model.WorkoutStep{{Type: "work", Distance: 1}}},
+		}}}, "cannot be nested"},
+		{"children on plain step", []model.WorkoutStep{{Type: "work", Distance: 1, Steps: []model.WorkoutStep{{Type: "work", Distance: 1}}}}, "only repeat steps"},
+		{"pace and heart rate", []model.WorkoutStep{{Type: "work", Distance: 1, Target: &model.StepTarget{MinPace: 240, MaxPace: 250, MinHeartRate: 150, MaxHeartRate: 160}}}, "not both"},
+		{"inverted pace", []model.WorkoutStep{{Type: "work", Distance: 1, Target: &model.StepTarget{MinPace: 250, MaxPace: 240}}}, "pace target"},
+		{"heart rate out of range", []model.WorkoutStep{{Type: "work", Distance: 1, Target: &model.StepTarget{MinHeartRate: 20, MaxHeartRate: 160}}}, "heart rate target"},
+		{"empty target", []model.WorkoutStep{{Type: "work", Distance: 1, Target: &model.StepTarget{}}}, "target is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSteps(tt.steps)
+			require.ErrorIs(t, err, ErrInvalidSteps)
+			assert.Contains(t, err.Error(), tt.msg)
+		})
+	}
+
+	t.Run("too many steps", func(t *testing.T) {
+		steps := make([]model.WorkoutStep, maxWorkoutSteps+1)
+		for i := range steps {
+			steps[i] = model.WorkoutStep{Type: "work", Distance: 1}
+		}
+		assert.ErrorIs(t, validateSteps(steps), ErrInvalidSteps)
+	})
+}
+
+func TestStepsDistance(t *testing.T) {
+	km, complete := StepsDistance(intervalSteps())
+	// 2 + 5*(0.4 + 90s at no pace) + 2: the recoveries are unknown.
+	assert.InDelta(t, 6.0, km, 1e-9)
+	assert.False(t, complete)
+
+	km, complete = StepsDistance([]model.WorkoutStep{
+		{Type: "warmup", Duration: 600, Target: &model.StepTarget{MinPace: 300, MaxPace: 300}},
+		{Type: "work", Distance: 5},
+	})
+	assert.InDelta(t, 7.0, km, 1e-9)
+	assert.True(t, complete)
+}
+
+func TestDescribeSteps(t *testing.T) {
+	assert.Equal(t,
+		"2km Warm-up\n5x (400m Work @ 3:50-4:00/km, 90s Recovery)\n2km Cool-down",
+		DescribeSteps(intervalSteps()),
+	)
+	assert.Equal(t,
+		"10min Work @ 150-160 bpm",
+		DescribeSteps([]model.WorkoutStep{{Type: "work", Duration: 600, Target: &model.StepTarget{MinHeartRate: 150, MaxHeartRate: 160}}}),
+	)
+}
+
+func TestWorkoutService_CreateWithSteps(t *testing.T) {
+	svc := setupWorkoutTest(t)
+	day := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("derives distance and description", func(t *testing.T) {
+		steps := []model.WorkoutStep{
+			{Type: "warmup", Distance: 2},
+			{Type: "repeat", Repeat: 4, Steps: []model.WorkoutStep{{Type: "work", Distance: 1}, {Type: "recovery", Distance: 0.5}}},
+			{Type: "cooldown", Distance: 2},
+		}
+		workout, err := svc.CreateWithSteps("plan-1", "intervals", day, "", 0, steps)
+		require.NoError(t, err)
+		assert.Equal(t, 10.0, workout.Distance)
+		assert.Equal(t, "2km Warm-up\n4x (1km Work, 500m Recovery)\n2km Cool-down", workout.Description)
+
+		stored, err := svc.GetByID(workout.ID)
+		require.NoError(t, err)
+		assert.Equal(t, steps, stored.Steps)
+	})
+
+	t.Run("keeps given distance when steps are incomplete", func(t *testing.T) {
+		workout, err := svc.CreateWithSteps("plan-1", "intervals", day, "Track session", 12, intervalSteps())
+		require.NoError(t, err)
+		assert.Equal(t, 12.0, workout.Distance)
+		assert.Equal(t, "Track session", workout.Description)
+	})
+
+	t.Run("invalid steps return ErrInvalidSteps", func(t *testing.T) {
+		_, err := svc.CreateWithSteps("plan-1", "intervals", day, "", 0, []model.WorkoutStep{{Type: "work"}})
+		assert.ErrorIs(t, err, ErrInvalidSteps)
+	})
+
+	t.Run("update re-derives distance", func(t *testing.T) {
+		workout, err := svc.Create("plan-1", "tempo_run", day, "Tempo", 8)
+		require.NoError(t, err)
+		workout.Steps = []model.WorkoutStep{{Type: "warmup", Distance: 3}, {Type: "work", Distance: 5}, {Type: "cooldown", Distance: 3}}
+		require.NoError(t, svc.Update(workout))
+		assert.Equal(t, 11.0, workout.Distance)
+	})
+
+	t.Run("batch validates steps per item", func(t *testing.T) {
+		plan := &model.TrainingPlan{ID: "plan-steps", StartDate: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), Weeks: 4}
+		_, err := svc.CreateBatch(plan, []BulkWorkoutInput{
+			{RunType: "easy_run", Week: 1, DayOfWeek: 1, Distance: 5},
+			{RunType: "intervals", Week: 1, DayOfWeek: 3, Steps: []model.WorkoutStep{{Type: "sprint", Distance: 1}}},
+		})
+		var bve *BatchValidationError
+		require.ErrorAs(t, err, &bve)
+		assert.Equal(t, 1, bve.Index)
+
+		workouts, err := svc.CreateBatch(plan, []BulkWorkoutInput{
+			{RunType: "intervals", Week: 1, DayOfWeek: 3, Steps: []model.WorkoutStep{{Type: "warmup", Distance: 2}, {Type: "work", Distance: 6}}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 8.0, workouts[0].Distance)
+		assert.Len(t, workouts[0].Steps, 2)
+	})
+}
+
+func TestParseDescriptionSteps(t *testing.T) {
+	t.Run("round-trips described steps", func(t *testing.T) {
+		steps, ok := ParseDescriptionSteps(DescribeSteps(intervalSteps()), 0)
+		require.True(t, ok)
+		assert.Equal(t, intervalSteps(), steps)
+	})
+
+	t.Run("easy blocks around tempo become warm-up and cool-down", func(t *testing.T) {
+		steps, ok := ParseDescriptionSteps("4k Easy\n3k Tempo\n3k Easy", 10)
+		require.True(t, ok)
+		assert.Equal(t, []model.WorkoutStep{
+			{Type: "warmup", Distance: 4},
+			{Type: "work", Distance: 3},
+			{Type: "cooldown", Distance: 3},
+		}, steps)
+	})
+
+	t.Run("extras follow the main run", func(t *testing.T) {
+		steps, ok := ParseDescriptionSteps("+4x 20s Strides", 8)
+		require.True(t, ok)
+		assert.Equal(t, []model.WorkoutStep{
+			{Type: "work", Distance: 8},
+			{Type: "repeat", Repeat: 4, Steps: []model.WorkoutStep{{Type: "work", Duration: 20}}},
+		}, steps)
+	})
+
+	t.Run("heart rate target and easy recovery inside repeat", func(t *testing.T) {
+		steps, ok := ParseDescriptionSteps("3x (5min @ 160-170 bpm, 2min easy)", 0)
+		require.True(t, ok)
+		assert.Equal(t, []model.WorkoutStep{
+			{Type: "repeat", Repeat: 3, Steps: []model.WorkoutStep{
+				{Type: "work", Duration: 300, Target: &model.StepTarget{MinHeartRate: 160, MaxHeartRate: 170}},
+				{Type: "recovery", Duration: 120},
+			}},
+		}, steps)
+	})
+
+	for _, desc := range []string{"", "All Easy", "Easy with some hills", "1x 400m"} {
+		t.Run("rejects "+desc, func(t *testing.T) {
+			_, ok := ParseDescriptionSteps(desc, 10)
+			assert.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Package session keeps gin sessions on the server. The cookie only holds
+// a signed random token, so a session can be listed and revoked, and
+// logging out really ends it.
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	ginsessions "github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+const (
+	// UserKey is the session value holding the logged-in user's ID.
+	UserKey = "uid"
+
+	defaultMaxAge = 30 * 24 * 60 * 60
+	// touchInterval limits how often reading a session writes its
+	// last-seen time.
+	touchInterval = time.Minute
+)
+
+// Store implements the gin-contrib/sessions Store interface on top of a
+// store.SessionStore. Sessions expire MaxAge after they were created,
+// however active they are.
+type Store struct {
+	sessions store.SessionStore
+	codecs   []securecookie.Codec
+	options  *gsessions.Options
+	now      func() time.Time
+}
+
+// NewStore signs the cookie with keyPairs, which work as in
+// cookie.NewStore.
+func NewStore(sessions store.SessionStore, keyPairs ...[]byte) *Store {
+	s := &Store{
+		sessions: sessions,
+		codecs:   securecookie.CodecsFromPairs(keyPairs...),
+		now:      time.Now,
+	}
+	s.Options(ginsessions.Options{Path: "/", MaxAge: defaultMaxAge, HttpOnly: true})
+	return s
+}
+
+func (s *Store) Options(options ginsessions.Options) {
+	if options.MaxAge == 0 {
+		options.MaxAge = defaultMaxAge
+	}
+	s.options = options.ToGorillaOptions()
+	for _, codec := range s.codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(options.MaxAge)
+		}
+	}
+}
+
+// Get returns the request's session, loading it once per request.
+func (s *Store) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session named by the request's cookie, or starts an empty
+// one when there is no valid cookie.
+func (s *Store) New(r *http.Request, name string) (*gsessions.Session, error) {
+	sess := gsessions.NewSession(s, name)
+	opts := *s.options
+	sess.Options = &opts
+	sess.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return sess, nil
+	}
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.codecs...); err != nil {
+		return sess, nil
+	}
+	row, err := s.sessions.GetByTokenHash(hashToken(token))
+	if errors.Is(err, store.ErrNotFound) {
+		return sess, nil
+	}
+	if err != nil {
+		return sess, err
+	}
+	now := s.now()
+	if !now.Before(row.ExpiresAt) {
+		_ = s.sessions.Delete(row.ID)
+		return sess, nil
+	}
+	if err := gob.NewDecoder(bytes.NewReader(row.Data)).Decode(&sess.Values); err != nil {
+		return sess, err
+	}
+	sess.ID = string(row.ID)
+	sess.IsNew = false
+
+	if now.Sub(row.LastSeenAt) >= touchInterval {
+		row.LastSeenAt = now.UTC()
+		row.IP = clientIP(r)
+		if err := s.sessions.Update(row); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return sess, err
+		}
+	}
+	return sess, nil
+}
+
+// Save stores the session. An empty session, or one with a negative
+// MaxAge, is deleted together with its cookie. Logging in starts a new
+// session under a new token, so a token planted before login is useless.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, sess *gsessions.Session) error {
+	if sess.Options.MaxAge < 0 || len(sess.Values) == 0 {
+		if sess.ID != "" {
+			if err := s.sessions.Delete(model.SessionID(sess.ID)); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+		}
+		opts := *sess.Options
+		opts.MaxAge = -1
+		http.SetCookie(w, gsessions.NewCookie(sess.Name(), "", &opts))
+		sess.ID = ""
+		return nil
+	}
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(sess.Values); err != nil {
+		return err
+	}
+	userID, _ := sess.Values[UserKey].(string)
+	now := s.now().UTC()
+
+	if sess.ID != "" {
+		row, err := s.sessions.GetByID(model.SessionID(sess.ID))
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		if err == nil && row.UserID == model.UserID(userID) {
+			row.Data = data.Bytes()
+			row.LastSeenAt = now
+			row.IP = clientIP(r)
+			return s.sessions.Update(row)
+		}
+		if err == nil {
+			if err := s.sessions.Delete(row.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+		}
+	}
+
+	token := newToken()
+	row := &model.Session{
+		ID:         model.SessionID(newSessionID()),
+		UserID:     model.UserID(userID),
+		TokenHash:  hashToken(token),
+		Data:       data.Bytes(),
+		UserAgent:  r.UserAgent(),
+		IP:         clientIP(r),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(time.Duration(sess.Options.MaxAge) * time.Second),
+	}
+	if err := s.sessions.Create(row); err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(sess.Name(), token, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, gsessions.NewCookie(sess.Name(), encoded, sess.Options))
+	sess.ID = string(row.ID)
+	sess.IsNew = false
+	return nil
+}
+
+type clientIPKey struct{}
+
+// ClientIP records the client address as gin resolves it, so the store
+// only believes X-Forwarded-For from the engine's trusted proxies. It must
+// run before the sessions middleware.
+func ClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), clientIPKey{}, c.ClientIP())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// clientIP is informational only. Without the ClientIP middleware it falls
+// back to the connection's address.
+func clientIP(r *http.Request) string {
+	if ip, _ := r.Context().Value(clientIPKey{}).(string); ip != "" {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func newToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
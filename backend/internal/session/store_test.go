@@ -0,0 +1,177 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ginsessions "github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kevsommer/runplanner/internal/store"
+	"github.com/kevsommer/runplanner/internal/store/mem"
+)
+
+func setupSessionRouter(t *testing.T) (*gin.Engine, *Store, store.SessionStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rows := mem.NewMemSessionStore()
+	s := NewStore(rows, []byte("test-secret"))
+
+	r := gin.New()
+	// httptest requests come from 192.0.2.1.
+	require.NoError(t, r.SetTrustedProxies([]string{"192.0.2.1"}))
+	r.Use(ClientIP(), ginsessions.Sessions("rp.sid", s))
+	r.POST("/login/:uid", func(c *gin.Context) {
+		sess := ginsessions.Default(c)
+		sess.Set(UserKey, c.Param("uid"))
+		require.NoError(t, sess.Save())
+		c.Status(http.StatusNoContent)
+	})
+	r.POST("/remember/:value", func(c *gin.Context) {
+		sess := ginsessions.Default(c)
+		sess.Set("remembered", c.Param("value"))
+		require.NoError(t, sess.Save())
+		c.Status(http.StatusNoContent)
+	})
+	r.POST("/logout", func(c *gin.Context) {
+		sess := ginsessions.Default(c)
+		sess.Clear()
+		require.NoError(t, sess.Save())
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/whoami", func(c *gin.Context) {
+		sess := ginsessions.Default(c)
+		uid, _ := sess.Get(UserKey).(string)
+		remembered, _ := sess.Get("remembered").(string)
+		c.JSON(http.StatusOK, gin.H{"uid": uid, "remembered": remembered, "id": sess.ID()})
+	})
+	return r, s, rows
+}
+
+func sessionRequest(r *gin.Engine, method, url string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	req.Header.Set("User-Agent", "test-browser")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStore(t *testing.T) {
+	t.Run("login creates a session row", func(t *testing.T) {
+		r, _, rows := setupSessionRouter(t)
+		w := sessionRequest(r, http.MethodPost, "/login/user1", nil)
+		require.Equal(t, http.StatusNoContent, w.Code)
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+
+		sessions, err := rows.ListByUser("user1")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "test-browser", sessions[0].UserAgent)
+		assert.Equal(t, "203.0.113.7", sessions[0].IP)
+		assert.WithinDuration(t, time.Now().Add(defaultMaxAge*time.Second), sessions[0].ExpiresAt, time.Minute)
+		assert.NotContains(t, cookies[0].Value, sessions[0].TokenHash)
+
+		w = sessionRequest(r, http.MethodGet, "/whoami", cookies)
+		assert.JSONEq(t, `{"uid": "user1", "remembered": "", "id": "`+string(sessions[0].ID)+`"}`, w.Body.String())
+	})
+
+	t.Run("X-Forwarded-For from an untrusted peer is ignored", func(t *testing.T) {
+		r, _, rows := setupSessionRouter(t)
+		req := httptest.NewRequest(http.MethodPost, "/login/user1", nil)
+		req.RemoteAddr = "198.51.100.9:4321"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		sessions, err := rows.ListByUser("user1")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "198.51.100.9", sessions[0].IP)
+	})
+
+	t.Run("login rotates the token", func(t *testing.T) {
+		r, _, rows := setupSessionRouter(t)
+		before := sessionRequest(r, http.MethodPost, "/remember/x", nil).Result().Cookies()
+		after := sessionRequest(r, http.MethodPost, "/login/user1", before).Result().Cookies()
+		require.Len(t, after, 1)
+		assert.NotEqual(t, before[0].Value, after[0].Value)
+
+		w := sessionRequest(r, http.MethodGet, "/whoami", after)
+		assert.Contains(t, w.Body.String(), `"remembered":"x"`)
+		w = sessionRequest(r, http.MethodGet, "/whoami", before)
+		assert.Contains(t, w.Body.String(), `"remembered":""`)
+
+		n, err := rows.DeleteExpired(time.Now().Add(24 * 365 * time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 1, n, "the anonymous session is replaced, not kept")
+	})
+
+	t.Run("logout deletes the row and the cookie", func(t *testing.T) {
+		r, _, rows := setupSessionRouter(t)
+		cookies := sessionRequest(r, http.MethodPost, "/login/user1", nil).Result().Cookies()
+
+		w := sessionRequest(r, http.MethodPost, "/logout", cookies)
+		require.Equal(t, http.StatusNoContent, w.Code)
+		cleared := w.Result().Cookies()
+		require.Len(t, cleared, 1)
+		assert.Less(t, cleared[0].MaxAge, 0)
+
+		sessions, err := rows.ListByUser("user1")
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+		w = sessionRequest(r, http.MethodGet, "/whoami", cookies)
+		assert.Contains(t, w.Body.String(), `"uid":""`)
+	})
+
+	t.Run("a revoked session is anonymous", func(t *testing.T) {
+		r, _, rows := setupSessionRouter(t)
+		cookies := sessionRequest(r, http.MethodPost, "/login/user1", nil).Result().Cookies()
+		require.NoError(t, rows.DeleteByUser("user1"))
+
+		w := sessionRequest(r, http.MethodGet, "/whoami", cookies)
+		assert.JSONEq(t, `{"uid": "", "remembered": "", "id": ""}`, w.Body.String())
+	})
+
+	t.Run("an expired session is anonymous and deleted", func(t *testing.T) {
+		r, s, rows := setupSessionRouter(t)
+		cookies := sessionRequest(r, http.MethodPost, "/login/user1", nil).Result().Cookies()
+		s.now = func() time.Time { return time.Now().Add((defaultMaxAge + 1) * time.Second) }
+
+		w := sessionRequest(r, http.MethodGet, "/whoami", cookies)
+		assert.Contains(t, w.Body.String(), `"uid":""`)
+		sessions, err := rows.ListByUser("user1")
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+
+	t.Run("reading a session updates its last-seen time", func(t *testing.T) {
+		r, s, rows := setupSessionRouter(t)
+		cookies := sessionRequest(r, http.MethodPost, "/login/user1", nil).Result().Cookies()
+		later := time.Now().Add(time.Hour).UTC()
+		s.now = func() time.Time { return later }
+
+		sessionRequest(r, http.MethodGet, "/whoami", cookies)
+		sessions, err := rows.ListByUser("user1")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.True(t, later.Equal(sessions[0].LastSeenAt))
+	})
+
+	t.Run("a forged cookie is ignored", func(t *testing.T) {
+		r, _, _ := setupSessionRouter(t)
+		forged := []*http.Cookie{{Name: "rp.sid", Value: "not-a-signed-token"}}
+		w := sessionRequest(r, http.MethodGet, "/whoami", forged)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"uid":""`)
+	})
+}
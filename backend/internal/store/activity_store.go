@@ -0,0 +1,9 @@
+package store
+
+import "github.com/kevsommer/runplanner/internal/model"
+
+type ActivityStore interface {
+	Create(activity *model.Activity) error
+	GetByWorkoutID(workoutID model.WorkoutID) (*model.Activity, error)
+	DeleteByWorkoutID(workoutID model.WorkoutID) error
+}
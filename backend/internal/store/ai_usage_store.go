@@ -0,0 +1,15 @@
+package store
+
+import (
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+type AIUsageStore interface {
+	Create(usage *model.AIUsage) error
+	// ListByUserSince returns the user's usage rows created at or after
+	// since, oldest first.
+	ListByUserSince(userID model.UserID, since time.Time) ([]*model.AIUsage, error)
+	DeleteByUser(userID model.UserID) error
+}
@@ -0,0 +1,17 @@
+package store
+
+import (
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+type APITokenStore interface {
+	Create(token *model.APIToken) error
+	GetByHash(hash string) (*model.APIToken, error)
+	// ListByUser returns the user's tokens, newest first.
+	ListByUser(userID model.UserID) ([]*model.APIToken, error)
+	Delete(id model.APITokenID) error
+	SetLastUsed(id model.APITokenID, at time.Time) error
+	DeleteByUser(userID model.UserID) error
+}
@@ -0,0 +1,19 @@
+package store
+
+import "github.com/kevsommer/runplanner/internal/model"
+
+type CoachStore interface {
+	CreateThread(thread *model.CoachThread) error
+	GetThread(id model.CoachThreadID) (*model.CoachThread, error)
+	// ListThreadsByPlan returns the plan's threads, most recently updated
+	// first.
+	ListThreadsByPlan(planID model.TrainingPlanID) ([]*model.CoachThread, error)
+	UpdateThread(thread *model.CoachThread) error
+	// DeleteThread removes a thread together with its messages.
+	DeleteThread(id model.CoachThreadID) error
+
+	AddMessage(message *model.CoachMessage) error
+	// ListMessages returns a thread's messages, oldest first.
+	ListMessages(threadID model.CoachThreadID) ([]*model.CoachMessage, error)
+	UpdateMessage(message *model.CoachMessage) error
+}
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+type CoachingStore interface {
+	// Create fails with ErrCoachingExists if the coach already coaches or
+	// has invited the athlete.
+	Create(coaching *model.Coaching) error
+	GetByID(id model.CoachingID) (*model.Coaching, error)
+	Get(coachID, athleteID model.UserID) (*model.Coaching, error)
+	// ListByCoach and ListByAthlete return newest first.
+	ListByCoach(coachID model.UserID) ([]*model.Coaching, error)
+	ListByAthlete(athleteID model.UserID) ([]*model.Coaching, error)
+	// Accept makes a pending coaching active.
+	Accept(id model.CoachingID, at time.Time) error
+	Delete(id model.CoachingID) error
+	// DeleteByUser removes the user's coachings in either role.
+	DeleteByUser(userID model.UserID) error
+}
+
+var ErrCoachingExists = Err("coaching already exists")
@@ -0,0 +1,18 @@
+package store
+
+import (
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+type EmailVerificationStore interface {
+	Create(v *model.EmailVerification) error
+	GetByTokenHash(hash string) (*model.EmailVerification, error)
+	// MarkUsed sets UsedAt on an unused verification; ErrNotFound if it
+	// does not exist or was already used.
+	MarkUsed(id model.EmailVerificationID, at time.Time) error
+	// InvalidateUser marks all of the user's unused verifications as used.
+	InvalidateUser(userID model.UserID, at time.Time) error
+	DeleteByUser(userID model.UserID) error
+}
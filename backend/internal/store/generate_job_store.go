@@ -0,0 +1,17 @@
+package store
+
+import (
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+type GenerateJobStore interface {
+	Create(job *model.GenerateJob) error
+	GetByID(id model.GenerateJobID) (*model.GenerateJob, error)
+	Update(job *model.GenerateJob) error
+	// FailUnfinished marks every pending or running job as failed with the
+	// given message. Used at startup for jobs cut off by a restart.
+	FailUnfinished(message string, at time.Time) error
+	DeleteByUser(userID model.UserID) error
+}
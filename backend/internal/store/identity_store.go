@@ -0,0 +1,14 @@
+package store
+
+import "github.com/kevsommer/runplanner/internal/model"
+
+type IdentityStore interface {
+	// Create fails with ErrIdentityTaken if the provider subject is
+	// already linked.
+	Create(identity *model.Identity) error
+	GetBySubject(provider, subject string) (*model.Identity, error)
+	ListByUser(userID model.UserID) ([]*model.Identity, error)
+	DeleteByUser(userID model.UserID) error
+}
+
+var ErrIdentityTaken = Err("identity already linked")
@@ -0,0 +1,43 @@
+package mem
+
+import (
+	"sync"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memActivityStore struct {
+	mu          sync.RWMutex
+	byWorkoutID map[model.WorkoutID]*model.Activity
+}
+
+func NewMemActivityStore() store.ActivityStore {
+	return &memActivityStore{
+		byWorkoutID: make(map[model.WorkoutID]*model.Activity),
+	}
+}
+
+func (s *memActivityStore) Create(activity *model.Activity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byWorkoutID[activity.WorkoutID] = activity
+	return nil
+}
+
+func (s *memActivityStore) GetByWorkoutID(workoutID model.WorkoutID) (*model.Activity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.byWorkoutID[workoutID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return a, nil
+}
+
+func (s *memActivityStore) DeleteByWorkoutID(workoutID model.WorkoutID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.byWorkoutID, workoutID)
+	return nil
+}
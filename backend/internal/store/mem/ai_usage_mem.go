@@ -0,0 +1,52 @@
+package mem
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memAIUsageStore struct {
+	mu   sync.RWMutex
+	rows []*model.AIUsage
+}
+
+func NewMemAIUsageStore() store.AIUsageStore {
+	return &memAIUsageStore{}
+}
+
+func (s *memAIUsageStore) Create(usage *model.AIUsage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := *usage
+	s.rows = append(s.rows, &u)
+	return nil
+}
+
+func (s *memAIUsageStore) ListByUserSince(userID model.UserID, since time.Time) ([]*model.AIUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*model.AIUsage
+	for _, u := range s.rows {
+		if u.UserID == userID && !u.CreatedAt.Before(since) {
+			c := *u
+			out = append(out, &c)
+		}
+	}
+	return out, nil
+}
+
+func (s *memAIUsageStore) DeleteByUser(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.rows[:0]
+	for _, u := range s.rows {
+		if u.UserID != userID {
+			kept = append(kept, u)
+		}
+	}
+	s.rows = kept
+	return nil
+}
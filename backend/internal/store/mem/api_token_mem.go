@@ -0,0 +1,88 @@
+package mem
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memAPITokenStore struct {
+	mu   sync.RWMutex
+	byID map[model.APITokenID]*model.APIToken
+}
+
+func NewMemAPITokenStore() store.APITokenStore {
+	return &memAPITokenStore{byID: make(map[model.APITokenID]*model.APIToken)}
+}
+
+func copyAPIToken(t *model.APIToken) *model.APIToken {
+	c := *t
+	c.Scopes = append([]string(nil), t.Scopes...)
+	return &c
+}
+
+func (s *memAPITokenStore) Create(token *model.APIToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byID[token.ID] = copyAPIToken(token)
+	return nil
+}
+
+func (s *memAPITokenStore) GetByHash(hash string) (*model.APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.byID {
+		if t.TokenHash == hash {
+			return copyAPIToken(t), nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *memAPITokenStore) ListByUser(userID model.UserID) ([]*model.APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*model.APIToken
+	for _, t := range s.byID {
+		if t.UserID == userID {
+			out = append(out, copyAPIToken(t))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *memAPITokenStore) Delete(id model.APITokenID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.byID, id)
+	return nil
+}
+
+func (s *memAPITokenStore) SetLastUsed(id model.APITokenID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.byID[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	t.LastUsedAt = &at
+	return nil
+}
+
+func (s *memAPITokenStore) DeleteByUser(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, t := range s.byID {
+		if t.UserID == userID {
+			delete(s.byID, id)
+		}
+	}
+	return nil
+}
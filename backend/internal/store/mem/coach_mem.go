@@ -0,0 +1,116 @@
+package mem
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memCoachStore struct {
+	mu       sync.RWMutex
+	threads  map[model.CoachThreadID]*model.CoachThread
+	messages map[model.CoachThreadID][]*model.CoachMessage
+}
+
+func NewMemCoachStore() store.CoachStore {
+	return &memCoachStore{
+		threads:  make(map[model.CoachThreadID]*model.CoachThread),
+		messages: make(map[model.CoachThreadID][]*model.CoachMessage),
+	}
+}
+
+// Threads and messages are copied in and out so callers can change them
+// without a store update, as with the SQLite store.
+func (s *memCoachStore) CreateThread(thread *model.CoachThread) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := *thread
+	s.threads[thread.ID] = &t
+	return nil
+}
+
+func (s *memCoachStore) GetThread(id model.CoachThreadID) (*model.CoachThread, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.threads[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	out := *t
+	return &out, nil
+}
+
+func (s *memCoachStore) ListThreadsByPlan(planID model.TrainingPlanID) ([]*model.CoachThread, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var threads []*model.CoachThread
+	for _, t := range s.threads {
+		if t.PlanID == planID {
+			out := *t
+			threads = append(threads, &out)
+		}
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].UpdatedAt.After(threads[j].UpdatedAt)
+	})
+	return threads, nil
+}
+
+func (s *memCoachStore) UpdateThread(thread *model.CoachThread) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.threads[thread.ID]; !ok {
+		return store.ErrNotFound
+	}
+	t := *thread
+	s.threads[thread.ID] = &t
+	return nil
+}
+
+func (s *memCoachStore) DeleteThread(id model.CoachThreadID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.threads[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.threads, id)
+	delete(s.messages, id)
+	return nil
+}
+
+func (s *memCoachStore) AddMessage(message *model.CoachMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[message.ThreadID] = append(s.messages[message.ThreadID], copyCoachMessage(message))
+	return nil
+}
+
+func (s *memCoachStore) ListMessages(threadID model.CoachThreadID) ([]*model.CoachMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	messages := make([]*model.CoachMessage, 0, len(s.messages[threadID]))
+	for _, m := range s.messages[threadID] {
+		messages = append(messages, copyCoachMessage(m))
+	}
+	return messages, nil
+}
+
+func (s *memCoachStore) UpdateMessage(message *model.CoachMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.messages[message.ThreadID] {
+		if m.ID == message.ID {
+			s.messages[message.ThreadID][i] = copyCoachMessage(message)
+			return nil
+		}
+	}
+	return store.ErrNotFound
+}
+
+func copyCoachMessage(m *model.CoachMessage) *model.CoachMessage {
+	out := *m
+	out.Actions = append([]model.CoachAction(nil), m.Actions...)
+	return &out
+}
@@ -0,0 +1,110 @@
+package mem
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memCoachingStore struct {
+	mu   sync.RWMutex
+	byID map[model.CoachingID]*model.Coaching
+}
+
+func NewMemCoachingStore() store.CoachingStore {
+	return &memCoachingStore{byID: make(map[model.CoachingID]*model.Coaching)}
+}
+
+func (s *memCoachingStore) Create(coaching *model.Coaching) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.byID {
+		if c.CoachID == coaching.CoachID && c.AthleteID == coaching.AthleteID {
+			return store.ErrCoachingExists
+		}
+	}
+	c := *coaching
+	s.byID[coaching.ID] = &c
+	return nil
+}
+
+func (s *memCoachingStore) GetByID(id model.CoachingID) (*model.Coaching, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.byID[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (s *memCoachingStore) Get(coachID, athleteID model.UserID) (*model.Coaching, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.byID {
+		if c.CoachID == coachID && c.AthleteID == athleteID {
+			cp := *c
+			return &cp, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *memCoachingStore) list(match func(*model.Coaching) bool) []*model.Coaching {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*model.Coaching
+	for _, c := range s.byID {
+		if match(c) {
+			cp := *c
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+func (s *memCoachingStore) ListByCoach(coachID model.UserID) ([]*model.Coaching, error) {
+	return s.list(func(c *model.Coaching) bool { return c.CoachID == coachID }), nil
+}
+
+func (s *memCoachingStore) ListByAthlete(athleteID model.UserID) ([]*model.Coaching, error) {
+	return s.list(func(c *model.Coaching) bool { return c.AthleteID == athleteID }), nil
+}
+
+func (s *memCoachingStore) Accept(id model.CoachingID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.byID[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	c.Status = model.CoachingActive
+	c.AcceptedAt = &at
+	return nil
+}
+
+func (s *memCoachingStore) Delete(id model.CoachingID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.byID, id)
+	return nil
+}
+
+func (s *memCoachingStore) DeleteByUser(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, c := range s.byID {
+		if c.CoachID == userID || c.AthleteID == userID {
+			delete(s.byID, id)
+		}
+	}
+	return nil
+}
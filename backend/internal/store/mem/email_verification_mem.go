@@ -0,0 +1,72 @@
+package mem
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memEmailVerificationStore struct {
+	mu   sync.RWMutex
+	byID map[model.EmailVerificationID]*model.EmailVerification
+}
+
+func NewMemEmailVerificationStore() store.EmailVerificationStore {
+	return &memEmailVerificationStore{byID: make(map[model.EmailVerificationID]*model.EmailVerification)}
+}
+
+func (s *memEmailVerificationStore) Create(v *model.EmailVerification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *v
+	s.byID[c.ID] = &c
+	return nil
+}
+
+func (s *memEmailVerificationStore) GetByTokenHash(hash string) (*model.EmailVerification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.byID {
+		if v.TokenHash == hash {
+			c := *v
+			return &c, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *memEmailVerificationStore) MarkUsed(id model.EmailVerificationID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.byID[id]
+	if !ok || v.UsedAt != nil {
+		return store.ErrNotFound
+	}
+	v.UsedAt = &at
+	return nil
+}
+
+func (s *memEmailVerificationStore) InvalidateUser(userID model.UserID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range s.byID {
+		if v.UserID == userID && v.UsedAt == nil {
+			t := at
+			v.UsedAt = &t
+		}
+	}
+	return nil
+}
+
+func (s *memEmailVerificationStore) DeleteByUser(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, v := range s.byID {
+		if v.UserID == userID {
+			delete(s.byID, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package mem
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memGenerateJobStore struct {
+	mu   sync.RWMutex
+	byID map[model.GenerateJobID]*model.GenerateJob
+}
+
+func NewMemGenerateJobStore() store.GenerateJobStore {
+	return &memGenerateJobStore{
+		byID: make(map[model.GenerateJobID]*model.GenerateJob),
+	}
+}
+
+// Jobs are copied in and out since the runner keeps updating its own copy.
+func (s *memGenerateJobStore) Create(job *model.GenerateJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := *job
+	s.byID[job.ID] = &j
+	return nil
+}
+
+func (s *memGenerateJobStore) GetByID(id model.GenerateJobID) (*model.GenerateJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	j, ok := s.byID[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	out := *j
+	return &out, nil
+}
+
+func (s *memGenerateJobStore) Update(job *model.GenerateJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[job.ID]; !ok {
+		return store.ErrNotFound
+	}
+	j := *job
+	s.byID[job.ID] = &j
+	return nil
+}
+
+func (s *memGenerateJobStore) FailUnfinished(message string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.byID {
+		if !j.Finished() {
+			j.Status = model.JobFailed
+			j.Error = message
+			j.UpdatedAt = at
+		}
+	}
+	return nil
+}
+
+func (s *memGenerateJobStore) DeleteByUser(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, j := range s.byID {
+		if j.UserID == userID {
+			delete(s.byID, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package mem
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memIdentityStore struct {
+	mu   sync.RWMutex
+	byID map[model.IdentityID]*model.Identity
+}
+
+func NewMemIdentityStore() store.IdentityStore {
+	return &memIdentityStore{byID: make(map[model.IdentityID]*model.Identity)}
+}
+
+func (s *memIdentityStore) Create(identity *model.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, i := range s.byID {
+		if i.Provider == identity.Provider && i.Subject == identity.Subject {
+			return store.ErrIdentityTaken
+		}
+	}
+	c := *identity
+	s.byID[identity.ID] = &c
+	return nil
+}
+
+func (s *memIdentityStore) GetBySubject(provider, subject string) (*model.Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, i := range s.byID {
+		if i.Provider == provider && i.Subject == subject {
+			c := *i
+			return &c, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *memIdentityStore) ListByUser(userID model.UserID) ([]*model.Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*model.Identity
+	for _, i := range s.byID {
+		if i.UserID == userID {
+			c := *i
+			out = append(out, &c)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].CreatedAt.Before(out[b].CreatedAt) })
+	return out, nil
+}
+
+func (s *memIdentityStore) DeleteByUser(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, i := range s.byID {
+		if i.UserID == userID {
+			delete(s.byID, id)
+		}
+	}
+	return nil
+}
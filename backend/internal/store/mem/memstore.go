@@ -67,6 +67,82 @@ func (s *memUserStore) SetActivePlan(userID model.UserID, planID *model.Training
 	return nil
 }
 
+func (s *memUserStore) SetPasswordHash(userID model.UserID, passwordHash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	u.PasswordHash = passwordHash
+	return nil
+}
+
+func (s *memUserStore) SetEmail(userID model.UserID, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if owner, exists := s.byEmail[email]; exists && owner != userID {
+		return store.ErrEmailTaken
+	}
+	delete(s.byEmail, u.Email)
+	s.byEmail[email] = userID
+	u.Email = email
+	u.EmailVerifiedAt = nil
+	return nil
+}
+
+func (s *memUserStore) SetEmailVerified(userID model.UserID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	u.EmailVerifiedAt = &at
+	return nil
+}
+
+func (s *memUserStore) SetCalendarTokenHash(userID model.UserID, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	u.CalendarTokenHash = hash
+	return nil
+}
+
+func (s *memUserStore) GetUserByCalendarTokenHash(hash string) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if hash == "" {
+		return nil, store.ErrNotFound
+	}
+	for _, u := range s.byID {
+		if u.CalendarTokenHash == hash {
+			return u, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *memUserStore) DeleteUser(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	delete(s.byEmail, u.Email)
+	delete(s.byID, userID)
+	return nil
+}
+
 func newID() string {
 	b := make([]byte, 16)
 	_, _ = rand.Read(b)
@@ -0,0 +1,72 @@
+package mem
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memPasswordResetStore struct {
+	mu   sync.RWMutex
+	byID map[model.PasswordResetID]*model.PasswordReset
+}
+
+func NewMemPasswordResetStore() store.PasswordResetStore {
+	return &memPasswordResetStore{byID: make(map[model.PasswordResetID]*model.PasswordReset)}
+}
+
+func (s *memPasswordResetStore) Create(reset *model.PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := *reset
+	s.byID[r.ID] = &r
+	return nil
+}
+
+func (s *memPasswordResetStore) GetByTokenHash(hash string) (*model.PasswordReset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.byID {
+		if r.TokenHash == hash {
+			c := *r
+			return &c, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *memPasswordResetStore) MarkUsed(id model.PasswordResetID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.byID[id]
+	if !ok || r.UsedAt != nil {
+		return store.ErrNotFound
+	}
+	r.UsedAt = &at
+	return nil
+}
+
+func (s *memPasswordResetStore) InvalidateUser(userID model.UserID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.byID {
+		if r.UserID == userID && r.UsedAt == nil {
+			t := at
+			r.UsedAt = &t
+		}
+	}
+	return nil
+}
+
+func (s *memPasswordResetStore) DeleteByUser(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, r := range s.byID {
+		if r.UserID == userID {
+			delete(s.byID, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package mem
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memPlanEditStore struct {
+	mu   sync.RWMutex
+	byID map[model.PlanEditID]*model.PlanEdit
+}
+
+func NewMemPlanEditStore() store.PlanEditStore {
+	return &memPlanEditStore{byID: make(map[model.PlanEditID]*model.PlanEdit)}
+}
+
+func (s *memPlanEditStore) Create(edit *model.PlanEdit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := *edit
+	s.byID[edit.ID] = &e
+	return nil
+}
+
+func (s *memPlanEditStore) ListByPlan(planID model.TrainingPlanID) ([]*model.PlanEdit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*model.PlanEdit
+	for _, e := range s.byID {
+		if e.PlanID == planID {
+			c := *e
+			out = append(out, &c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *memPlanEditStore) DeleteByPlan(planID model.TrainingPlanID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, e := range s.byID {
+		if e.PlanID == planID {
+			delete(s.byID, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package mem
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memSessionStore struct {
+	mu   sync.RWMutex
+	byID map[model.SessionID]*model.Session
+}
+
+func NewMemSessionStore() store.SessionStore {
+	return &memSessionStore{byID: make(map[model.SessionID]*model.Session)}
+}
+
+func copySession(s *model.Session) *model.Session {
+	c := *s
+	c.Data = append([]byte(nil), s.Data...)
+	return &c
+}
+
+func (s *memSessionStore) Create(session *model.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byID[session.ID] = copySession(session)
+	return nil
+}
+
+func (s *memSessionStore) GetByID(id model.SessionID) (*model.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.byID[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return copySession(session), nil
+}
+
+func (s *memSessionStore) GetByTokenHash(hash string) (*model.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, session := range s.byID {
+		if session.TokenHash == hash {
+			return copySession(session), nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *memSessionStore) Update(session *model.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.byID[session.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	existing.UserID = session.UserID
+	existing.Data = append([]byte(nil), session.Data...)
+	existing.IP = session.IP
+	existing.LastSeenAt = session.LastSeenAt
+	return nil
+}
+
+func (s *memSessionStore) ListByUser(userID model.UserID) ([]*model.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*model.Session
+	for _, session := range s.byID {
+		if session.UserID == userID {
+			out = append(out, copySession(session))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeenAt.After(out[j].LastSeenAt) })
+	return out, nil
+}
+
+func (s *memSessionStore) Delete(id model.SessionID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.byID, id)
+	return nil
+}
+
+func (s *memSessionStore) DeleteByUser(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, session := range s.byID {
+		if session.UserID == userID {
+			delete(s.byID, id)
+		}
+	}
+	return nil
+}
+
+func (s *memSessionStore) DeleteExpired(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, session := range s.byID {
+		if !now.Before(session.ExpiresAt) {
+			delete(s.byID, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,84 @@
+package mem
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memShareLinkStore struct {
+	mu   sync.RWMutex
+	byID map[model.ShareLinkID]*model.ShareLink
+}
+
+func NewMemShareLinkStore() store.ShareLinkStore {
+	return &memShareLinkStore{byID: make(map[model.ShareLinkID]*model.ShareLink)}
+}
+
+func (s *memShareLinkStore) Create(link *model.ShareLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *link
+	s.byID[link.ID] = &c
+	return nil
+}
+
+func (s *memShareLinkStore) GetByID(id model.ShareLinkID) (*model.ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l, ok := s.byID[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	c := *l
+	return &c, nil
+}
+
+func (s *memShareLinkStore) GetBySlug(slug string) (*model.ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, l := range s.byID {
+		if l.Slug == slug {
+			c := *l
+			return &c, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *memShareLinkStore) ListByPlan(planID model.TrainingPlanID) ([]*model.ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*model.ShareLink
+	for _, l := range s.byID {
+		if l.PlanID == planID {
+			c := *l
+			out = append(out, &c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *memShareLinkStore) Delete(id model.ShareLinkID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.byID, id)
+	return nil
+}
+
+func (s *memShareLinkStore) DeleteByPlan(planID model.TrainingPlanID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, l := range s.byID {
+		if l.PlanID == planID {
+			delete(s.byID, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package mem
+
+import (
+	"sync"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type memTwoFactorStore struct {
+	mu     sync.RWMutex
+	byUser map[model.UserID]*model.TwoFactor
+}
+
+func NewMemTwoFactorStore() store.TwoFactorStore {
+	return &memTwoFactorStore{byUser: make(map[model.UserID]*model.TwoFactor)}
+}
+
+func copyTwoFactor(tf *model.TwoFactor) *model.TwoFactor {
+	c := *tf
+	c.RecoveryCodeHashes = append([]string(nil), tf.RecoveryCodeHashes...)
+	return &c
+}
+
+func (s *memTwoFactorStore) Get(userID model.UserID) (*model.TwoFactor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tf, ok := s.byUser[userID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return copyTwoFactor(tf), nil
+}
+
+func (s *memTwoFactorStore) Save(tf *model.TwoFactor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byUser[tf.UserID] = copyTwoFactor(tf)
+	return nil
+}
+
+func (s *memTwoFactorStore) Delete(userID model.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byUser[userID]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.byUser, userID)
+	return nil
+}
@@ -0,0 +1,19 @@
+package store
+
+import (
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+type PasswordResetStore interface {
+	Create(reset *model.PasswordReset) error
+	GetByTokenHash(hash string) (*model.PasswordReset, error)
+	// MarkUsed sets UsedAt on an unused reset. It returns ErrNotFound if the
+	// reset does not exist or was already used, so a token is consumed once
+	// even under concurrent requests.
+	MarkUsed(id model.PasswordResetID, at time.Time) error
+	// InvalidateUser marks all of the user's unused resets as used.
+	InvalidateUser(userID model.UserID, at time.Time) error
+	DeleteByUser(userID model.UserID) error
+}
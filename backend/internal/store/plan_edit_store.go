@@ -0,0 +1,10 @@
+package store
+
+import "github.com/kevsommer/runplanner/internal/model"
+
+type PlanEditStore interface {
+	Create(edit *model.PlanEdit) error
+	// ListByPlan returns the plan's edits, newest first.
+	ListByPlan(planID model.TrainingPlanID) ([]*model.PlanEdit, error)
+	DeleteByPlan(planID model.TrainingPlanID) error
+}
@@ -0,0 +1,22 @@
+package store
+
+import (
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+type SessionStore interface {
+	Create(session *model.Session) error
+	GetByID(id model.SessionID) (*model.Session, error)
+	GetByTokenHash(hash string) (*model.Session, error)
+	// Update saves the user, data and last-seen time of a session.
+	Update(session *model.Session) error
+	// ListByUser returns the user's sessions, most recently seen first.
+	ListByUser(userID model.UserID) ([]*model.Session, error)
+	Delete(id model.SessionID) error
+	DeleteByUser(userID model.UserID) error
+	// DeleteExpired removes sessions that expired before now and returns
+	// how many there were.
+	DeleteExpired(now time.Time) (int, error)
+}
@@ -0,0 +1,13 @@
+package store
+
+import "github.com/kevsommer/runplanner/internal/model"
+
+type ShareLinkStore interface {
+	Create(link *model.ShareLink) error
+	GetByID(id model.ShareLinkID) (*model.ShareLink, error)
+	GetBySlug(slug string) (*model.ShareLink, error)
+	// ListByPlan returns the plan's links, newest first.
+	ListByPlan(planID model.TrainingPlanID) ([]*model.ShareLink, error)
+	Delete(id model.ShareLinkID) error
+	DeleteByPlan(planID model.TrainingPlanID) error
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type ActivityStore struct {
+	db *sql.DB
+}
+
+func NewActivityStore(db *sql.DB) *ActivityStore {
+	return &ActivityStore{db: db}
+}
+
+func (s *ActivityStore) Create(activity *model.Activity) error {
+	laps, err := json.Marshal(activity.Laps)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO activities (id, workout_id, source, start_time, distance, moving_time, avg_pace, elevation_gain, avg_heart_rate, max_heart_rate, avg_cadence, laps, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		activity.ID, activity.WorkoutID, activity.Source, activity.StartTime, activity.Distance, activity.MovingTime, activity.AvgPace, activity.ElevationGain,
+		activity.AvgHeartRate, activity.MaxHeartRate, activity.AvgCadence, string(laps), activity.CreatedAt,
+	)
+	return err
+}
+
+func (s *ActivityStore) GetByWorkoutID(workoutID model.WorkoutID) (*model.Activity, error) {
+	row := s.db.QueryRow(
+		`SELECT id, workout_id, source, start_time, distance, moving_time, avg_pace, elevation_gain, avg_heart_rate, max_heart_rate, avg_cadence, laps, created_at FROM activities WHERE workout_id = ?`,
+		workoutID,
+	)
+	var a model.Activity
+	var laps string
+	if err := row.Scan(&a.ID, &a.WorkoutID, &a.Source, &a.StartTime, &a.Distance, &a.MovingTime, &a.AvgPace, &a.ElevationGain,
+		&a.AvgHeartRate, &a.MaxHeartRate, &a.AvgCadence, &laps, &a.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(laps), &a.Laps); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *ActivityStore) DeleteByWorkoutID(workoutID model.WorkoutID) error {
+	_, err := s.db.Exec(`DELETE FROM activities WHERE workout_id = ?`, workoutID)
+	return err
+}
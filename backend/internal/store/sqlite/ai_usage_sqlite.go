@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+type AIUsageStore struct {
+	db *sql.DB
+}
+
+func NewAIUsageStore(db *sql.DB) *AIUsageStore {
+	return &AIUsageStore{db: db}
+}
+
+func (s *AIUsageStore) Create(usage *model.AIUsage) error {
+	_, err := s.db.Exec(
+		`INSERT INTO ai_usage (id, user_id, feature, requests, input_tokens, output_tokens, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		usage.ID, usage.UserID, usage.Feature, usage.Requests, usage.InputTokens, usage.OutputTokens, usage.CreatedAt,
+	)
+	return err
+}
+
+func (s *AIUsageStore) ListByUserSince(userID model.UserID, since time.Time) ([]*model.AIUsage, error) {
+	rows, err := s.db.Query(
+		`SELECT id, user_id, feature, requests, input_tokens, output_tokens, created_at FROM ai_usage WHERE user_id = ? AND created_at >= ? ORDER BY created_at ASC`,
+		userID, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*model.AIUsage
+	for rows.Next() {
+		var u model.AIUsage
+		if err := rows.Scan(&u.ID, &u.UserID, &u.Feature, &u.Requests, &u.InputTokens, &u.OutputTokens, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &u)
+	}
+	return out, rows.Err()
+}
+
+func (s *AIUsageStore) DeleteByUser(userID model.UserID) error {
+	_, err := s.db.Exec(`DELETE FROM ai_usage WHERE user_id = ?`, userID)
+	return err
+}
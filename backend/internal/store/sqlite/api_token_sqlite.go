@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type APITokenStore struct {
+	db *sql.DB
+}
+
+func NewAPITokenStore(db *sql.DB) *APITokenStore {
+	return &APITokenStore{db: db}
+}
+
+const apiTokenColumns = `id, user_id, name, scopes, prefix, token_hash, expires_at, last_used_at, created_at`
+
+func (s *APITokenStore) Create(token *model.APIToken) error {
+	scopes, err := json.Marshal(token.Scopes)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO api_tokens (`+apiTokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.ID, token.UserID, token.Name, string(scopes), token.Prefix, token.TokenHash, token.ExpiresAt, token.LastUsedAt, token.CreatedAt,
+	)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIToken(row rowScanner) (*model.APIToken, error) {
+	var t model.APIToken
+	var scopes string
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &t.Prefix, &t.TokenHash, &t.ExpiresAt, &lastUsedAt, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &t.Scopes); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		t.LastUsedAt = &lastUsedAt.Time
+	}
+	return &t, nil
+}
+
+func (s *APITokenStore) GetByHash(hash string) (*model.APIToken, error) {
+	t, err := scanAPIToken(s.db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	return t, err
+}
+
+func (s *APITokenStore) ListByUser(userID model.UserID) ([]*model.APIToken, error) {
+	rows, err := s.db.Query(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []*model.APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *APITokenStore) Delete(id model.APITokenID) error {
+	res, err := s.db.Exec(`DELETE FROM api_tokens WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *APITokenStore) SetLastUsed(id model.APITokenID, at time.Time) error {
+	res, err := s.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, at, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *APITokenStore) DeleteByUser(userID model.UserID) error {
+	_, err := s.db.Exec(`DELETE FROM api_tokens WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type CoachStore struct {
+	db *sql.DB
+}
+
+func NewCoachStore(db *sql.DB) *CoachStore {
+	return &CoachStore{db: db}
+}
+
+func (s *CoachStore) CreateThread(thread *model.CoachThread) error {
+	_, err := s.db.Exec(
+		`INSERT INTO coach_threads (id, user_id, plan_id, title, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		thread.ID, thread.UserID, thread.PlanID, thread.Title, thread.CreatedAt, thread.UpdatedAt,
+	)
+	return err
+}
+
+func (s *CoachStore) GetThread(id model.CoachThreadID) (*model.CoachThread, error) {
+	row := s.db.QueryRow(
+		`SELECT id, user_id, plan_id, title, created_at, updated_at FROM coach_threads WHERE id = ?`,
+		id,
+	)
+	var t model.CoachThread
+	if err := row.Scan(&t.ID, &t.UserID, &t.PlanID, &t.Title, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *CoachStore) ListThreadsByPlan(planID model.TrainingPlanID) ([]*model.CoachThread, error) {
+	rows, err := s.db.Query(
+		`SELECT id, user_id, plan_id, title, created_at, updated_at FROM coach_threads WHERE plan_id = ? ORDER BY updated_at DESC`,
+		planID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var threads []*model.CoachThread
+	for rows.Next() {
+		var t model.CoachThread
+		if err := rows.Scan(&t.ID, &t.UserID, &t.PlanID, &t.Title, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		threads = append(threads, &t)
+	}
+	return threads, rows.Err()
+}
+
+func (s *CoachStore) UpdateThread(thread *model.CoachThread) error {
+	res, err := s.db.Exec(
+		`UPDATE coach_threads SET title = ?, updated_at = ? WHERE id = ?`,
+		thread.Title, thread.UpdatedAt, thread.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *CoachStore) DeleteThread(id model.CoachThreadID) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM coach_messages WHERE thread_id = ?`, id); err != nil {
+		return err
+	}
+	res, err := tx.Exec(`DELETE FROM coach_threads WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(res); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *CoachStore) AddMessage(message *model.CoachMessage) error {
+	actions, err := json.Marshal(message.Actions)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO coach_messages (id, thread_id, role, content, actions, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		message.ID, message.ThreadID, message.Role, message.Content, string(actions), message.CreatedAt,
+	)
+	return err
+}
+
+func (s *CoachStore) ListMessages(threadID model.CoachThreadID) ([]*model.CoachMessage, error) {
+	// rowid breaks ties between a question and a reply stored in the same
+	// instant.
+	rows, err := s.db.Query(
+		`SELECT id, thread_id, role, content, actions, created_at FROM coach_messages WHERE thread_id = ? ORDER BY created_at ASC, rowid ASC`,
+		threadID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages := []*model.CoachMessage{}
+	for rows.Next() {
+		var m model.CoachMessage
+		var actions string
+		if err := rows.Scan(&m.ID, &m.ThreadID, &m.Role, &m.Content, &actions, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(actions), &m.Actions); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+	return messages, rows.Err()
+}
+
+func (s *CoachStore) UpdateMessage(message *model.CoachMessage) error {
+	actions, err := json.Marshal(message.Actions)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(
+		`UPDATE coach_messages SET content = ?, actions = ? WHERE id = ?`,
+		message.Content, string(actions), message.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func checkRowsAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
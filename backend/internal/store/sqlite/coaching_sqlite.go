@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type CoachingStore struct {
+	db *sql.DB
+}
+
+func NewCoachingStore(db *sql.DB) *CoachingStore {
+	return &CoachingStore{db: db}
+}
+
+const coachingColumns = `id, coach_id, athlete_id, status, created_at, accepted_at`
+
+func (s *CoachingStore) Create(coaching *model.Coaching) error {
+	_, err := s.db.Exec(
+		`INSERT INTO coachings (`+coachingColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		coaching.ID, coaching.CoachID, coaching.AthleteID, coaching.Status, coaching.CreatedAt, coaching.AcceptedAt,
+	)
+	if isUniqueViolation(err) {
+		return store.ErrCoachingExists
+	}
+	return err
+}
+
+func scanCoaching(row rowScanner) (*model.Coaching, error) {
+	var c model.Coaching
+	var acceptedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.CoachID, &c.AthleteID, &c.Status, &c.CreatedAt, &acceptedAt); err != nil {
+		return nil, err
+	}
+	if acceptedAt.Valid {
+		c.AcceptedAt = &acceptedAt.Time
+	}
+	return &c, nil
+}
+
+func (s *CoachingStore) getOne(query string, args ...interface{}) (*model.Coaching, error) {
+	c, err := scanCoaching(s.db.QueryRow(`SELECT `+coachingColumns+` FROM coachings WHERE `+query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	return c, err
+}
+
+func (s *CoachingStore) GetByID(id model.CoachingID) (*model.Coaching, error) {
+	return s.getOne(`id = ?`, id)
+}
+
+func (s *CoachingStore) Get(coachID, athleteID model.UserID) (*model.Coaching, error) {
+	return s.getOne(`coach_id = ? AND athlete_id = ?`, coachID, athleteID)
+}
+
+func (s *CoachingStore) list(query string, arg interface{}) ([]*model.Coaching, error) {
+	rows, err := s.db.Query(`SELECT `+coachingColumns+` FROM coachings WHERE `+query+` ORDER BY created_at DESC`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var coachings []*model.Coaching
+	for rows.Next() {
+		c, err := scanCoaching(rows)
+		if err != nil {
+			return nil, err
+		}
+		coachings = append(coachings, c)
+	}
+	return coachings, rows.Err()
+}
+
+func (s *CoachingStore) ListByCoach(coachID model.UserID) ([]*model.Coaching, error) {
+	return s.list(`coach_id = ?`, coachID)
+}
+
+func (s *CoachingStore) ListByAthlete(athleteID model.UserID) ([]*model.Coaching, error) {
+	return s.list(`athlete_id = ?`, athleteID)
+}
+
+func (s *CoachingStore) Accept(id model.CoachingID, at time.Time) error {
+	res, err := s.db.Exec(`UPDATE coachings SET status = ?, accepted_at = ? WHERE id = ?`, model.CoachingActive, at, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *CoachingStore) Delete(id model.CoachingID) error {
+	res, err := s.db.Exec(`DELETE FROM coachings WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *CoachingStore) DeleteByUser(userID model.UserID) error {
+	_, err := s.db.Exec(`DELETE FROM coachings WHERE coach_id = ? OR athlete_id = ?`, userID, userID)
+	return err
+}
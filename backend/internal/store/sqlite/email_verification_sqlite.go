@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type EmailVerificationStore struct {
+	db *sql.DB
+}
+
+func NewEmailVerificationStore(db *sql.DB) *EmailVerificationStore {
+	return &EmailVerificationStore{db: db}
+}
+
+func (s *EmailVerificationStore) Create(v *model.EmailVerification) error {
+	_, err := s.db.Exec(
+		`INSERT INTO email_verifications (id, user_id, token_hash, expires_at, used_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		v.ID, v.UserID, v.TokenHash, v.ExpiresAt, v.UsedAt, v.CreatedAt,
+	)
+	return err
+}
+
+func (s *EmailVerificationStore) GetByTokenHash(hash string) (*model.EmailVerification, error) {
+	var v model.EmailVerification
+	var usedAt sql.NullTime
+	err := s.db.QueryRow(
+		`SELECT id, user_id, token_hash, expires_at, used_at, created_at FROM email_verifications WHERE token_hash = ?`,
+		hash,
+	).Scan(&v.ID, &v.UserID, &v.TokenHash, &v.ExpiresAt, &usedAt, &v.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	if usedAt.Valid {
+		v.UsedAt = &usedAt.Time
+	}
+	return &v, nil
+}
+
+func (s *EmailVerificationStore) MarkUsed(id model.EmailVerificationID, at time.Time) error {
+	res, err := s.db.Exec(`UPDATE email_verifications SET used_at = ? WHERE id = ? AND used_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *EmailVerificationStore) InvalidateUser(userID model.UserID, at time.Time) error {
+	_, err := s.db.Exec(`UPDATE email_verifications SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, at, userID)
+	return err
+}
+
+func (s *EmailVerificationStore) DeleteByUser(userID model.UserID) error {
+	_, err := s.db.Exec(`DELETE FROM email_verifications WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type GenerateJobStore struct {
+	db *sql.DB
+}
+
+func NewGenerateJobStore(db *sql.DB) *GenerateJobStore {
+	return &GenerateJobStore{db: db}
+}
+
+func (s *GenerateJobStore) Create(job *model.GenerateJob) error {
+	warnings, err := json.Marshal(job.Warnings)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO generate_jobs (id, user_id, status, phase, plan_id, attempts, warnings, error, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.UserID, job.Status, job.Phase, job.PlanID, job.Attempts, string(warnings), job.Error, job.CreatedAt, job.UpdatedAt,
+	)
+	return err
+}
+
+func (s *GenerateJobStore) GetByID(id model.GenerateJobID) (*model.GenerateJob, error) {
+	row := s.db.QueryRow(
+		`SELECT id, user_id, status, phase, plan_id, attempts, warnings, error, created_at, updated_at FROM generate_jobs WHERE id = ?`,
+		id,
+	)
+	var j model.GenerateJob
+	var planID sql.NullString
+	var warnings string
+	if err := row.Scan(&j.ID, &j.UserID, &j.Status, &j.Phase, &planID, &j.Attempts, &warnings, &j.Error, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	if planID.Valid {
+		id := model.TrainingPlanID(planID.String)
+		j.PlanID = &id
+	}
+	if err := json.Unmarshal([]byte(warnings), &j.Warnings); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (s *GenerateJobStore) Update(job *model.GenerateJob) error {
+	warnings, err := json.Marshal(job.Warnings)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(
+		`UPDATE generate_jobs SET status = ?, phase = ?, plan_id = ?, attempts = ?, warnings = ?, error = ?, updated_at = ? WHERE id = ?`,
+		job.Status, job.Phase, job.PlanID, job.Attempts, string(warnings), job.Error, job.UpdatedAt, job.ID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func (s *GenerateJobStore) FailUnfinished(message string, at time.Time) error {
+	_, err := s.db.Exec(
+		`UPDATE generate_jobs SET status = ?, error = ?, updated_at = ? WHERE status IN (?, ?)`,
+		model.JobFailed, message, at, model.JobPending, model.JobRunning,
+	)
+	return err
+}
+
+func (s *GenerateJobStore) DeleteByUser(userID model.UserID) error {
+	_, err := s.db.Exec(`DELETE FROM generate_jobs WHERE user_id = ?`, userID)
+	return err
+}
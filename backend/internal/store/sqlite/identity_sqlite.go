@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type IdentityStore struct {
+	db *sql.DB
+}
+
+func NewIdentityStore(db *sql.DB) *IdentityStore {
+	return &IdentityStore{db: db}
+}
+
+const identityColumns = `id, user_id, provider, subject, email, created_at`
+
+func (s *IdentityStore) Create(identity *model.Identity) error {
+	_, err := s.db.Exec(
+		`INSERT INTO user_identities (`+identityColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt,
+	)
+	if isUniqueViolation(err) {
+		return store.ErrIdentityTaken
+	}
+	return err
+}
+
+func scanIdentity(row rowScanner) (*model.Identity, error) {
+	var i model.Identity
+	if err := row.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (s *IdentityStore) GetBySubject(provider, subject string) (*model.Identity, error) {
+	i, err := scanIdentity(s.db.QueryRow(
+		`SELECT `+identityColumns+` FROM user_identities WHERE provider = ? AND subject = ?`, provider, subject,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	return i, err
+}
+
+func (s *IdentityStore) ListByUser(userID model.UserID) ([]*model.Identity, error) {
+	rows, err := s.db.Query(`SELECT `+identityColumns+` FROM user_identities WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var identities []*model.Identity
+	for rows.Next() {
+		i, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
+
+func (s *IdentityStore) DeleteByUser(userID model.UserID) error {
+	_, err := s.db.Exec(`DELETE FROM user_identities WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type PasswordResetStore struct {
+	db *sql.DB
+}
+
+func NewPasswordResetStore(db *sql.DB) *PasswordResetStore {
+	return &PasswordResetStore{db: db}
+}
+
+func (s *PasswordResetStore) Create(reset *model.PasswordReset) error {
+	_, err := s.db.Exec(
+		`INSERT INTO password_resets (id, user_id, token_hash, expires_at, used_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		reset.ID, reset.UserID, reset.TokenHash, reset.ExpiresAt, reset.UsedAt, reset.CreatedAt,
+	)
+	return err
+}
+
+func (s *PasswordResetStore) GetByTokenHash(hash string) (*model.PasswordReset, error) {
+	var r model.PasswordReset
+	var usedAt sql.NullTime
+	err := s.db.QueryRow(
+		`SELECT id, user_id, token_hash, expires_at, used_at, created_at FROM password_resets WHERE token_hash = ?`,
+		hash,
+	).Scan(&r.ID, &r.UserID, &r.TokenHash, &r.ExpiresAt, &usedAt, &r.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	if usedAt.Valid {
+		r.UsedAt = &usedAt.Time
+	}
+	return &r, nil
+}
+
+func (s *PasswordResetStore) MarkUsed(id model.PasswordResetID, at time.Time) error {
+	res, err := s.db.Exec(`UPDATE password_resets SET used_at = ? WHERE id = ? AND used_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *PasswordResetStore) InvalidateUser(userID model.UserID, at time.Time) error {
+	_, err := s.db.Exec(`UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, at, userID)
+	return err
+}
+
+func (s *PasswordResetStore) DeleteByUser(userID model.UserID) error {
+	_, err := s.db.Exec(`DELETE FROM password_resets WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
+
+type PlanEditStore struct {
+	db *sql.DB
+}
+
+func NewPlanEditStore(db *sql.DB) *PlanEditStore {
+	return &PlanEditStore{db: db}
+}
+
+const planEditColumns = `id, plan_id, workout_id, editor_id, action, created_at`
+
+func (s *PlanEditStore) Create(edit *model.PlanEdit) error {
+	_, err := s.db.Exec(
+		`INSERT INTO plan_edits (`+planEditColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		edit.ID, edit.PlanID, edit.WorkoutID, edit.EditorID, edit.Action, edit.CreatedAt,
+	)
+	return err
+}
+
+func (s *PlanEditStore) ListByPlan(planID model.TrainingPlanID) ([]*model.PlanEdit, error) {
+	rows, err := s.db.Query(`SELECT `+planEditColumns+` FROM plan_edits WHERE plan_id = ? ORDER BY created_at DESC`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var edits []*model.PlanEdit
+	for rows.Next() {
+		var e model.PlanEdit
+		var workoutID sql.NullString
+		if err := rows.Scan(&e.ID, &e.PlanID, &workoutID, &e.EditorID, &e.Action, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if workoutID.Valid {
+			id := model.WorkoutID(workoutID.String)
+			e.WorkoutID = &id
+		}
+		edits = append(edits, &e)
+	}
+	return edits, rows.Err()
+}
+
+func (s *PlanEditStore) DeleteByPlan(planID model.TrainingPlanID) error {
+	_, err := s.db.Exec(`DELETE FROM plan_edits WHERE plan_id = ?`, planID)
+	return err
+}
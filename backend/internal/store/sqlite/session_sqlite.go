@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type SessionStore struct {
+	db *sql.DB
+}
+
+func NewSessionStore(db *sql.DB) *SessionStore {
+	return &SessionStore{db: db}
+}
+
+const sessionColumns = `id, user_id, token_hash, data, user_agent, ip, created_at, last_seen_at, expires_at`
+
+func (s *SessionStore) Create(session *model.Session) error {
+	_, err := s.db.Exec(
+		`INSERT INTO sessions (`+sessionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, nullableUserID(session.UserID), session.TokenHash, session.Data, session.UserAgent, session.IP,
+		session.CreatedAt, session.LastSeenAt, session.ExpiresAt,
+	)
+	return err
+}
+
+func scanSession(row rowScanner) (*model.Session, error) {
+	var session model.Session
+	var userID sql.NullString
+	if err := row.Scan(&session.ID, &userID, &session.TokenHash, &session.Data, &session.UserAgent, &session.IP,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+		return nil, err
+	}
+	session.UserID = model.UserID(userID.String)
+	return &session, nil
+}
+
+func (s *SessionStore) get(where string, arg interface{}) (*model.Session, error) {
+	session, err := scanSession(s.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE `+where+` = ?`, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	return session, err
+}
+
+func (s *SessionStore) GetByID(id model.SessionID) (*model.Session, error) {
+	return s.get("id", id)
+}
+
+func (s *SessionStore) GetByTokenHash(hash string) (*model.Session, error) {
+	return s.get("token_hash", hash)
+}
+
+func (s *SessionStore) Update(session *model.Session) error {
+	res, err := s.db.Exec(
+		`UPDATE sessions SET user_id = ?, data = ?, ip = ?, last_seen_at = ? WHERE id = ?`,
+		nullableUserID(session.UserID), session.Data, session.IP, session.LastSeenAt, session.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *SessionStore) ListByUser(userID model.UserID) ([]*model.Session, error) {
+	rows, err := s.db.Query(`SELECT `+sessionColumns+` FROM sessions WHERE user_id = ? ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []*model.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *SessionStore) Delete(id model.SessionID) error {
+	res, err := s.db.Exec(`DELETE FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *SessionStore) DeleteByUser(userID model.UserID) error {
+	_, err := s.db.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID)
+	return err
+}
+
+func (s *SessionStore) DeleteExpired(now time.Time) (int, error) {
+	res, err := s.db.Exec(`DELETE FROM sessions WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// nullableUserID stores anonymous sessions with a NULL user.
+func nullableUserID(id model.UserID) interface{} {
+	if id == "" {
+		return nil
+	}
+	return id
+}
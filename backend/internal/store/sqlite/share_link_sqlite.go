@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type ShareLinkStore struct {
+	db *sql.DB
+}
+
+func NewShareLinkStore(db *sql.DB) *ShareLinkStore {
+	return &ShareLinkStore{db: db}
+}
+
+const shareLinkColumns = `id, plan_id, slug, expires_at, created_at`
+
+func (s *ShareLinkStore) Create(link *model.ShareLink) error {
+	_, err := s.db.Exec(
+		`INSERT INTO share_links (`+shareLinkColumns+`) VALUES (?, ?, ?, ?, ?)`,
+		link.ID, link.PlanID, link.Slug, link.ExpiresAt, link.CreatedAt,
+	)
+	return err
+}
+
+func scanShareLink(row rowScanner) (*model.ShareLink, error) {
+	var l model.ShareLink
+	var expiresAt sql.NullTime
+	if err := row.Scan(&l.ID, &l.PlanID, &l.Slug, &expiresAt, &l.CreatedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		l.ExpiresAt = &expiresAt.Time
+	}
+	return &l, nil
+}
+
+func (s *ShareLinkStore) getOne(query string, arg interface{}) (*model.ShareLink, error) {
+	l, err := scanShareLink(s.db.QueryRow(`SELECT `+shareLinkColumns+` FROM share_links WHERE `+query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	return l, err
+}
+
+func (s *ShareLinkStore) GetByID(id model.ShareLinkID) (*model.ShareLink, error) {
+	return s.getOne(`id = ?`, id)
+}
+
+func (s *ShareLinkStore) GetBySlug(slug string) (*model.ShareLink, error) {
+	return s.getOne(`slug = ?`, slug)
+}
+
+func (s *ShareLinkStore) ListByPlan(planID model.TrainingPlanID) ([]*model.ShareLink, error) {
+	rows, err := s.db.Query(`SELECT `+shareLinkColumns+` FROM share_links WHERE plan_id = ? ORDER BY created_at DESC`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var links []*model.ShareLink
+	for rows.Next() {
+		l, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+func (s *ShareLinkStore) Delete(id model.ShareLinkID) error {
+	res, err := s.db.Exec(`DELETE FROM share_links WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *ShareLinkStore) DeleteByPlan(planID model.TrainingPlanID) error {
+	_, err := s.db.Exec(`DELETE FROM share_links WHERE plan_id = ?`, planID)
+	return err
+}
@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/kevsommer/runplanner/internal/model"
+	"github.com/kevsommer/runplanner/internal/store"
+)
+
+type TwoFactorStore struct {
+	db *sql.DB
+}
+
+func NewTwoFactorStore(db *sql.DB) *TwoFactorStore {
+	return &TwoFactorStore{db: db}
+}
+
+func (s *TwoFactorStore) Get(userID model.UserID) (*model.TwoFactor, error) {
+	var tf model.TwoFactor
+	var enabledAt sql.NullTime
+	var codes string
+	err := s.db.QueryRow(
+		`SELECT user_id, secret, enabled_at, last_used_step, recovery_code_hashes, created_at FROM two_factor WHERE user_id = ?`,
+		userID,
+	).Scan(&tf.UserID, &tf.Secret, &enabledAt, &tf.LastUsedStep, &codes, &tf.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enabledAt.Valid {
+		tf.EnabledAt = &enabledAt.Time
+	}
+	if err := json.Unmarshal([]byte(codes), &tf.RecoveryCodeHashes); err != nil {
+		return nil, err
+	}
+	return &tf, nil
+}
+
+func (s *TwoFactorStore) Save(tf *model.TwoFactor) error {
+	codes, err := json.Marshal(tf.RecoveryCodeHashes)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO two_factor (user_id, secret, enabled_at, last_used_step, recovery_code_hashes, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(user_id) DO UPDATE SET
+		   secret = excluded.secret,
+		   enabled_at = excluded.enabled_at,
+		   last_used_step = excluded.last_used_step,
+		   recovery_code_hashes = excluded.recovery_code_hashes,
+		   created_at = excluded.created_at`,
+		tf.UserID, tf.Secret, tf.EnabledAt, tf.LastUsedStep, string(codes), tf.CreatedAt,
+	)
+	return err
+}
+
+func (s *TwoFactorStore) Delete(userID model.UserID) error {
+	res, err := s.db.Exec(`DELETE FROM two_factor WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
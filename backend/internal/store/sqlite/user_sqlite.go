@@ -50,7 +50,7 @@ func (s *UserStore) CreateUser(email string, hash []byte) (*model.User, error) {
 
 func (s *UserStore) GetUserByEmail(email string) (*model.User, error) {
 	row := s.db.QueryRow(
-		`SELECT id, email, password_hash, created_at, active_plan_id FROM users WHERE email = ?`,
+		`SELECT id, email, password_hash, created_at, active_plan_id, calendar_token_hash, email_verified_at FROM users WHERE email = ?`,
 		email,
 	)
 	return scanUser(row)
@@ -58,7 +58,7 @@ func (s *UserStore) GetUserByEmail(email string) (*model.User, error) {
 
 func (s *UserStore) GetUserByID(id model.UserID) (*model.User, error) {
 	row := s.db.QueryRow(
-		`SELECT id, email, password_hash, created_at, active_plan_id FROM users WHERE id = ?`,
+		`SELECT id, email, password_hash, created_at, active_plan_id, calendar_token_hash, email_verified_at FROM users WHERE id = ?`,
 		id,
 	)
 	u, err := scanUser(row)
@@ -80,10 +80,63 @@ func (s *UserStore) SetActivePlan(userID model.UserID, planID *model.TrainingPla
 	return err
 }
 
+func (s *UserStore) SetPasswordHash(userID model.UserID, passwordHash []byte) error {
+	res, err := s.db.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *UserStore) SetEmail(userID model.UserID, email string) error {
+	res, err := s.db.Exec(`UPDATE users SET email = ?, email_verified_at = NULL WHERE id = ?`, email, userID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return store.ErrEmailTaken
+		}
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *UserStore) SetEmailVerified(userID model.UserID, at time.Time) error {
+	res, err := s.db.Exec(`UPDATE users SET email_verified_at = ? WHERE id = ?`, at, userID)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *UserStore) SetCalendarTokenHash(userID model.UserID, hash string) error {
+	var val interface{}
+	if hash != "" {
+		val = hash
+	}
+	_, err := s.db.Exec(`UPDATE users SET calendar_token_hash = ? WHERE id = ?`, val, userID)
+	return err
+}
+
+func (s *UserStore) GetUserByCalendarTokenHash(hash string) (*model.User, error) {
+	row := s.db.QueryRow(
+		`SELECT id, email, password_hash, created_at, active_plan_id, calendar_token_hash, email_verified_at FROM users WHERE calendar_token_hash = ?`,
+		hash,
+	)
+	return scanUser(row)
+}
+
+func (s *UserStore) DeleteUser(userID model.UserID) error {
+	res, err := s.db.Exec(`DELETE FROM users WHERE id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
 func scanUser(row *sql.Row) (*model.User, error) {
 	var u model.User
-	var activePlanID sql.NullString
-	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt, &activePlanID); err != nil {
+	var activePlanID, calendarTokenHash sql.NullString
+	var emailVerifiedAt sql.NullTime
+	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt, &activePlanID, &calendarTokenHash, &emailVerifiedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, store.ErrNotFound
 		}
@@ -93,6 +146,10 @@ func scanUser(row *sql.Row) (*model.User, error) {
 		id := model.TrainingPlanID(activePlanID.String)
 		u.ActivePlanID = &id
 	}
+	u.CalendarTokenHash = calendarTokenHash.String
+	if emailVerifiedAt.Valid {
+		u.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
 	return &u, nil
 }
 
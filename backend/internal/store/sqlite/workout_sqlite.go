@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -18,9 +19,13 @@ func NewWorkoutStore(db *sql.DB) *WorkoutStore {
 }
 
 func (s *WorkoutStore) Create(workout *model.Workout) error {
-	_, err := s.db.Exec(
-		`INSERT INTO workouts (id, plan_id, runType, day, description, notes, status, distance) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		workout.ID, workout.PlanID, workout.RunType, workout.Day.Format(dateFormat), workout.Description, workout.Notes, workout.Status, workout.Distance,
+	steps, err := marshalSteps(workout.Steps)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO workouts (id, plan_id, runType, day, description, notes, status, distance, steps) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		workout.ID, workout.PlanID, workout.RunType, workout.Day.Format(dateFormat), workout.Description, workout.Notes, workout.Status, workout.Distance, steps,
 	)
 	return err
 }
@@ -31,9 +36,14 @@ func (s *WorkoutStore) CreateBatch(workouts []*model.Workout) error {
 		return err
 	}
 	for _, w := range workouts {
-		_, err := tx.Exec(
-			`INSERT INTO workouts (id, plan_id, runType, day, description, notes, status, distance) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-			w.ID, w.PlanID, w.RunType, w.Day.Format(dateFormat), w.Description, w.Notes, w.Status, w.Distance,
+		steps, err := marshalSteps(w.Steps)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		_, err = tx.Exec(
+			`INSERT INTO workouts (id, plan_id, runType, day, description, notes, status, distance, steps) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			w.ID, w.PlanID, w.RunType, w.Day.Format(dateFormat), w.Description, w.Notes, w.Status, w.Distance, steps,
 		)
 		if err != nil {
 			tx.Rollback()
@@ -45,7 +55,7 @@ func (s *WorkoutStore) CreateBatch(workouts []*model.Workout) error {
 
 func (s *WorkoutStore) GetByID(id model.WorkoutID) (*model.Workout, error) {
 	row := s.db.QueryRow(
-		`SELECT id, plan_id, runType, day, description, notes, status, distance FROM workouts WHERE id = ?`,
+		`SELECT id, plan_id, runType, day, description, notes, status, distance, steps FROM workouts WHERE id = ?`,
 		id,
 	)
 	return scanWorkout(row)
@@ -53,7 +63,7 @@ func (s *WorkoutStore) GetByID(id model.WorkoutID) (*model.Workout, error) {
 
 func (s *WorkoutStore) GetByPlanID(planID model.TrainingPlanID) ([]*model.Workout, error) {
 	rows, err := s.db.Query(
-		`SELECT id, plan_id, runType, day, description, notes, status, distance FROM workouts WHERE plan_id = ?`,
+		`SELECT id, plan_id, runType, day, description, notes, status, distance, steps FROM workouts WHERE plan_id = ?`,
 		planID,
 	)
 	if err != nil {
@@ -72,9 +82,13 @@ func (s *WorkoutStore) GetByPlanID(planID model.TrainingPlanID) ([]*model.Workou
 }
 
 func (s *WorkoutStore) Update(workout *model.Workout) error {
-	_, err := s.db.Exec(
-		`UPDATE workouts SET runType = ?, day = ?, description = ?, notes = ?, status = ?, distance = ? WHERE id = ?`,
-		workout.RunType, workout.Day.Format(dateFormat), workout.Description, workout.Notes, workout.Status, workout.Distance, workout.ID,
+	steps, err := marshalSteps(workout.Steps)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`UPDATE workouts SET runType = ?, day = ?, description = ?, notes = ?, status = ?, distance = ?, steps = ? WHERE id = ?`,
+		workout.RunType, workout.Day.Format(dateFormat), workout.Description, workout.Notes, workout.Status, workout.Distance, steps, workout.ID,
 	)
 	return err
 }
@@ -85,15 +99,19 @@ func (s *WorkoutStore) Delete(id model.WorkoutID) error {
 }
 
 func scanWorkout(row *sql.Row) (*model.Workout, error) {
-	var id, pid, runType, dayStr, description, notes, status string
+	var id, pid, runType, dayStr, description, notes, status, stepsJSON string
 	var distance float64
-	if err := row.Scan(&id, &pid, &runType, &dayStr, &description, &notes, &status, &distance); err != nil {
+	if err := row.Scan(&id, &pid, &runType, &dayStr, &description, &notes, &status, &distance, &stepsJSON); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, store.ErrNotFound
 		}
 		return nil, err
 	}
 	day, _ := time.Parse(dateFormat, dayStr)
+	steps, err := unmarshalSteps(stepsJSON)
+	if err != nil {
+		return nil, err
+	}
 	return &model.Workout{
 		ID:          model.WorkoutID(id),
 		PlanID:      model.TrainingPlanID(pid),
@@ -103,17 +121,22 @@ func scanWorkout(row *sql.Row) (*model.Workout, error) {
 		Notes:       notes,
 		Status:      status,
 		Distance:    distance,
+		Steps:       steps,
 	}, nil
 }
 
 func scanWorkoutFromRows(rows *sql.Rows) (*model.Workout, error) {
-	var id, pid, runType, dayStr, description, notes, status string
+	var id, pid, runType, dayStr, description, notes, status, stepsJSON string
 	var distance float64
-	if err := rows.Scan(&id, &pid, &runType, &dayStr, &description, &notes, &status, &distance); err != nil {
+	if err := rows.Scan(&id, &pid, &runType, &dayStr, &description, &notes, &status, &distance, &stepsJSON); err != nil {
 		return nil, err
 	}
 
 	day, _ := time.Parse(dateFormat, dayStr)
+	steps, err := unmarshalSteps(stepsJSON)
+	if err != nil {
+		return nil, err
+	}
 	return &model.Workout{
 		ID:          model.WorkoutID(id),
 		PlanID:      model.TrainingPlanID(pid),
@@ -123,5 +146,26 @@ func scanWorkoutFromRows(rows *sql.Rows) (*model.Workout, error) {
 		Notes:       notes,
 		Status:      status,
 		Distance:    distance,
+		Steps:       steps,
 	}, nil
 }
+
+// Steps are stored as a JSON array; workouts without structure store "[]".
+func marshalSteps(steps []model.WorkoutStep) (string, error) {
+	if len(steps) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal(steps)
+	return string(b), err
+}
+
+func unmarshalSteps(s string) ([]model.WorkoutStep, error) {
+	var steps []model.WorkoutStep
+	if err := json.Unmarshal([]byte(s), &steps); err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	return steps, nil
+}
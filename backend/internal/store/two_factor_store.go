@@ -0,0 +1,10 @@
+package store
+
+import "github.com/kevsommer/runplanner/internal/model"
+
+type TwoFactorStore interface {
+	Get(userID model.UserID) (*model.TwoFactor, error)
+	// Save creates or replaces the user's enrolment.
+	Save(tf *model.TwoFactor) error
+	Delete(userID model.UserID) error
+}
@@ -1,6 +1,10 @@
 package store
 
-import "github.com/kevsommer/runplanner/internal/model"
+import (
+	"time"
+
+	"github.com/kevsommer/runplanner/internal/model"
+)
 
 // UserStore defines the persistence boundary.
 type UserStore interface {
@@ -8,6 +12,17 @@ type UserStore interface {
 	GetUserByEmail(email string) (*model.User, error)
 	GetUserByID(id model.UserID) (*model.User, error)
 	SetActivePlan(userID model.UserID, planID *model.TrainingPlanID) error
+	SetPasswordHash(userID model.UserID, passwordHash []byte) error
+	// SetEmail changes the user's address and marks it unverified;
+	// ErrEmailTaken if another account uses it.
+	SetEmail(userID model.UserID, email string) error
+	SetEmailVerified(userID model.UserID, at time.Time) error
+	// SetCalendarTokenHash replaces the feed token hash; "" disables the feed.
+	SetCalendarTokenHash(userID model.UserID, hash string) error
+	GetUserByCalendarTokenHash(hash string) (*model.User, error)
+	// DeleteUser removes only the user row; the services delete the
+	// user's other data first.
+	DeleteUser(userID model.UserID) error
 }
 
 // Domain errors for portability.
@@ -1,6 +1,39 @@
-import axios from 'axios'
+import axios, { type InternalAxiosRequestConfig } from 'axios'
 
 export const api = axios.create({
   baseURL: import.meta.env.VITE_API_BASE_URL ?? '/api',
   withCredentials: true, // send cookie on XHR/fetch
 })
+
+// State-changing requests echo the CSRF token from /auth/csrf in a header.
+// The token is fetched once and again if the server stops accepting it.
+let csrfToken: Promise<string> | null = null
+
+function fetchCSRFToken(): Promise<string> {
+  csrfToken ??= api.get('/auth/csrf').then(r => r.data.csrfToken).catch(e => {
+    csrfToken = null
+    throw e
+  })
+  return csrfToken
+}
+
+const safeMethods = ['get', 'head', 'options']
+
+type RetriedConfig = InternalAxiosRequestConfig & { csrfRetried?: boolean }
+
+api.interceptors.request.use(async config => {
+  if (!safeMethods.includes((config.method ?? 'get').toLowerCase())) {
+    config.headers.set('X-CSRF-Token', await fetchCSRFToken())
+  }
+  return config
+})
+
+api.interceptors.response.use(r => r, async err => {
+  const config = err?.config as RetriedConfig | undefined
+  if (err?.response?.status === 403 && err.response.data?.error === 'invalid CSRF token' && config && !config.csrfRetried) {
+    csrfToken = null
+    config.csrfRetried = true
+    return api.request(config)
+  }
+  return Promise.reject(err)
+})
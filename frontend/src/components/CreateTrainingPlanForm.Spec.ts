@@ -1,4 +1,4 @@
-import { describe, it, expect, beforeEach } from "vitest";
+import { describe, it, expect, beforeEach, afterEach, vi } from "vitest";
 import { mount, flushPromises } from "@vue/test-utils";
 import PrimeVue from "primevue/config";
 import { ToastService } from "primevue";
@@ -17,29 +17,56 @@ function mountForm() {
 }
 
 beforeEach(() => {
+  vi.useFakeTimers();
+  api.get.mockReset();
   api.post.mockReset();
   router.push.mockReset();
   toast.add.mockReset();
 });
 
+afterEach(() => {
+  vi.useRealTimers();
+});
+
 describe("CreateTrainingPlanForm", () => {
-  it("redirects to plan page on successful creation (AI mode, default)", async () => {
-    api.post.mockResolvedValue({ data: { plan: { id: "plan-42" } } });
+  it("redirects to plan page once the generation job succeeds (AI mode, default)", async () => {
+    api.post.mockResolvedValue({ data: { job: { id: "job-1", status: "pending", phase: "" } } });
+    api.get.mockResolvedValue({ data: { job: { id: "job-1", status: "succeeded", planId: "plan-42" } } });
     const wrapper = mountForm();
 
     await wrapper.find("form").trigger("submit");
+    await vi.runAllTimersAsync();
     await flushPromises();
 
     expect(api.post).toHaveBeenCalledWith(
       "/plans/generate",
       expect.objectContaining({ name: "", weeks: 10 }),
     );
+    expect(api.get).toHaveBeenCalledWith("/generate-jobs/job-1");
     expect(router.push).toHaveBeenCalledWith({
       name: "plan",
       params: { id: "plan-42" },
     });
   });
 
+  it("shows the job error when generation fails", async () => {
+    api.post.mockResolvedValue({ data: { job: { id: "job-1", status: "running", phase: "prompting" } } });
+    api.get.mockResolvedValue({ data: { job: { id: "job-1", status: "failed", error: "AI generation failed: rate limited" } } });
+    const wrapper = mountForm();
+
+    await wrapper.find("form").trigger("submit");
+    await vi.runAllTimersAsync();
+    await flushPromises();
+
+    expect(router.push).not.toHaveBeenCalled();
+    expect(toast.add).toHaveBeenCalledWith(
+      expect.objectContaining({
+        severity: "error",
+        detail: "AI generation failed: rate limited",
+      }),
+    );
+  });
+
   it("shows error toast and does not redirect on API failure", async () => {
     api.post.mockRejectedValue(new Error("Server error"));
     const wrapper = mountForm();
@@ -28,8 +28,17 @@ async function check() {
 }
 
 
-async function login(email: string, password: string) {
-  await api.post('/auth/login', { email, password })
+// login resolves to true when the account needs a second factor; finish
+// with loginTwoFactor.
+async function login(email: string, password: string): Promise<boolean> {
+  const { data } = await api.post('/auth/login', { email, password })
+  if (data?.twoFactorRequired) return true
+  await check()
+  return false
+}
+
+async function loginTwoFactor(code: string) {
+  await api.post('/auth/login/2fa', { code })
   await check()
 }
 
@@ -51,5 +60,5 @@ function setActivePlanId(id: string | null) {
 }
 
 export function useAuth() {
-  return { user, loading, error, isAuthed: computed(() => !!user.value), check, login, register, logout, setActivePlanId }
+  return { user, loading, error, isAuthed: computed(() => !!user.value), check, login, loginTwoFactor, register, logout, setActivePlanId }
 }
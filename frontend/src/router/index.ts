@@ -10,8 +10,14 @@ const routes: RouteRecordRaw[] = [
   { path: '/login', name: 'login', component: LoginView, meta: { public: true } },
   { path: '/register', name: 'register', component: RegisterView, meta: { public: true } },
   { path: '/logout', name: 'logout', component: LogoutView, meta: { public: true } },
+  { path: '/forgot-password', name: 'forgot-password', component: () => import('@/views/ForgotPasswordView.vue'), meta: { public: true } },
+  { path: '/verify-email', name: 'verify-email', component: () => import('@/views/VerifyEmailView.vue'), meta: { public: true } },
+  { path: '/reset-password', name: 'reset-password', component: () => import('@/views/ResetPasswordView.vue'), meta: { public: true } },
   { path: '/dashboard', name: 'dashboard', component: () => import('@/views/DashboardView.vue'), meta: { requiresAuth: true } },
+  { path: '/security', name: 'security', component: () => import('@/views/SecurityView.vue'), meta: { requiresAuth: true } },
+  { path: '/coaching', name: 'coaching', component: () => import('@/views/CoachingView.vue'), meta: { requiresAuth: true } },
   { path: '/plans/:id', name: 'plan', component: () => import('@/views/PlanView.vue'), meta: { requiresAuth: true } },
+  { path: '/shared/:slug', name: 'shared-plan', component: () => import('@/views/SharedPlanView.vue'), meta: { public: true } },
 ]
 
 api.interceptors.response.use(r => r, err => {
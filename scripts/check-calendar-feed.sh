@@ -0,0 +1,31 @@
+#!/bin/sh
+# Checks against a running docker compose stack that the calendar feed URL
+# handed out by the API serves the feed rather than the app's HTML.
+#
+#   docker compose up -d --build && scripts/check-calendar-feed.sh
+set -eu
+
+base=${1:-http://localhost:3000}
+jar=$(mktemp)
+trap 'rm -f "$jar"' EXIT
+
+csrf=$(curl -fsS -c "$jar" -b "$jar" "$base/api/auth/csrf" | sed -n 's/.*"csrfToken":"\([^"]*\)".*/\1/p')
+email="feed-check-$(date +%s)@example.com"
+curl -fsS -o /dev/null -c "$jar" -b "$jar" -H "X-CSRF-Token: $csrf" -H "Content-Type: application/json" \
+	-d "{\"email\":\"$email\",\"password\":\"feed-check-password\"}" "$base/api/auth/register"
+
+url=$(curl -fsS -c "$jar" -b "$jar" -H "X-CSRF-Token: $csrf" -X POST "$base/api/calendar/token" |
+	sed -n 's/.*"url":"\([^"]*\)".*/\1/p')
+if [ -z "$url" ]; then
+	echo "no feed URL returned" >&2
+	exit 1
+fi
+
+type=$(curl -fsS -o /dev/null -w '%{content_type}' "$url")
+case "$type" in
+text/calendar*) echo "ok: $url serves $type" ;;
+*)
+	echo "$url serves $type, expected text/calendar" >&2
+	exit 1
+	;;
+esac